@@ -1,6 +1,7 @@
 package accounts
 
 import (
+	"crypto"
 	"errors"
 	"sort"
 	"time"
@@ -13,6 +14,8 @@ var (
 	ErrAccountAlreadyExists = errors.New("account already exists")
 	// ErrProfileIDAlreadyExists is returned when an account is registered by the ProfileID already exists.
 	ErrProfileIDAlreadyExists = errors.New("profileID already exists")
+	// ErrLastAccount is returned when deleting an Account would leave its profile without any Accounts.
+	ErrLastAccount = errors.New("account is the last one in its profile")
 )
 
 // Account is a representation of a user's identifier. It maps
@@ -90,9 +93,15 @@ func Apply(change Change, account Account) Account {
 // are set to the zero value when FillDefaults is called. It returns
 // a copy of the specified Account with those defaults applied.
 func FillDefaults(account Account) Account {
+	return FillDefaultsWithClock(account, nil)
+}
+
+// FillDefaultsWithClock is like FillDefaults, but reads the current time
+// from the passed Clock. If the Clock is nil, the system clock is used.
+func FillDefaultsWithClock(account Account, clock Clock) Account {
 	res := account
 	if res.Created.IsZero() {
-		res.Created = time.Now()
+		res.Created = Now(clock)
 	}
 	if res.LastUsed.IsZero() {
 		res.LastUsed = res.Created
@@ -108,6 +117,31 @@ func FillDefaults(account Account) Account {
 // their own place in every function's signature.
 type Dependencies struct {
 	Storer Storer
+
+	// Clock is used to determine the current time. If it is nil, the
+	// system clock is used.
+	Clock Clock
+
+	// ReceiptSigner signs the receipts returned when profiles are erased.
+	// It must use an Ed25519 key.
+	ReceiptSigner crypto.Signer
+
+	// PendingLinks stores the Accounts waiting to be confirmed by
+	// StartLink and ConfirmLink.
+	PendingLinks PendingLinkStorer
+
+	// Denylist stores the DenyRules that CheckDenylist enforces. If it is
+	// nil, every ID is allowed.
+	Denylist DenylistStorer
+
+	// DomainPolicy decides which email domains CheckEmailDomain allows.
+	// If it is nil, every domain is allowed.
+	DomainPolicy DomainPolicy
+}
+
+// Now returns the current time, according to the Dependencies' Clock.
+func (d Dependencies) Now() time.Time {
+	return Now(d.Clock)
 }
 
 // ByLastUsedDesc sorts the passed slice of Accounts by their LastUsed
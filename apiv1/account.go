@@ -26,6 +26,92 @@ type Change struct {
 	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
 }
 
+// ErasureReceipt is the API representation of an ErasureReceipt. It
+// dictates what the JSON representation of ErasureReceipts will be.
+//
+// It has the same JSON encoding as accounts.ErasureReceipt, so receipts can
+// be decoded into one and checked with its Verify method.
+type ErasureReceipt struct {
+	Version   int       `json:"version"`
+	ProfileID string    `json:"profileID"`
+	Accounts  []string  `json:"accounts"`
+	ErasedAt  time.Time `json:"erasedAt"`
+	Signature []byte    `json:"signature"`
+}
+
+// PendingLink is the API representation of a PendingLink. It dictates what
+// the JSON representation of PendingLinks will be. The token that confirms a
+// PendingLink is never included.
+type PendingLink struct {
+	AccountID string    `json:"accountID"`
+	ProfileID string    `json:"profileID"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// LinkConfirmation is the API representation of a request to confirm a
+// PendingLink.
+type LinkConfirmation struct {
+	Token string `json:"token"`
+}
+
+// DenyRule is the API representation of a DenyRule. It dictates what the
+// JSON representation of DenyRules will be.
+type DenyRule struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	Pattern   string    `json:"pattern"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func coreDenyRule(rule DenyRule) accounts.DenyRule {
+	return accounts.DenyRule{
+		ID:      rule.ID,
+		Kind:    accounts.DenyRuleKind(rule.Kind),
+		Pattern: rule.Pattern,
+		Reason:  rule.Reason,
+		Created: rule.CreatedAt,
+	}
+}
+
+func apiDenyRule(rule accounts.DenyRule) DenyRule {
+	return DenyRule{
+		ID:        rule.ID,
+		Kind:      string(rule.Kind),
+		Pattern:   rule.Pattern,
+		Reason:    rule.Reason,
+		CreatedAt: rule.Created,
+	}
+}
+
+func apiDenyRules(rules []accounts.DenyRule) []DenyRule {
+	res := make([]DenyRule, 0, len(rules))
+	for _, rule := range rules {
+		res = append(res, apiDenyRule(rule))
+	}
+	return res
+}
+
+func apiPendingLink(link accounts.PendingLink) PendingLink {
+	return PendingLink{
+		AccountID: link.AccountID,
+		ProfileID: link.ProfileID,
+		CreatedAt: link.Created,
+		ExpiresAt: link.Expires,
+	}
+}
+
+func apiErasureReceipt(receipt accounts.ErasureReceipt) *ErasureReceipt {
+	return &ErasureReceipt{
+		Version:   receipt.Version,
+		ProfileID: receipt.ProfileID,
+		Accounts:  receipt.Accounts,
+		ErasedAt:  receipt.ErasedAt,
+		Signature: receipt.Signature,
+	}
+}
+
 func coreAccount(account Account) accounts.Account {
 	return accounts.Account{
 		ID:             account.ID,
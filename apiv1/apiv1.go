@@ -3,6 +3,7 @@ package apiv1
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"darlinggo.co/api"
 	yall "yall.in"
@@ -11,6 +12,13 @@ import (
 	"lockbox.dev/sessions"
 )
 
+// TokenVerifier retrieves and verifies the access token a request was
+// made with. It should return a nil token and no error for requests without
+// an access token. sessions.Dependencies is a TokenVerifier.
+type TokenVerifier interface {
+	TokenFromRequest(r *http.Request) (*sessions.AccessToken, error)
+}
+
 // APIv1 holds all the information that we want to
 // be available for all the functions in the API,
 // things like our logging, metrics, and other
@@ -18,7 +26,32 @@ import (
 type APIv1 struct {
 	accounts.Dependencies
 	Log      *yall.Logger
-	Sessions sessions.Dependencies
+	Sessions TokenVerifier
+
+	// Authorizer decides what each access token is allowed to do. If it
+	// is nil, OwnerOnly is used.
+	Authorizer Authorizer
+
+	// FreshAuthWindow is how recently an access token must have been
+	// issued to add Accounts to an existing profile, delete Accounts, or
+	// change Accounts. Older access tokens get a 401 response with the
+	// RequestErrReauthenticate slug. If it is zero, access tokens of any
+	// age are accepted.
+	FreshAuthWindow time.Duration
+
+	// LinkSender delivers the tokens that confirm PendingLinks. If it is
+	// set, Accounts added to an existing profile aren't created until
+	// they're confirmed, and the Dependencies' PendingLinks must be set.
+	// If it is nil, Accounts are added to existing profiles immediately.
+	LinkSender LinkSender
+
+	// LinkTTL is how long PendingLinks can be confirmed for. If it is
+	// zero, accounts.DefaultLinkTTL is used.
+	LinkTTL time.Duration
+
+	// RateLimits throttle requests to create and retrieve Accounts. A
+	// request has to be allowed by every RateLimit to be served.
+	RateLimits []RateLimit
 }
 
 // GetAuthToken returns the access token associated
@@ -48,7 +81,10 @@ func (a APIv1) GetAuthToken(r *http.Request) (*sessions.AccessToken, *Response)
 // Response is used to encode JSON responses; it is
 // the global response format for all API responses.
 type Response struct {
-	Accounts []Account          `json:"accounts,omitempty"`
-	Errors   []api.RequestError `json:"errors,omitempty"`
-	Status   int                `json:"-"`
+	Accounts     []Account          `json:"accounts,omitempty"`
+	PendingLinks []PendingLink      `json:"pendingLinks,omitempty"`
+	Receipt      *ErasureReceipt    `json:"receipt,omitempty"`
+	DenyRules    []DenyRule         `json:"denyRules,omitempty"`
+	Errors       []api.RequestError `json:"errors,omitempty"`
+	Status       int                `json:"-"`
 }
@@ -0,0 +1,175 @@
+package apiv1
+
+import (
+	"context"
+	"net/http"
+
+	"darlinggo.co/api"
+	yall "yall.in"
+
+	"lockbox.dev/accounts"
+	"lockbox.dev/sessions"
+)
+
+// Action is something a request wants to do to a Target.
+type Action string
+
+const (
+	// ActionCreate is adding an Account to an existing profile.
+	ActionCreate Action = "create"
+
+	// ActionGet is retrieving an Account, or everything about a profile.
+	ActionGet Action = "get"
+
+	// ActionList is listing the Accounts of a profile.
+	ActionList Action = "list"
+
+	// ActionDelete is deleting an Account, or erasing a profile.
+	ActionDelete Action = "delete"
+
+	// ActionUpdate is changing an Account.
+	ActionUpdate Action = "update"
+
+	// ActionViewDenylist is listing the DenyRules that keep Accounts
+	// from being created. Its Target is empty.
+	ActionViewDenylist Action = "view_denylist"
+
+	// ActionManageDenylist is adding or removing DenyRules. Its Target
+	// is empty.
+	ActionManageDenylist Action = "manage_denylist"
+)
+
+const (
+	// ScopeReadAny lets an access token get and list the Accounts of
+	// any profile.
+	ScopeReadAny = "accounts:read:any"
+
+	// ScopeAdmin lets an access token do anything to any profile.
+	ScopeAdmin = "accounts:admin"
+)
+
+// Target is what an Action is being done to.
+type Target struct {
+	// ProfileID is the profile the Action affects.
+	ProfileID string
+
+	// Account is the Account the Action is being done to. It is nil when
+	// the Action is being done to the whole profile.
+	Account *accounts.Account
+}
+
+// Authorizer decides whether the holder of an access token is allowed to do
+// something.
+type Authorizer interface {
+	// Authorize returns whether `sess` may do `action` to `target`. An
+	// error means no decision could be made, and the request fails. `sess`
+	// is never nil; requests without a valid access token are rejected
+	// before an Authorizer is consulted.
+	Authorize(ctx context.Context, sess *sessions.AccessToken, action Action, target Target) (bool, error)
+}
+
+// OwnerOnly is the default Authorizer. It only lets access tokens act on
+// the profile they were issued for.
+type OwnerOnly struct{}
+
+// Authorize returns whether `sess` was issued for the profile `target`
+// belongs to.
+func (OwnerOnly) Authorize(_ context.Context, sess *sessions.AccessToken, _ Action, target Target) (bool, error) {
+	return target.ProfileID != "" && sess.ProfileID == target.ProfileID, nil
+}
+
+// Scopes is an Authorizer that lets access tokens act on profiles other
+// than their own if they have a scope allowing it, so trusted services can
+// work with any Account. ScopeReadAny allows ActionGet and ActionList, and
+// ScopeAdmin allows every Action. Everything else is left to Fallback.
+//
+// Every request allowed on another profile because of a scope is logged,
+// with the access token's client ID, using the logger in the request's
+// context.
+//
+// Scopes is only used if it's set as APIv1.Authorizer.
+type Scopes struct {
+	// Fallback decides requests the access token's scopes don't allow.
+	// If it is nil, OwnerOnly is used.
+	Fallback Authorizer
+}
+
+// grantingScope returns the scope in `sess` that allows `action` on any
+// profile, or an empty string if none do.
+func grantingScope(sess *sessions.AccessToken, action Action) string {
+	for _, scope := range sess.Scopes {
+		switch {
+		case scope == ScopeAdmin:
+			return scope
+		case scope == ScopeReadAny && (action == ActionGet || action == ActionList):
+			return scope
+		}
+	}
+	return ""
+}
+
+// Authorize returns whether `sess` may do `action` to `target`, either
+// because of its scopes or because Fallback allows it.
+func (s Scopes) Authorize(ctx context.Context, sess *sessions.AccessToken, action Action, target Target) (bool, error) {
+	fallback := s.Fallback
+	if fallback == nil {
+		fallback = OwnerOnly{}
+	}
+	scope := grantingScope(sess, action)
+	// targets without a profile, like the denylist, are never the access
+	// token's own, even if the access token has no profile either
+	if scope == "" || (target.ProfileID != "" && sess.ProfileID == target.ProfileID) {
+		return fallback.Authorize(ctx, sess, action, target)
+	}
+	yall.FromContext(ctx).WithField("client_id", sess.ClientID).WithField("scope", scope).
+		WithField("action", string(action)).WithField("profile_id", target.ProfileID).
+		WithField("token_profile_id", sess.ProfileID).Info("Allowing cross-profile access")
+	return true, nil
+}
+
+func (a APIv1) authorizer() Authorizer { //nolint:ireturn // returning the configured interface
+	if a.Authorizer == nil {
+		return OwnerOnly{}
+	}
+	return a.Authorizer
+}
+
+// authenticate returns the access token `r` was made with, or the Response
+// to render if it doesn't have a valid one.
+func (a APIv1) authenticate(r *http.Request) (*sessions.AccessToken, *Response) {
+	sess, resp := a.GetAuthToken(r)
+	if resp != nil {
+		return nil, resp
+	}
+	if sess == nil {
+		return nil, &Response{
+			Status: http.StatusUnauthorized,
+			Errors: []api.RequestError{
+				{Header: "Authorization", Slug: api.RequestErrAccessDenied},
+			},
+		}
+	}
+	return sess, nil
+}
+
+// authorize returns whether `sess` may do `action` to `target`, or the
+// Response to render if the APIv1's Authorizer couldn't decide or `sess` is
+// too old to do `action` with.
+func (a APIv1) authorize(r *http.Request, sess *sessions.AccessToken, action Action, target Target) (bool, *Response) {
+	ok, err := a.authorizer().Authorize(r.Context(), sess, action, target)
+	if err != nil {
+		yall.FromContext(r.Context()).WithField("action", string(action)).WithField("profile_id", target.ProfileID).
+			WithError(err).Error("Error authorizing request")
+		return false, &Response{Status: http.StatusInternalServerError, Errors: api.ActOfGodError}
+	}
+	if !ok {
+		// only tell requesters their access token is too old if it
+		// would otherwise be allowed, so the freshness check can't be
+		// used to find out who is registered
+		return false, nil
+	}
+	if resp := a.checkFreshAuth(r, sess, action); resp != nil {
+		return false, resp
+	}
+	return true, nil
+}
@@ -0,0 +1,196 @@
+package apiv1_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"lockbox.dev/accounts"
+	"lockbox.dev/accounts/apiv1"
+	"lockbox.dev/sessions"
+)
+
+// authorizerFunc is an Authorizer that calls itself.
+type authorizerFunc func(sess *sessions.AccessToken, action apiv1.Action, target apiv1.Target) (bool, error)
+
+func (f authorizerFunc) Authorize(_ context.Context, sess *sessions.AccessToken, action apiv1.Action, target apiv1.Target) (bool, error) {
+	return f(sess, action, target)
+}
+
+func TestOwnerOnly(t *testing.T) {
+	t.Parallel()
+
+	account := accounts.Account{ID: "paddy@impractical.co", ProfileID: "paddy"}
+	tests := map[string]struct {
+		sess   sessions.AccessToken
+		target apiv1.Target
+		want   bool
+	}{
+		"owner":         {sess: sessions.AccessToken{ProfileID: "paddy"}, target: apiv1.Target{ProfileID: "paddy", Account: &account}, want: true},
+		"owner-profile": {sess: sessions.AccessToken{ProfileID: "paddy"}, target: apiv1.Target{ProfileID: "paddy"}, want: true},
+		"someone-else":  {sess: sessions.AccessToken{ProfileID: "sam"}, target: apiv1.Target{ProfileID: "paddy", Account: &account}, want: false},
+		"empty-profile": {sess: sessions.AccessToken{}, target: apiv1.Target{}, want: false},
+	}
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			for _, action := range []apiv1.Action{apiv1.ActionCreate, apiv1.ActionGet, apiv1.ActionList, apiv1.ActionDelete, apiv1.ActionUpdate} {
+				got, err := apiv1.OwnerOnly{}.Authorize(context.Background(), &test.sess, action, test.target)
+				if err != nil {
+					t.Fatalf("Unexpected error: %+v\n", err)
+				}
+				if got != test.want {
+					t.Errorf("Expected %s to be %v, got %v", action, test.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestScopes(t *testing.T) {
+	t.Parallel()
+
+	account := accounts.Account{ID: "paddy@impractical.co", ProfileID: "paddy"}
+	target := apiv1.Target{ProfileID: "paddy", Account: &account}
+	tests := map[string]struct {
+		scopes []string
+		want   map[apiv1.Action]bool
+	}{
+		"none": {},
+		"unrelated": {
+			scopes: []string{"sessions:admin"},
+		},
+		"read-any": {
+			scopes: []string{apiv1.ScopeReadAny},
+			want:   map[apiv1.Action]bool{apiv1.ActionGet: true, apiv1.ActionList: true},
+		},
+		"admin": {
+			scopes: []string{"sessions:admin", apiv1.ScopeAdmin},
+			want: map[apiv1.Action]bool{
+				apiv1.ActionCreate: true, apiv1.ActionGet: true, apiv1.ActionList: true,
+				apiv1.ActionDelete: true, apiv1.ActionUpdate: true,
+			},
+		},
+	}
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			sess := sessions.AccessToken{ProfileID: "login-service", ClientID: "login", Scopes: test.scopes}
+			for _, action := range []apiv1.Action{apiv1.ActionCreate, apiv1.ActionGet, apiv1.ActionList, apiv1.ActionDelete, apiv1.ActionUpdate} {
+				got, err := apiv1.Scopes{}.Authorize(context.Background(), &sess, action, target)
+				if err != nil {
+					t.Fatalf("Unexpected error: %+v\n", err)
+				}
+				if got != test.want[action] {
+					t.Errorf("Expected %s to be %v, got %v", action, test.want[action], got)
+				}
+			}
+		})
+	}
+}
+
+func TestScopesFallback(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	target := apiv1.Target{ProfileID: "paddy"}
+	owner := sessions.AccessToken{ProfileID: "paddy"}
+	ok, err := apiv1.Scopes{}.Authorize(ctx, &owner, apiv1.ActionDelete, target)
+	if err != nil || !ok {
+		t.Errorf("Expected the owner to be allowed by the default fallback, got %v, %v", ok, err)
+	}
+
+	denying := authorizerFunc(func(_ *sessions.AccessToken, _ apiv1.Action, _ apiv1.Target) (bool, error) {
+		return false, nil
+	})
+	ok, err = apiv1.Scopes{Fallback: denying}.Authorize(ctx, &owner, apiv1.ActionDelete, target)
+	if err != nil || ok {
+		t.Errorf("Expected the fallback to deny the owner, got %v, %v", ok, err)
+	}
+	service := sessions.AccessToken{Scopes: []string{apiv1.ScopeAdmin}}
+	ok, err = apiv1.Scopes{}.Authorize(ctx, &service, apiv1.ActionManageDenylist, apiv1.Target{})
+	if err != nil || !ok {
+		t.Errorf("Expected the scope to allow a token without a profile ID to act on a target without one, got %v, %v", ok, err)
+	}
+	reader := sessions.AccessToken{ProfileID: "login-service", Scopes: []string{apiv1.ScopeReadAny}}
+	ok, err = apiv1.Scopes{Fallback: denying}.Authorize(ctx, &reader, apiv1.ActionGet, target)
+	if err != nil || !ok {
+		t.Errorf("Expected the scope to allow access regardless of the fallback, got %v, %v", ok, err)
+	}
+}
+
+func TestAuthorizationErrorPaths(t *testing.T) {
+	t.Parallel()
+
+	failing := authorizerFunc(func(_ *sessions.AccessToken, _ apiv1.Action, _ apiv1.Target) (bool, error) {
+		return false, errors.New("policy unavailable") //nolint:goerr113 // test error
+	})
+	denying := authorizerFunc(func(_ *sessions.AccessToken, _ apiv1.Action, _ apiv1.Target) (bool, error) {
+		return false, nil
+	})
+	admin := authorizerFunc(func(sess *sessions.AccessToken, _ apiv1.Action, target apiv1.Target) (bool, error) {
+		return sess.ProfileID == "admin" || sess.ProfileID == target.ProfileID, nil
+	})
+	addAccount := `{"id":"paddy@carvers.co","profileID":"paddy"}`
+
+	tests := map[string]struct {
+		authorizer apiv1.Authorizer
+		route      string
+		token      string
+		body       string
+		wantStatus int
+		wantCreate bool
+	}{
+		"create-invalid-token": {route: "POST /", token: "invalid", body: addAccount, wantStatus: http.StatusUnauthorized},
+		"create-broken-token":  {route: "POST /", token: "broken", body: addAccount, wantStatus: http.StatusInternalServerError},
+		"create-no-token":      {route: "POST /", body: addAccount, wantStatus: http.StatusUnauthorized},
+		"create-someone-else":  {route: "POST /", token: "sam", body: addAccount, wantStatus: http.StatusForbidden},
+		"create-owner":         {route: "POST /", token: "paddy", body: addAccount, wantStatus: http.StatusCreated, wantCreate: true},
+		"create-failing":       {authorizer: failing, route: "POST /", token: "paddy", body: addAccount, wantStatus: http.StatusInternalServerError},
+		"get-failing":          {authorizer: failing, route: "GET /paddy@impractical.co", token: "paddy", wantStatus: http.StatusInternalServerError},
+		"get-denied":           {authorizer: denying, route: "GET /paddy@impractical.co", token: "paddy", wantStatus: http.StatusNotFound},
+		"get-admin":            {authorizer: admin, route: "GET /paddy@impractical.co", token: "admin", wantStatus: http.StatusOK},
+		"list-invalid-token":   {route: "GET /?profileID=paddy", token: "invalid", wantStatus: http.StatusUnauthorized},
+		"list-denied":          {authorizer: denying, route: "GET /?profileID=paddy", token: "paddy", wantStatus: http.StatusForbidden},
+		"list-admin":           {authorizer: admin, route: "GET /?profileID=paddy", token: "admin", wantStatus: http.StatusOK},
+		"delete-broken-token":  {route: "DELETE /paddy@impractical.co", token: "broken", wantStatus: http.StatusInternalServerError},
+		"delete-failing":       {authorizer: failing, route: "DELETE /paddy@impractical.co", token: "paddy", wantStatus: http.StatusInternalServerError},
+		"erase-invalid-token":  {route: "DELETE /profiles/paddy", token: "invalid", wantStatus: http.StatusUnauthorized},
+		"erase-someone-else":   {route: "DELETE /profiles/paddy", token: "sam", wantStatus: http.StatusForbidden},
+		"export-broken-token":  {route: "GET /profiles/paddy/export", token: "broken", wantStatus: http.StatusInternalServerError},
+		"export-failing":       {authorizer: failing, route: "GET /profiles/paddy/export", token: "paddy", wantStatus: http.StatusInternalServerError},
+		"get-read-any":         {authorizer: apiv1.Scopes{}, route: "GET /paddy@impractical.co", token: "login " + apiv1.ScopeReadAny, wantStatus: http.StatusOK},
+		"list-read-any":        {authorizer: apiv1.Scopes{}, route: "GET /?profileID=paddy", token: "login " + apiv1.ScopeReadAny, wantStatus: http.StatusOK},
+		"delete-read-any":      {authorizer: apiv1.Scopes{}, route: "DELETE /paddy@impractical.co", token: "login " + apiv1.ScopeReadAny, wantStatus: http.StatusNotFound},
+		"create-read-any":      {authorizer: apiv1.Scopes{}, route: "POST /", token: "login " + apiv1.ScopeReadAny, body: addAccount, wantStatus: http.StatusForbidden},
+		"create-admin":         {authorizer: apiv1.Scopes{}, route: "POST /", token: "login " + apiv1.ScopeAdmin, body: addAccount, wantStatus: http.StatusCreated, wantCreate: true},
+		"get-default-read-any": {route: "GET /paddy@impractical.co", token: "login " + apiv1.ScopeReadAny, wantStatus: http.StatusNotFound},
+		"create-default-admin": {route: "POST /", token: "login " + apiv1.ScopeAdmin, body: addAccount, wantStatus: http.StatusForbidden},
+	}
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newTestAPIWithAuthorizer(t, test.authorizer)
+			resp := client.doBody(t, test.route, test.token, test.body)
+			if resp.status != test.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", test.wantStatus, resp.status, resp.body)
+			}
+			var created bool
+			for _, call := range resp.calls {
+				if call == "Create" {
+					created = true
+				}
+			}
+			if created != test.wantCreate {
+				t.Errorf("Expected account to be created to be %v, got %v", test.wantCreate, created)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package apiv1
+
+import (
+	"errors"
+	"net/http"
+
+	"darlinggo.co/api"
+	"darlinggo.co/trout/v2"
+	yall "yall.in"
+
+	"lockbox.dev/accounts"
+)
+
+// RequestErrDeniedID is the slug used in the errors of responses to requests
+// to create an Account whose ID matches a DenyRule. Which DenyRule matched,
+// and why it exists, is only logged.
+const RequestErrDeniedID = "denied"
+
+// authorizeDenylist returns the Response to render if the request isn't
+// allowed to do `action` to the denylist, or nil if it is.
+func (a APIv1) authorizeDenylist(r *http.Request, action Action) *Response {
+	sess, resp := a.authenticate(r)
+	if resp != nil {
+		return resp
+	}
+	allowed, resp := a.authorize(r, sess, action, Target{})
+	if resp != nil {
+		return resp
+	}
+	if !allowed {
+		return &Response{
+			Status: http.StatusForbidden,
+			Errors: []api.RequestError{
+				{Header: "Authorization", Slug: api.RequestErrAccessDenied},
+			},
+		}
+	}
+	return nil
+}
+
+func (a APIv1) handleListDenyRules(w http.ResponseWriter, r *http.Request) {
+	if resp := a.authorizeDenylist(r, ActionViewDenylist); resp != nil {
+		api.Encode(w, r, resp.Status, resp)
+		return
+	}
+	rules, err := a.ListDenyRules(r.Context())
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Error("Error listing deny rules")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	api.Encode(w, r, http.StatusOK, Response{DenyRules: apiDenyRules(rules)})
+}
+
+func (a APIv1) handleCreateDenyRule(w http.ResponseWriter, r *http.Request) {
+	var body DenyRule
+	err := api.Decode(r, &body)
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Debug("Error decoding request body")
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: api.InvalidFormatError})
+		return
+	}
+	var reqErrs []api.RequestError
+	if body.Kind == "" {
+		reqErrs = append(reqErrs, api.RequestError{Field: "/kind", Slug: api.RequestErrMissing})
+	}
+	if body.Pattern == "" {
+		reqErrs = append(reqErrs, api.RequestError{Field: "/pattern", Slug: api.RequestErrMissing})
+	}
+	if len(reqErrs) > 0 {
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: reqErrs})
+		return
+	}
+	if resp := a.authorizeDenylist(r, ActionManageDenylist); resp != nil {
+		api.Encode(w, r, resp.Status, resp)
+		return
+	}
+	rule := coreDenyRule(body)
+	switch rule.Kind {
+	case accounts.DenyRuleExact, accounts.DenyRuleSuffix, accounts.DenyRuleRegex:
+	default:
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/kind", Slug: api.RequestErrInvalidValue}}})
+		return
+	}
+	rule, err = a.AddDenyRule(r.Context(), rule)
+	if errors.Is(err, accounts.ErrInvalidDenyRule) {
+		yall.FromContext(r.Context()).WithError(err).Debug("Invalid deny rule")
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/pattern", Slug: api.RequestErrInvalidValue}}})
+		return
+	}
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Error("Error creating deny rule")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	yall.FromContext(r.Context()).WithField("deny_rule_id", rule.ID).WithField("kind", string(rule.Kind)).Info("Deny rule created")
+	api.Encode(w, r, http.StatusCreated, Response{DenyRules: []DenyRule{apiDenyRule(rule)}})
+}
+
+func (a APIv1) handleDeleteDenyRule(w http.ResponseWriter, r *http.Request) {
+	vars := trout.RequestVars(r)
+	id := vars.Get("ruleID")
+	if id == "" {
+		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "ruleID", Slug: api.RequestErrNotFound}}})
+		return
+	}
+	if resp := a.authorizeDenylist(r, ActionManageDenylist); resp != nil {
+		api.Encode(w, r, resp.Status, resp)
+		return
+	}
+	err := a.RemoveDenyRule(r.Context(), id)
+	if errors.Is(err, accounts.ErrDenyRuleNotFound) {
+		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "ruleID", Slug: api.RequestErrNotFound}}})
+		return
+	}
+	if err != nil {
+		yall.FromContext(r.Context()).WithField("deny_rule_id", id).WithError(err).Error("Error deleting deny rule")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	yall.FromContext(r.Context()).WithField("deny_rule_id", id).Info("Deny rule deleted")
+	api.Encode(w, r, http.StatusOK, Response{})
+}
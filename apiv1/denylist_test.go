@@ -0,0 +1,109 @@
+package apiv1_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"lockbox.dev/accounts"
+	"lockbox.dev/accounts/apiv1"
+	"lockbox.dev/accounts/storers/memory"
+)
+
+func TestDenylist(t *testing.T) {
+	t.Parallel()
+
+	storer, err := memory.NewStorer()
+	if err != nil {
+		t.Fatalf("Error creating storer: %+v\n", err)
+	}
+	err = storer.Create(context.Background(), accounts.Account{ID: "paddy@impractical.co", ProfileID: "paddy", IsRegistration: true})
+	if err != nil {
+		t.Fatalf("Error creating account: %+v\n", err)
+	}
+	api := apiv1.APIv1{
+		Dependencies: accounts.Dependencies{Storer: storer, Denylist: storer},
+		Sessions:     bearerProfiles{},
+		Authorizer:   apiv1.Scopes{},
+	}
+	client := testClient{storer: &recordingStorer{Storer: storer}, handler: api.Server("/")}
+	admin := "admin " + apiv1.ScopeAdmin
+
+	// only admins can manage the denylist
+	resp := client.doBody(t, "POST /admin/denylist", "paddy", `{"kind":"exact","pattern":"admin"}`)
+	if resp.status != http.StatusForbidden {
+		t.Errorf("Expected status %d adding a rule without the admin scope, got %d: %s", http.StatusForbidden, resp.status, resp.body)
+	}
+	resp = client.do(t, "GET /admin/denylist", "paddy "+apiv1.ScopeReadAny)
+	if resp.status != http.StatusForbidden {
+		t.Errorf("Expected status %d listing rules without the admin scope, got %d: %s", http.StatusForbidden, resp.status, resp.body)
+	}
+
+	for body, field := range map[string]string{
+		`{"kind":"prefix","pattern":"admin"}`: "/kind",
+		`{"kind":"regex","pattern":"(ad"}`:    "/pattern",
+		`{"kind":"exact"}`:                    "/pattern",
+	} {
+		resp = client.doBody(t, "POST /admin/denylist", admin, body)
+		if resp.status != http.StatusBadRequest || !strings.Contains(resp.body, `"`+field+`"`) {
+			t.Errorf("Expected an error on %s adding %s, got status %d: %s", field, body, resp.status, resp.body)
+		}
+	}
+
+	var ruleIDs []string
+	for _, body := range []string{
+		`{"kind":"exact","pattern":"admin","reason":"reserved"}`,
+		`{"kind":"suffix","pattern":"@lockbox.dev"}`,
+		`{"kind":"regex","pattern":"^support"}`,
+	} {
+		resp = client.doBody(t, "POST /admin/denylist", admin, body)
+		if resp.status != http.StatusCreated {
+			t.Fatalf("Expected status %d adding %s, got %d: %s", http.StatusCreated, body, resp.status, resp.body)
+		}
+		var created apiv1.Response
+		err = json.Unmarshal([]byte(resp.body), &created)
+		if err != nil {
+			t.Fatalf("Error decoding response: %+v\n", err)
+		}
+		if len(created.DenyRules) != 1 || created.DenyRules[0].ID == "" {
+			t.Fatalf("Expected the created rule in the response, got %s", resp.body)
+		}
+		ruleIDs = append(ruleIDs, created.DenyRules[0].ID)
+	}
+	// service tokens often have no profile ID
+	for _, token := range []string{admin, "- " + apiv1.ScopeAdmin} {
+		resp = client.do(t, "GET /admin/denylist", token)
+		if resp.status != http.StatusOK || strings.Count(resp.body, `"pattern"`) != len(ruleIDs) {
+			t.Errorf("Expected %d rules to be listed with %q, got status %d: %s", len(ruleIDs), token, resp.status, resp.body)
+		}
+	}
+
+	for _, id := range []string{"Admin", "paddy@LockBox.dev", "support@impractical.co"} {
+		resp = client.doBody(t, "POST /", "", `{"id":"`+id+`","isRegistration":true}`)
+		if resp.status != http.StatusBadRequest || !strings.Contains(resp.body, apiv1.RequestErrDeniedID) {
+			t.Errorf("Expected %q to be denied, got status %d: %s", id, resp.status, resp.body)
+		}
+		if strings.Contains(resp.body, "reserved") {
+			t.Errorf("Expected the rule's reason not to be shown, got %s", resp.body)
+		}
+	}
+	resp = client.doBody(t, "POST /", "", `{"id":"paddy@carvers.co","isRegistration":true}`)
+	if resp.status != http.StatusCreated {
+		t.Errorf("Expected status %d registering an ID no rule matches, got %d: %s", http.StatusCreated, resp.status, resp.body)
+	}
+
+	resp = client.do(t, "DELETE /admin/denylist/"+ruleIDs[0], admin)
+	if resp.status != http.StatusOK {
+		t.Fatalf("Expected status %d deleting a rule, got %d: %s", http.StatusOK, resp.status, resp.body)
+	}
+	resp = client.do(t, "DELETE /admin/denylist/"+ruleIDs[0], admin)
+	if resp.status != http.StatusNotFound {
+		t.Errorf("Expected status %d deleting a deleted rule, got %d: %s", http.StatusNotFound, resp.status, resp.body)
+	}
+	resp = client.doBody(t, "POST /", "", `{"id":"admin","isRegistration":true}`)
+	if resp.status != http.StatusCreated {
+		t.Errorf("Expected status %d registering an ID once its rule is deleted, got %d: %s", http.StatusCreated, resp.status, resp.body)
+	}
+}
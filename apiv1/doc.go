@@ -7,7 +7,40 @@
 // The lockbox.dev/sessions package is used to authenticate a JWT bearer token
 // for deleting Accounts, retrieving a specific Account, adding new Accounts to
 // an existing profile, or listing Accounts associated with a profile. The
-// bearer token's AccountID will be used as an Account's ID, and that Account's
-// ProfileID must match the ProfileID of the Accounts being acted on or the
-// profile Accounts are being listed for.
+// bearer token's AccountID will be used as an Account's ID, and by default,
+// that Account's ProfileID must match the ProfileID of the Accounts being
+// acted on or the profile Accounts are being listed for. Other policies can
+// be used by setting APIv1.Authorizer; setting it to Scopes also allows bearer
+// tokens with the accounts:read:any or accounts:admin scope to act on other
+// profiles.
+//
+// Setting APIv1.FreshAuthWindow requires access tokens used to add Accounts
+// to an existing profile, delete Accounts, or erase a profile to have been
+// issued recently, so a stolen long-lived access token can't be used to take
+// over a profile. Requests with older access tokens get a 401 response with
+// the reauthentication_required slug.
+//
+// Deleting the only Account associated with a profile would leave the
+// profile unreachable, so it is refused with the last_account slug unless
+// the allowLastAccount query parameter is set to true.
+//
+// If APIv1.LinkSender is set, Accounts added to an existing profile aren't
+// created right away. A pending link is created instead, and the
+// LinkSender sends its token to the new Account's ID. The Account is created
+// when the token is posted to /links/confirm, proving control of the ID.
+//
+// If the Dependencies' Denylist is set, Accounts can't be created with IDs
+// matching any of its deny rules; requests to create them get a 400 response
+// with the denied slug. When APIv1.Authorizer is Scopes, access tokens with
+// the accounts:admin scope can list deny rules with GET /admin/denylist, add
+// them by posting their kind, pattern, and reason to /admin/denylist, and
+// remove them with DELETE /admin/denylist/{ruleID}.
+//
+// If the Dependencies' DomainPolicy is set, Accounts whose IDs are email
+// addresses can only be created at domains it allows; requests to create
+// others get a 400 response with the domain_not_allowed slug.
+//
+// Requests to add and retrieve Accounts can be rate limited by setting
+// APIv1.RateLimits. Requests over a limit get a 429 response with a
+// Retry-After header.
 package apiv1
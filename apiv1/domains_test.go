@@ -0,0 +1,61 @@
+package apiv1_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"lockbox.dev/accounts"
+	"lockbox.dev/accounts/apiv1"
+	"lockbox.dev/accounts/storers/memory"
+)
+
+func TestCreateAccountDomainPolicy(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "blocklist")
+	err := os.WriteFile(path, []byte("mailinator.com\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing blocklist: %+v\n", err)
+	}
+	policy, err := accounts.NewFileDomainPolicy("", path)
+	if err != nil {
+		t.Fatalf("Error creating domain policy: %+v\n", err)
+	}
+	storer, err := memory.NewStorer()
+	if err != nil {
+		t.Fatalf("Error creating storer: %+v\n", err)
+	}
+	api := apiv1.APIv1{
+		Dependencies: accounts.Dependencies{Storer: storer, DomainPolicy: policy},
+		Sessions:     bearerProfiles{},
+	}
+	client := testClient{storer: &recordingStorer{Storer: storer}, handler: api.Server("/")}
+
+	resp := client.doBody(t, "POST /", "", `{"id":"paddy@eu.Mailinator.com","isRegistration":true}`)
+	if resp.status != http.StatusBadRequest || !strings.Contains(resp.body, apiv1.RequestErrDomainNotAllowed) {
+		t.Errorf("Expected a blocked domain to be rejected, got status %d: %s", resp.status, resp.body)
+	}
+	if len(resp.calls) > 0 {
+		t.Errorf("Expected no storer calls for a blocked domain, got %v", resp.calls)
+	}
+	resp = client.doBody(t, "POST /", "", `{"id":"paddy@impractical.co","isRegistration":true}`)
+	if resp.status != http.StatusCreated {
+		t.Errorf("Expected status %d registering an allowed domain, got %d: %s", http.StatusCreated, resp.status, resp.body)
+	}
+
+	err = os.WriteFile(path, []byte("impractical.co\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing blocklist: %+v\n", err)
+	}
+	err = policy.Reload()
+	if err != nil {
+		t.Fatalf("Error reloading domain policy: %+v\n", err)
+	}
+	resp = client.doBody(t, "POST /", "", `{"id":"paddy@mailinator.com","isRegistration":true}`)
+	if resp.status != http.StatusCreated {
+		t.Errorf("Expected status %d once the domain is unblocked, got %d: %s", http.StatusCreated, resp.status, resp.body)
+	}
+}
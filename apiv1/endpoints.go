@@ -32,13 +32,25 @@ func (a APIv1) Server(baseURL string) http.Handler {
 	var router trout.Router
 	router.SetPrefix(baseURL)
 	router.Endpoint("/").Methods("POST").
-		Handler(logEndpoint(http.HandlerFunc(a.handleCreateAccount)))
+		Handler(logEndpoint(a.rateLimited(http.HandlerFunc(a.handleCreateAccount))))
 	router.Endpoint("/").Methods("GET").
 		Handler(logEndpoint(http.HandlerFunc(a.handleListAccounts)))
 	router.Endpoint("/{id}").Methods("GET").
-		Handler(logEndpoint(http.HandlerFunc(a.handleGetAccount)))
+		Handler(logEndpoint(a.rateLimited(http.HandlerFunc(a.handleGetAccount))))
 	router.Endpoint("/{id}").Methods("DELETE").
 		Handler(logEndpoint(http.HandlerFunc(a.handleDeleteAccount)))
+	router.Endpoint("/links/confirm").Methods("POST").
+		Handler(logEndpoint(a.rateLimited(http.HandlerFunc(a.handleConfirmLink))))
+	router.Endpoint("/profiles/{profileID}").Methods("DELETE").
+		Handler(logEndpoint(http.HandlerFunc(a.handleEraseProfile)))
+	router.Endpoint("/profiles/{profileID}/export").Methods("GET").
+		Handler(logEndpoint(http.HandlerFunc(a.handleExportProfile)))
+	router.Endpoint("/admin/denylist").Methods("GET").
+		Handler(logEndpoint(http.HandlerFunc(a.handleListDenyRules)))
+	router.Endpoint("/admin/denylist").Methods("POST").
+		Handler(logEndpoint(http.HandlerFunc(a.handleCreateDenyRule)))
+	router.Endpoint("/admin/denylist/{ruleID}").Methods("DELETE").
+		Handler(logEndpoint(http.HandlerFunc(a.handleDeleteDenyRule)))
 
 	return api.NegotiateMiddleware(router)
 }
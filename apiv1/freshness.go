@@ -0,0 +1,44 @@
+package apiv1
+
+import (
+	"net/http"
+
+	"darlinggo.co/api"
+	yall "yall.in"
+
+	"lockbox.dev/sessions"
+)
+
+// RequestErrReauthenticate is the slug used in the errors of responses to
+// requests that need an access token issued more recently than the one they
+// were made with. Clients should have the user authenticate again, then
+// retry the request with the new access token.
+const RequestErrReauthenticate = "reauthentication_required"
+
+// needsFreshAuth returns whether `action` changes which Accounts a profile
+// has or can have, and so can only be done with an access token issued
+// within the APIv1's FreshAuthWindow.
+func needsFreshAuth(action Action) bool {
+	return action != ActionGet && action != ActionList && action != ActionViewDenylist
+}
+
+// checkFreshAuth returns the Response to render if `action` needs an access
+// token issued within the APIv1's FreshAuthWindow and `sess` is older than
+// that, or nil otherwise.
+func (a APIv1) checkFreshAuth(r *http.Request, sess *sessions.AccessToken, action Action) *Response {
+	if a.FreshAuthWindow <= 0 || !needsFreshAuth(action) {
+		return nil
+	}
+	age := a.Now().Sub(sess.CreatedAt)
+	if !sess.CreatedAt.IsZero() && age <= a.FreshAuthWindow {
+		return nil
+	}
+	yall.FromContext(r.Context()).WithField("action", string(action)).WithField("token_age", age.String()).
+		WithField("client_id", sess.ClientID).Info("Access token too old for action")
+	return &Response{
+		Status: http.StatusUnauthorized,
+		Errors: []api.RequestError{
+			{Header: "Authorization", Slug: RequestErrReauthenticate},
+		},
+	}
+}
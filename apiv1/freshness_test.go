@@ -0,0 +1,89 @@
+package apiv1_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"lockbox.dev/accounts"
+	"lockbox.dev/accounts/apiv1"
+	"lockbox.dev/accounts/storers/memory"
+	"lockbox.dev/sessions"
+)
+
+// issuedAt is a TokenVerifier that returns an access token for the "paddy"
+// profile, issued at the time in the bearer token.
+type issuedAt struct{}
+
+func (issuedAt) TokenFromRequest(r *http.Request) (*sessions.AccessToken, error) {
+	created, err := time.Parse(time.RFC3339, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	if err != nil {
+		return nil, sessions.ErrInvalidToken
+	}
+	return &sessions.AccessToken{ProfileID: "paddy", CreatedAt: created}, nil
+}
+
+func TestFreshAuthWindow(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2022, time.March, 14, 15, 9, 26, 0, time.UTC)
+	fresh := now.Add(-4 * time.Minute).Format(time.RFC3339)
+	stale := now.Add(-6 * time.Minute).Format(time.RFC3339)
+
+	tests := map[string]struct {
+		route      string
+		token      string
+		body       string
+		wantStatus int
+	}{
+		"link-fresh":          {route: "POST /", token: fresh, body: `{"id":"paddy@carvers.co","profileID":"paddy"}`, wantStatus: http.StatusCreated},
+		"link-stale":          {route: "POST /", token: stale, body: `{"id":"paddy@carvers.co","profileID":"paddy"}`, wantStatus: http.StatusUnauthorized},
+		"register-stale":      {route: "POST /", token: stale, body: `{"id":"paddy@carvers.co","isRegistration":true}`, wantStatus: http.StatusCreated},
+		"delete-fresh":        {route: "DELETE /paddy@impractical.co", token: fresh, wantStatus: http.StatusOK},
+		"delete-stale":        {route: "DELETE /paddy@impractical.co", token: stale, wantStatus: http.StatusUnauthorized},
+		"delete-missing":      {route: "DELETE /nobody@impractical.co", token: stale, wantStatus: http.StatusNotFound},
+		"erase-stale":         {route: "DELETE /profiles/paddy", token: stale, wantStatus: http.StatusUnauthorized},
+		"get-stale":           {route: "GET /paddy@impractical.co", token: stale, wantStatus: http.StatusOK},
+		"list-stale":          {route: "GET /?profileID=paddy", token: stale, wantStatus: http.StatusOK},
+		"unknown-issued-time": {route: "DELETE /paddy@impractical.co", token: time.Time{}.Format(time.RFC3339), wantStatus: http.StatusUnauthorized},
+	}
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			storer, err := memory.NewStorer()
+			if err != nil {
+				t.Fatalf("Error creating storer: %+v\n", err)
+			}
+			for _, account := range []accounts.Account{
+				{ID: "paddy@impractical.co", ProfileID: "paddy", IsRegistration: true},
+				{ID: "paddycarver", ProfileID: "paddy"},
+			} {
+				err = storer.Create(context.Background(), account)
+				if err != nil {
+					t.Fatalf("Error creating account: %+v\n", err)
+				}
+			}
+			api := apiv1.APIv1{
+				Dependencies:    accounts.Dependencies{Storer: storer, Clock: accounts.NewFakeClock(now)},
+				Sessions:        issuedAt{},
+				FreshAuthWindow: 5 * time.Minute,
+			}
+			parts := strings.SplitN(test.route, " ", 2) //nolint:gomnd // method and path
+			req := httptest.NewRequest(parts[0], parts[1], strings.NewReader(test.body))
+			req.Header.Set("Authorization", "Bearer "+test.token)
+			w := httptest.NewRecorder()
+			api.Server("/").ServeHTTP(w, req)
+			if w.Code != test.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", test.wantStatus, w.Code, w.Body.String())
+			}
+			if test.wantStatus == http.StatusUnauthorized && !strings.Contains(w.Body.String(), apiv1.RequestErrReauthenticate) {
+				t.Errorf("Expected %q error, got %s", apiv1.RequestErrReauthenticate, w.Body.String())
+			}
+		})
+	}
+}
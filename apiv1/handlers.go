@@ -1,8 +1,11 @@
 package apiv1
 
 import (
+	"bytes"
 	"errors"
 	"net/http"
+	"net/url"
+	"strconv"
 
 	"darlinggo.co/api"
 	"darlinggo.co/trout/v2"
@@ -21,7 +24,7 @@ func (a APIv1) handleCreateAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	account := coreAccount(body)
-	account = accounts.FillDefaults(account)
+	account = accounts.FillDefaultsWithClock(account, a.Clock)
 	var reqErrs []api.RequestError
 	if account.ID == "" {
 		reqErrs = append(reqErrs, api.RequestError{Field: "/id", Slug: api.RequestErrMissing})
@@ -33,11 +36,37 @@ func (a APIv1) handleCreateAccount(w http.ResponseWriter, r *http.Request) {
 		api.Encode(w, r, http.StatusBadRequest, reqErrs)
 		return
 	}
+	err = a.CheckDenylist(r.Context(), account.ID)
+	if errors.Is(err, accounts.ErrAccountIDDenied) {
+		yall.FromContext(r.Context()).WithError(err).Info("Account ID denied")
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/id", Slug: RequestErrDeniedID}}})
+		return
+	}
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Error("Error checking denylist")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	err = a.CheckEmailDomain(r.Context(), account.ID)
+	if errors.Is(err, accounts.ErrEmailDomainNotAllowed) {
+		yall.FromContext(r.Context()).WithError(err).Info("Email domain not allowed")
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/id", Slug: RequestErrDomainNotAllowed}}})
+		return
+	}
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Error("Error checking email domain")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
 	if account.ProfileID != "" {
 		if resp := a.validateAddingAccountToProfile(r, account); resp != nil {
 			api.Encode(w, r, resp.Status, resp)
 			return
 		}
+		if a.LinkSender != nil {
+			a.startLink(w, r, account)
+			return
+		}
 	} else {
 		var profileID string
 		profileID, err = uuid.GenerateUUID()
@@ -50,10 +79,17 @@ func (a APIv1) handleCreateAccount(w http.ResponseWriter, r *http.Request) {
 	}
 	err = a.Storer.Create(r.Context(), account)
 	if err != nil {
-		if errors.Is(err, accounts.ErrAccountAlreadyExists) {
+		// recently released IDs look like they're still in use, so
+		// deleted Accounts can't be discovered through them
+		if errors.Is(err, accounts.ErrAccountAlreadyExists) || errors.Is(err, accounts.ErrAccountIDRecentlyReleased) {
 			api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/id", Slug: api.RequestErrConflict}}})
 			return
 		}
+		if errors.Is(err, accounts.ErrProfileAccountLimitReached) {
+			yall.FromContext(r.Context()).WithField("profile_id", account.ProfileID).Info("Profile account limit reached")
+			api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/profileID", Slug: api.RequestErrOverflow}}})
+			return
+		}
 		yall.FromContext(r.Context()).WithError(err).Error("Error creating account")
 		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
 		return
@@ -62,38 +98,64 @@ func (a APIv1) handleCreateAccount(w http.ResponseWriter, r *http.Request) {
 	api.Encode(w, r, http.StatusCreated, Response{Accounts: []Account{apiAccount(account)}})
 }
 
+// RequestErrDomainNotAllowed is the slug used in the errors of responses to
+// requests to create an Account whose ID is an email address at a domain the
+// DomainPolicy doesn't allow.
+const RequestErrDomainNotAllowed = "domain_not_allowed"
+
+// RequestErrLastAccount is the slug used in the errors of responses to
+// requests to delete the only Account associated with a profile. Setting the
+// allowLastAccount query parameter to true deletes it anyway, leaving the
+// profile without any Accounts.
+const RequestErrLastAccount = "last_account"
+
+// RequestErrNotSupported is the slug used in the errors of responses to
+// requests the Storer can't carry out, like erasing a profile in a Storer
+// that doesn't implement accounts.ProfileEraser.
+const RequestErrNotSupported = "not_supported"
+
+// notSupported is the Response for requests the Storer can't carry out.
+func notSupported(param string) Response {
+	return Response{Errors: []api.RequestError{{Param: param, Slug: RequestErrNotSupported}}}
+}
+
+// accountNotFound is the Response for requests about an Account that
+// doesn't exist, or that the requester isn't allowed to know exists.
+func accountNotFound() Response {
+	return Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}}
+}
+
 func (a APIv1) handleGetAccount(w http.ResponseWriter, r *http.Request) {
 	vars := trout.RequestVars(r)
 	id := vars.Get("id")
 	if id == "" {
-		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
+		api.Encode(w, r, http.StatusNotFound, accountNotFound())
 		return
 	}
-	account, err := a.Storer.Get(r.Context(), id)
-	if err != nil {
-		if errors.Is(err, accounts.ErrAccountNotFound) {
-			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Field: "/id", Slug: api.RequestErrNotFound}}})
-			return
-		}
-		yall.FromContext(r.Context()).WithField("account_id", id).WithError(err).Error("Error retrieving account")
-		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
-		return
-	}
-	sess, resp := a.GetAuthToken(r)
+	sess, resp := a.authenticate(r)
 	if resp != nil {
 		api.Encode(w, r, resp.Status, resp)
 		return
 	}
-	if sess == nil {
-		api.Encode(w, r, http.StatusUnauthorized, Response{Errors: []api.RequestError{
-			{Header: "Authorization", Slug: api.RequestErrAccessDenied},
-		}})
+	account, err := a.Storer.Get(r.Context(), id)
+	if err != nil && !errors.Is(err, accounts.ErrAccountNotFound) {
+		yall.FromContext(r.Context()).WithField("account_id", id).WithError(err).Error("Error retrieving account")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
 		return
 	}
-	if sess.ProfileID != account.ProfileID {
-		api.Encode(w, r, http.StatusForbidden, Response{Errors: []api.RequestError{
-			{Param: "id", Slug: api.RequestErrAccessDenied},
-		}})
+	var allowed bool
+	if err == nil {
+		allowed, resp = a.authorize(r, sess, ActionGet, Target{ProfileID: account.ProfileID, Account: &account})
+		if resp != nil {
+			api.Encode(w, r, resp.Status, resp)
+			return
+		}
+	}
+	if !allowed {
+		// Accounts the requester isn't allowed to see get the same
+		// response as Accounts that don't exist, so they can't be used
+		// to find out who is registered
+		api.Encode(w, r, http.StatusNotFound, accountNotFound())
 		return
 	}
 	api.Encode(w, r, http.StatusOK, Response{Accounts: []Account{apiAccount(account)}})
@@ -103,37 +165,52 @@ func (a APIv1) handleDeleteAccount(w http.ResponseWriter, r *http.Request) {
 	vars := trout.RequestVars(r)
 	id := vars.Get("id")
 	if id == "" {
-		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "id", Slug: api.RequestErrNotFound}}})
+		api.Encode(w, r, http.StatusNotFound, accountNotFound())
+		return
+	}
+	sess, resp := a.authenticate(r)
+	if resp != nil {
+		api.Encode(w, r, resp.Status, resp)
 		return
 	}
 	account, err := a.Storer.Get(r.Context(), id)
-	if err != nil {
-		if errors.Is(err, accounts.ErrAccountNotFound) {
-			api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Field: "/id", Slug: api.RequestErrNotFound}}})
-			return
-		}
+	if err != nil && !errors.Is(err, accounts.ErrAccountNotFound) {
 		yall.FromContext(r.Context()).WithField("account_id", id).WithError(err).Error("Error retrieving account")
 		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
 		return
 	}
-	sess, resp := a.GetAuthToken(r)
-	if resp != nil {
-		api.Encode(w, r, resp.Status, resp)
+	var allowed bool
+	if err == nil {
+		allowed, resp = a.authorize(r, sess, ActionDelete, Target{ProfileID: account.ProfileID, Account: &account})
+		if resp != nil {
+			api.Encode(w, r, resp.Status, resp)
+			return
+		}
+	}
+	if !allowed {
+		// Accounts the requester isn't allowed to see get the same
+		// response as Accounts that don't exist, so they can't be used
+		// to find out who is registered
+		api.Encode(w, r, http.StatusNotFound, accountNotFound())
 		return
 	}
-	if sess == nil {
-		api.Encode(w, r, http.StatusUnauthorized, Response{Errors: []api.RequestError{
-			{Header: "Authorization", Slug: api.RequestErrAccessDenied},
-		}})
+	// deleting a profile's last Account would leave it unreachable, so
+	// it has to be asked for explicitly, and Storers that can't check
+	// for that only allow deleting when it is
+	guard, canGuard := a.Storer.(accounts.LastAccountGuard)
+	switch {
+	case r.URL.Query().Get("allowLastAccount") == "true":
+		err = a.Storer.Delete(r.Context(), id)
+	case canGuard:
+		err = guard.DeleteUnlessLast(r.Context(), id)
+	default:
+		api.Encode(w, r, http.StatusNotImplemented, notSupported("allowLastAccount"))
 		return
 	}
-	if sess.ProfileID != account.ProfileID {
-		api.Encode(w, r, http.StatusForbidden, Response{Errors: []api.RequestError{
-			{Param: "id", Slug: api.RequestErrAccessDenied},
-		}})
+	if errors.Is(err, accounts.ErrLastAccount) {
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Param: "id", Slug: RequestErrLastAccount}}})
 		return
 	}
-	err = a.Storer.Delete(r.Context(), id)
 	if err != nil {
 		yall.FromContext(r.Context()).WithField("account_id", id).WithError(err).Error("Error deleting account")
 		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
@@ -149,18 +226,17 @@ func (a APIv1) handleListAccounts(w http.ResponseWriter, r *http.Request) {
 		api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Param: "profileID", Slug: api.RequestErrMissing}}})
 		return
 	}
-	sess, resp := a.GetAuthToken(r)
+	sess, resp := a.authenticate(r)
 	if resp != nil {
 		api.Encode(w, r, resp.Status, resp)
 		return
 	}
-	if sess == nil {
-		api.Encode(w, r, http.StatusUnauthorized, Response{Errors: []api.RequestError{
-			{Header: "Authorization", Slug: api.RequestErrAccessDenied},
-		}})
+	allowed, resp := a.authorize(r, sess, ActionList, Target{ProfileID: profileID})
+	if resp != nil {
+		api.Encode(w, r, resp.Status, resp)
 		return
 	}
-	if sess.ProfileID != profileID {
+	if !allowed {
 		api.Encode(w, r, http.StatusForbidden, Response{Errors: []api.RequestError{
 			{Param: "profileID", Slug: api.RequestErrAccessDenied},
 		}})
@@ -175,20 +251,105 @@ func (a APIv1) handleListAccounts(w http.ResponseWriter, r *http.Request) {
 	api.Encode(w, r, http.StatusOK, Response{Accounts: apiAccounts(accts)})
 }
 
+func (a APIv1) handleEraseProfile(w http.ResponseWriter, r *http.Request) {
+	vars := trout.RequestVars(r)
+	profileID := vars.Get("profileID")
+	if profileID == "" {
+		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "profileID", Slug: api.RequestErrNotFound}}})
+		return
+	}
+	sess, resp := a.authenticate(r)
+	if resp != nil {
+		api.Encode(w, r, resp.Status, resp)
+		return
+	}
+	allowed, resp := a.authorize(r, sess, ActionDelete, Target{ProfileID: profileID})
+	if resp != nil {
+		api.Encode(w, r, resp.Status, resp)
+		return
+	}
+	if !allowed {
+		api.Encode(w, r, http.StatusForbidden, Response{Errors: []api.RequestError{
+			{Param: "profileID", Slug: api.RequestErrAccessDenied},
+		}})
+		return
+	}
+	receipt, err := a.EraseProfile(r.Context(), profileID)
+	if errors.Is(err, accounts.ErrEraseNotSupported) {
+		api.Encode(w, r, http.StatusNotImplemented, notSupported("profileID"))
+		return
+	}
+	if err != nil {
+		// the profile may already be erased if only signing failed, so
+		// log enough to issue the receipt by hand
+		yall.FromContext(r.Context()).WithField("profile_id", profileID).WithField("erased_accounts", receipt.Accounts).
+			WithError(err).Error("Error erasing profile")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	if len(receipt.Accounts) < 1 {
+		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "profileID", Slug: api.RequestErrNotFound}}})
+		return
+	}
+	yall.FromContext(r.Context()).WithField("profile_id", profileID).WithField("erased_accounts", len(receipt.Accounts)).Info("Profile erased")
+	api.Encode(w, r, http.StatusOK, Response{Receipt: apiErasureReceipt(receipt)})
+}
+
+func (a APIv1) handleExportProfile(w http.ResponseWriter, r *http.Request) {
+	vars := trout.RequestVars(r)
+	profileID := vars.Get("profileID")
+	if profileID == "" {
+		api.Encode(w, r, http.StatusNotFound, Response{Errors: []api.RequestError{{Param: "profileID", Slug: api.RequestErrNotFound}}})
+		return
+	}
+	sess, resp := a.authenticate(r)
+	if resp != nil {
+		api.Encode(w, r, resp.Status, resp)
+		return
+	}
+	allowed, resp := a.authorize(r, sess, ActionGet, Target{ProfileID: profileID})
+	if resp != nil {
+		api.Encode(w, r, resp.Status, resp)
+		return
+	}
+	if !allowed {
+		api.Encode(w, r, http.StatusForbidden, Response{Errors: []api.RequestError{
+			{Param: "profileID", Slug: api.RequestErrAccessDenied},
+		}})
+		return
+	}
+	// build the whole archive before writing anything, so errors can
+	// still be reported with the right status code
+	var archive bytes.Buffer
+	manifest, err := a.WriteSubjectAccessArchive(r.Context(), &archive, profileID)
+	if err != nil {
+		yall.FromContext(r.Context()).WithField("profile_id", profileID).WithError(err).Error("Error exporting profile")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	yall.FromContext(r.Context()).WithField("profile_id", profileID).WithField("files", len(manifest.Files)).Info("Profile exported")
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="accounts-`+url.PathEscape(profileID)+`.zip"`)
+	w.Header().Set("Content-Length", strconv.Itoa(archive.Len()))
+	w.WriteHeader(http.StatusOK)
+	_, err = archive.WriteTo(w)
+	if err != nil {
+		yall.FromContext(r.Context()).WithField("profile_id", profileID).WithError(err).Warn("Error writing export")
+	}
+}
+
+// validateAddingAccountToProfile returns the Response to render if the
+// request isn't allowed to add `account` to its profile, or nil if it is.
 func (a APIv1) validateAddingAccountToProfile(r *http.Request, account accounts.Account) *Response {
-	sess, resp := a.GetAuthToken(r)
+	sess, resp := a.authenticate(r)
 	if resp != nil {
-		return nil
+		return resp
 	}
-	if sess == nil {
-		return &Response{
-			Status: http.StatusUnauthorized,
-			Errors: []api.RequestError{
-				{Header: "Authorization", Slug: api.RequestErrAccessDenied},
-			},
-		}
+	allowed, resp := a.authorize(r, sess, ActionCreate, Target{ProfileID: account.ProfileID, Account: &account})
+	if resp != nil {
+		return resp
 	}
-	if sess.ProfileID != account.ProfileID {
+	if !allowed {
 		return &Response{
 			Status: http.StatusForbidden,
 			Errors: []api.RequestError{
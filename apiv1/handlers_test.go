@@ -0,0 +1,302 @@
+package apiv1_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"lockbox.dev/accounts"
+	"lockbox.dev/accounts/apiv1"
+	"lockbox.dev/accounts/storers/memory"
+	"lockbox.dev/sessions"
+)
+
+// bearerProfiles is a TokenVerifier that treats the bearer token as the
+// profile ID of the session, optionally followed by a space-separated list
+// of scopes. The profile ID "-" stands for a session without a profile ID,
+// like a service's. The tokens "invalid" and "broken" fail verification and
+// fail to be verified, respectively.
+type bearerProfiles struct{}
+
+func (bearerProfiles) TokenFromRequest(r *http.Request) (*sessions.AccessToken, error) {
+	fields := strings.Fields(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	if len(fields) < 1 {
+		return nil, nil //nolint:nilnil // no token is not an error
+	}
+	switch fields[0] {
+	case "invalid":
+		return nil, sessions.ErrInvalidToken
+	case "broken":
+		return nil, errors.New("key server unavailable") //nolint:goerr113 // test error
+	case "-":
+		fields[0] = ""
+	}
+	return &sessions.AccessToken{ProfileID: fields[0], ClientID: "test-client", Scopes: fields[1:]}, nil
+}
+
+// recordingStorer records the calls made to the Storer it wraps.
+type recordingStorer struct {
+	accounts.Storer
+	calls []string
+	lock  sync.Mutex
+}
+
+func (r *recordingStorer) record(call string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *recordingStorer) Get(ctx context.Context, id string) (accounts.Account, error) {
+	r.record("Get")
+	return r.Storer.Get(ctx, id)
+}
+
+func (r *recordingStorer) Create(ctx context.Context, account accounts.Account) error {
+	r.record("Create")
+	return r.Storer.Create(ctx, account)
+}
+
+func (r *recordingStorer) Delete(ctx context.Context, id string) error {
+	r.record("Delete")
+	return r.Storer.Delete(ctx, id)
+}
+
+func (r *recordingStorer) DeleteUnlessLast(ctx context.Context, id string) error {
+	r.record("DeleteUnlessLast")
+	return r.Storer.(accounts.LastAccountGuard).DeleteUnlessLast(ctx, id) //nolint:forcetypeassert // only used with Storers that guard last Accounts
+}
+
+func (r *recordingStorer) EraseProfile(ctx context.Context, profileID string) ([]accounts.Account, error) {
+	r.record("EraseProfile")
+	return r.Storer.(accounts.ProfileEraser).EraseProfile(ctx, profileID) //nolint:forcetypeassert // only used with Storers that erase profiles
+}
+
+func (r *recordingStorer) reset() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	calls := r.calls
+	r.calls = nil
+	return calls
+}
+
+// testClient makes requests to an APIv1 backed by a recordingStorer. The
+// "paddy" profile starts with two Accounts, paddy@impractical.co and
+// paddycarver.
+type testClient struct {
+	storer  *recordingStorer
+	handler http.Handler
+}
+
+func newTestAPI(t *testing.T) testClient {
+	t.Helper()
+
+	return newTestAPIWithAuthorizer(t, nil)
+}
+
+func newTestAPIWithAuthorizer(t *testing.T, authorizer apiv1.Authorizer) testClient {
+	t.Helper()
+
+	storer, err := memory.NewStorer(memory.WithReleaseQuarantine(accounts.DefaultReleaseQuarantine, bytes.Repeat([]byte{0x24}, 32)))
+	if err != nil {
+		t.Fatalf("Error creating storer: %+v\n", err)
+	}
+	err = storer.Create(context.Background(), accounts.Account{
+		ID:             "paddy@impractical.co",
+		ProfileID:      "paddy",
+		IsRegistration: true,
+	})
+	if err != nil {
+		t.Fatalf("Error creating account: %+v\n", err)
+	}
+	err = storer.Create(context.Background(), accounts.Account{
+		ID:        "paddycarver",
+		ProfileID: "paddy",
+	})
+	if err != nil {
+		t.Fatalf("Error creating account: %+v\n", err)
+	}
+	recorder := &recordingStorer{Storer: storer}
+	api := apiv1.APIv1{
+		Dependencies: accounts.Dependencies{Storer: recorder},
+		Sessions:     bearerProfiles{},
+		Authorizer:   authorizer,
+	}
+	return testClient{storer: recorder, handler: api.Server("/")}
+}
+
+type response struct {
+	status int
+	body   string
+	calls  []string
+}
+
+// do makes a request, described by `route` as a method and path, using
+// `profileID` as the bearer token.
+func (c testClient) do(t *testing.T, route, profileID string) response {
+	t.Helper()
+
+	return c.doBody(t, route, profileID, "")
+}
+
+// doBody makes a request like do, with `body` as its body.
+func (c testClient) doBody(t *testing.T, route, profileID, body string) response {
+	t.Helper()
+
+	parts := strings.SplitN(route, " ", 2) //nolint:gomnd // method and path
+	req := httptest.NewRequest(parts[0], parts[1], strings.NewReader(body))
+	req.Header.Set("Accept", "application/json")
+	if profileID != "" {
+		req.Header.Set("Authorization", "Bearer "+profileID)
+	}
+	w := httptest.NewRecorder()
+	c.handler.ServeHTTP(w, req)
+	return response{status: w.Code, body: w.Body.String(), calls: c.storer.reset()}
+}
+
+// TestAccountLookupsDoNotLeakExistence checks that requests about an Account
+// that exists but belongs to someone else are indistinguishable from
+// requests about an Account that doesn't exist: they get the same status
+// and body, and make the same sequence of Storer calls.
+//
+// Response times aren't measured; timing assertions are too noisy to be
+// reliable in CI. Making the same Storer calls in the same order is what
+// keeps the two cases taking the same time, so the call sequence stands in
+// for timing here.
+func TestAccountLookupsDoNotLeakExistence(t *testing.T) {
+	t.Parallel()
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		method := method
+		t.Run(method, func(t *testing.T) {
+			t.Parallel()
+
+			client := newTestAPI(t)
+
+			// without authenticating, nothing is looked up
+			existing := client.do(t, method+" /paddy@impractical.co", "")
+			missing := client.do(t, method+" /nobody@impractical.co", "")
+			if existing.status != http.StatusUnauthorized {
+				t.Errorf("Expected status %d without authenticating, got %d", http.StatusUnauthorized, existing.status)
+			}
+			if diff := cmp.Diff(missing, existing, cmp.AllowUnexported(response{})); diff != "" {
+				t.Errorf("Unauthenticated responses differ (-missing, +existing): %s", diff)
+			}
+			if len(existing.calls) > 0 {
+				t.Errorf("Expected no storer calls without authenticating, got %v", existing.calls)
+			}
+
+			// authenticated as someone else, existing accounts look
+			// like missing ones
+			existing = client.do(t, method+" /paddy@impractical.co", "someone-else")
+			missing = client.do(t, method+" /nobody@impractical.co", "someone-else")
+			if existing.status != http.StatusNotFound {
+				t.Errorf("Expected status %d for someone else's account, got %d", http.StatusNotFound, existing.status)
+			}
+			if diff := cmp.Diff(missing, existing, cmp.AllowUnexported(response{})); diff != "" {
+				t.Errorf("Responses differ (-missing, +existing): %s", diff)
+			}
+
+			// the owner can still see the account
+			owner := client.do(t, method+" /paddy@impractical.co", "paddy")
+			if owner.status != http.StatusOK {
+				t.Errorf("Expected status %d for the owner, got %d", http.StatusOK, owner.status)
+			}
+		})
+	}
+}
+
+func TestDeleteLastAccount(t *testing.T) {
+	t.Parallel()
+
+	client := newTestAPI(t)
+
+	resp := client.do(t, "DELETE /paddycarver", "paddy")
+	if resp.status != http.StatusOK {
+		t.Fatalf("Expected status %d deleting one of two accounts, got %d: %s", http.StatusOK, resp.status, resp.body)
+	}
+
+	resp = client.do(t, "DELETE /paddy@impractical.co", "paddy")
+	if resp.status != http.StatusBadRequest {
+		t.Errorf("Expected status %d deleting the last account, got %d: %s", http.StatusBadRequest, resp.status, resp.body)
+	}
+	if !strings.Contains(resp.body, apiv1.RequestErrLastAccount) {
+		t.Errorf("Expected %q error, got %s", apiv1.RequestErrLastAccount, resp.body)
+	}
+	resp = client.do(t, "GET /paddy@impractical.co", "paddy")
+	if resp.status != http.StatusOK {
+		t.Errorf("Expected the last account to still exist, got status %d: %s", resp.status, resp.body)
+	}
+
+	resp = client.do(t, "DELETE /paddy@impractical.co?allowLastAccount=true", "paddy")
+	if resp.status != http.StatusOK {
+		t.Errorf("Expected status %d deleting the last account explicitly, got %d: %s", http.StatusOK, resp.status, resp.body)
+	}
+	resp = client.do(t, "GET /paddy@impractical.co", "paddy")
+	if resp.status != http.StatusNotFound {
+		t.Errorf("Expected the last account to be deleted, got status %d: %s", resp.status, resp.body)
+	}
+}
+
+func TestStorerWithoutOptionalInterfaces(t *testing.T) {
+	t.Parallel()
+
+	full := newTestAPI(t)
+	// hide every method but accounts.Storer's
+	minimal := struct{ accounts.Storer }{full.storer.Storer}
+	api := apiv1.APIv1{
+		Dependencies: accounts.Dependencies{Storer: minimal},
+		Sessions:     bearerProfiles{},
+	}
+	client := testClient{storer: &recordingStorer{Storer: minimal}, handler: api.Server("/")}
+
+	resp := client.do(t, "DELETE /paddycarver", "paddy")
+	if resp.status != http.StatusNotImplemented || !strings.Contains(resp.body, apiv1.RequestErrNotSupported) {
+		t.Errorf("Expected status %d deleting without a LastAccountGuard, got %d: %s", http.StatusNotImplemented, resp.status, resp.body)
+	}
+	resp = client.do(t, "DELETE /paddycarver?allowLastAccount=true", "paddy")
+	if resp.status != http.StatusOK {
+		t.Errorf("Expected status %d deleting explicitly without a LastAccountGuard, got %d: %s", http.StatusOK, resp.status, resp.body)
+	}
+	resp = client.do(t, "DELETE /profiles/paddy", "paddy")
+	if resp.status != http.StatusNotImplemented || !strings.Contains(resp.body, apiv1.RequestErrNotSupported) {
+		t.Errorf("Expected status %d erasing without a ProfileEraser, got %d: %s", http.StatusNotImplemented, resp.status, resp.body)
+	}
+	resp = client.do(t, "GET /paddy@impractical.co", "paddy")
+	if resp.status != http.StatusOK {
+		t.Errorf("Expected the profile not to be erased, got status %d: %s", resp.status, resp.body)
+	}
+}
+
+func TestCreateReleasedAccountID(t *testing.T) {
+	t.Parallel()
+
+	client := newTestAPI(t)
+
+	resp := client.do(t, "DELETE /paddycarver", "paddy")
+	if resp.status != http.StatusOK {
+		t.Fatalf("Expected status %d deleting an account, got %d: %s", http.StatusOK, resp.status, resp.body)
+	}
+
+	// a released ID looks just like one that's in use
+	released := client.doBody(t, "POST /", "", `{"id":"paddycarver","isRegistration":true}`)
+	existing := client.doBody(t, "POST /", "", `{"id":"paddy@impractical.co","isRegistration":true}`)
+	if released.status != http.StatusBadRequest {
+		t.Errorf("Expected status %d registering a released ID, got %d: %s", http.StatusBadRequest, released.status, released.body)
+	}
+	if diff := cmp.Diff(existing.body, released.body); diff != "" {
+		t.Errorf("Responses differ (-existing, +released): %s", diff)
+	}
+
+	resp = client.doBody(t, "POST /", "paddy", `{"id":"paddycarver","profileID":"paddy"}`)
+	if resp.status != http.StatusCreated {
+		t.Errorf("Expected status %d when the profile that released the ID takes it back, got %d: %s", http.StatusCreated, resp.status, resp.body)
+	}
+}
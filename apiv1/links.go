@@ -0,0 +1,100 @@
+package apiv1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"darlinggo.co/api"
+	yall "yall.in"
+
+	"lockbox.dev/accounts"
+)
+
+// RequestErrLinkExpired is the slug used in the errors of responses to
+// requests to confirm a PendingLink that has expired. The Account has to be
+// added to the profile again, to send a new token.
+const RequestErrLinkExpired = "expired"
+
+// LinkSender delivers the token that confirms a PendingLink to the Account
+// ID it was created for, for example by emailing it to an email address. The
+// token should only be readable by whoever controls the Account ID.
+type LinkSender interface {
+	SendLinkToken(ctx context.Context, link accounts.PendingLink, token string) error
+}
+
+// startLink creates a PendingLink for `account` and sends its token with the
+// APIv1's LinkSender, instead of creating `account` immediately.
+func (a APIv1) startLink(w http.ResponseWriter, r *http.Request, account accounts.Account) {
+	token, link, err := a.StartLink(r.Context(), account, a.LinkTTL)
+	// recently released IDs look like they're still in use, like they do
+	// when creating Accounts directly
+	if errors.Is(err, accounts.ErrAccountAlreadyExists) || errors.Is(err, accounts.ErrAccountIDRecentlyReleased) {
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/id", Slug: api.RequestErrConflict}}})
+		return
+	}
+	if errors.Is(err, accounts.ErrAccountIDDenied) {
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/id", Slug: RequestErrDeniedID}}})
+		return
+	}
+	if errors.Is(err, accounts.ErrEmailDomainNotAllowed) {
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/id", Slug: RequestErrDomainNotAllowed}}})
+		return
+	}
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Error("Error creating pending link")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	err = a.LinkSender.SendLinkToken(r.Context(), link, token)
+	if err != nil {
+		yall.FromContext(r.Context()).WithField("profile_id", link.ProfileID).WithError(err).Error("Error sending link token")
+		api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+		return
+	}
+	yall.FromContext(r.Context()).WithField("profile_id", link.ProfileID).Debug("Pending link created")
+	api.Encode(w, r, http.StatusAccepted, Response{PendingLinks: []PendingLink{apiPendingLink(link)}})
+}
+
+func (a APIv1) handleConfirmLink(w http.ResponseWriter, r *http.Request) {
+	var body LinkConfirmation
+	err := api.Decode(r, &body)
+	if err != nil {
+		yall.FromContext(r.Context()).WithError(err).Debug("Error decoding request body")
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: api.InvalidFormatError})
+		return
+	}
+	if body.Token == "" {
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{{Field: "/token", Slug: api.RequestErrMissing}}})
+		return
+	}
+	// the token proves control of the Account ID, so no access token is
+	// needed; the profile owner was authorized when the link was created
+	account, err := a.ConfirmLink(r.Context(), body.Token)
+	if err != nil {
+		var reqErr api.RequestError
+		switch {
+		case errors.Is(err, accounts.ErrPendingLinkNotFound):
+			reqErr = api.RequestError{Field: "/token", Slug: api.RequestErrNotFound}
+		case errors.Is(err, accounts.ErrPendingLinkExpired):
+			reqErr = api.RequestError{Field: "/token", Slug: RequestErrLinkExpired}
+		case errors.Is(err, accounts.ErrAccountAlreadyExists), errors.Is(err, accounts.ErrAccountIDRecentlyReleased):
+			reqErr = api.RequestError{Field: "/token", Slug: api.RequestErrConflict}
+		case errors.Is(err, accounts.ErrProfileAccountLimitReached):
+			reqErr = api.RequestError{Field: "/token", Slug: api.RequestErrOverflow}
+		case errors.Is(err, accounts.ErrAccountIDDenied):
+			// the denylist changed since the link was created
+			reqErr = api.RequestError{Field: "/token", Slug: RequestErrDeniedID}
+		case errors.Is(err, accounts.ErrEmailDomainNotAllowed):
+			reqErr = api.RequestError{Field: "/token", Slug: RequestErrDomainNotAllowed}
+		default:
+			yall.FromContext(r.Context()).WithError(err).Error("Error confirming link")
+			api.Encode(w, r, http.StatusInternalServerError, Response{Errors: api.ActOfGodError})
+			return
+		}
+		api.Encode(w, r, http.StatusBadRequest, Response{Errors: []api.RequestError{reqErr}})
+		return
+	}
+	yall.FromContext(r.Context()).WithField("account_id", account.ID).Debug("Link confirmed, account created")
+	api.Encode(w, r, http.StatusCreated, Response{Accounts: []Account{apiAccount(account)}})
+}
@@ -0,0 +1,132 @@
+package apiv1_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"lockbox.dev/accounts"
+	"lockbox.dev/accounts/apiv1"
+	"lockbox.dev/accounts/storers/memory"
+)
+
+// outbox is a LinkSender that keeps the tokens it is asked to send.
+type outbox struct {
+	tokens map[string]string
+	lock   sync.Mutex
+}
+
+func (o *outbox) SendLinkToken(_ context.Context, link accounts.PendingLink, token string) error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.tokens[link.AccountID] = token
+	return nil
+}
+
+func (o *outbox) token(accountID string) string {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return o.tokens[accountID]
+}
+
+func TestPendingLinks(t *testing.T) {
+	t.Parallel()
+
+	storer, err := memory.NewStorer()
+	if err != nil {
+		t.Fatalf("Error creating storer: %+v\n", err)
+	}
+	err = storer.Create(context.Background(), accounts.Account{ID: "paddy@impractical.co", ProfileID: "paddy", IsRegistration: true})
+	if err != nil {
+		t.Fatalf("Error creating account: %+v\n", err)
+	}
+	sent := &outbox{tokens: map[string]string{}}
+	api := apiv1.APIv1{
+		Dependencies: accounts.Dependencies{Storer: storer, PendingLinks: storer},
+		Sessions:     bearerProfiles{},
+		LinkSender:   sent,
+	}
+	client := testClient{storer: &recordingStorer{Storer: storer}, handler: api.Server("/")}
+
+	resp := client.doBody(t, "POST /", "paddy", `{"id":"paddy@carvers.co","profileID":"paddy"}`)
+	if resp.status != http.StatusAccepted {
+		t.Fatalf("Expected status %d adding an account, got %d: %s", http.StatusAccepted, resp.status, resp.body)
+	}
+	if !strings.Contains(resp.body, "pendingLinks") {
+		t.Errorf("Expected the response to describe the pending link, got %s", resp.body)
+	}
+	token := sent.token("paddy@carvers.co")
+	if token == "" {
+		t.Fatal("Expected a link token to be sent")
+	}
+	if strings.Contains(resp.body, token) {
+		t.Errorf("Expected the token not to be in the response, got %s", resp.body)
+	}
+	resp = client.do(t, "GET /paddy@carvers.co", "paddy")
+	if resp.status != http.StatusNotFound {
+		t.Errorf("Expected the account not to exist until confirmed, got status %d", resp.status)
+	}
+
+	resp = client.doBody(t, "POST /links/confirm", "", `{"token":"not-the-token"}`)
+	if resp.status != http.StatusBadRequest || !strings.Contains(resp.body, "not_found") {
+		t.Errorf("Expected a wrong token to be rejected, got status %d: %s", resp.status, resp.body)
+	}
+	resp = client.doBody(t, "POST /links/confirm", "", `{"token":"`+token+`"}`)
+	if resp.status != http.StatusCreated {
+		t.Fatalf("Expected status %d confirming the link, got %d: %s", http.StatusCreated, resp.status, resp.body)
+	}
+	resp = client.do(t, "GET /paddy@carvers.co", "paddy")
+	if resp.status != http.StatusOK {
+		t.Errorf("Expected the account to exist once confirmed, got status %d", resp.status)
+	}
+	resp = client.doBody(t, "POST /links/confirm", "", `{"token":"`+token+`"}`)
+	if resp.status != http.StatusBadRequest {
+		t.Errorf("Expected a used token to be rejected, got status %d: %s", resp.status, resp.body)
+	}
+
+	// registrations don't need confirming
+	resp = client.doBody(t, "POST /", "", `{"id":"sam@impractical.co","isRegistration":true}`)
+	if resp.status != http.StatusCreated {
+		t.Errorf("Expected status %d registering, got %d: %s", http.StatusCreated, resp.status, resp.body)
+	}
+}
+
+func TestConfirmLinkDeniedSinceStarted(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storer, err := memory.NewStorer()
+	if err != nil {
+		t.Fatalf("Error creating storer: %+v\n", err)
+	}
+	err = storer.Create(ctx, accounts.Account{ID: "paddy@impractical.co", ProfileID: "paddy", IsRegistration: true})
+	if err != nil {
+		t.Fatalf("Error creating account: %+v\n", err)
+	}
+	sent := &outbox{tokens: map[string]string{}}
+	api := apiv1.APIv1{
+		Dependencies: accounts.Dependencies{Storer: storer, PendingLinks: storer, Denylist: storer},
+		Sessions:     bearerProfiles{},
+		LinkSender:   sent,
+	}
+	client := testClient{storer: &recordingStorer{Storer: storer}, handler: api.Server("/")}
+
+	resp := client.doBody(t, "POST /", "paddy", `{"id":"admin@carvers.co","profileID":"paddy"}`)
+	if resp.status != http.StatusAccepted {
+		t.Fatalf("Expected status %d adding an account, got %d: %s", http.StatusAccepted, resp.status, resp.body)
+	}
+	_, err = api.AddDenyRule(ctx, accounts.DenyRule{Kind: accounts.DenyRuleExact, Pattern: "admin@carvers.co"})
+	if err != nil {
+		t.Fatalf("Error adding deny rule: %+v\n", err)
+	}
+	resp = client.doBody(t, "POST /links/confirm", "", `{"token":"`+sent.token("admin@carvers.co")+`"}`)
+	if resp.status != http.StatusBadRequest || !strings.Contains(resp.body, apiv1.RequestErrDeniedID) {
+		t.Errorf("Expected an ID denied since the link was started to be rejected, got status %d: %s", resp.status, resp.body)
+	}
+	resp = client.do(t, "GET /admin@carvers.co", "paddy")
+	if resp.status != http.StatusNotFound {
+		t.Errorf("Expected the account not to be created, got status %d", resp.status)
+	}
+}
@@ -0,0 +1,293 @@
+package apiv1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"darlinggo.co/api"
+	"darlinggo.co/trout/v2"
+	yall "yall.in"
+
+	"lockbox.dev/accounts"
+)
+
+const (
+	// RequestErrRateLimited is the slug used in the errors of responses to
+	// requests that were rejected for being over a rate limit.
+	RequestErrRateLimited = "rate_limited"
+
+	// maxTargetIDBody is the most of a request body KeyByTargetID will
+	// read looking for an ID.
+	maxTargetIDBody = 1 << 16
+)
+
+// ErrInvalidTokenBucket is returned when a TokenBucket is created with a
+// burst or interval that isn't positive.
+var ErrInvalidTokenBucket = errors.New("token bucket burst and interval must be positive")
+
+// Limiter decides whether requests should be allowed, based on how many
+// requests with the same key have been allowed recently.
+//
+// Limiters backed by shared storage let multiple instances of the API
+// enforce a single limit.
+type Limiter interface {
+	// Check returns whether a request with `key` would be allowed,
+	// without recording it. If it wouldn't be, Check also returns how
+	// long until a request with `key` would be allowed.
+	Check(ctx context.Context, key string) (bool, time.Duration, error)
+
+	// Allow records a request with `key`, and returns whether it should
+	// be allowed. If it shouldn't be, Allow also returns how long until a
+	// request with `key` would be allowed.
+	Allow(ctx context.Context, key string) (bool, time.Duration, error)
+}
+
+// RateKeyFunc returns the key a request should be rate limited under.
+// Requests that return an empty key aren't rate limited.
+type RateKeyFunc func(r *http.Request) string
+
+// RateLimit limits requests that share a key to what a Limiter allows.
+type RateLimit struct {
+	Limiter Limiter
+	Key     RateKeyFunc
+}
+
+// KeyByIP rate limits requests by the IP address of the client that made
+// them, according to http.Request.RemoteAddr. If the API is behind a proxy,
+// RemoteAddr needs to be set to the client's address before the request
+// reaches the API.
+func KeyByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if host == "" {
+		return ""
+	}
+	return "ip:" + host
+}
+
+// KeyByProfile rate limits requests by the profile ID of the session making
+// them. Requests without a valid session aren't rate limited by it, so it
+// should be combined with KeyByIP.
+func KeyByProfile(deps TokenVerifier) RateKeyFunc {
+	return func(r *http.Request) string {
+		sess, err := deps.TokenFromRequest(r)
+		if err != nil || sess == nil {
+			return ""
+		}
+		return "profile:" + sess.ProfileID
+	}
+}
+
+// KeyByTargetID rate limits requests by the Account ID they're about, taken
+// from the URL or, if the URL doesn't have one, from the "id" property of
+// the request body. This keeps attempts to guess whether a specific Account
+// exists slow, no matter how many clients they're spread across.
+func KeyByTargetID(r *http.Request) string {
+	id := trout.RequestVars(r).Get("id")
+	if id == "" && r.Body != nil {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxTargetIDBody))
+		if err != nil {
+			return ""
+		}
+		// put the body back, so the handler can still read it
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+		var target struct {
+			ID string `json:"id"`
+		}
+		if json.Unmarshal(body, &target) != nil {
+			return ""
+		}
+		id = target.ID
+	}
+	if id == "" {
+		return ""
+	}
+	return "id:" + strings.ToLower(id)
+}
+
+// limitFunc is checkLimit or allowLimit.
+type limitFunc func(ctx context.Context, limiter Limiter, key string) (bool, time.Duration, error)
+
+func checkLimit(ctx context.Context, limiter Limiter, key string) (bool, time.Duration, error) {
+	return limiter.Check(ctx, key)
+}
+
+func allowLimit(ctx context.Context, limiter Limiter, key string) (bool, time.Duration, error) {
+	return limiter.Allow(ctx, key)
+}
+
+// applyLimits calls `limit` for every RateLimit with a key, returning
+// whether they all allowed the request and, if they didn't, the longest
+// time to wait before retrying.
+func (a APIv1) applyLimits(ctx context.Context, keys []string, limit limitFunc) (bool, time.Duration) {
+	var retryAfter time.Duration
+	allowed := true
+	for pos, rateLimit := range a.RateLimits {
+		if keys[pos] == "" {
+			continue
+		}
+		ok, retry, err := limit(ctx, rateLimit.Limiter, keys[pos])
+		if err != nil {
+			yall.FromContext(ctx).WithError(err).Error("Error checking rate limit")
+			continue
+		}
+		if !ok {
+			allowed = false
+			if retry > retryAfter {
+				retryAfter = retry
+			}
+		}
+	}
+	return allowed, retryAfter
+}
+
+// rateLimited only calls `h` for requests that every one of the APIv1's
+// RateLimits allows. Other requests get a 429 response, with a Retry-After
+// header saying when to try again. Every RateLimit is checked before any of
+// them record the request, so a request rejected by one RateLimit doesn't
+// count against the others.
+//
+// If a Limiter returns an error, the request is allowed, so an outage of a
+// shared Limiter doesn't take the API down with it.
+func (a APIv1) rateLimited(h http.Handler) http.Handler {
+	if len(a.RateLimits) < 1 {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys := make([]string, 0, len(a.RateLimits))
+		for _, limit := range a.RateLimits {
+			keys = append(keys, limit.Key(r))
+		}
+		allowed, retryAfter := a.applyLimits(r.Context(), keys, checkLimit)
+		if allowed {
+			// another request may have used up a limit since it was
+			// checked, so recording the request can still reject it
+			allowed, retryAfter = a.applyLimits(r.Context(), keys, allowLimit)
+		}
+		if !allowed {
+			yall.FromContext(r.Context()).WithField("retry_after", retryAfter.String()).Info("Request rate limited")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			api.Encode(w, r, http.StatusTooManyRequests, Response{Errors: []api.RequestError{{Slug: RequestErrRateLimited}}})
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// bucket is the state of a single key in a TokenBucket.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// TokenBucket is an in-memory Limiter. Each key gets a bucket holding up to
+// `burst` tokens, which refills at one token every `interval`. Each request
+// takes a token, and requests are rejected when the bucket is empty.
+//
+// TokenBucket only limits requests made to the same process; use a Limiter
+// backed by shared storage to limit requests across multiple processes.
+type TokenBucket struct {
+	burst    int
+	interval time.Duration
+	clock    accounts.Clock
+
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	lock      sync.Mutex
+}
+
+// NewTokenBucket returns a TokenBucket that allows bursts of `burst`
+// requests per key, refilling at one request every `interval`, using `clock`
+// to tell the time. If `clock` is nil, the system clock is used. Both
+// `burst` and `interval` must be positive, or ErrInvalidTokenBucket is
+// returned.
+func NewTokenBucket(burst int, interval time.Duration, clock accounts.Clock) (*TokenBucket, error) {
+	if burst < 1 || interval <= 0 {
+		return nil, ErrInvalidTokenBucket
+	}
+	return &TokenBucket{
+		burst:    burst,
+		interval: interval,
+		clock:    clock,
+		buckets:  map[string]*bucket{},
+	}, nil
+}
+
+// refill returns how many tokens `b` has at `now`.
+func (t *TokenBucket) refill(b *bucket, now time.Time) float64 {
+	tokens := b.tokens + float64(now.Sub(b.last))/float64(t.interval)
+	if tokens > float64(t.burst) {
+		return float64(t.burst)
+	}
+	return tokens
+}
+
+// sweep forgets about buckets that have refilled completely, so keys that
+// stop making requests don't use memory forever.
+func (t *TokenBucket) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < t.interval*time.Duration(t.burst) {
+		return
+	}
+	for key, b := range t.buckets {
+		if t.refill(b, now) >= float64(t.burst) {
+			delete(t.buckets, key)
+		}
+	}
+	t.lastSweep = now
+}
+
+// current returns the bucket for `key`, refilled up to `now`. It must be
+// called with the TokenBucket's lock held.
+func (t *TokenBucket) current(key string, now time.Time) *bucket {
+	t.sweep(now)
+	b, ok := t.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(t.burst), last: now}
+		t.buckets[key] = b
+	}
+	b.tokens, b.last = t.refill(b, now), now
+	return b
+}
+
+// retryAfter returns how long until `b` has a token.
+func (t *TokenBucket) retryAfter(b *bucket) time.Duration {
+	return time.Duration((1 - b.tokens) * float64(t.interval))
+}
+
+// Check returns whether the bucket for `key` has a token, without taking
+// it.
+func (t *TokenBucket) Check(_ context.Context, key string) (bool, time.Duration, error) {
+	now := accounts.Now(t.clock)
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	b := t.current(key, now)
+	if b.tokens < 1 {
+		return false, t.retryAfter(b), nil
+	}
+	return true, 0, nil
+}
+
+// Allow takes a token from the bucket for `key`, if there is one.
+func (t *TokenBucket) Allow(_ context.Context, key string) (bool, time.Duration, error) {
+	now := accounts.Now(t.clock)
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	b := t.current(key, now)
+	if b.tokens < 1 {
+		return false, t.retryAfter(b), nil
+	}
+	b.tokens--
+	return true, 0, nil
+}
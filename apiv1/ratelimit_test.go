@@ -0,0 +1,215 @@
+package apiv1
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"lockbox.dev/accounts"
+)
+
+func TestTokenBucket(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	clock := accounts.NewFakeClock(time.Date(2022, time.March, 14, 15, 9, 26, 0, time.UTC))
+	limiter, err := NewTokenBucket(2, time.Minute, clock)
+	if err != nil {
+		t.Fatalf("Unexpected error creating token bucket: %+v\n", err)
+	}
+
+	ok, _, err := limiter.Check(ctx, "ip:192.0.2.1")
+	if err != nil || !ok {
+		t.Fatalf("Expected request to be allowed, got %v, %v", ok, err)
+	}
+	// checking doesn't take a token
+	for i := 0; i < 2; i++ {
+		ok, _, err := limiter.Allow(ctx, "ip:192.0.2.1")
+		if err != nil || !ok {
+			t.Fatalf("Expected request %d to be allowed, got %v, %v", i, ok, err)
+		}
+	}
+	ok, retry, err := limiter.Check(ctx, "ip:192.0.2.1")
+	if err != nil || ok {
+		t.Fatalf("Expected check over the burst to be rejected, got %v, %v", ok, err)
+	}
+	if retry != time.Minute {
+		t.Errorf("Expected to retry after %s, got %s", time.Minute, retry)
+	}
+	ok, retry, err = limiter.Allow(ctx, "ip:192.0.2.1")
+	if err != nil || ok {
+		t.Fatalf("Expected request over the burst to be rejected, got %v, %v", ok, err)
+	}
+	if retry != time.Minute {
+		t.Errorf("Expected to retry after %s, got %s", time.Minute, retry)
+	}
+
+	// other keys have their own buckets
+	ok, _, err = limiter.Allow(ctx, "ip:192.0.2.2")
+	if err != nil || !ok {
+		t.Errorf("Expected a different key to be allowed, got %v, %v", ok, err)
+	}
+
+	clock.Advance(30 * time.Second)
+	_, retry, _ = limiter.Allow(ctx, "ip:192.0.2.1")
+	if retry != 30*time.Second {
+		t.Errorf("Expected to retry after %s, got %s", 30*time.Second, retry)
+	}
+	clock.Advance(30 * time.Second)
+	ok, _, err = limiter.Allow(ctx, "ip:192.0.2.1")
+	if err != nil || !ok {
+		t.Errorf("Expected request to be allowed once the bucket refilled, got %v, %v", ok, err)
+	}
+}
+
+func TestNewTokenBucket(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		burst    int
+		interval time.Duration
+		wantErr  error
+	}{
+		"valid":         {burst: 1, interval: time.Second},
+		"zero-burst":    {burst: 0, interval: time.Second, wantErr: ErrInvalidTokenBucket},
+		"zero-interval": {burst: 1, wantErr: ErrInvalidTokenBucket},
+		"negative":      {burst: -1, interval: -time.Second, wantErr: ErrInvalidTokenBucket},
+	}
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// a nil clock uses the system clock
+			limiter, err := NewTokenBucket(test.burst, test.interval, nil)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("Expected error %v, got %v", test.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			ok, _, err := limiter.Allow(context.Background(), "ip:192.0.2.1")
+			if err != nil || !ok {
+				t.Errorf("Expected request to be allowed, got %v, %v", ok, err)
+			}
+		})
+	}
+}
+
+type erroringLimiter struct{}
+
+func (erroringLimiter) Check(_ context.Context, _ string) (bool, time.Duration, error) {
+	return false, 0, errors.New("limiter unavailable") //nolint:goerr113 // test error
+}
+
+func (erroringLimiter) Allow(_ context.Context, _ string) (bool, time.Duration, error) {
+	return false, 0, errors.New("limiter unavailable") //nolint:goerr113 // test error
+}
+
+func newTokenBucket(t *testing.T, burst int, interval time.Duration, clock accounts.Clock) *TokenBucket {
+	t.Helper()
+
+	limiter, err := NewTokenBucket(burst, interval, clock)
+	if err != nil {
+		t.Fatalf("Unexpected error creating token bucket: %+v\n", err)
+	}
+	return limiter
+}
+
+func TestRateLimited(t *testing.T) {
+	t.Parallel()
+
+	clock := accounts.NewFakeClock(time.Date(2022, time.March, 14, 15, 9, 26, 0, time.UTC))
+	var served int
+	handler := APIv1{RateLimits: []RateLimit{
+		{Limiter: newTokenBucket(t, 1, 90*time.Second, clock), Key: KeyByIP},
+		{Limiter: erroringLimiter{}, Key: KeyByIP},
+	}}.rateLimited(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+	}))
+
+	for i, wantStatus := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodGet, "/paddy@impractical.co", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != wantStatus {
+			t.Errorf("Expected request %d to get status %d, got %d", i, wantStatus, w.Code)
+		}
+	}
+	if served != 1 {
+		t.Errorf("Expected 1 request to be served, got %d", served)
+	}
+}
+
+func TestRateLimitedChecksBeforeAllowing(t *testing.T) {
+	t.Parallel()
+
+	clock := accounts.NewFakeClock(time.Date(2022, time.March, 14, 15, 9, 26, 0, time.UTC))
+	byIP := newTokenBucket(t, 3, time.Hour, clock)
+	byTarget := newTokenBucket(t, 1, time.Hour, clock)
+	handler := APIv1{RateLimits: []RateLimit{
+		{Limiter: byIP, Key: KeyByIP},
+		{Limiter: byTarget, Key: KeyByTargetID},
+	}}.rateLimited(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// only the first request for the target is allowed, and the
+	// rejected ones shouldn't use up the IP's limit
+	for i, wantStatus := range []int{http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"id":"paddy@impractical.co"}`))
+		req.RemoteAddr = "192.0.2.1:1234"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != wantStatus {
+			t.Errorf("Expected request %d to get status %d, got %d", i, wantStatus, w.Code)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		ok, _, err := byIP.Allow(context.Background(), "ip:192.0.2.1")
+		if err != nil || !ok {
+			t.Errorf("Expected the IP to have %d requests left, got %v, %v at %d", 2, ok, err, i)
+		}
+	}
+}
+
+func TestRateLimitedRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	clock := accounts.NewFakeClock(time.Date(2022, time.March, 14, 15, 9, 26, 0, time.UTC))
+	handler := APIv1{RateLimits: []RateLimit{
+		{Limiter: newTokenBucket(t, 1, 90*time.Second, clock), Key: KeyByIP},
+	}}.rateLimited(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	var w *httptest.ResponseRecorder
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+	}
+	if got := w.Header().Get("Retry-After"); got != "90" {
+		t.Errorf("Expected Retry-After to be 90, got %q", got)
+	}
+}
+
+func TestKeyByTargetIDRestoresBody(t *testing.T) {
+	t.Parallel()
+
+	body := `{"id":"Paddy@Impractical.co","isRegistration":true}`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	if got := KeyByTargetID(req); got != "id:paddy@impractical.co" {
+		t.Errorf("Expected key %q, got %q", "id:paddy@impractical.co", got)
+	}
+	remaining, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatalf("Error reading body: %+v\n", err)
+	}
+	if string(remaining) != body {
+		t.Errorf("Expected body %q to be left for the handler, got %q", body, remaining)
+	}
+}
@@ -0,0 +1,68 @@
+package accounts
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a source of the current time. It lets the passage of time be
+// controlled, so time-dependent behavior can be tested with exact
+// timestamps.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is a Clock that reports the current time according to the
+// system.
+type SystemClock struct{}
+
+// Now returns the current system time.
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// FakeClock is a Clock for testing purposes. It reports whatever time it
+// has been set to, and only moves forward when it's told to.
+type FakeClock struct {
+	now  time.Time
+	lock sync.RWMutex
+}
+
+// NewFakeClock returns a FakeClock that will report `now` as the current
+// time until it is changed.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the time the FakeClock is currently set to.
+func (f *FakeClock) Now() time.Time {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return f.now
+}
+
+// Set changes the time the FakeClock reports to `now`.
+func (f *FakeClock) Set(now time.Time) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.now = now
+}
+
+// Advance moves the time the FakeClock reports forward by `d`, and returns
+// the new time.
+func (f *FakeClock) Advance(d time.Duration) time.Time {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.now = f.now.Add(d)
+	return f.now
+}
+
+// Now returns the current time according to `clock`, or according to the
+// system if `clock` is nil, so Clocks can be left unset to use the system
+// clock.
+func Now(clock Clock) time.Time {
+	if clock == nil {
+		return time.Now()
+	}
+	return clock.Now()
+}
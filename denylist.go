@@ -0,0 +1,324 @@
+package accounts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	uuid "github.com/hashicorp/go-uuid"
+	yall "yall.in"
+)
+
+// DenyRuleKind determines how a DenyRule's Pattern is matched against IDs.
+type DenyRuleKind string
+
+const (
+	// DenyRuleExact matches IDs equal to the Pattern, ignoring case.
+	DenyRuleExact DenyRuleKind = "exact"
+
+	// DenyRuleSuffix matches IDs ending in the Pattern, ignoring case,
+	// like every address at a domain when the Pattern is "@example.com".
+	DenyRuleSuffix DenyRuleKind = "suffix"
+
+	// DenyRuleRegex matches IDs that the Pattern, as a regular
+	// expression in the syntax of the regexp package, matches, ignoring
+	// case. Patterns match anywhere in the ID unless they're anchored.
+	DenyRuleRegex DenyRuleKind = "regex"
+)
+
+var (
+	// ErrAccountIDDenied is returned when an ID matches a DenyRule.
+	ErrAccountIDDenied = errors.New("account ID is denied")
+	// ErrInvalidDenyRule is returned when a DenyRule has an unknown Kind,
+	// an empty Pattern, or a Pattern that isn't a valid regular
+	// expression.
+	ErrInvalidDenyRule = errors.New("invalid deny rule")
+	// ErrDenyRuleNotFound is returned when a DenyRule that doesn't exist
+	// is deleted.
+	ErrDenyRuleNotFound = errors.New("deny rule not found")
+	// ErrNoDenylistStorer is returned when managing DenyRules without a
+	// DenylistStorer set in the Dependencies.
+	ErrNoDenylistStorer = errors.New("no denylist storer configured")
+)
+
+// DenyRule keeps Accounts from being created with the IDs it matches, like
+// reserved names or IDs matching abusive patterns.
+type DenyRule struct {
+	// ID uniquely identifies the DenyRule.
+	ID string
+
+	// Kind determines how Pattern is matched.
+	Kind DenyRuleKind
+
+	// Pattern is what IDs are matched against.
+	Pattern string
+
+	// Reason explains why the DenyRule exists, for administrators. It is
+	// never shown to people whose IDs are denied.
+	Reason string
+
+	// Created is when the DenyRule was created.
+	Created time.Time
+}
+
+// Validate returns an ErrInvalidDenyRule error if the DenyRule can't be
+// matched against IDs.
+func (r DenyRule) Validate() error {
+	_, err := r.compile()
+	return err
+}
+
+// compile returns the compiled Pattern of a regex DenyRule, or nil for other
+// Kinds. An ErrInvalidDenyRule error is returned if the DenyRule isn't
+// valid.
+func (r DenyRule) compile() (*regexp.Regexp, error) {
+	if r.Pattern == "" {
+		return nil, fmt.Errorf("%w: empty pattern", ErrInvalidDenyRule)
+	}
+	switch r.Kind {
+	case DenyRuleExact, DenyRuleSuffix:
+		return nil, nil //nolint:nilnil // only regex DenyRules have a compiled Pattern
+	case DenyRuleRegex:
+		re, err := regexp.Compile("(?i)" + r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidDenyRule, err.Error())
+		}
+		return re, nil
+	}
+	return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidDenyRule, r.Kind)
+}
+
+// Matches returns whether `id` is denied by the DenyRule. An
+// ErrInvalidDenyRule error is returned if the DenyRule isn't valid.
+//
+// Regex Patterns are compiled every time Matches is called; CheckDenylist
+// compiles them once per rule set instead.
+func (r DenyRule) Matches(id string) (bool, error) {
+	re, err := r.compile()
+	if err != nil {
+		return false, err
+	}
+	return r.matches(id, re), nil
+}
+
+// matches returns whether `id` is denied by the DenyRule, using `re` as its
+// compiled Pattern. The DenyRule must be valid.
+func (r DenyRule) matches(id string, re *regexp.Regexp) bool {
+	switch r.Kind {
+	case DenyRuleExact:
+		return strings.EqualFold(id, r.Pattern)
+	case DenyRuleSuffix:
+		return strings.HasSuffix(strings.ToLower(id), strings.ToLower(r.Pattern))
+	case DenyRuleRegex:
+		return re.MatchString(id)
+	}
+	return false
+}
+
+// compiledDenyRule is a valid DenyRule with its Pattern compiled.
+type compiledDenyRule struct {
+	rule DenyRule
+	re   *regexp.Regexp
+}
+
+// denyRuleSet is a set of valid DenyRules, ready to be matched against IDs.
+type denyRuleSet []compiledDenyRule
+
+// compileDenyRules compiles `rules` into a denyRuleSet. Rules that aren't
+// valid can only have been stored without going through AddDenyRule, and
+// are logged using the logger in `ctx` and left out, so one bad rule
+// doesn't keep every Account from being created.
+func compileDenyRules(ctx context.Context, rules []DenyRule) denyRuleSet {
+	set := make(denyRuleSet, 0, len(rules))
+	for _, rule := range rules {
+		re, err := rule.compile()
+		if err != nil {
+			yall.FromContext(ctx).WithField("deny_rule_id", rule.ID).WithError(err).Error("skipping invalid deny rule")
+			continue
+		}
+		set = append(set, compiledDenyRule{rule: rule, re: re})
+	}
+	return set
+}
+
+// match returns the first DenyRule in the set that denies `id`, if any.
+func (s denyRuleSet) match(id string) (DenyRule, bool) {
+	for _, compiled := range s {
+		if compiled.rule.matches(id, compiled.re) {
+			return compiled.rule, true
+		}
+	}
+	return DenyRule{}, false
+}
+
+// DenylistStorer persists DenyRules.
+type DenylistStorer interface {
+	// CreateDenyRule stores `rule`.
+	CreateDenyRule(ctx context.Context, rule DenyRule) error
+
+	// ListDenyRules returns every DenyRule, in no particular order.
+	ListDenyRules(ctx context.Context) ([]DenyRule, error)
+
+	// DeleteDenyRule deletes the DenyRule whose ID is `id`. If no
+	// DenyRule matches, ErrDenyRuleNotFound is returned.
+	DeleteDenyRule(ctx context.Context, id string) error
+}
+
+// CachedDenylist is a DenylistStorer that keeps the DenyRules of another
+// DenylistStorer in memory, with their Patterns compiled, so CheckDenylist
+// doesn't list and compile every DenyRule each time an ID is checked. The
+// DenyRules are listed again when they were listed more than the
+// CachedDenylist's maximum age ago, or as soon as they're changed through
+// the CachedDenylist. Changes made elsewhere, like by other instances of the
+// service, take up to the maximum age to be enforced.
+type CachedDenylist struct {
+	storer DenylistStorer
+	maxAge time.Duration
+	clock  Clock
+
+	lock    sync.RWMutex
+	rules   denyRuleSet
+	fetched time.Time
+	// generation is incremented whenever the cache is cleared, so rules
+	// listed before a change aren't cached after it
+	generation uint64
+}
+
+// NewCachedDenylist returns a CachedDenylist that caches the DenyRules in
+// `storer` for up to `maxAge`, using `clock` to tell how old they are. If
+// `clock` is nil, the system clock is used.
+func NewCachedDenylist(storer DenylistStorer, maxAge time.Duration, clock Clock) *CachedDenylist {
+	return &CachedDenylist{storer: storer, maxAge: maxAge, clock: clock}
+}
+
+// CreateDenyRule stores `rule` in the underlying DenylistStorer, and clears
+// the cache.
+func (c *CachedDenylist) CreateDenyRule(ctx context.Context, rule DenyRule) error {
+	defer c.invalidate()
+	return c.storer.CreateDenyRule(ctx, rule)
+}
+
+// ListDenyRules returns every DenyRule in the underlying DenylistStorer. It
+// doesn't use the cache, so administrators always see the stored rules.
+func (c *CachedDenylist) ListDenyRules(ctx context.Context) ([]DenyRule, error) {
+	return c.storer.ListDenyRules(ctx)
+}
+
+// DeleteDenyRule deletes the DenyRule whose ID is `id` from the underlying
+// DenylistStorer, and clears the cache.
+func (c *CachedDenylist) DeleteDenyRule(ctx context.Context, id string) error {
+	defer c.invalidate()
+	return c.storer.DeleteDenyRule(ctx, id)
+}
+
+func (c *CachedDenylist) invalidate() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.rules, c.fetched = nil, time.Time{}
+	c.generation++
+}
+
+// denyRules returns the cached denyRuleSet, listing and compiling the
+// DenyRules again if the cache is empty or too old. compileDenyRules never
+// returns nil, so a nil denyRuleSet means nothing is cached.
+func (c *CachedDenylist) denyRules(ctx context.Context) (denyRuleSet, error) {
+	now := Now(c.clock)
+	c.lock.RLock()
+	rules, fetched, generation := c.rules, c.fetched, c.generation
+	c.lock.RUnlock()
+	if rules != nil && now.Sub(fetched) < c.maxAge {
+		return rules, nil
+	}
+	listed, err := c.storer.ListDenyRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing deny rules: %w", err)
+	}
+	rules = compileDenyRules(ctx, listed)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.generation == generation {
+		c.rules, c.fetched = rules, now
+	}
+	return rules, nil
+}
+
+// denyRules returns the Dependencies' DenyRules, compiled, using the cache
+// if the Denylist is a CachedDenylist.
+func (d Dependencies) denyRules(ctx context.Context) (denyRuleSet, error) {
+	if cached, ok := d.Denylist.(*CachedDenylist); ok {
+		return cached.denyRules(ctx)
+	}
+	rules, err := d.Denylist.ListDenyRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing deny rules: %w", err)
+	}
+	return compileDenyRules(ctx, rules), nil
+}
+
+// CheckDenylist returns an error wrapping ErrAccountIDDenied if `id` matches
+// any DenyRule. If the Dependencies have no Denylist, every ID is allowed.
+// DenyRules that aren't valid are logged and ignored.
+//
+// Every DenyRule is listed and compiled each time an ID is checked, unless
+// the Denylist is a CachedDenylist.
+func (d Dependencies) CheckDenylist(ctx context.Context, id string) error {
+	if d.Denylist == nil {
+		return nil
+	}
+	rules, err := d.denyRules(ctx)
+	if err != nil {
+		return err
+	}
+	if rule, ok := rules.match(id); ok {
+		return fmt.Errorf("%w by rule %s", ErrAccountIDDenied, rule.ID)
+	}
+	return nil
+}
+
+// AddDenyRule validates `rule`, gives it an ID and creation time, and
+// stores it, returning the stored DenyRule.
+func (d Dependencies) AddDenyRule(ctx context.Context, rule DenyRule) (DenyRule, error) {
+	if d.Denylist == nil {
+		return DenyRule{}, ErrNoDenylistStorer
+	}
+	err := rule.Validate()
+	if err != nil {
+		return DenyRule{}, err
+	}
+	rule.ID, err = uuid.GenerateUUID()
+	if err != nil {
+		return DenyRule{}, fmt.Errorf("error generating deny rule ID: %w", err)
+	}
+	rule.Created = d.Now()
+	err = d.Denylist.CreateDenyRule(ctx, rule)
+	if err != nil {
+		return DenyRule{}, err
+	}
+	return rule, nil
+}
+
+// ListDenyRules returns every DenyRule, oldest first.
+func (d Dependencies) ListDenyRules(ctx context.Context) ([]DenyRule, error) {
+	if d.Denylist == nil {
+		return nil, ErrNoDenylistStorer
+	}
+	rules, err := d.Denylist.ListDenyRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].Created.Before(rules[j].Created) })
+	return rules, nil
+}
+
+// RemoveDenyRule deletes the DenyRule whose ID is `id`.
+func (d Dependencies) RemoveDenyRule(ctx context.Context, id string) error {
+	if d.Denylist == nil {
+		return ErrNoDenylistStorer
+	}
+	return d.Denylist.DeleteDenyRule(ctx, id)
+}
@@ -0,0 +1,243 @@
+package accounts_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"lockbox.dev/accounts"
+	"lockbox.dev/accounts/storers/memory"
+)
+
+func TestDenyRuleMatches(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		rule    accounts.DenyRule
+		id      string
+		matches bool
+	}
+
+	tests := map[string]testCase{
+		"exact":              {rule: accounts.DenyRule{Kind: accounts.DenyRuleExact, Pattern: "admin"}, id: "admin", matches: true},
+		"exact-case":         {rule: accounts.DenyRule{Kind: accounts.DenyRuleExact, Pattern: "admin"}, id: "ADMIN", matches: true},
+		"exact-substring":    {rule: accounts.DenyRule{Kind: accounts.DenyRuleExact, Pattern: "admin"}, id: "admin2", matches: false},
+		"suffix":             {rule: accounts.DenyRule{Kind: accounts.DenyRuleSuffix, Pattern: "@lockbox.dev"}, id: "support@Lockbox.dev", matches: true},
+		"suffix-elsewhere":   {rule: accounts.DenyRule{Kind: accounts.DenyRuleSuffix, Pattern: "@lockbox.dev"}, id: "support@lockbox.dev.example", matches: false},
+		"regex":              {rule: accounts.DenyRule{Kind: accounts.DenyRuleRegex, Pattern: "^(support|abuse)@"}, id: "Abuse@example.com", matches: true},
+		"regex-unanchored":   {rule: accounts.DenyRule{Kind: accounts.DenyRuleRegex, Pattern: "h[a4]x"}, id: "leeth4xor", matches: true},
+		"regex-doesnt-match": {rule: accounts.DenyRule{Kind: accounts.DenyRuleRegex, Pattern: "^(support|abuse)@"}, id: "paddy@support.example", matches: false},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			matches, err := test.rule.Matches(test.id)
+			if err != nil {
+				t.Fatalf("Unexpected error matching: %+v\n", err)
+			}
+			if matches != test.matches {
+				t.Errorf("Expected %v matching %q, got %v", test.matches, test.id, matches)
+			}
+		})
+	}
+}
+
+func TestDenyRuleValidate(t *testing.T) {
+	t.Parallel()
+
+	for name, rule := range map[string]accounts.DenyRule{
+		"unknown-kind":  {Kind: "prefix", Pattern: "admin"},
+		"empty-pattern": {Kind: accounts.DenyRuleExact},
+		"invalid-regex": {Kind: accounts.DenyRuleRegex, Pattern: "(admin"},
+	} {
+		err := rule.Validate()
+		if !errors.Is(err, accounts.ErrInvalidDenyRule) {
+			t.Errorf("Expected %v for %s rule, got %v", accounts.ErrInvalidDenyRule, name, err)
+		}
+		// matching twice makes sure invalid patterns aren't cached
+		for i := 0; i < 2; i++ {
+			_, err = rule.Matches("admin")
+			if !errors.Is(err, accounts.ErrInvalidDenyRule) {
+				t.Errorf("Expected %v matching %s rule, got %v", accounts.ErrInvalidDenyRule, name, err)
+			}
+		}
+	}
+}
+
+func TestDenylist(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storer, err := memory.NewStorer()
+	if err != nil {
+		t.Fatalf("Error creating memory Storer: %+v\n", err)
+	}
+	clock := accounts.NewFakeClock(time.Date(2022, time.April, 1, 12, 0, 0, 0, time.UTC))
+	deps := accounts.Dependencies{Storer: storer, Denylist: storer, Clock: clock}
+	createAccounts(ctx, t, storer, accounts.Account{ID: "paddy@carvers.co", ProfileID: "profile-1"})
+
+	_, err = deps.AddDenyRule(ctx, accounts.DenyRule{Kind: accounts.DenyRuleRegex, Pattern: "(admin"})
+	if !errors.Is(err, accounts.ErrInvalidDenyRule) {
+		t.Errorf("Expected %v adding an invalid rule, got %v", accounts.ErrInvalidDenyRule, err)
+	}
+	exact, err := deps.AddDenyRule(ctx, accounts.DenyRule{Kind: accounts.DenyRuleExact, Pattern: "admin"})
+	if err != nil {
+		t.Fatalf("Error adding deny rule: %+v\n", err)
+	}
+	clock.Advance(time.Second)
+	suffix, err := deps.AddDenyRule(ctx, accounts.DenyRule{Kind: accounts.DenyRuleSuffix, Pattern: "@lockbox.dev"})
+	if err != nil {
+		t.Fatalf("Error adding deny rule: %+v\n", err)
+	}
+	rules, err := deps.ListDenyRules(ctx)
+	if err != nil {
+		t.Fatalf("Error listing deny rules: %+v\n", err)
+	}
+	if len(rules) != 2 || rules[0].ID != exact.ID || rules[1].ID != suffix.ID {
+		t.Errorf("Expected rules %s and %s, oldest first, got %+v", exact.ID, suffix.ID, rules)
+	}
+
+	err = deps.CheckDenylist(ctx, "Admin")
+	if !errors.Is(err, accounts.ErrAccountIDDenied) {
+		t.Errorf("Expected %v checking a denied ID, got %v", accounts.ErrAccountIDDenied, err)
+	}
+	err = deps.CheckDenylist(ctx, "paddy@impractical.co")
+	if err != nil {
+		t.Errorf("Expected an ID no rule matches to be allowed, got %+v", err)
+	}
+	err = deps.RenameAccount(ctx, "paddy@carvers.co", "paddy@lockbox.dev")
+	if !errors.Is(err, accounts.ErrAccountIDDenied) {
+		t.Errorf("Expected %v renaming to a denied ID, got %v", accounts.ErrAccountIDDenied, err)
+	}
+
+	err = deps.RemoveDenyRule(ctx, suffix.ID)
+	if err != nil {
+		t.Fatalf("Error removing deny rule: %+v\n", err)
+	}
+	err = deps.RemoveDenyRule(ctx, suffix.ID)
+	if !errors.Is(err, accounts.ErrDenyRuleNotFound) {
+		t.Errorf("Expected %v removing a removed rule, got %v", accounts.ErrDenyRuleNotFound, err)
+	}
+	err = deps.RenameAccount(ctx, "paddy@carvers.co", "paddy@lockbox.dev")
+	if err != nil {
+		t.Errorf("Expected rename to succeed once its rule is removed, got %+v", err)
+	}
+}
+
+func TestDenylistWithoutStorer(t *testing.T) {
+	t.Parallel()
+
+	deps := accounts.Dependencies{}
+	err := deps.CheckDenylist(context.Background(), "admin")
+	if err != nil {
+		t.Errorf("Expected every ID to be allowed without a denylist, got %+v", err)
+	}
+	_, err = deps.AddDenyRule(context.Background(), accounts.DenyRule{Kind: accounts.DenyRuleExact, Pattern: "admin"})
+	if !errors.Is(err, accounts.ErrNoDenylistStorer) {
+		t.Errorf("Expected %v, got %v", accounts.ErrNoDenylistStorer, err)
+	}
+}
+
+func TestCheckDenylistSkipsInvalidRules(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storer, err := memory.NewStorer()
+	if err != nil {
+		t.Fatalf("Error creating memory Storer: %+v\n", err)
+	}
+	// rules stored without going through AddDenyRule aren't validated
+	for _, rule := range []accounts.DenyRule{
+		{ID: "invalid", Kind: accounts.DenyRuleRegex, Pattern: "(admin"},
+		{ID: "valid", Kind: accounts.DenyRuleExact, Pattern: "admin"},
+	} {
+		err = storer.CreateDenyRule(ctx, rule)
+		if err != nil {
+			t.Fatalf("Error creating deny rule: %+v\n", err)
+		}
+	}
+	deps := accounts.Dependencies{Storer: storer, Denylist: storer}
+
+	err = deps.CheckDenylist(ctx, "paddy@impractical.co")
+	if err != nil {
+		t.Errorf("Expected an invalid rule to be skipped, got %+v", err)
+	}
+	err = deps.CheckDenylist(ctx, "admin")
+	if !errors.Is(err, accounts.ErrAccountIDDenied) {
+		t.Errorf("Expected valid rules to still be enforced, got %v", err)
+	}
+}
+
+type countingDenylist struct {
+	accounts.DenylistStorer
+	lists int
+}
+
+func (c *countingDenylist) ListDenyRules(ctx context.Context) ([]accounts.DenyRule, error) {
+	c.lists++
+	return c.DenylistStorer.ListDenyRules(ctx)
+}
+
+func TestCachedDenylist(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storer, err := memory.NewStorer()
+	if err != nil {
+		t.Fatalf("Error creating memory Storer: %+v\n", err)
+	}
+	clock := accounts.NewFakeClock(time.Date(2022, time.April, 1, 12, 0, 0, 0, time.UTC))
+	counter := &countingDenylist{DenylistStorer: storer}
+	deps := accounts.Dependencies{
+		Storer:   storer,
+		Denylist: accounts.NewCachedDenylist(counter, time.Minute, clock),
+		Clock:    clock,
+	}
+
+	for i := 0; i < 3; i++ {
+		err = deps.CheckDenylist(ctx, "admin")
+		if err != nil {
+			t.Fatalf("Expected admin to be allowed, got %+v", err)
+		}
+	}
+	if counter.lists != 1 {
+		t.Errorf("Expected rules to be listed once, got %d", counter.lists)
+	}
+
+	// changes through the cache are enforced right away
+	rule, err := deps.AddDenyRule(ctx, accounts.DenyRule{Kind: accounts.DenyRuleExact, Pattern: "admin"})
+	if err != nil {
+		t.Fatalf("Error adding deny rule: %+v\n", err)
+	}
+	err = deps.CheckDenylist(ctx, "admin")
+	if !errors.Is(err, accounts.ErrAccountIDDenied) {
+		t.Errorf("Expected %v after adding a rule, got %v", accounts.ErrAccountIDDenied, err)
+	}
+	err = deps.RemoveDenyRule(ctx, rule.ID)
+	if err != nil {
+		t.Fatalf("Error removing deny rule: %+v\n", err)
+	}
+	err = deps.CheckDenylist(ctx, "admin")
+	if err != nil {
+		t.Errorf("Expected admin to be allowed after removing its rule, got %+v", err)
+	}
+
+	// changes made elsewhere are enforced once the cache is too old
+	err = storer.CreateDenyRule(ctx, accounts.DenyRule{ID: "elsewhere", Kind: accounts.DenyRuleSuffix, Pattern: "@lockbox.dev"})
+	if err != nil {
+		t.Fatalf("Error creating deny rule: %+v\n", err)
+	}
+	err = deps.CheckDenylist(ctx, "paddy@lockbox.dev")
+	if err != nil {
+		t.Errorf("Expected the cached rules to be used, got %+v", err)
+	}
+	clock.Advance(time.Minute)
+	err = deps.CheckDenylist(ctx, "paddy@lockbox.dev")
+	if !errors.Is(err, accounts.ErrAccountIDDenied) {
+		t.Errorf("Expected %v once the cache expired, got %v", accounts.ErrAccountIDDenied, err)
+	}
+}
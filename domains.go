@@ -0,0 +1,176 @@
+package accounts
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	yall "yall.in"
+)
+
+// ErrEmailDomainNotAllowed is returned when the domain of an email Account's
+// ID isn't allowed by the DomainPolicy.
+var ErrEmailDomainNotAllowed = errors.New("email domain is not allowed")
+
+// DomainPolicy decides which email domains Accounts can be created with.
+type DomainPolicy interface {
+	// CheckDomain returns an error wrapping ErrEmailDomainNotAllowed if
+	// Accounts can't be created with email addresses at `domain`.
+	// `domain` is always lowercase.
+	CheckDomain(ctx context.Context, domain string) error
+}
+
+// EmailDomain returns the lowercased domain of the email address `id`, or
+// an empty string if `id` isn't an email address.
+func EmailDomain(id string) string {
+	if KindOf(id) != KindEmail {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ToLower(id[strings.LastIndex(id, "@")+1:]), ".")
+}
+
+// CheckEmailDomain returns an error wrapping ErrEmailDomainNotAllowed if `id`
+// is an email address at a domain the Dependencies' DomainPolicy doesn't
+// allow. IDs of other Kinds are always allowed, as is every ID if the
+// Dependencies have no DomainPolicy.
+func (d Dependencies) CheckEmailDomain(ctx context.Context, id string) error {
+	if d.DomainPolicy == nil || KindOf(id) != KindEmail {
+		return nil
+	}
+	return d.DomainPolicy.CheckDomain(ctx, EmailDomain(id))
+}
+
+// domainSet is a set of lowercase domains. A domain in the set also matches
+// all of its subdomains.
+type domainSet map[string]struct{}
+
+func (s domainSet) contains(domain string) bool {
+	for {
+		if _, ok := s[domain]; ok {
+			return true
+		}
+		dot := strings.Index(domain, ".")
+		if dot < 0 {
+			return false
+		}
+		domain = domain[dot+1:]
+	}
+}
+
+// readDomainSet reads a domainSet from the file at `path`, which has one
+// domain per line. Blank lines and lines starting with # are ignored.
+func readDomainSet(path string) (domainSet, error) {
+	file, err := os.Open(path) //nolint:gosec // the path is set by whoever deploys the service
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close() //nolint:errcheck // nothing to do about errors closing a file we only read
+	set := domainSet{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		set[strings.TrimSuffix(strings.ToLower(line), ".")] = struct{}{}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// FileDomainPolicy is a DomainPolicy that reads an allowlist and a blocklist
+// of email domains from local files. The files have one domain per line,
+// and blank lines and lines starting with # are ignored, so published lists
+// of disposable email providers can be used as blocklists as-is. A domain
+// in either list also matches all of its subdomains.
+//
+// Domains in the blocklist are never allowed. If there is an allowlist, only
+// domains in it are allowed, so an empty allowlist allows nothing; without
+// one, every domain not in the blocklist is allowed.
+type FileDomainPolicy struct {
+	allowlistPath string
+	blocklistPath string
+	allow         domainSet
+	block         domainSet
+	lock          sync.RWMutex
+}
+
+// NewFileDomainPolicy returns a FileDomainPolicy using the allowlist in the
+// file at `allowlistPath` and the blocklist in the file at `blocklistPath`.
+// Either path can be empty to go without that list.
+func NewFileDomainPolicy(allowlistPath, blocklistPath string) (*FileDomainPolicy, error) {
+	policy := &FileDomainPolicy{allowlistPath: allowlistPath, blocklistPath: blocklistPath}
+	err := policy.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Reload reads the FileDomainPolicy's files again, so the lists can be
+// changed without restarting. If either file can't be read, the lists in use
+// are left unchanged.
+func (p *FileDomainPolicy) Reload() error {
+	var allow, block domainSet
+	var err error
+	if p.allowlistPath != "" {
+		allow, err = readDomainSet(p.allowlistPath)
+		if err != nil {
+			return fmt.Errorf("error reading domain allowlist: %w", err)
+		}
+	}
+	if p.blocklistPath != "" {
+		block, err = readDomainSet(p.blocklistPath)
+		if err != nil {
+			return fmt.Errorf("error reading domain blocklist: %w", err)
+		}
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.allow, p.block = allow, block
+	return nil
+}
+
+// ReloadEvery calls Reload every `interval`, until `ctx` is canceled. Errors
+// are logged using the logger in `ctx`, and the last lists that were read
+// successfully keep being used.
+//
+// ReloadEvery blocks until `ctx` is canceled, so it should usually be run
+// in its own goroutine.
+func (p *FileDomainPolicy) ReloadEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.Reload(); err != nil {
+				yall.FromContext(ctx).WithError(err).Error("error reloading email domain lists")
+				continue
+			}
+			yall.FromContext(ctx).Debug("reloaded email domain lists")
+		}
+	}
+}
+
+// CheckDomain returns an error wrapping ErrEmailDomainNotAllowed if `domain`
+// is in the blocklist, or if there is an allowlist and `domain` isn't in it.
+func (p *FileDomainPolicy) CheckDomain(_ context.Context, domain string) error {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.block.contains(domain) {
+		return fmt.Errorf("%w: %s is blocked", ErrEmailDomainNotAllowed, domain)
+	}
+	if p.allow != nil && !p.allow.contains(domain) {
+		return fmt.Errorf("%w: %s is not in the allowlist", ErrEmailDomainNotAllowed, domain)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package accounts_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"lockbox.dev/accounts"
+	"lockbox.dev/accounts/storers/memory"
+)
+
+func writeDomainList(t *testing.T, path, contents string) {
+	t.Helper()
+
+	err := os.WriteFile(path, []byte(contents), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing domain list: %+v\n", err)
+	}
+}
+
+func TestEmailDomain(t *testing.T) {
+	t.Parallel()
+
+	for id, expected := range map[string]string{
+		"paddy@Impractical.CO":  "impractical.co",
+		"paddy@carvers.co.":     "carvers.co",
+		`"pa@ddy"@lockbox.dev`:  "lockbox.dev",
+		"paddycarver":           "",
+		"+15555555555":          "",
+		"github:paddycarver":    "",
+		"paddy@mail.carvers.co": "mail.carvers.co",
+	} {
+		if got := accounts.EmailDomain(id); got != expected {
+			t.Errorf("Expected domain of %q to be %q, got %q", id, expected, got)
+		}
+	}
+}
+
+func TestFileDomainPolicy(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		allowlist string
+		blocklist string
+		allowed   []string
+		denied    []string
+	}
+
+	tests := map[string]testCase{
+		"blocklist": {
+			blocklist: "# disposable providers\nmailinator.com\n\nGuerrillaMail.com\n",
+			allowed:   []string{"paddy@impractical.co", "paddy@notmailinator.com", "paddycarver", "+15555555555"},
+			denied:    []string{"paddy@mailinator.com", "paddy@MAILINATOR.com.", "paddy@eu.mailinator.com", "paddy@guerrillamail.com"},
+		},
+		"allowlist": {
+			allowlist: "impractical.co\nlockbox.dev\n",
+			allowed:   []string{"paddy@impractical.co", "paddy@staff.lockbox.dev", "paddycarver"},
+			denied:    []string{"paddy@carvers.co", "paddy@impractical.co.example.com"},
+		},
+		"both": {
+			allowlist: "lockbox.dev\n",
+			blocklist: "contractors.lockbox.dev\n",
+			allowed:   []string{"paddy@lockbox.dev"},
+			denied:    []string{"paddy@contractors.lockbox.dev", "paddy@impractical.co"},
+		},
+		"empty-allowlist": {
+			allowlist: "# nobody yet\n",
+			allowed:   []string{"paddycarver"},
+			denied:    []string{"paddy@impractical.co"},
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			dir := t.TempDir()
+			var allowlistPath, blocklistPath string
+			if test.allowlist != "" {
+				allowlistPath = filepath.Join(dir, "allowlist")
+				writeDomainList(t, allowlistPath, test.allowlist)
+			}
+			if test.blocklist != "" {
+				blocklistPath = filepath.Join(dir, "blocklist")
+				writeDomainList(t, blocklistPath, test.blocklist)
+			}
+			policy, err := accounts.NewFileDomainPolicy(allowlistPath, blocklistPath)
+			if err != nil {
+				t.Fatalf("Error creating domain policy: %+v\n", err)
+			}
+			deps := accounts.Dependencies{DomainPolicy: policy}
+			for _, id := range test.allowed {
+				if err := deps.CheckEmailDomain(ctx, id); err != nil {
+					t.Errorf("Expected %q to be allowed, got %+v", id, err)
+				}
+			}
+			for _, id := range test.denied {
+				if err := deps.CheckEmailDomain(ctx, id); !errors.Is(err, accounts.ErrEmailDomainNotAllowed) {
+					t.Errorf("Expected %v for %q, got %v", accounts.ErrEmailDomainNotAllowed, id, err)
+				}
+			}
+		})
+	}
+}
+
+func TestFileDomainPolicyReload(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "blocklist")
+	writeDomainList(t, path, "mailinator.com\n")
+	policy, err := accounts.NewFileDomainPolicy("", path)
+	if err != nil {
+		t.Fatalf("Error creating domain policy: %+v\n", err)
+	}
+
+	writeDomainList(t, path, "guerrillamail.com\n")
+	err = policy.Reload()
+	if err != nil {
+		t.Fatalf("Error reloading domain policy: %+v\n", err)
+	}
+	if err = policy.CheckDomain(ctx, "mailinator.com"); err != nil {
+		t.Errorf("Expected a domain removed from the blocklist to be allowed, got %+v", err)
+	}
+	if err = policy.CheckDomain(ctx, "guerrillamail.com"); !errors.Is(err, accounts.ErrEmailDomainNotAllowed) {
+		t.Errorf("Expected %v for a domain added to the blocklist, got %v", accounts.ErrEmailDomainNotAllowed, err)
+	}
+
+	// a failed reload keeps the lists that were in use
+	err = os.Remove(path)
+	if err != nil {
+		t.Fatalf("Error removing blocklist: %+v\n", err)
+	}
+	if err = policy.Reload(); err == nil {
+		t.Error("Expected an error reloading a missing blocklist")
+	}
+	if err = policy.CheckDomain(ctx, "guerrillamail.com"); !errors.Is(err, accounts.ErrEmailDomainNotAllowed) {
+		t.Errorf("Expected %v after a failed reload, got %v", accounts.ErrEmailDomainNotAllowed, err)
+	}
+
+	_, err = accounts.NewFileDomainPolicy("", path)
+	if err == nil {
+		t.Error("Expected an error creating a domain policy with a missing blocklist")
+	}
+}
+
+func TestRenameToDisallowedDomain(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "allowlist")
+	writeDomainList(t, path, "impractical.co\n")
+	policy, err := accounts.NewFileDomainPolicy(path, "")
+	if err != nil {
+		t.Fatalf("Error creating domain policy: %+v\n", err)
+	}
+	storer, err := memory.NewStorer()
+	if err != nil {
+		t.Fatalf("Error creating memory Storer: %+v\n", err)
+	}
+	createAccounts(ctx, t, storer, accounts.Account{ID: "paddy@impractical.co", ProfileID: "profile-1"})
+	deps := accounts.Dependencies{Storer: storer, DomainPolicy: policy}
+
+	err = deps.RenameAccount(ctx, "paddy@impractical.co", "paddy@carvers.co")
+	if !errors.Is(err, accounts.ErrEmailDomainNotAllowed) {
+		t.Errorf("Expected %v renaming to a disallowed domain, got %v", accounts.ErrEmailDomainNotAllowed, err)
+	}
+	err = deps.RenameAccount(ctx, "paddy@impractical.co", "paddycarver")
+	if err != nil {
+		t.Errorf("Expected renaming to a username to be allowed, got %+v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package accounts
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// ErasureReceiptVersion is the version of the ErasureReceipt format
+	// that EraseProfile produces.
+	ErasureReceiptVersion = 1
+
+	erasedPlaceholderPrefix = "erased:"
+
+	// receiptKeyLabel is signed by the ReceiptSigner to derive the key
+	// ReceiptPlaceholder hashes Account IDs with. Ed25519 signatures are
+	// deterministic, so the same signer always derives the same key.
+	receiptKeyLabel = "lockbox.dev/accounts erasure receipt placeholder key v1"
+)
+
+var (
+	// ErrNoReceiptSigner is returned when erasing a profile without a
+	// ReceiptSigner set in the Dependencies.
+	ErrNoReceiptSigner = errors.New("no erasure receipt signer configured")
+	// ErrUnsupportedReceiptKey is returned when the ReceiptSigner doesn't
+	// use an Ed25519 key.
+	ErrUnsupportedReceiptKey = errors.New("erasure receipts must be signed with an Ed25519 key")
+	// ErrInvalidReceiptSignature is returned when an ErasureReceipt's
+	// signature doesn't match its contents.
+	ErrInvalidReceiptSignature = errors.New("invalid erasure receipt signature")
+	// ErrEraseNotSupported is returned when erasing a profile in a Storer
+	// that doesn't implement ProfileEraser.
+	ErrEraseNotSupported = errors.New("storer does not support erasing profiles")
+)
+
+// ErasedPlaceholder returns the value that replaces the Account ID `id` in
+// history and audit records when its profile is erased. It is a hash of the
+// case-insensitive ID, so records about the same Account can still be
+// correlated with each other, and checked against an ID someone presents,
+// without the ID itself being kept.
+//
+// The hash isn't keyed, so anyone can check guesses against it. Storers that
+// only keep keyed hashes of IDs should use ErasedHashPlaceholder instead, so
+// erasing an ID doesn't make it easier to recover.
+func ErasedPlaceholder(id string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(id)))
+	return erasedPlaceholderPrefix + "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// ErasedHashPlaceholder returns the value that replaces an Account ID in
+// history and audit records when its profile is erased, for Storers that
+// keep a keyed hash of the ID, like an HMAC, instead of the ID itself. The
+// placeholder keeps `hash` as-is, so it can only be checked against an ID by
+// whoever has the key.
+func ErasedHashPlaceholder(hash string) string {
+	return erasedPlaceholderPrefix + hash
+}
+
+// IsErasedPlaceholder returns whether `id` is an ErasedPlaceholder or an
+// ErasedHashPlaceholder, so records that were already erased aren't hashed
+// again.
+func IsErasedPlaceholder(id string) bool {
+	return strings.HasPrefix(id, erasedPlaceholderPrefix)
+}
+
+// ErasureReceipt is a signed record that a profile was erased. It lists the
+// erased Accounts by their ReceiptPlaceholder, not their IDs, so it can be
+// kept as proof of erasure without retaining what was erased, and only
+// whoever holds the ReceiptSigner can check an ID against it.
+type ErasureReceipt struct {
+	Version   int       `json:"version"`
+	ProfileID string    `json:"profileID"`
+	Accounts  []string  `json:"accounts"`
+	ErasedAt  time.Time `json:"erasedAt"`
+
+	// Signature is the Ed25519 signature of the receipt's JSON encoding
+	// with the Signature left empty.
+	Signature []byte `json:"signature,omitempty"`
+}
+
+func (r ErasureReceipt) signedPayload() ([]byte, error) {
+	r.Signature = nil
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding erasure receipt: %w", err)
+	}
+	return payload, nil
+}
+
+// Verify checks that the ErasureReceipt was signed by the private key
+// matching `key`, and hasn't been altered since. If the signature doesn't
+// match, ErrInvalidReceiptSignature is returned.
+func (r ErasureReceipt) Verify(key ed25519.PublicKey) error {
+	payload, err := r.signedPayload()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, payload, r.Signature) {
+		return ErrInvalidReceiptSignature
+	}
+	return nil
+}
+
+// ReceiptPlaceholder returns the value that stands in for the Account ID `id`
+// in an ErasureReceipt. It is an HMAC of the case-insensitive ID, keyed with
+// a key derived from the ReceiptSigner, so an ID someone presents can be
+// checked against a receipt by whoever holds the signer, but not by anyone
+// else who has a copy of the receipt.
+//
+// If no ReceiptSigner is set, ErrNoReceiptSigner is returned, and if it
+// doesn't use an Ed25519 key, ErrUnsupportedReceiptKey is returned.
+func (d Dependencies) ReceiptPlaceholder(id string) (string, error) {
+	key, err := d.receiptKey()
+	if err != nil {
+		return "", err
+	}
+	return receiptPlaceholder(key, id), nil
+}
+
+func (d Dependencies) receiptKey() ([]byte, error) {
+	if d.ReceiptSigner == nil {
+		return nil, ErrNoReceiptSigner
+	}
+	if _, ok := d.ReceiptSigner.Public().(ed25519.PublicKey); !ok {
+		return nil, ErrUnsupportedReceiptKey
+	}
+	sig, err := d.ReceiptSigner.Sign(rand.Reader, []byte(receiptKeyLabel), crypto.Hash(0))
+	if err != nil {
+		return nil, fmt.Errorf("error deriving erasure receipt key: %w", err)
+	}
+	key := sha256.Sum256(sig)
+	return key[:], nil
+}
+
+func receiptPlaceholder(key []byte, id string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strings.ToLower(id))) //nolint:errcheck // hash.Hash never returns an error
+	return erasedPlaceholderPrefix + "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// EraseProfile deletes every Account associated with `profileID` from the
+// Storer, along with the identifiers in any history kept about them, and
+// returns a receipt signed with the ReceiptSigner.
+//
+// If no ReceiptSigner is set, ErrNoReceiptSigner is returned, and if the
+// Storer doesn't implement ProfileEraser, ErrEraseNotSupported is returned;
+// either way, nothing is erased. Erasing a profile that has no Accounts
+// returns a receipt listing no Accounts.
+func (d Dependencies) EraseProfile(ctx context.Context, profileID string) (ErasureReceipt, error) {
+	eraser, ok := d.Storer.(ProfileEraser)
+	if !ok {
+		return ErasureReceipt{}, ErrEraseNotSupported
+	}
+	// derive the key before erasing anything, so a signer that can't be
+	// used doesn't leave the profile erased without a receipt
+	key, err := d.receiptKey()
+	if err != nil {
+		return ErasureReceipt{}, err
+	}
+	erased, err := eraser.EraseProfile(ctx, profileID)
+	if err != nil {
+		return ErasureReceipt{}, fmt.Errorf("error erasing profile %s: %w", profileID, err)
+	}
+	receipt := ErasureReceipt{
+		Version:   ErasureReceiptVersion,
+		ProfileID: profileID,
+		Accounts:  make([]string, 0, len(erased)),
+		ErasedAt:  d.Now().UTC(),
+	}
+	for _, account := range erased {
+		receipt.Accounts = append(receipt.Accounts, receiptPlaceholder(key, account.ID))
+	}
+	payload, err := receipt.signedPayload()
+	if err != nil {
+		return receipt, err
+	}
+	// the profile is already gone, so the receipt is returned even if
+	// signing fails, for the caller to log
+	receipt.Signature, err = d.ReceiptSigner.Sign(rand.Reader, payload, crypto.Hash(0))
+	if err != nil {
+		return receipt, fmt.Errorf("error signing erasure receipt: %w", err)
+	}
+	return receipt, nil
+}
@@ -0,0 +1,135 @@
+package accounts_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"lockbox.dev/accounts"
+)
+
+func TestEraseProfile(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storer := newMemoryStorer(t)
+	fixtures := exportFixtures(ctx, t, storer)
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %+v\n", err)
+	}
+	now := time.Date(2022, time.April, 1, 12, 0, 0, 0, time.UTC)
+	deps := accounts.Dependencies{Storer: storer, Clock: accounts.NewFakeClock(now), ReceiptSigner: priv}
+
+	receipt, err := deps.EraseProfile(ctx, "profile-1")
+	if err != nil {
+		t.Fatalf("Error erasing profile: %+v\n", err)
+	}
+	var wantAccounts []string
+	for _, id := range []string{"Z@impractical.co", "a@impractical.co"} {
+		placeholder, err := deps.ReceiptPlaceholder(id)
+		if err != nil {
+			t.Fatalf("Error getting placeholder for %s: %+v\n", id, err)
+		}
+		wantAccounts = append(wantAccounts, placeholder)
+		for _, got := range receipt.Accounts {
+			if got == accounts.ErasedPlaceholder(id) {
+				t.Errorf("Receipt lists unkeyed placeholder for %s", id)
+			}
+		}
+	}
+	if diff := cmp.Diff(wantAccounts, receipt.Accounts, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+		t.Errorf("Unexpected erased accounts (-wanted, +got): %s", diff)
+	}
+	if receipt.ProfileID != "profile-1" || !receipt.ErasedAt.Equal(now) {
+		t.Errorf("Unexpected receipt: %+v", receipt)
+	}
+	err = receipt.Verify(pub)
+	if err != nil {
+		t.Errorf("Error verifying receipt: %+v\n", err)
+	}
+	for _, account := range fixtures {
+		_, err := storer.Get(ctx, account.ID)
+		if account.ProfileID == "profile-1" && !errors.Is(err, accounts.ErrAccountNotFound) {
+			t.Errorf("Expected %s to be erased, got %v", account.ID, err)
+		}
+		if account.ProfileID != "profile-1" && err != nil {
+			t.Errorf("Expected %s to be kept, got %v", account.ID, err)
+		}
+	}
+
+	receipt.Accounts = receipt.Accounts[1:]
+	err = receipt.Verify(pub)
+	if !errors.Is(err, accounts.ErrInvalidReceiptSignature) {
+		t.Errorf("Expected tampered receipt to fail verification, got %v", err)
+	}
+}
+
+func TestEraseProfileWithoutSigner(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storer := newMemoryStorer(t)
+	exportFixtures(ctx, t, storer)
+	deps := accounts.Dependencies{Storer: storer}
+
+	_, err := deps.EraseProfile(ctx, "profile-1")
+	if !errors.Is(err, accounts.ErrNoReceiptSigner) {
+		t.Fatalf("Expected ErrNoReceiptSigner, got %v", err)
+	}
+	accts, err := storer.ListByProfile(ctx, "profile-1")
+	if err != nil {
+		t.Fatalf("Error listing accounts: %+v\n", err)
+	}
+	if len(accts) != 2 { //nolint:gomnd // profile-1 has two accounts
+		t.Errorf("Expected nothing to be erased, got %d accounts left", len(accts))
+	}
+}
+
+func TestEraseProfileWithoutEraser(t *testing.T) {
+	t.Parallel()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %+v\n", err)
+	}
+	deps := accounts.Dependencies{Storer: struct{ accounts.Storer }{newMemoryStorer(t)}, ReceiptSigner: priv}
+	_, err = deps.EraseProfile(context.Background(), "profile-1")
+	if !errors.Is(err, accounts.ErrEraseNotSupported) {
+		t.Errorf("Expected %v, got %v", accounts.ErrEraseNotSupported, err)
+	}
+}
+
+func TestReceiptPlaceholderKeyedBySigner(t *testing.T) {
+	t.Parallel()
+
+	placeholders := map[string]struct{}{}
+	for i := 0; i < 2; i++ {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("Error generating key: %+v\n", err)
+		}
+		deps := accounts.Dependencies{ReceiptSigner: priv}
+		first, err := deps.ReceiptPlaceholder("Test@impractical.co")
+		if err != nil {
+			t.Fatalf("Error getting placeholder: %+v\n", err)
+		}
+		second, err := deps.ReceiptPlaceholder("test@IMPRACTICAL.co")
+		if err != nil {
+			t.Fatalf("Error getting placeholder: %+v\n", err)
+		}
+		if first != second {
+			t.Errorf("Expected placeholders to be case-insensitive and stable, got %q and %q", first, second)
+		}
+		placeholders[first] = struct{}{}
+	}
+	if len(placeholders) != 2 { //nolint:gomnd // one placeholder per signer
+		t.Errorf("Expected different signers to produce different placeholders, got %v", placeholders)
+	}
+}
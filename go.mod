@@ -8,7 +8,9 @@ require (
 	github.com/hashicorp/go-memdb v1.3.4
 	github.com/hashicorp/go-uuid v1.0.3
 	github.com/lib/pq v1.10.7
+	github.com/mattn/go-sqlite3 v1.14.16
 	github.com/rubenv/sql-migrate v0.0.0-20200616145509-8d140a17f351
+	go.etcd.io/bbolt v1.3.6
 	lockbox.dev/sessions v0.3.0
 	yall.in v0.0.8
 )
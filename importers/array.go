@@ -0,0 +1,82 @@
+package importers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ArrayDecoder streams the elements of an array stored in a property of a
+// JSON object, without reading the whole object into memory. Exports that
+// wrap their users in an object, like `{"users": [...]}`, can be read with
+// it one user at a time.
+type ArrayDecoder struct {
+	dec     *json.Decoder
+	key     string
+	started bool
+	done    bool
+}
+
+// NewArrayDecoder returns an ArrayDecoder that reads the array stored in the
+// `key` property of the JSON object in `r`.
+func NewArrayDecoder(r io.Reader, key string) *ArrayDecoder {
+	return &ArrayDecoder{dec: json.NewDecoder(r), key: key}
+}
+
+func (a *ArrayDecoder) expectDelim(want json.Delim) error {
+	tok, err := a.dec.Token()
+	if err != nil {
+		return fmt.Errorf("error reading JSON: %w", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("expected %q, got %v", want, tok) //nolint:goerr113 // malformed input isn't a condition callers check for
+	}
+	return nil
+}
+
+// seek advances the decoder to the first element of the array. If the
+// object doesn't have the property, the ArrayDecoder is marked done.
+func (a *ArrayDecoder) seek() error {
+	err := a.expectDelim('{')
+	if err != nil {
+		return err
+	}
+	for a.dec.More() {
+		tok, err := a.dec.Token()
+		if err != nil {
+			return fmt.Errorf("error reading JSON: %w", err)
+		}
+		if tok == a.key {
+			return a.expectDelim('[')
+		}
+		var skip json.RawMessage
+		err = a.dec.Decode(&skip)
+		if err != nil {
+			return fmt.Errorf("error reading JSON: %w", err)
+		}
+	}
+	a.done = true
+	return nil
+}
+
+// Next decodes the next element of the array into `v`. When there are no
+// more elements, io.EOF is returned.
+func (a *ArrayDecoder) Next(v interface{}) error {
+	if !a.started {
+		a.started = true
+		err := a.seek()
+		if err != nil {
+			a.done = true
+			return err
+		}
+	}
+	if a.done || !a.dec.More() {
+		a.done = true
+		return io.EOF
+	}
+	err := a.dec.Decode(v)
+	if err != nil {
+		return fmt.Errorf("error decoding JSON: %w", err)
+	}
+	return nil
+}
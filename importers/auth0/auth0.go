@@ -0,0 +1,101 @@
+package auth0
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"lockbox.dev/accounts/importers"
+)
+
+const (
+	// the largest user we'll read; Auth0 limits metadata to 16KB, and
+	// the rest of a user is much smaller than that
+	maxLineSize = 1024 * 1024
+)
+
+// identity is a connection the user has logged in with.
+type identity struct {
+	Provider   string `json:"provider"`
+	UserID     string `json:"user_id"`
+	Connection string `json:"connection"`
+	IsSocial   bool   `json:"isSocial"`
+}
+
+// user is a single line of an Auth0 export.
+type user struct {
+	UserID        string     `json:"user_id"`
+	Email         string     `json:"email"`
+	EmailVerified bool       `json:"email_verified"`
+	Username      string     `json:"username"`
+	PhoneNumber   string     `json:"phone_number"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastLogin     time.Time  `json:"last_login"`
+	Blocked       bool       `json:"blocked"`
+	Identities    []identity `json:"identities"`
+}
+
+func (u user) toImport() importers.User {
+	res := importers.User{
+		SourceID: u.UserID,
+		Created:  u.CreatedAt,
+		LastUsed: u.LastLogin,
+		Disabled: u.Blocked,
+	}
+	if u.Email != "" {
+		res.Identities = append(res.Identities, importers.Identity{Kind: importers.KindEmail, ID: u.Email, Verified: u.EmailVerified})
+	}
+	if u.Username != "" {
+		res.Identities = append(res.Identities, importers.Identity{Kind: importers.KindUsername, ID: u.Username})
+	}
+	for _, ident := range u.Identities {
+		switch ident.Provider {
+		case "auth0", "email":
+			// database and passwordless email connections log in
+			// with the email address or username
+			continue
+		case "sms":
+			res.Identities = append(res.Identities, importers.Identity{Kind: importers.KindPhone, ID: u.PhoneNumber})
+		default:
+			res.Identities = append(res.Identities, importers.Identity{Kind: importers.KindSocial, ID: ident.Provider + ":" + ident.UserID})
+		}
+	}
+	return res
+}
+
+// Reader reads users from an Auth0 export. It implements
+// importers.Source.
+type Reader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewReader returns a Reader that reads the Auth0 export in `r`.
+func NewReader(r io.Reader) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLineSize)
+	return &Reader{scanner: scanner}
+}
+
+// Next returns the next user in the export. When there are no more users,
+// io.EOF is returned.
+func (r *Reader) Next() (importers.User, error) {
+	for r.scanner.Scan() {
+		r.line++
+		if len(r.scanner.Bytes()) == 0 {
+			continue
+		}
+		var u user
+		err := json.Unmarshal(r.scanner.Bytes(), &u)
+		if err != nil {
+			return importers.User{}, fmt.Errorf("error decoding line %d: %w", r.line, err)
+		}
+		return u.toImport(), nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return importers.User{}, fmt.Errorf("error reading export: %w", err)
+	}
+	return importers.User{}, io.EOF
+}
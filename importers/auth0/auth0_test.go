@@ -0,0 +1,84 @@
+package auth0_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"lockbox.dev/accounts/importers"
+	"lockbox.dev/accounts/importers/auth0"
+)
+
+func TestReader(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open("testdata/users.ndjson")
+	if err != nil {
+		t.Fatalf("Error opening fixture: %+v\n", err)
+	}
+	defer f.Close()
+
+	var got []importers.User
+	reader := auth0.NewReader(f)
+	for {
+		user, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Error reading user: %+v\n", err)
+		}
+		got = append(got, user)
+	}
+
+	want := []importers.User{
+		{
+			SourceID: "auth0|60ee2d3a0b8c1f0069a1b2c3",
+			Identities: []importers.Identity{
+				{Kind: importers.KindEmail, ID: "paddy@impractical.co", Verified: true},
+				{Kind: importers.KindUsername, ID: "paddy"},
+			},
+			Created:  time.Date(2021, time.July, 14, 0, 12, 42, 451000000, time.UTC),
+			LastUsed: time.Date(2022, time.March, 14, 15, 9, 26, 535000000, time.UTC),
+		},
+		{
+			SourceID: "google-oauth2|109876543210987654321",
+			Identities: []importers.Identity{
+				{Kind: importers.KindEmail, ID: "paddy@carvers.co", Verified: true},
+				{Kind: importers.KindSocial, ID: "google-oauth2:109876543210987654321"},
+				{Kind: importers.KindSocial, ID: "github:1234567"},
+			},
+			Created:  time.Date(2021, time.August, 1, 9, 30, 0, 0, time.UTC),
+			LastUsed: time.Date(2022, time.January, 2, 3, 4, 5, 0, time.UTC),
+		},
+		{
+			SourceID: "auth0|60ee2d3a0b8c1f0069a1b2c4",
+			Identities: []importers.Identity{
+				{Kind: importers.KindEmail, ID: "unverified@impractical.co"},
+			},
+			Created: time.Date(2021, time.September, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			SourceID: "sms|60ee2d3a0b8c1f0069a1b2c5",
+			Identities: []importers.Identity{
+				{Kind: importers.KindPhone, ID: "+15555550100"},
+			},
+			Created: time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			SourceID: "auth0|60ee2d3a0b8c1f0069a1b2c6",
+			Identities: []importers.Identity{
+				{Kind: importers.KindEmail, ID: "blocked@impractical.co", Verified: true},
+			},
+			Created:  time.Date(2021, time.November, 1, 0, 0, 0, 0, time.UTC),
+			Disabled: true,
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected users (-wanted, +got): %s", diff)
+	}
+}
@@ -0,0 +1,14 @@
+// Package auth0 reads users from Auth0's bulk user export, so they can be
+// imported with lockbox.dev/accounts/importers.
+//
+// Auth0's export job writes one JSON object per line, with the fields that
+// were requested when the job was created. The user_id, email,
+// email_verified, username, phone_number, created_at, last_login, blocked,
+// and identities fields are used; any others are ignored.
+//
+// A user's email address and username are imported, as is the subject of
+// every social or enterprise connection they've logged in with, as
+// "{provider}:{user_id}". Database and passwordless email connections are
+// represented by the email address and username already, and passwordless
+// SMS connections are reported as unsupported phone numbers.
+package auth0
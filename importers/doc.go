@@ -0,0 +1,16 @@
+// Package importers creates lockbox.dev/accounts Accounts from the users of
+// other identity providers.
+//
+// The subpackages of importers each read a hosted identity provider's
+// documented user export format, and turn every user in it into a User. A
+// User's Identities are the ways that user could log in: an email address, a
+// username, or the subject of a social connection. Import creates an Account
+// for each Identity of each User, with every Account for a User sharing a
+// single newly-generated profile ID, and exactly one of them marked as the
+// registration.
+//
+// Identities that can't safely be imported, like email addresses the provider
+// never verified, are skipped, and Identities that are already in use are
+// recorded as conflicts. Both are listed in the Report that Import returns,
+// so they can be followed up on by hand.
+package importers
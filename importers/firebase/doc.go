@@ -0,0 +1,12 @@
+// Package firebase reads users from a Firebase Authentication export, so
+// they can be imported with lockbox.dev/accounts/importers.
+//
+// Exports are created with `firebase auth:export --format=JSON`, which
+// writes a JSON object with every user in its "users" property. Users are
+// read one at a time, so large exports don't need to fit in memory.
+//
+// A user's email address is imported, as is the subject of every federated
+// provider they've linked, as "{providerId}:{rawId}". The password provider
+// is represented by the email address already, and phone numbers are
+// reported as unsupported.
+package firebase
@@ -0,0 +1,104 @@
+package firebase
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"lockbox.dev/accounts/importers"
+)
+
+// providerUserInfo is a provider a user has linked to their account.
+type providerUserInfo struct {
+	ProviderID string `json:"providerId"`
+	RawID      string `json:"rawId"`
+}
+
+// user is a single user in a Firebase export.
+type user struct {
+	LocalID          string             `json:"localId"`
+	Email            string             `json:"email"`
+	EmailVerified    bool               `json:"emailVerified"`
+	PhoneNumber      string             `json:"phoneNumber"`
+	CreatedAt        string             `json:"createdAt"`
+	LastSignedInAt   string             `json:"lastSignedInAt"`
+	Disabled         bool               `json:"disabled"`
+	ProviderUserInfo []providerUserInfo `json:"providerUserInfo"`
+}
+
+// parseMillis parses the string-encoded milliseconds since the epoch that
+// Firebase uses for timestamps.
+func parseMillis(millis string) (time.Time, error) {
+	if millis == "" {
+		return time.Time{}, nil
+	}
+	parsed, err := strconv.ParseInt(millis, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error parsing timestamp %q: %w", millis, err)
+	}
+	return time.UnixMilli(parsed).UTC(), nil
+}
+
+func (u user) toImport() (importers.User, error) {
+	created, err := parseMillis(u.CreatedAt)
+	if err != nil {
+		return importers.User{}, err
+	}
+	lastUsed, err := parseMillis(u.LastSignedInAt)
+	if err != nil {
+		return importers.User{}, err
+	}
+	res := importers.User{
+		SourceID: u.LocalID,
+		Created:  created,
+		LastUsed: lastUsed,
+		Disabled: u.Disabled,
+	}
+	if u.Email != "" {
+		res.Identities = append(res.Identities, importers.Identity{Kind: importers.KindEmail, ID: u.Email, Verified: u.EmailVerified})
+	}
+	if u.PhoneNumber != "" {
+		res.Identities = append(res.Identities, importers.Identity{Kind: importers.KindPhone, ID: u.PhoneNumber})
+	}
+	for _, provider := range u.ProviderUserInfo {
+		switch provider.ProviderID {
+		case "password", "phone":
+			// these log in with the email address or phone number
+			continue
+		default:
+			res.Identities = append(res.Identities, importers.Identity{Kind: importers.KindSocial, ID: provider.ProviderID + ":" + provider.RawID})
+		}
+	}
+	return res, nil
+}
+
+// Reader reads users from a Firebase export. It implements
+// importers.Source.
+type Reader struct {
+	dec *importers.ArrayDecoder
+}
+
+// NewReader returns a Reader that reads the Firebase export in `r`.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{dec: importers.NewArrayDecoder(r, "users")}
+}
+
+// Next returns the next user in the export. When there are no more users,
+// io.EOF is returned.
+func (r *Reader) Next() (importers.User, error) {
+	var u user
+	err := r.dec.Next(&u)
+	if errors.Is(err, io.EOF) {
+		return importers.User{}, io.EOF
+	}
+	if err != nil {
+		return importers.User{}, err
+	}
+	res, err := u.toImport()
+	if err != nil {
+		return importers.User{}, fmt.Errorf("error reading user %s: %w", u.LocalID, err)
+	}
+	return res, nil
+}
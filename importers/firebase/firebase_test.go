@@ -0,0 +1,68 @@
+package firebase_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"lockbox.dev/accounts/importers"
+	"lockbox.dev/accounts/importers/firebase"
+)
+
+func TestReader(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open("testdata/users.json")
+	if err != nil {
+		t.Fatalf("Error opening fixture: %+v\n", err)
+	}
+	defer f.Close()
+
+	var got []importers.User
+	reader := firebase.NewReader(f)
+	for {
+		user, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Error reading user: %+v\n", err)
+		}
+		got = append(got, user)
+	}
+
+	want := []importers.User{
+		{
+			SourceID: "Ht8Xm2Xq0YbM4cLq7jT3vN1pR5s2",
+			Identities: []importers.Identity{
+				{Kind: importers.KindEmail, ID: "paddy@impractical.co", Verified: true},
+				{Kind: importers.KindSocial, ID: "google.com:109876543210987654321"},
+			},
+			Created:  time.Date(2021, time.July, 14, 0, 12, 42, 451000000, time.UTC),
+			LastUsed: time.Date(2022, time.March, 14, 15, 9, 26, 535000000, time.UTC),
+		},
+		{
+			SourceID: "Qw9Er8Ty7Ui6Op5As4Df3Gh2Jk1",
+			Identities: []importers.Identity{
+				{Kind: importers.KindEmail, ID: "unverified@impractical.co"},
+				{Kind: importers.KindPhone, ID: "+15555550100"},
+			},
+			Created: time.Date(2021, time.September, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			SourceID: "Zx1Cv2Bn3Mm4Ll5Kk6Jj7Hh8Gg9",
+			Identities: []importers.Identity{
+				{Kind: importers.KindEmail, ID: "disabled@impractical.co", Verified: true},
+			},
+			Created:  time.Date(2021, time.October, 1, 0, 0, 0, 0, time.UTC),
+			Disabled: true,
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected users (-wanted, +got): %s", diff)
+	}
+}
@@ -0,0 +1,239 @@
+package importers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	uuid "github.com/hashicorp/go-uuid"
+
+	"lockbox.dev/accounts"
+)
+
+// IdentityKind describes what sort of identifier an Identity is.
+type IdentityKind string
+
+const (
+	// KindEmail is an Identity that is an email address.
+	KindEmail IdentityKind = "email"
+
+	// KindUsername is an Identity that is a username.
+	KindUsername IdentityKind = "username"
+
+	// KindSocial is an Identity that is the subject of a social or
+	// federated connection, like a Google or GitHub account. Its ID should
+	// be the name of the connection, a colon, and the subject.
+	KindSocial IdentityKind = "social"
+
+	// KindPhone is an Identity that is a phone number. Phone numbers
+	// aren't imported, but are recorded in the Report as skipped.
+	KindPhone IdentityKind = "phone"
+)
+
+// Identity is a single way a User could log in.
+type Identity struct {
+	Kind IdentityKind
+	ID   string
+
+	// Verified is whether the identity provider confirmed that the User
+	// controls the Identity. Email addresses that weren't verified are not
+	// imported, as that would let anyone who signed up with somebody
+	// else's email address log in as them.
+	Verified bool
+}
+
+// User is a single user of another identity provider.
+type User struct {
+	// SourceID is the identity provider's ID for the User. It is only used
+	// in Reports.
+	SourceID string
+
+	// Identities are the ways the User could log in. The first Identity
+	// that is imported becomes the registration, so importers should list
+	// the User's primary Identity first.
+	Identities []Identity
+
+	// Created is when the User was created. If it's not set, the time of
+	// the import is used.
+	Created time.Time
+
+	// LastUsed is when the User last logged in. If it's not set, Created
+	// is used.
+	LastUsed time.Time
+
+	// Disabled is true when the identity provider won't let the User log
+	// in. Disabled Users are skipped.
+	Disabled bool
+
+	// SkipReason is set when the importer knows the User shouldn't be
+	// imported, like when it belongs to a service instead of a person. The
+	// User is listed in the Report as skipped, with SkipReason as the
+	// reason.
+	SkipReason string
+}
+
+// Source is a stream of Users to import.
+type Source interface {
+	// Next returns the next User. When there are no more Users, io.EOF is
+	// returned.
+	Next() (User, error)
+}
+
+// Problem describes an Identity that wasn't imported.
+type Problem struct {
+	// SourceID is the identity provider's ID for the User the Identity
+	// belongs to.
+	SourceID string
+
+	// Identity is the ID of the Identity that wasn't imported. It is
+	// empty when the whole User wasn't imported.
+	Identity string
+
+	// Reason is a human-readable explanation of why the Identity wasn't
+	// imported.
+	Reason string
+}
+
+// Report describes the outcome of an import.
+type Report struct {
+	// Users is the number of Users that had at least one Account created.
+	Users int
+
+	// Accounts is the number of Accounts created.
+	Accounts int
+
+	// Skipped lists the Identities and Users that were deliberately not
+	// imported.
+	Skipped []Problem
+
+	// Conflicts lists the Identities that weren't imported because the
+	// Storer wouldn't accept them: an Account with the same ID already
+	// exists, the ID was recently released by another profile, or the
+	// User's profile has reached its account limit.
+	Conflicts []Problem
+}
+
+// Options controls how Users are imported.
+type Options struct {
+	// Clock is used to determine the current time, for Users that don't
+	// have a creation time. If it is nil, the system clock is used.
+	Clock accounts.Clock
+}
+
+// importable returns the Identities of `user` that should be imported, in
+// order, recording any that shouldn't in `report`.
+func importable(user User, report *Report) []Identity {
+	res := make([]Identity, 0, len(user.Identities))
+	seen := map[string]struct{}{}
+	for _, identity := range user.Identities {
+		skip := func(reason string) {
+			report.Skipped = append(report.Skipped, Problem{SourceID: user.SourceID, Identity: identity.ID, Reason: reason})
+		}
+		switch {
+		case identity.ID == "":
+			skip("empty " + string(identity.Kind))
+			continue
+		case identity.Kind == KindPhone:
+			skip("phone numbers are not supported")
+			continue
+		case identity.Kind == KindEmail && !identity.Verified:
+			skip("email address was never verified")
+			continue
+		}
+		// providers often list the same identifier more than once, like
+		// when the username is the email address
+		if _, ok := seen[strings.ToLower(identity.ID)]; ok {
+			continue
+		}
+		seen[strings.ToLower(identity.ID)] = struct{}{}
+		res = append(res, identity)
+	}
+	return res
+}
+
+// conflictReason returns the reason to list in the Report when creating an
+// Account fails with `err` because the Storer won't accept it, or an empty
+// string if `err` isn't a conflict.
+func conflictReason(err error) string {
+	switch {
+	case errors.Is(err, accounts.ErrAccountAlreadyExists):
+		return "account already exists"
+	case errors.Is(err, accounts.ErrAccountIDRecentlyReleased):
+		return "account ID was recently released by another profile"
+	case errors.Is(err, accounts.ErrProfileAccountLimitReached):
+		return "profile has reached its account limit"
+	default:
+		return ""
+	}
+}
+
+// importUser creates an Account in `storer` for each importable Identity of
+// `user`, all sharing a newly-generated profile ID.
+func importUser(ctx context.Context, storer accounts.Storer, user User, opts Options, report *Report) error {
+	if user.SkipReason != "" {
+		report.Skipped = append(report.Skipped, Problem{SourceID: user.SourceID, Reason: user.SkipReason})
+		return nil
+	}
+	if user.Disabled {
+		report.Skipped = append(report.Skipped, Problem{SourceID: user.SourceID, Reason: "user is disabled"})
+		return nil
+	}
+	identities := importable(user, report)
+	if len(identities) == 0 {
+		report.Skipped = append(report.Skipped, Problem{SourceID: user.SourceID, Reason: "user has no importable identities"})
+		return nil
+	}
+	profileID, err := uuid.GenerateUUID()
+	if err != nil {
+		return fmt.Errorf("error generating profile ID: %w", err)
+	}
+	var registered bool
+	for _, identity := range identities {
+		account := accounts.FillDefaultsWithClock(accounts.Account{
+			ID:             identity.ID,
+			ProfileID:      profileID,
+			Created:        user.Created,
+			LastUsed:       user.LastUsed,
+			IsRegistration: !registered,
+		}, opts.Clock)
+		err = storer.Create(ctx, account)
+		if reason := conflictReason(err); reason != "" {
+			report.Conflicts = append(report.Conflicts, Problem{SourceID: user.SourceID, Identity: identity.ID, Reason: reason})
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error creating account %s for user %s: %w", identity.ID, user.SourceID, err)
+		}
+		registered = true
+		report.Accounts++
+	}
+	if registered {
+		report.Users++
+	}
+	return nil
+}
+
+// Import creates Accounts in `storer` for every User in `src`. Each User's
+// Accounts share a newly-generated profile ID, and the first of them to be
+// created is marked as the registration. Identities that are skipped or
+// conflict with existing Accounts are listed in the returned Report; an
+// error is only returned when reading `src` or writing to `storer` fails.
+func Import(ctx context.Context, storer accounts.Storer, src Source, opts Options) (Report, error) {
+	var report Report
+	for {
+		user, err := src.Next()
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		if err != nil {
+			return report, fmt.Errorf("error reading user: %w", err)
+		}
+		err = importUser(ctx, storer, user, opts, &report)
+		if err != nil {
+			return report, err
+		}
+	}
+}
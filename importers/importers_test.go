@@ -0,0 +1,155 @@
+package importers_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"lockbox.dev/accounts"
+	"lockbox.dev/accounts/importers"
+	"lockbox.dev/accounts/storers/memory"
+)
+
+type sliceSource []importers.User
+
+func (s *sliceSource) Next() (importers.User, error) {
+	if len(*s) == 0 {
+		return importers.User{}, io.EOF
+	}
+	user := (*s)[0]
+	*s = (*s)[1:]
+	return user, nil
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storer, err := memory.NewStorer(
+		memory.WithProfileLimits(accounts.ProfileLimits{Max: 2}),
+		memory.WithReleaseQuarantine(accounts.DefaultReleaseQuarantine, bytes.Repeat([]byte{0x24}, 32)), //nolint:gomnd // minimum key size
+	)
+	if err != nil {
+		t.Fatalf("Error creating storer: %+v\n", err)
+	}
+	for _, id := range []string{"taken@impractical.co", "released"} {
+		err = storer.Create(ctx, accounts.Account{ID: id, ProfileID: "existing", IsRegistration: id == "taken@impractical.co"})
+		if err != nil {
+			t.Fatalf("Error creating account: %+v\n", err)
+		}
+	}
+	err = storer.Delete(ctx, "released")
+	if err != nil {
+		t.Fatalf("Error deleting account: %+v\n", err)
+	}
+
+	created := time.Date(2021, time.July, 14, 0, 12, 42, 451000000, time.UTC)
+	lastUsed := time.Date(2022, time.March, 14, 15, 9, 26, 535000000, time.UTC)
+	src := sliceSource{
+		{
+			SourceID: "user-1",
+			Identities: []importers.Identity{
+				{Kind: importers.KindEmail, ID: "paddy@impractical.co", Verified: true},
+				{Kind: importers.KindUsername, ID: "Paddy@Impractical.co"},
+				{Kind: importers.KindSocial, ID: "github:1234567"},
+				{Kind: importers.KindUsername, ID: ""},
+			},
+			Created:  created,
+			LastUsed: lastUsed,
+		},
+		{
+			// the primary identity conflicts, so the next one should
+			// become the registration
+			SourceID: "user-2",
+			Identities: []importers.Identity{
+				{Kind: importers.KindEmail, ID: "taken@impractical.co", Verified: true},
+				{Kind: importers.KindEmail, ID: "unverified@impractical.co"},
+				{Kind: importers.KindUsername, ID: "sam"},
+				{Kind: importers.KindPhone, ID: "+15555550100"},
+			},
+			Created: created,
+		},
+		{
+			SourceID:   "user-3",
+			Identities: []importers.Identity{{Kind: importers.KindUsername, ID: "blocked"}},
+			Disabled:   true,
+		},
+		{
+			SourceID:   "user-4",
+			Identities: []importers.Identity{{Kind: importers.KindUsername, ID: "github:1234567"}},
+		},
+		{
+			// conflicts with the quarantine and the profile limit
+			// shouldn't stop the import
+			SourceID: "user-5",
+			Identities: []importers.Identity{
+				{Kind: importers.KindUsername, ID: "released"},
+				{Kind: importers.KindUsername, ID: "jo"},
+				{Kind: importers.KindEmail, ID: "jo@impractical.co", Verified: true},
+				{Kind: importers.KindSocial, ID: "github:7654321"},
+			},
+		},
+		{
+			SourceID:   "user-6",
+			Identities: []importers.Identity{{Kind: importers.KindUsername, ID: "service-account-lockbox"}},
+			SkipReason: "service account for client lockbox",
+		},
+	}
+
+	report, err := importers.Import(ctx, storer, &src, importers.Options{})
+	if err != nil {
+		t.Fatalf("Error importing users: %+v\n", err)
+	}
+	wantReport := importers.Report{
+		Users:    3,
+		Accounts: 5,
+		Skipped: []importers.Problem{
+			{SourceID: "user-1", Reason: "empty username"},
+			{SourceID: "user-2", Identity: "unverified@impractical.co", Reason: "email address was never verified"},
+			{SourceID: "user-2", Identity: "+15555550100", Reason: "phone numbers are not supported"},
+			{SourceID: "user-3", Reason: "user is disabled"},
+			{SourceID: "user-6", Reason: "service account for client lockbox"},
+		},
+		Conflicts: []importers.Problem{
+			{SourceID: "user-2", Identity: "taken@impractical.co", Reason: "account already exists"},
+			{SourceID: "user-4", Identity: "github:1234567", Reason: "account already exists"},
+			{SourceID: "user-5", Identity: "released", Reason: "account ID was recently released by another profile"},
+			{SourceID: "user-5", Identity: "github:7654321", Reason: "profile has reached its account limit"},
+		},
+	}
+	if diff := cmp.Diff(wantReport, report); diff != "" {
+		t.Errorf("Unexpected report (-wanted, +got): %s", diff)
+	}
+
+	paddy, err := storer.Get(ctx, "paddy@impractical.co")
+	if err != nil {
+		t.Fatalf("Error retrieving account: %+v\n", err)
+	}
+	if !paddy.IsRegistration || !paddy.Created.Equal(created) || !paddy.LastUsed.Equal(lastUsed) {
+		t.Errorf("Unexpected account: %+v", paddy)
+	}
+	profile, err := storer.ListByProfile(ctx, paddy.ProfileID)
+	if err != nil {
+		t.Fatalf("Error listing accounts: %+v\n", err)
+	}
+	ids := make([]string, 0, len(profile))
+	for _, account := range profile {
+		ids = append(ids, account.ID)
+	}
+	if diff := cmp.Diff([]string{"github:1234567", "paddy@impractical.co"}, ids, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+		t.Errorf("Unexpected accounts for profile (-wanted, +got): %s", diff)
+	}
+
+	sam, err := storer.Get(ctx, "sam")
+	if err != nil {
+		t.Fatalf("Error retrieving account: %+v\n", err)
+	}
+	if !sam.IsRegistration || sam.ProfileID == paddy.ProfileID || sam.ProfileID == "existing" {
+		t.Errorf("Expected sam to be registered to a new profile, got %+v", sam)
+	}
+}
@@ -0,0 +1,13 @@
+// Package keycloak reads users from a Keycloak realm export, so they can be
+// imported with lockbox.dev/accounts/importers.
+//
+// Realm exports are JSON objects with the realm's users in their "users"
+// property. When Keycloak is told to export users to separate files, each
+// of those files has the same shape, and can be read the same way. Users
+// are read one at a time, so large exports don't need to fit in memory.
+//
+// A user's email address and username are imported, as is the subject of
+// every identity provider they've linked, as "{identityProvider}:{userId}".
+// Service account users belong to clients, not people, and are reported as
+// skipped.
+package keycloak
@@ -0,0 +1,77 @@
+package keycloak
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"lockbox.dev/accounts/importers"
+)
+
+// federatedIdentity is an identity provider a user has linked to their
+// account.
+type federatedIdentity struct {
+	IdentityProvider string `json:"identityProvider"`
+	UserID           string `json:"userId"`
+}
+
+// user is a single user in a Keycloak realm export.
+type user struct {
+	ID                     string              `json:"id"`
+	Username               string              `json:"username"`
+	Email                  string              `json:"email"`
+	EmailVerified          bool                `json:"emailVerified"`
+	Enabled                bool                `json:"enabled"`
+	CreatedTimestamp       int64               `json:"createdTimestamp"`
+	ServiceAccountClientID string              `json:"serviceAccountClientId"`
+	FederatedIdentities    []federatedIdentity `json:"federatedIdentities"`
+}
+
+func (u user) toImport() importers.User {
+	res := importers.User{
+		SourceID: u.ID,
+		Disabled: !u.Enabled,
+	}
+	// Keycloak doesn't export when users last logged in
+	if u.CreatedTimestamp != 0 {
+		res.Created = time.UnixMilli(u.CreatedTimestamp).UTC()
+	}
+	if u.Email != "" {
+		res.Identities = append(res.Identities, importers.Identity{Kind: importers.KindEmail, ID: u.Email, Verified: u.EmailVerified})
+	}
+	if u.Username != "" {
+		res.Identities = append(res.Identities, importers.Identity{Kind: importers.KindUsername, ID: u.Username})
+	}
+	for _, fed := range u.FederatedIdentities {
+		res.Identities = append(res.Identities, importers.Identity{Kind: importers.KindSocial, ID: fed.IdentityProvider + ":" + fed.UserID})
+	}
+	return res
+}
+
+// Reader reads users from a Keycloak realm export. It implements
+// importers.Source.
+type Reader struct {
+	dec *importers.ArrayDecoder
+}
+
+// NewReader returns a Reader that reads the Keycloak realm export in `r`.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{dec: importers.NewArrayDecoder(r, "users")}
+}
+
+// Next returns the next user in the export. When there are no more users,
+// io.EOF is returned.
+func (r *Reader) Next() (importers.User, error) {
+	var u user
+	err := r.dec.Next(&u)
+	if errors.Is(err, io.EOF) {
+		return importers.User{}, io.EOF
+	}
+	if err != nil {
+		return importers.User{}, err
+	}
+	if u.ServiceAccountClientID != "" {
+		return importers.User{SourceID: u.ID, SkipReason: "service account for client " + u.ServiceAccountClientID}, nil
+	}
+	return u.toImport(), nil
+}
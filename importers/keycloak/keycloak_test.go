@@ -0,0 +1,73 @@
+package keycloak_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"lockbox.dev/accounts/importers"
+	"lockbox.dev/accounts/importers/keycloak"
+)
+
+func TestReader(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open("testdata/realm-export.json")
+	if err != nil {
+		t.Fatalf("Error opening fixture: %+v\n", err)
+	}
+	defer f.Close()
+
+	var got []importers.User
+	reader := keycloak.NewReader(f)
+	for {
+		user, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Error reading user: %+v\n", err)
+		}
+		got = append(got, user)
+	}
+
+	want := []importers.User{
+		{
+			SourceID: "5b8d2f1e-7c3a-4e6b-9d0f-1a2b3c4d5e6f",
+			Identities: []importers.Identity{
+				{Kind: importers.KindEmail, ID: "paddy@impractical.co", Verified: true},
+				{Kind: importers.KindUsername, ID: "paddy"},
+				{Kind: importers.KindSocial, ID: "github:1234567"},
+			},
+			Created: time.Date(2021, time.July, 14, 0, 12, 42, 451000000, time.UTC),
+		},
+		{
+			SourceID: "6c9e3a2f-8d4b-4f7c-0e1a-2b3c4d5e6f70",
+			Identities: []importers.Identity{
+				{Kind: importers.KindEmail, ID: "sam@impractical.co", Verified: true},
+				{Kind: importers.KindUsername, ID: "sam@impractical.co"},
+			},
+			Created: time.Date(2021, time.September, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			SourceID:   "7d0f4b3a-9e5c-4a8d-1f2b-3c4d5e6f7081",
+			SkipReason: "service account for client lockbox",
+		},
+		{
+			SourceID: "8e1a5c4b-0f6d-4b9e-2a3c-4d5e6f708192",
+			Identities: []importers.Identity{
+				{Kind: importers.KindEmail, ID: "disabled@impractical.co", Verified: true},
+				{Kind: importers.KindUsername, ID: "disabled"},
+			},
+			Created:  time.Date(2021, time.November, 1, 0, 0, 0, 0, time.UTC),
+			Disabled: true,
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected users (-wanted, +got): %s", diff)
+	}
+}
@@ -0,0 +1,339 @@
+package accounts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// ExportVersion is the version of the JSON Lines export format that
+	// Encoder writes. Decoder will refuse to read exports with a different
+	// version.
+	ExportVersion = 1
+
+	exportBatchSize = 100
+
+	recordTypeHeader  = "header"
+	recordTypeAccount = "account"
+)
+
+var (
+	// ErrMissingExportHeader is returned when reading an export that
+	// doesn't start with a header record.
+	ErrMissingExportHeader = errors.New("export is missing its header")
+	// ErrUnsupportedExportVersion is returned when reading an export
+	// written in a version of the format this package doesn't understand.
+	ErrUnsupportedExportVersion = errors.New("unsupported export version")
+	// ErrUnexpectedExportRecord is returned when reading an export that
+	// contains a record of an unknown type.
+	ErrUnexpectedExportRecord = errors.New("unexpected export record")
+	// ErrInvalidExportRecord is returned when reading an export that
+	// contains an Account record without an ID.
+	ErrInvalidExportRecord = errors.New("invalid export record")
+)
+
+// EnumerableStorer is a Storer that can list every Account it stores.
+type EnumerableStorer interface {
+	Storer
+	Enumerator
+}
+
+// exportHeader is the first line of an export.
+type exportHeader struct {
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+}
+
+// exportRecord is a line of an export after the header, holding an
+// Account.
+type exportRecord struct {
+	Type string `json:"type"`
+
+	ID             string    `json:"id"`
+	ProfileID      string    `json:"profileID,omitempty"`
+	Created        time.Time `json:"created"`
+	LastUsed       time.Time `json:"lastUsed"`
+	LastSeen       time.Time `json:"lastSeen"`
+	IsRegistration bool      `json:"isRegistration,omitempty"`
+}
+
+// Encoder writes Accounts to an io.Writer in the JSON Lines export format.
+type Encoder struct {
+	enc *json.Encoder
+}
+
+// NewEncoder returns an Encoder that writes to `w`. The export's header is
+// written immediately, so even an export with no Accounts in it can be
+// read back.
+func NewEncoder(w io.Writer) (*Encoder, error) {
+	enc := json.NewEncoder(w)
+	err := enc.Encode(exportHeader{Type: recordTypeHeader, Version: ExportVersion})
+	if err != nil {
+		return nil, fmt.Errorf("error writing export header: %w", err)
+	}
+	return &Encoder{enc: enc}, nil
+}
+
+// Encode writes `account` to the export.
+func (e *Encoder) Encode(account Account) error {
+	return e.enc.Encode(exportRecord{
+		Type:           recordTypeAccount,
+		ID:             account.ID,
+		ProfileID:      account.ProfileID,
+		Created:        account.Created,
+		LastUsed:       account.LastUsed,
+		LastSeen:       account.LastSeen,
+		IsRegistration: account.IsRegistration,
+	})
+}
+
+// Decoder reads Accounts from an io.Reader in the JSON Lines export format.
+type Decoder struct {
+	dec *json.Decoder
+}
+
+// NewDecoder returns a Decoder that reads from `r`. The export's header is
+// read immediately; if it is missing, ErrMissingExportHeader is returned,
+// and if the export was written in an unsupported version of the format,
+// ErrUnsupportedExportVersion is returned.
+func NewDecoder(r io.Reader) (*Decoder, error) {
+	dec := json.NewDecoder(r)
+	var header exportHeader
+	err := dec.Decode(&header)
+	if errors.Is(err, io.EOF) {
+		return nil, ErrMissingExportHeader
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading export header: %w", err)
+	}
+	if header.Type != recordTypeHeader {
+		return nil, ErrMissingExportHeader
+	}
+	if header.Version != ExportVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedExportVersion, header.Version)
+	}
+	return &Decoder{dec: dec}, nil
+}
+
+// Decode reads the next Account from the export. When there are no more
+// Accounts to read, io.EOF is returned. Records without an Account ID are
+// rejected with an error wrapping ErrInvalidExportRecord.
+func (d *Decoder) Decode() (Account, error) {
+	var record exportRecord
+	err := d.dec.Decode(&record)
+	if err != nil {
+		// not wrapped, so callers can check for io.EOF
+		return Account{}, err
+	}
+	if record.Type != recordTypeAccount {
+		return Account{}, fmt.Errorf("%w: %q", ErrUnexpectedExportRecord, record.Type)
+	}
+	if record.ID == "" {
+		return Account{}, fmt.Errorf("%w: account has no id", ErrInvalidExportRecord)
+	}
+	return Account{
+		ID:             record.ID,
+		ProfileID:      record.ProfileID,
+		Created:        record.Created,
+		LastUsed:       record.LastUsed,
+		LastSeen:       record.LastSeen,
+		IsRegistration: record.IsRegistration,
+	}, nil
+}
+
+// exportProfile writes every Account associated with `profileID` to
+// `enc`, registrations first, so that importing them in order satisfies
+// the registration rules of Storers. It returns the number of Accounts
+// written.
+func exportProfile(ctx context.Context, storer Storer, enc *Encoder, profileID string) (int, error) {
+	accts, err := storer.ListByProfile(ctx, profileID)
+	if err != nil {
+		return 0, fmt.Errorf("error listing accounts for profile %s: %w", profileID, err)
+	}
+	var written int
+	for _, registrations := range []bool{true, false} {
+		for _, account := range accts {
+			if account.IsRegistration != registrations {
+				continue
+			}
+			err = enc.Encode(account)
+			if err != nil {
+				return written, fmt.Errorf("error writing account %s: %w", account.ID, err)
+			}
+			written++
+		}
+	}
+	return written, nil
+}
+
+// ExportAll writes every Account in `storer` to `w` in the JSON Lines
+// export format, streaming them a page at a time. Accounts are grouped by
+// profile, with each profile's registration first. It returns the number
+// of Accounts written.
+func ExportAll(ctx context.Context, storer EnumerableStorer, w io.Writer) (int, error) {
+	enc, err := NewEncoder(w)
+	if err != nil {
+		return 0, err
+	}
+	var written int
+	var after string
+	exported := map[string]struct{}{}
+	for {
+		batch, err := storer.ListAll(ctx, after, exportBatchSize)
+		if err != nil {
+			return written, fmt.Errorf("error listing accounts: %w", err)
+		}
+		if len(batch) == 0 {
+			return written, nil
+		}
+		for _, account := range batch {
+			if _, ok := exported[account.ProfileID]; ok {
+				continue
+			}
+			n, err := exportProfile(ctx, storer, enc, account.ProfileID)
+			written += n
+			if err != nil {
+				return written, err
+			}
+			exported[account.ProfileID] = struct{}{}
+		}
+		after = batch[len(batch)-1].ID
+	}
+}
+
+// ExportProfiles writes every Account associated with any of `profileIDs`
+// to `w` in the JSON Lines export format, with each profile's
+// registration first. It returns the number of Accounts written.
+func ExportProfiles(ctx context.Context, storer Storer, w io.Writer, profileIDs ...string) (int, error) {
+	enc, err := NewEncoder(w)
+	if err != nil {
+		return 0, err
+	}
+	var written int
+	for _, profileID := range profileIDs {
+		n, err := exportProfile(ctx, storer, enc, profileID)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// ConflictPolicy determines what Import does with an Account that
+// conflicts with one already in the Storer.
+type ConflictPolicy int
+
+const (
+	// ConflictFail stops the import with an error when an Account
+	// conflicts. The error wraps ErrAccountAlreadyExists or
+	// ErrProfileIDAlreadyExists.
+	ConflictFail ConflictPolicy = iota
+
+	// ConflictSkip leaves the existing Account alone and moves on to the
+	// next Account in the export.
+	ConflictSkip
+
+	// ConflictOverwrite replaces an existing Account that has the same ID
+	// as the imported Account. A registration whose profile ID is already
+	// in use is imported as a non-registration Account of that profile.
+	//
+	// If the Storer implements Replacer, the existing Account is replaced
+	// in a single operation. Otherwise, it is deleted before the imported
+	// Account is created, so the Account will briefly not exist.
+	ConflictOverwrite
+)
+
+// ImportReport describes the outcome of an import.
+type ImportReport struct {
+	// Imported is the number of Accounts created that didn't conflict with
+	// anything.
+	Imported int
+
+	// Skipped is the number of conflicting Accounts that were skipped.
+	Skipped int
+
+	// Overwritten is the number of conflicting Accounts that were
+	// imported anyway, replacing the existing Account or joining the
+	// existing profile.
+	Overwritten int
+}
+
+// overwrite creates `account` in `storer`, resolving whatever conflict
+// `err` describes.
+func overwrite(ctx context.Context, storer Storer, account Account, err error) error {
+	replacer, canReplace := storer.(Replacer)
+	var replacing bool
+	// each conflict can only be resolved once, so give up if Create keeps
+	// failing
+	for attempts := 0; attempts < 2 && err != nil; attempts++ {
+		switch {
+		case errors.Is(err, ErrAccountAlreadyExists) && canReplace:
+			// deleting the Account would release its ID, and
+			// Storers that quarantine released IDs wouldn't let
+			// another profile create it again
+			replacing = true
+		case errors.Is(err, ErrAccountAlreadyExists):
+			err = storer.Delete(ctx, account.ID)
+			if err != nil {
+				return fmt.Errorf("error deleting account %s: %w", account.ID, err)
+			}
+		case errors.Is(err, ErrProfileIDAlreadyExists):
+			account.IsRegistration = false
+		default:
+			return err
+		}
+		if replacing {
+			err = replacer.Replace(ctx, account)
+		} else {
+			err = storer.Create(ctx, account)
+		}
+	}
+	return err
+}
+
+// Import reads an export in the JSON Lines export format from `r`, and
+// creates every Account in it in `storer`. Accounts that conflict with
+// Accounts already in `storer` are handled according to `policy`.
+func Import(ctx context.Context, storer Storer, r io.Reader, policy ConflictPolicy) (ImportReport, error) {
+	var report ImportReport
+	dec, err := NewDecoder(r)
+	if err != nil {
+		return report, err
+	}
+	for {
+		account, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		if err != nil {
+			return report, fmt.Errorf("error reading export: %w", err)
+		}
+		err = storer.Create(ctx, account)
+		if err == nil {
+			report.Imported++
+			continue
+		}
+		if !errors.Is(err, ErrAccountAlreadyExists) && !errors.Is(err, ErrProfileIDAlreadyExists) {
+			return report, fmt.Errorf("error creating account %s: %w", account.ID, err)
+		}
+		switch policy {
+		case ConflictSkip:
+			report.Skipped++
+		case ConflictOverwrite:
+			err = overwrite(ctx, storer, account, err)
+			if err != nil {
+				return report, fmt.Errorf("error overwriting account %s: %w", account.ID, err)
+			}
+			report.Overwritten++
+		case ConflictFail:
+			return report, fmt.Errorf("error importing account %s: %w", account.ID, err)
+		default:
+			return report, fmt.Errorf("error importing account %s: unknown conflict policy %d: %w", account.ID, policy, err)
+		}
+	}
+}
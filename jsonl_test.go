@@ -0,0 +1,271 @@
+package accounts_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"lockbox.dev/accounts"
+	"lockbox.dev/accounts/storers/memory"
+)
+
+func newMemoryStorer(t *testing.T) *memory.Storer {
+	t.Helper()
+
+	storer, err := memory.NewStorer()
+	if err != nil {
+		t.Fatalf("Error creating memory Storer: %+v\n", err)
+	}
+	return storer
+}
+
+// exportFixtures creates two profiles in `storer`, with the non-registration
+// Accounts sorting before the registrations, and returns every Account
+// created.
+func exportFixtures(ctx context.Context, t *testing.T, storer accounts.Storer) []accounts.Account {
+	t.Helper()
+
+	clock := accounts.NewFakeClock(time.Date(2022, time.March, 14, 15, 9, 26, 535000000, time.UTC))
+	fixtures := []accounts.Account{
+		{ID: "z@impractical.co", ProfileID: "profile-1", IsRegistration: true},
+		{ID: "a@impractical.co", ProfileID: "profile-1"},
+		{ID: "y@impractical.co", ProfileID: "profile-2", IsRegistration: true},
+		{ID: "b@impractical.co", ProfileID: "profile-2"},
+	}
+	for pos, account := range fixtures {
+		account.Created = clock.Advance(time.Minute)
+		account.LastUsed = clock.Advance(time.Hour)
+		account.LastSeen = clock.Advance(time.Second)
+		fixtures[pos] = account
+		err := storer.Create(ctx, account)
+		if err != nil {
+			t.Fatalf("Error creating account: %+v\n", err)
+		}
+	}
+	return fixtures
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	src := newMemoryStorer(t)
+	fixtures := exportFixtures(ctx, t, src)
+
+	var buf bytes.Buffer
+	written, err := accounts.ExportAll(ctx, src, &buf)
+	if err != nil {
+		t.Fatalf("Error exporting accounts: %+v\n", err)
+	}
+	if written != len(fixtures) {
+		t.Errorf("Expected %d accounts to be written, got %d", len(fixtures), written)
+	}
+
+	dst := newMemoryStorer(t)
+	report, err := accounts.Import(ctx, dst, &buf, accounts.ConflictFail)
+	if err != nil {
+		t.Fatalf("Error importing accounts: %+v\n", err)
+	}
+	if diff := cmp.Diff(accounts.ImportReport{Imported: len(fixtures)}, report); diff != "" {
+		t.Errorf("Unexpected import report (-wanted, +got): %s", diff)
+	}
+	for _, want := range fixtures {
+		got, err := dst.Get(ctx, want.ID)
+		if err != nil {
+			t.Fatalf("Error retrieving account %s: %+v\n", want.ID, err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unexpected account %s (-wanted, +got): %s", want.ID, diff)
+		}
+	}
+}
+
+func TestExportProfiles(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storer := newMemoryStorer(t)
+	exportFixtures(ctx, t, storer)
+
+	var buf bytes.Buffer
+	written, err := accounts.ExportProfiles(ctx, storer, &buf, "profile-2")
+	if err != nil {
+		t.Fatalf("Error exporting accounts: %+v\n", err)
+	}
+	if written != 2 { //nolint:gomnd // profile-2 has two accounts
+		t.Errorf("Expected 2 accounts to be written, got %d", written)
+	}
+
+	dec, err := accounts.NewDecoder(&buf)
+	if err != nil {
+		t.Fatalf("Error reading export header: %+v\n", err)
+	}
+	var ids []string
+	for {
+		account, err := dec.Decode()
+		if err != nil {
+			break
+		}
+		ids = append(ids, account.ID)
+	}
+	if diff := cmp.Diff([]string{"y@impractical.co", "b@impractical.co"}, ids); diff != "" {
+		t.Errorf("Unexpected exported accounts (-wanted, +got): %s", diff)
+	}
+}
+
+func TestImportConflictPolicies(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		// existingProfile is the profile of the Account that's
+		// already in the Storer, profile-1 if it's empty
+		existingProfile string
+		policy          accounts.ConflictPolicy
+		wantErr         error
+		wantReport      accounts.ImportReport
+		wantLast        time.Time
+	}
+
+	existingUsed := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]testCase{
+		"fail": {
+			// z@impractical.co is exported first, and conflicts on
+			// its profile ID
+			policy:  accounts.ConflictFail,
+			wantErr: accounts.ErrProfileIDAlreadyExists,
+		},
+		"skip": {
+			policy:     accounts.ConflictSkip,
+			wantReport: accounts.ImportReport{Imported: 2, Skipped: 2},
+			wantLast:   existingUsed,
+		},
+		"overwrite": {
+			policy:     accounts.ConflictOverwrite,
+			wantReport: accounts.ImportReport{Imported: 2, Overwritten: 2},
+		},
+		"overwrite-other-profile": {
+			// replacing the Account mustn't release its ID,
+			// or the quarantine would keep profile-1 from
+			// taking it
+			existingProfile: "profile-3",
+			policy:          accounts.ConflictOverwrite,
+			wantReport:      accounts.ImportReport{Imported: 3, Overwritten: 1},
+		},
+	}
+
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			src := newMemoryStorer(t)
+			fixtures := exportFixtures(ctx, t, src)
+			var buf bytes.Buffer
+			_, err := accounts.ExportAll(ctx, src, &buf)
+			if err != nil {
+				t.Fatalf("Error exporting accounts: %+v\n", err)
+			}
+
+			existingProfile := test.existingProfile
+			if existingProfile == "" {
+				existingProfile = "profile-1"
+			}
+			dst := newMemoryStorer(t)
+			err = dst.Create(ctx, accounts.Account{
+				ID:             "A@impractical.co",
+				ProfileID:      existingProfile,
+				LastUsed:       existingUsed,
+				IsRegistration: true,
+			})
+			if err != nil {
+				t.Fatalf("Error creating account: %+v\n", err)
+			}
+
+			report, err := accounts.Import(ctx, dst, &buf, test.policy)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("Expected %v, got %v", test.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error importing accounts: %+v\n", err)
+			}
+			if diff := cmp.Diff(test.wantReport, report); diff != "" {
+				t.Errorf("Unexpected import report (-wanted, +got): %s", diff)
+			}
+			account, err := dst.Get(ctx, "a@impractical.co")
+			if err != nil {
+				t.Fatalf("Error retrieving account: %+v\n", err)
+			}
+			wantLast := test.wantLast
+			if wantLast.IsZero() {
+				wantLast = fixtures[1].LastUsed
+			}
+			if !account.LastUsed.Equal(wantLast) {
+				t.Errorf("Expected LastUsed to be %s, got %s", wantLast, account.LastUsed)
+			}
+			if test.policy == accounts.ConflictOverwrite && account.ProfileID != fixtures[1].ProfileID {
+				t.Errorf("Expected ProfileID to be %s, got %s", fixtures[1].ProfileID, account.ProfileID)
+			}
+		})
+	}
+}
+
+func TestEncoderHeader(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	_, err := accounts.NewEncoder(&buf)
+	if err != nil {
+		t.Fatalf("Error creating encoder: %+v\n", err)
+	}
+	want := `{"type":"header","version":1}` + "\n"
+	if buf.String() != want {
+		t.Errorf("Expected header %q, got %q", want, buf.String())
+	}
+}
+
+func TestDecoderRejectsMissingIDs(t *testing.T) {
+	t.Parallel()
+
+	input := `{"type":"header","version":1}` + "\n" + `{"type":"account","profileID":"paddy"}` + "\n"
+	dec, err := accounts.NewDecoder(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Error creating decoder: %+v\n", err)
+	}
+	_, err = dec.Decode()
+	if !errors.Is(err, accounts.ErrInvalidExportRecord) {
+		t.Errorf("Expected %v, got %v", accounts.ErrInvalidExportRecord, err)
+	}
+}
+
+func TestDecoderRejectsBadHeaders(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		input   string
+		wantErr error
+	}{
+		"empty":     {input: "", wantErr: accounts.ErrMissingExportHeader},
+		"no-header": {input: `{"type":"account","id":"paddy@impractical.co"}` + "\n", wantErr: accounts.ErrMissingExportHeader},
+		"version":   {input: `{"type":"header","version":2}` + "\n", wantErr: accounts.ErrUnsupportedExportVersion},
+	}
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := accounts.NewDecoder(strings.NewReader(test.input))
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("Expected %v, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
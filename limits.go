@@ -0,0 +1,90 @@
+package accounts
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrProfileAccountLimitReached is returned when creating an Account
+	// would give its profile more Accounts than its ProfileLimits allow.
+	ErrProfileAccountLimitReached = errors.New("profile has reached its account limit")
+)
+
+// Kind describes what sort of identifier an Account's ID is.
+type Kind string
+
+const (
+	// KindEmail is an Account whose ID is an email address.
+	KindEmail Kind = "email"
+
+	// KindPhone is an Account whose ID is a phone number, in E.164 format.
+	KindPhone Kind = "phone"
+
+	// KindSocial is an Account whose ID is the subject of a social or
+	// enterprise identity provider, in "provider:subject" format.
+	KindSocial Kind = "social"
+
+	// KindUsername is an Account whose ID is anything else.
+	KindUsername Kind = "username"
+)
+
+// KindOf returns the Kind of the Account whose ID is `id`.
+func KindOf(id string) Kind {
+	switch {
+	case strings.Contains(id, "@"):
+		return KindEmail
+	case isPhoneNumber(id):
+		return KindPhone
+	case strings.Contains(id, ":"):
+		return KindSocial
+	default:
+		return KindUsername
+	}
+}
+
+func isPhoneNumber(id string) bool {
+	if len(id) < 2 || id[0] != '+' { //nolint:gomnd // a plus and at least one digit
+		return false
+	}
+	for _, r := range id[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ProfileLimits caps how many Accounts a single profile can have. Storers
+// that support ProfileLimits check them in the same operation that creates
+// an Account, so concurrent requests can't get around them.
+type ProfileLimits struct {
+	// Max is the most Accounts a profile can have. Zero means there is
+	// no limit.
+	Max int
+
+	// PerKind overrides Max for Accounts of specific Kinds. An Account
+	// whose Kind is in PerKind can only be created if the profile has
+	// fewer Accounts of that Kind than the limit; Accounts of other Kinds
+	// aren't counted. A limit of zero means there is no limit for that
+	// Kind.
+	PerKind map[Kind]int
+}
+
+// For returns the limit that applies when creating an Account of `kind`,
+// and whether only Accounts of the same Kind count against it. A limit of
+// zero means there is no limit.
+func (l ProfileLimits) For(kind Kind) (limit int, sameKindOnly bool) {
+	if limit, ok := l.PerKind[kind]; ok {
+		return limit, true
+	}
+	return l.Max, false
+}
+
+// Allows returns whether a profile can have an Account of `kind` added to it
+// when it already has `existing` Accounts. If the limit for `kind` only
+// counts Accounts of the same Kind, `existing` should only count those.
+func (l ProfileLimits) Allows(kind Kind, existing int) bool {
+	limit, _ := l.For(kind)
+	return limit < 1 || existing < limit
+}
@@ -0,0 +1,54 @@
+package accounts_test
+
+import (
+	"testing"
+
+	"lockbox.dev/accounts"
+)
+
+func TestKindOf(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]accounts.Kind{
+		"paddy@impractical.co": accounts.KindEmail,
+		"+15555550100":         accounts.KindPhone,
+		"+1555-555-0100":       accounts.KindUsername,
+		"github:1234567":       accounts.KindSocial,
+		"paddy":                accounts.KindUsername,
+		"+":                    accounts.KindUsername,
+	}
+	for id, want := range tests {
+		if got := accounts.KindOf(id); got != want {
+			t.Errorf("Expected %q to be %q, got %q", id, want, got)
+		}
+	}
+}
+
+func TestProfileLimitsAllows(t *testing.T) {
+	t.Parallel()
+
+	limits := accounts.ProfileLimits{
+		Max:     3,
+		PerKind: map[accounts.Kind]int{accounts.KindEmail: 1, accounts.KindSocial: 0},
+	}
+	tests := []struct {
+		kind     accounts.Kind
+		existing int
+		want     bool
+	}{
+		{kind: accounts.KindUsername, existing: 2, want: true},
+		{kind: accounts.KindUsername, existing: 3, want: false},
+		{kind: accounts.KindEmail, existing: 0, want: true},
+		{kind: accounts.KindEmail, existing: 1, want: false},
+		{kind: accounts.KindSocial, existing: 100, want: true},
+		{kind: accounts.KindPhone, existing: 3, want: false},
+	}
+	for _, test := range tests {
+		if got := limits.Allows(test.kind, test.existing); got != test.want {
+			t.Errorf("Expected Allows(%q, %d) to be %v, got %v", test.kind, test.existing, test.want, got)
+		}
+	}
+	if !(accounts.ProfileLimits{}).Allows(accounts.KindEmail, 1000) {
+		t.Error("Expected empty limits to allow any number of accounts")
+	}
+}
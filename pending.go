@@ -0,0 +1,230 @@
+package accounts
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	yall "yall.in"
+)
+
+const (
+	// DefaultLinkTTL is how long a pending link can be confirmed for if
+	// StartLink isn't given a TTL.
+	DefaultLinkTTL = 24 * time.Hour
+
+	linkTokenSize = 32
+)
+
+var (
+	// ErrPendingLinkNotFound is returned when confirming a link with a
+	// token that doesn't match any pending link, including one that was
+	// already confirmed.
+	ErrPendingLinkNotFound = errors.New("pending link not found")
+	// ErrPendingLinkExpired is returned when confirming a link after its
+	// pending link expired.
+	ErrPendingLinkExpired = errors.New("pending link expired")
+	// ErrNoPendingLinkStorer is returned when linking Accounts without a
+	// PendingLinkStorer set in the Dependencies.
+	ErrNoPendingLinkStorer = errors.New("no pending link storer configured")
+)
+
+// PendingLink is an Account that has been requested, but won't be created
+// until whoever controls its ID confirms it, by presenting the token that
+// was sent to it.
+type PendingLink struct {
+	// TokenHash is the hash of the token that confirms the PendingLink,
+	// as returned by HashLinkToken. The token itself is never stored.
+	TokenHash string
+
+	// AccountID is the ID of the Account to create.
+	AccountID string
+
+	// ProfileID is the profile to create the Account in.
+	ProfileID string
+
+	// Created is when the PendingLink was created.
+	Created time.Time
+
+	// Expires is when the PendingLink can no longer be confirmed.
+	Expires time.Time
+}
+
+// PendingLinkStorer persists PendingLinks until they're confirmed or expire.
+type PendingLinkStorer interface {
+	// CreatePendingLink stores `link`.
+	CreatePendingLink(ctx context.Context, link PendingLink) error
+
+	// TakePendingLink deletes the PendingLink whose TokenHash is
+	// `tokenHash` and returns it, in a single atomic operation, so each
+	// PendingLink can only be taken once. If no PendingLink matches,
+	// ErrPendingLinkNotFound is returned.
+	TakePendingLink(ctx context.Context, tokenHash string) (PendingLink, error)
+
+	// DeleteExpiredPendingLinks deletes every PendingLink that expired
+	// before `now`, and returns how many were deleted.
+	DeleteExpiredPendingLinks(ctx context.Context, now time.Time) (int, error)
+}
+
+// PendingLinkLister is implemented by PendingLinkStorers that can list the
+// PendingLinks for a profile.
+type PendingLinkLister interface {
+	// ListPendingLinksByProfile returns every PendingLink for the passed
+	// profile ID, including expired ones that haven't been deleted yet, in
+	// no particular order.
+	ListPendingLinksByProfile(ctx context.Context, profileID string) ([]PendingLink, error)
+}
+
+// HashLinkToken returns the hash of `token` that is stored in place of the
+// token itself. Tokens are random and long enough that they can't be
+// guessed, so a fast hash is enough to keep a copy of the database from
+// being used to confirm links.
+func HashLinkToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// StartLink creates a PendingLink for adding `account` to its profile, which
+// expires after `ttl`, or DefaultLinkTTL if `ttl` is zero. It returns the
+// token that confirms the PendingLink, which should be sent somewhere only
+// whoever controls the Account's ID can read it.
+//
+// The PendingLink is only created if the Account could be created now: if
+// an Account with the same ID already exists, ErrAccountAlreadyExists is
+// returned; if the Storer implements ReleaseChecker and another profile
+// released the ID within its quarantine, ErrAccountIDRecentlyReleased is
+// returned; and errors wrapping ErrAccountIDDenied or
+// ErrEmailDomainNotAllowed are returned if the denylist or DomainPolicy
+// don't allow the ID.
+func (d Dependencies) StartLink(ctx context.Context, account Account, ttl time.Duration) (string, PendingLink, error) {
+	if d.PendingLinks == nil {
+		return "", PendingLink{}, ErrNoPendingLinkStorer
+	}
+	err := d.checkLinkable(ctx, account)
+	if err != nil {
+		return "", PendingLink{}, err
+	}
+	if ttl <= 0 {
+		ttl = DefaultLinkTTL
+	}
+	raw := make([]byte, linkTokenSize)
+	_, err = io.ReadFull(rand.Reader, raw)
+	if err != nil {
+		return "", PendingLink{}, fmt.Errorf("error generating link token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	now := d.Now()
+	link := PendingLink{
+		TokenHash: HashLinkToken(token),
+		AccountID: account.ID,
+		ProfileID: account.ProfileID,
+		Created:   now,
+		Expires:   now.Add(ttl),
+	}
+	err = d.PendingLinks.CreatePendingLink(ctx, link)
+	if err != nil {
+		return "", PendingLink{}, fmt.Errorf("error storing pending link: %w", err)
+	}
+	return token, link, nil
+}
+
+// checkLinkable returns an error if `account` couldn't be created now, so
+// PendingLinks aren't started for Accounts ConfirmLink would reject.
+func (d Dependencies) checkLinkable(ctx context.Context, account Account) error {
+	err := d.CheckDenylist(ctx, account.ID)
+	if err != nil {
+		return err
+	}
+	err = d.CheckEmailDomain(ctx, account.ID)
+	if err != nil {
+		return err
+	}
+	_, err = d.Storer.Get(ctx, account.ID)
+	if err == nil {
+		return ErrAccountAlreadyExists
+	}
+	if !errors.Is(err, ErrAccountNotFound) {
+		return fmt.Errorf("error checking for account %s: %w", account.ID, err)
+	}
+	if checker, ok := d.Storer.(ReleaseChecker); ok {
+		return checker.CheckReleased(ctx, account.ID, account.ProfileID)
+	}
+	return nil
+}
+
+// ConfirmLink creates the Account that the PendingLink confirmed by `token`
+// was created for, and returns it. A token can only be used once, whether or
+// not the Account is created.
+//
+// If the token doesn't match a PendingLink, ErrPendingLinkNotFound is
+// returned. If the PendingLink has expired, ErrPendingLinkExpired is
+// returned. The denylist and DomainPolicy are checked again, in case they
+// changed while the PendingLink was waiting, so errors wrapping
+// ErrAccountIDDenied or ErrEmailDomainNotAllowed can be returned too. Errors
+// from creating the Account, like ErrAccountAlreadyExists or
+// ErrProfileAccountLimitReached, are returned as-is.
+func (d Dependencies) ConfirmLink(ctx context.Context, token string) (Account, error) {
+	if d.PendingLinks == nil {
+		return Account{}, ErrNoPendingLinkStorer
+	}
+	link, err := d.PendingLinks.TakePendingLink(ctx, HashLinkToken(token))
+	if err != nil {
+		return Account{}, err
+	}
+	if !d.Now().Before(link.Expires) {
+		return Account{}, ErrPendingLinkExpired
+	}
+	err = d.CheckDenylist(ctx, link.AccountID)
+	if err != nil {
+		return Account{}, err
+	}
+	err = d.CheckEmailDomain(ctx, link.AccountID)
+	if err != nil {
+		return Account{}, err
+	}
+	account := FillDefaultsWithClock(Account{ID: link.AccountID, ProfileID: link.ProfileID}, d.Clock)
+	err = d.Storer.Create(ctx, account)
+	if err != nil {
+		return Account{}, err
+	}
+	return account, nil
+}
+
+// CollectExpiredLinks deletes every PendingLink that has expired, and
+// returns how many were deleted.
+func (d Dependencies) CollectExpiredLinks(ctx context.Context) (int, error) {
+	if d.PendingLinks == nil {
+		return 0, ErrNoPendingLinkStorer
+	}
+	return d.PendingLinks.DeleteExpiredPendingLinks(ctx, d.Now())
+}
+
+// CollectExpiredLinksEvery calls CollectExpiredLinks every `interval`, until
+// `ctx` is canceled. Errors are logged using the logger in `ctx`, and don't
+// stop future collections from being attempted.
+//
+// CollectExpiredLinksEvery blocks until `ctx` is canceled, so it should
+// usually be run in its own goroutine.
+func (d Dependencies) CollectExpiredLinksEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			collected, err := d.CollectExpiredLinks(ctx)
+			if err != nil {
+				yall.FromContext(ctx).WithError(err).Error("error collecting expired pending links")
+				continue
+			}
+			yall.FromContext(ctx).WithField("collected", collected).Debug("collected expired pending links")
+		}
+	}
+}
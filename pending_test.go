@@ -0,0 +1,340 @@
+package accounts_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"lockbox.dev/accounts"
+	"lockbox.dev/accounts/storers/memory"
+	"lockbox.dev/accounts/storers/postgres"
+	"lockbox.dev/accounts/storertest"
+)
+
+// pendingLinkStorer is a Storer that also stores and lists PendingLinks,
+// stores DenyRules, and erases profiles.
+type pendingLinkStorer interface {
+	accounts.Storer
+	accounts.PendingLinkStorer
+	accounts.PendingLinkLister
+	accounts.DenylistStorer
+	accounts.ProfileEraser
+}
+
+// runWithPendingLinkStorers runs `test` as a subtest against every Storer
+// that stores PendingLinks.
+func runWithPendingLinkStorers(t *testing.T, test func(*testing.T, pendingLinkStorer)) {
+	t.Helper()
+
+	factories := map[string]storertest.Factory{"memory": memory.Factory{Options: []memory.Option{
+		memory.WithReleaseQuarantine(accounts.DefaultReleaseQuarantine, releaseKey()),
+	}}}
+	quarantine := postgres.WithReleaseQuarantine(accounts.DefaultReleaseQuarantine)
+	if factory := postgresFactory(t, quarantine); factory != nil {
+		factories["postgres"] = factory
+	}
+	if factory := postgresFactory(t, quarantine, postgres.WithHashedIDs(hashedIDKeys())); factory != nil {
+		factories["postgres-hashed"] = factory
+	}
+	for name, factory := range factories {
+		name, factory := name, factory
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			t.Cleanup(func() {
+				if err := factory.TeardownStorers(); err != nil {
+					t.Errorf("Error cleaning up after %T: %+v\n", factory, err)
+				}
+			})
+			storer, err := factory.NewStorer(context.Background())
+			if err != nil {
+				t.Fatalf("Error creating Storer from %T: %+v\n", factory, err)
+			}
+			links, ok := storer.(pendingLinkStorer)
+			if !ok {
+				t.Fatalf("%T doesn't store pending links", storer)
+			}
+			test(t, links)
+		})
+	}
+}
+
+func TestConfirmLink(t *testing.T) {
+	t.Parallel()
+
+	runWithPendingLinkStorers(t, testConfirmLink)
+}
+
+func testConfirmLink(t *testing.T, storer pendingLinkStorer) {
+	ctx := context.Background()
+	now := time.Date(2022, time.April, 1, 12, 0, 0, 0, time.UTC)
+	clock := accounts.NewFakeClock(now)
+	deps := accounts.Dependencies{Storer: storer, Clock: clock, PendingLinks: storer}
+
+	token, link, err := deps.StartLink(ctx, accounts.Account{ID: "paddy@carvers.co", ProfileID: "profile-1"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Error starting link: %+v\n", err)
+	}
+	if link.TokenHash != accounts.HashLinkToken(token) || link.TokenHash == token {
+		t.Errorf("Expected the pending link to store a hash of the token, got %+v", link)
+	}
+	if !link.Expires.Equal(now.Add(time.Hour)) {
+		t.Errorf("Expected link to expire at %s, got %s", now.Add(time.Hour), link.Expires)
+	}
+	_, err = storer.Get(ctx, "paddy@carvers.co")
+	if !errors.Is(err, accounts.ErrAccountNotFound) {
+		t.Errorf("Expected account not to exist before confirmation, got %v", err)
+	}
+
+	_, err = deps.ConfirmLink(ctx, "not-the-token")
+	if !errors.Is(err, accounts.ErrPendingLinkNotFound) {
+		t.Errorf("Expected ErrPendingLinkNotFound for the wrong token, got %v", err)
+	}
+
+	clock.Advance(time.Minute)
+	account, err := deps.ConfirmLink(ctx, token)
+	if err != nil {
+		t.Fatalf("Error confirming link: %+v\n", err)
+	}
+	want := accounts.Account{
+		ID:        "paddy@carvers.co",
+		ProfileID: "profile-1",
+		Created:   now.Add(time.Minute),
+		LastUsed:  now.Add(time.Minute),
+		LastSeen:  now.Add(time.Minute),
+	}
+	if diff := cmp.Diff(want, account); diff != "" {
+		t.Errorf("Unexpected account (-wanted, +got): %s", diff)
+	}
+	stored, err := storer.Get(ctx, "paddy@carvers.co")
+	if err != nil {
+		t.Fatalf("Error retrieving confirmed account: %+v\n", err)
+	}
+	if diff := cmp.Diff(want, stored); diff != "" {
+		t.Errorf("Unexpected stored account (-wanted, +got): %s", diff)
+	}
+
+	_, err = deps.ConfirmLink(ctx, token)
+	if !errors.Is(err, accounts.ErrPendingLinkNotFound) {
+		t.Errorf("Expected a used token to be rejected with ErrPendingLinkNotFound, got %v", err)
+	}
+
+	_, _, err = deps.StartLink(ctx, accounts.Account{ID: "paddy@carvers.co", ProfileID: "profile-2"}, time.Hour)
+	if !errors.Is(err, accounts.ErrAccountAlreadyExists) {
+		t.Errorf("Expected ErrAccountAlreadyExists linking an existing account, got %v", err)
+	}
+}
+
+func TestConfirmLinkRechecksPolicies(t *testing.T) {
+	t.Parallel()
+
+	runWithPendingLinkStorers(t, testConfirmLinkRechecksPolicies)
+}
+
+func testConfirmLinkRechecksPolicies(t *testing.T, storer pendingLinkStorer) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "blocklist")
+	writeDomainList(t, path, "")
+	policy, err := accounts.NewFileDomainPolicy("", path)
+	if err != nil {
+		t.Fatalf("Error creating domain policy: %+v\n", err)
+	}
+	deps := accounts.Dependencies{Storer: storer, PendingLinks: storer, Denylist: storer, DomainPolicy: policy}
+
+	denied, _, err := deps.StartLink(ctx, accounts.Account{ID: "admin@carvers.co", ProfileID: "profile-1"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Error starting link: %+v\n", err)
+	}
+	blocked, _, err := deps.StartLink(ctx, accounts.Account{ID: "paddy@mailinator.com", ProfileID: "profile-1"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Error starting link: %+v\n", err)
+	}
+	links, err := storer.ListPendingLinksByProfile(ctx, "profile-1")
+	if err != nil {
+		t.Fatalf("Error listing pending links: %+v\n", err)
+	}
+	if len(links) != 2 { //nolint:gomnd // two links were started
+		t.Errorf("Expected 2 pending links, got %+v", links)
+	}
+
+	// the rules change while the links are pending
+	_, err = deps.AddDenyRule(ctx, accounts.DenyRule{Kind: accounts.DenyRuleRegex, Pattern: "^admin@"})
+	if err != nil {
+		t.Fatalf("Error adding deny rule: %+v\n", err)
+	}
+	writeDomainList(t, path, "mailinator.com\n")
+	err = policy.Reload()
+	if err != nil {
+		t.Fatalf("Error reloading domain policy: %+v\n", err)
+	}
+
+	_, err = deps.ConfirmLink(ctx, denied)
+	if !errors.Is(err, accounts.ErrAccountIDDenied) {
+		t.Errorf("Expected %v confirming a denied ID, got %v", accounts.ErrAccountIDDenied, err)
+	}
+	_, err = deps.ConfirmLink(ctx, blocked)
+	if !errors.Is(err, accounts.ErrEmailDomainNotAllowed) {
+		t.Errorf("Expected %v confirming a blocked domain, got %v", accounts.ErrEmailDomainNotAllowed, err)
+	}
+	for _, id := range []string{"admin@carvers.co", "paddy@mailinator.com"} {
+		_, err = storer.Get(ctx, id)
+		if !errors.Is(err, accounts.ErrAccountNotFound) {
+			t.Errorf("Expected %s not to be created, got %v", id, err)
+		}
+	}
+}
+
+func testStartLinkChecksPolicies(t *testing.T, storer pendingLinkStorer) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "blocklist")
+	writeDomainList(t, path, "mailinator.com\n")
+	policy, err := accounts.NewFileDomainPolicy("", path)
+	if err != nil {
+		t.Fatalf("Error creating domain policy: %+v\n", err)
+	}
+	deps := accounts.Dependencies{Storer: storer, PendingLinks: storer, Denylist: storer, DomainPolicy: policy}
+	_, err = deps.AddDenyRule(ctx, accounts.DenyRule{Kind: accounts.DenyRuleRegex, Pattern: "^admin@"})
+	if err != nil {
+		t.Fatalf("Error adding deny rule: %+v\n", err)
+	}
+	released := accounts.Account{ID: "paddy@carvers.co", ProfileID: "profile-2", Created: time.Now()}
+	err = storer.Create(ctx, released)
+	if err != nil {
+		t.Fatalf("Error creating account: %+v\n", err)
+	}
+	err = storer.Delete(ctx, released.ID)
+	if err != nil {
+		t.Fatalf("Error deleting account: %+v\n", err)
+	}
+
+	tests := map[string]struct {
+		id  string
+		err error
+	}{
+		"denied":   {id: "admin@carvers.co", err: accounts.ErrAccountIDDenied},
+		"blocked":  {id: "paddy@mailinator.com", err: accounts.ErrEmailDomainNotAllowed},
+		"released": {id: released.ID, err: accounts.ErrAccountIDRecentlyReleased},
+	}
+	for name, test := range tests {
+		_, _, err = deps.StartLink(ctx, accounts.Account{ID: test.id, ProfileID: "profile-1"}, time.Hour)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %v starting a link, got %v", name, test.err, err)
+		}
+	}
+	links, err := storer.ListPendingLinksByProfile(ctx, "profile-1")
+	if err != nil {
+		t.Fatalf("Error listing pending links: %+v\n", err)
+	}
+	if len(links) != 0 {
+		t.Errorf("Expected no pending links, got %+v", links)
+	}
+}
+
+func TestStartLinkChecksPolicies(t *testing.T) {
+	t.Parallel()
+
+	runWithPendingLinkStorers(t, testStartLinkChecksPolicies)
+}
+
+func TestEraseProfileDeletesPendingLinks(t *testing.T) {
+	t.Parallel()
+
+	runWithPendingLinkStorers(t, testEraseProfileDeletesPendingLinks)
+}
+
+func testEraseProfileDeletesPendingLinks(t *testing.T, storer pendingLinkStorer) {
+	ctx := context.Background()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %+v\n", err)
+	}
+	deps := accounts.Dependencies{Storer: storer, PendingLinks: storer, ReceiptSigner: priv}
+	err = storer.Create(ctx, accounts.Account{ID: "paddy@impractical.co", ProfileID: "profile-1", IsRegistration: true})
+	if err != nil {
+		t.Fatalf("Error creating account: %+v\n", err)
+	}
+	erased, _, err := deps.StartLink(ctx, accounts.Account{ID: "paddy@carvers.co", ProfileID: "profile-1"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Error starting link: %+v\n", err)
+	}
+	kept, _, err := deps.StartLink(ctx, accounts.Account{ID: "sam@carvers.co", ProfileID: "profile-2"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Error starting link: %+v\n", err)
+	}
+
+	_, err = deps.EraseProfile(ctx, "profile-1")
+	if err != nil {
+		t.Fatalf("Error erasing profile: %+v\n", err)
+	}
+	_, err = deps.ConfirmLink(ctx, erased)
+	if !errors.Is(err, accounts.ErrPendingLinkNotFound) {
+		t.Errorf("Expected %v confirming a link for an erased profile, got %v", accounts.ErrPendingLinkNotFound, err)
+	}
+	_, err = storer.Get(ctx, "paddy@carvers.co")
+	if !errors.Is(err, accounts.ErrAccountNotFound) {
+		t.Errorf("Expected no account to be recreated for the erased profile, got %v", err)
+	}
+	_, err = deps.ConfirmLink(ctx, kept)
+	if err != nil {
+		t.Errorf("Expected other profiles' links to be kept, got %+v", err)
+	}
+}
+
+func TestExpiredLinks(t *testing.T) {
+	t.Parallel()
+
+	runWithPendingLinkStorers(t, testExpiredLinks)
+}
+
+func testExpiredLinks(t *testing.T, storer pendingLinkStorer) {
+	ctx := context.Background()
+	now := time.Date(2022, time.April, 1, 12, 0, 0, 0, time.UTC)
+	clock := accounts.NewFakeClock(now)
+	deps := accounts.Dependencies{Storer: storer, Clock: clock, PendingLinks: storer}
+
+	expiring, _, err := deps.StartLink(ctx, accounts.Account{ID: "paddy@carvers.co", ProfileID: "profile-1"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Error starting link: %+v\n", err)
+	}
+	_, _, err = deps.StartLink(ctx, accounts.Account{ID: "paddy@impractical.co", ProfileID: "profile-1"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Error starting link: %+v\n", err)
+	}
+	clock.Advance(time.Hour)
+	_, err = deps.ConfirmLink(ctx, expiring)
+	if !errors.Is(err, accounts.ErrPendingLinkExpired) {
+		t.Errorf("Expected ErrPendingLinkExpired, got %v", err)
+	}
+	_, err = storer.Get(ctx, "paddy@carvers.co")
+	if !errors.Is(err, accounts.ErrAccountNotFound) {
+		t.Errorf("Expected an expired link not to create an account, got %v", err)
+	}
+
+	// the default TTL outlasts the others
+	_, _, err = deps.StartLink(ctx, accounts.Account{ID: "paddycarver", ProfileID: "profile-1"}, 0)
+	if err != nil {
+		t.Fatalf("Error starting link: %+v\n", err)
+	}
+	clock.Advance(time.Minute)
+	collected, err := deps.CollectExpiredLinks(ctx)
+	if err != nil {
+		t.Fatalf("Error collecting expired links: %+v\n", err)
+	}
+	if collected != 1 {
+		t.Errorf("Expected 1 expired link to be collected, got %d", collected)
+	}
+	clock.Advance(accounts.DefaultLinkTTL)
+	collected, err = deps.CollectExpiredLinks(ctx)
+	if err != nil {
+		t.Fatalf("Error collecting expired links: %+v\n", err)
+	}
+	if collected != 1 {
+		t.Errorf("Expected the default TTL link to be collected, got %d", collected)
+	}
+}
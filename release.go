@@ -0,0 +1,70 @@
+package accounts
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// DefaultReleaseQuarantine is a reasonable length for a Storer's release
+// quarantine: how long it keeps other profiles from taking an ID that was
+// released. Storers don't quarantine released IDs unless they're configured
+// to.
+//
+// An ID is released when the Account using it is deleted or renamed. Until
+// the quarantine ends, only the profile that released it can create an
+// Account with it, so whoever gets the ID next, like the new owner of a
+// recycled email address, doesn't immediately inherit the trust other
+// systems placed in the old Account.
+const DefaultReleaseQuarantine = 30 * 24 * time.Hour
+
+// ErrAccountIDRecentlyReleased is returned when creating an Account, or
+// renaming one, with an ID that another profile released within the Storer's
+// release quarantine.
+var ErrAccountIDRecentlyReleased = errors.New("account ID was recently released by another profile")
+
+// HashReleasedID returns the hash of `id` that Storers record when it is
+// released, so the quarantine can be enforced without keeping the ID
+// itself. IDs are compared case-insensitively, so it is a hash of the
+// lowercased ID.
+//
+// The hash is an HMAC keyed with `key`, so IDs can't be recovered from it by
+// hashing guesses without the key. Storers that keep IDs in plain text
+// anyway may pass an empty key, which makes it a plain SHA-256 hash.
+func HashReleasedID(key []byte, id string) string {
+	if len(key) < 1 {
+		sum := sha256.Sum256([]byte(strings.ToLower(id)))
+		return hex.EncodeToString(sum[:])
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strings.ToLower(id))) //nolint:errcheck // hash.Hash never returns an error
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ReleaseChecker is implemented by Storers that quarantine released IDs, so
+// the quarantine can be checked before an Account is created, like when a
+// PendingLink for it is started.
+type ReleaseChecker interface {
+	// CheckReleased returns ErrAccountIDRecentlyReleased if a profile
+	// other than `profileID` released `id` within the Storer's release
+	// quarantine.
+	CheckReleased(ctx context.Context, id, profileID string) error
+}
+
+// Replacer is implemented by Storers that can replace an Account in a single
+// atomic operation. Storers that quarantine released IDs should implement
+// it, or overwriting an Account with one from another profile releases the
+// ID and then refuses to reuse it.
+type Replacer interface {
+	// Replace deletes the Account with the same ID as `account`, if
+	// there is one, and creates `account`, without releasing the ID. If
+	// `account` can't be created, because it is a registration for a
+	// profile already in use or its profile is full, the existing Account
+	// is left alone and ErrProfileIDAlreadyExists or
+	// ErrProfileAccountLimitReached is returned.
+	Replace(ctx context.Context, account Account) error
+}
@@ -0,0 +1,217 @@
+package accounts_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"lockbox.dev/accounts"
+	"lockbox.dev/accounts/storers/bolt"
+	"lockbox.dev/accounts/storers/memory"
+	"lockbox.dev/accounts/storers/postgres"
+	"lockbox.dev/accounts/storers/sqlite"
+	"lockbox.dev/accounts/storertest"
+)
+
+func TestCreateReleasedID(t *testing.T) {
+	t.Parallel()
+
+	clock := accounts.NewFakeClock(time.Date(2022, time.April, 1, 12, 0, 0, 0, time.UTC))
+	runWithRenamers(t, clock, func(t *testing.T, storer renamerStorer) {
+		ctx := context.Background()
+		createAccounts(ctx, t, storer,
+			accounts.Account{ID: "paddy@carvers.co", ProfileID: "profile-1"},
+			accounts.Account{ID: "paddy@impractical.co", ProfileID: "profile-1"},
+			accounts.Account{ID: "paddy@lockbox.dev", ProfileID: "profile-1"},
+		)
+		err := storer.Delete(ctx, "paddy@carvers.co")
+		if err != nil {
+			t.Fatalf("Error deleting account: %+v\n", err)
+		}
+		err = storer.DeleteUnlessLast(ctx, "paddy@impractical.co")
+		if err != nil {
+			t.Fatalf("Error deleting account: %+v\n", err)
+		}
+
+		for _, id := range []string{"Paddy@Carvers.co", "paddy@impractical.co"} {
+			err = storer.Create(ctx, accounts.Account{ID: id, ProfileID: "profile-2", IsRegistration: true})
+			if !errors.Is(err, accounts.ErrAccountIDRecentlyReleased) {
+				t.Errorf("Expected %v claiming %q from another profile, got %v", accounts.ErrAccountIDRecentlyReleased, id, err)
+			}
+		}
+		// the profile that released the IDs can take them back
+		createAccounts(ctx, t, storer,
+			accounts.Account{ID: "paddy@carvers.co", ProfileID: "profile-1"},
+			accounts.Account{ID: "paddy@impractical.co", ProfileID: "profile-1"},
+		)
+	})
+}
+
+// quarantineStorer is a Storer with a release quarantine.
+type quarantineStorer interface {
+	accounts.Storer
+	accounts.LastAccountGuard
+	accounts.ReleaseChecker
+	accounts.Replacer
+	DeleteExpiredReleasedIDs(ctx context.Context) (int, error)
+}
+
+// runWithQuarantines runs `test` against every Storer that can quarantine
+// released IDs, with a quarantine of `period`. Each Storer gets its own
+// Clock, passed to `test`, so the Storers can be tested in parallel.
+func runWithQuarantines(t *testing.T, period time.Duration, test func(*testing.T, quarantineStorer, *accounts.FakeClock)) {
+	t.Helper()
+
+	clocks := map[string]*accounts.FakeClock{}
+	for _, name := range []string{"memory", "sqlite", "bolt", "postgres", "postgres-hashed"} {
+		clocks[name] = accounts.NewFakeClock(time.Date(2022, time.April, 1, 12, 0, 0, 0, time.UTC))
+	}
+	factories := map[string]storertest.Factory{
+		"memory": memory.Factory{Options: []memory.Option{
+			memory.WithClock(clocks["memory"]), memory.WithReleaseQuarantine(period, releaseKey()),
+		}},
+		"sqlite": sqliteFactory(t, sqlite.WithClock(clocks["sqlite"]), sqlite.WithReleaseQuarantine(period)),
+		"bolt":   boltFactory(t, bolt.WithClock(clocks["bolt"]), bolt.WithReleaseQuarantine(period)),
+	}
+	quarantine := postgres.WithReleaseQuarantine(period)
+	if factory := postgresFactory(t, postgres.WithClock(clocks["postgres"]), quarantine); factory != nil {
+		factories["postgres"] = factory
+	}
+	if factory := postgresFactory(t, postgres.WithClock(clocks["postgres-hashed"]), quarantine, postgres.WithHashedIDs(hashedIDKeys())); factory != nil {
+		factories["postgres-hashed"] = factory
+	}
+	for name, factory := range factories {
+		name, factory := name, factory
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			t.Cleanup(func() {
+				if err := factory.TeardownStorers(); err != nil {
+					t.Errorf("Error cleaning up after %T: %+v\n", factory, err)
+				}
+			})
+			storer, err := factory.NewStorer(context.Background())
+			if err != nil {
+				t.Fatalf("Error creating Storer from %T: %+v\n", factory, err)
+			}
+			quarantined, ok := storer.(quarantineStorer)
+			if !ok {
+				t.Fatalf("%T can't quarantine released IDs", storer)
+			}
+			test(t, quarantined, clocks[name])
+		})
+	}
+}
+
+func TestCreateAfterReleaseQuarantine(t *testing.T) {
+	t.Parallel()
+
+	runWithQuarantines(t, time.Hour, func(t *testing.T, storer quarantineStorer, clock *accounts.FakeClock) {
+		ctx := context.Background()
+		createAccounts(ctx, t, storer,
+			accounts.Account{ID: "paddy@carvers.co", ProfileID: "profile-1"},
+			accounts.Account{ID: "paddy@impractical.co", ProfileID: "profile-1"},
+		)
+		err := storer.Delete(ctx, "paddy@carvers.co")
+		if err != nil {
+			t.Fatalf("Error deleting account: %+v\n", err)
+		}
+		err = storer.DeleteUnlessLast(ctx, "paddy@impractical.co")
+		if !errors.Is(err, accounts.ErrLastAccount) {
+			t.Fatalf("Expected %v deleting the last account, got %v", accounts.ErrLastAccount, err)
+		}
+		// only IDs that were actually deleted are released
+		err = storer.CheckReleased(ctx, "paddy@impractical.co", "profile-2")
+		if err != nil {
+			t.Errorf("Expected an ID that wasn't deleted not to be quarantined, got %+v", err)
+		}
+
+		clock.Advance(time.Hour - time.Second)
+		collected, err := storer.DeleteExpiredReleasedIDs(ctx)
+		if err != nil {
+			t.Fatalf("Error deleting expired released IDs: %+v\n", err)
+		}
+		if collected != 0 {
+			t.Errorf("Expected no released IDs to have expired, %d were deleted", collected)
+		}
+		err = storer.CheckReleased(ctx, "Paddy@Carvers.co", "profile-2")
+		if !errors.Is(err, accounts.ErrAccountIDRecentlyReleased) {
+			t.Errorf("Expected %v checking during the quarantine, got %v", accounts.ErrAccountIDRecentlyReleased, err)
+		}
+		err = storer.Create(ctx, accounts.Account{ID: "paddy@carvers.co", ProfileID: "profile-2"})
+		if !errors.Is(err, accounts.ErrAccountIDRecentlyReleased) {
+			t.Errorf("Expected %v during the quarantine, got %v", accounts.ErrAccountIDRecentlyReleased, err)
+		}
+		// a conflicting ID takes precedence over the quarantine
+		err = storer.Create(ctx, accounts.Account{ID: "paddy@impractical.co", ProfileID: "profile-2"})
+		if !errors.Is(err, accounts.ErrAccountAlreadyExists) {
+			t.Errorf("Expected %v claiming an ID in use, got %v", accounts.ErrAccountAlreadyExists, err)
+		}
+
+		clock.Advance(time.Second)
+		collected, err = storer.DeleteExpiredReleasedIDs(ctx)
+		if err != nil {
+			t.Fatalf("Error deleting expired released IDs: %+v\n", err)
+		}
+		if collected != 1 {
+			t.Errorf("Expected 1 released ID to have expired, %d were deleted", collected)
+		}
+		err = storer.Create(ctx, accounts.Account{ID: "paddy@carvers.co", ProfileID: "profile-2"})
+		if err != nil {
+			t.Errorf("Expected create to succeed after the quarantine, got %+v", err)
+		}
+	})
+}
+
+func TestReplaceDoesntRelease(t *testing.T) {
+	t.Parallel()
+
+	runWithQuarantines(t, accounts.DefaultReleaseQuarantine, func(t *testing.T, storer quarantineStorer, _ *accounts.FakeClock) {
+		ctx := context.Background()
+		createAccounts(ctx, t, storer,
+			accounts.Account{ID: "paddy@carvers.co", ProfileID: "profile-1", IsRegistration: true},
+			accounts.Account{ID: "paddy@impractical.co", ProfileID: "profile-2", IsRegistration: true},
+		)
+		err := storer.Replace(ctx, accounts.Account{ID: "paddy@carvers.co", ProfileID: "profile-2", IsRegistration: true})
+		if !errors.Is(err, accounts.ErrProfileIDAlreadyExists) {
+			t.Errorf("Expected %v replacing with a second registration, got %v", accounts.ErrProfileIDAlreadyExists, err)
+		}
+		account, err := storer.Get(ctx, "paddy@carvers.co")
+		if err != nil || account.ProfileID != "profile-1" {
+			t.Errorf("Expected a failed replace to leave the account alone, got %+v, %v", account, err)
+		}
+
+		err = storer.Replace(ctx, accounts.Account{ID: "paddy@carvers.co", ProfileID: "profile-2"})
+		if err != nil {
+			t.Fatalf("Error replacing account: %+v\n", err)
+		}
+		account, err = storer.Get(ctx, "paddy@carvers.co")
+		if err != nil || account.ProfileID != "profile-2" {
+			t.Errorf("Expected the account to be replaced, got %+v, %v", account, err)
+		}
+		err = storer.CheckReleased(ctx, "paddy@carvers.co", "profile-3")
+		if err != nil {
+			t.Errorf("Expected replacing an account not to release its ID, got %+v", err)
+		}
+	})
+}
+
+func TestCreateReleasedIDWithoutQuarantine(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storer, err := memory.NewStorer(memory.WithReleaseQuarantine(0, nil))
+	if err != nil {
+		t.Fatalf("Error creating memory Storer: %+v\n", err)
+	}
+	createAccounts(ctx, t, storer, accounts.Account{ID: "paddy@carvers.co", ProfileID: "profile-1"})
+	err = storer.Delete(ctx, "paddy@carvers.co")
+	if err != nil {
+		t.Fatalf("Error deleting account: %+v\n", err)
+	}
+	err = storer.Create(ctx, accounts.Account{ID: "paddy@carvers.co", ProfileID: "profile-2"})
+	if err != nil {
+		t.Errorf("Expected create to succeed without a quarantine, got %+v", err)
+	}
+}
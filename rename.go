@@ -0,0 +1,59 @@
+package accounts
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	// HistoryActionRenamed is the Action of the HistoryRecords kept when
+	// an Account's ID is changed. The record's AccountID is the new ID,
+	// and its Details hold the old ID under HistoryDetailPreviousID.
+	HistoryActionRenamed = "renamed"
+
+	// HistoryDetailPreviousID is the key in a HistoryRecord's Details
+	// that holds the ID an Account had before it was renamed.
+	HistoryDetailPreviousID = "previousID"
+)
+
+// ErrRenameNotSupported is returned when renaming an Account in a Storer
+// that doesn't implement Renamer.
+var ErrRenameNotSupported = errors.New("storer does not support renaming accounts")
+
+// Renamer is implemented by Storers that can change the ID of an Account.
+type Renamer interface {
+	// Rename changes the ID of the Account identified by `id` to
+	// `newID`, keeping its profile and timestamps, and keeps a
+	// HistoryRecord of the change, in a single atomic operation. Renaming
+	// an Account to a different capitalization of its own ID is allowed.
+	//
+	// If no Account matches `id`, ErrAccountNotFound is returned. If
+	// another Account already uses `newID`, ErrAccountAlreadyExists is
+	// returned. If `newID` was released by another profile within the
+	// Storer's release quarantine, ErrAccountIDRecentlyReleased is
+	// returned. The old ID is released, unless only its capitalization
+	// changed.
+	Rename(ctx context.Context, id, newID string) error
+}
+
+// RenameAccount changes the ID of the Account identified by `id` to `newID`.
+// If the Storer doesn't implement Renamer, ErrRenameNotSupported is
+// returned. If `newID` matches a DenyRule, an error wrapping
+// ErrAccountIDDenied is returned, and if it is an email address at a domain
+// the DomainPolicy doesn't allow, an error wrapping ErrEmailDomainNotAllowed
+// is returned.
+func (d Dependencies) RenameAccount(ctx context.Context, id, newID string) error {
+	renamer, ok := d.Storer.(Renamer)
+	if !ok {
+		return ErrRenameNotSupported
+	}
+	err := d.CheckDenylist(ctx, newID)
+	if err != nil {
+		return err
+	}
+	err = d.CheckEmailDomain(ctx, newID)
+	if err != nil {
+		return err
+	}
+	return renamer.Rename(ctx, id, newID)
+}
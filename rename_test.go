@@ -0,0 +1,290 @@
+package accounts_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"lockbox.dev/accounts"
+	"lockbox.dev/accounts/storers/memory"
+	"lockbox.dev/accounts/storers/postgres"
+	"lockbox.dev/accounts/storertest"
+)
+
+// renamerStorer is a Storer that can rename Accounts and list their history,
+// along with the other optional interfaces Storers with history implement.
+type renamerStorer interface {
+	accounts.Storer
+	accounts.Renamer
+	accounts.HistoryLister
+	accounts.LastAccountGuard
+	accounts.ProfileEraser
+}
+
+// runWithRenamers runs `test` as a subtest against every Storer that can
+// rename Accounts, with each Storer reading the time from `clock`.
+func runWithRenamers(t *testing.T, clock accounts.Clock, test func(*testing.T, renamerStorer)) {
+	t.Helper()
+
+	factories := map[string]storertest.Factory{"memory": memory.Factory{Options: []memory.Option{
+		memory.WithClock(clock), memory.WithReleaseQuarantine(accounts.DefaultReleaseQuarantine, releaseKey()),
+	}}}
+	quarantine := postgres.WithReleaseQuarantine(accounts.DefaultReleaseQuarantine)
+	if factory := postgresFactory(t, postgres.WithClock(clock), quarantine); factory != nil {
+		factories["postgres"] = factory
+	}
+	if factory := postgresFactory(t, postgres.WithClock(clock), quarantine, postgres.WithHashedIDs(hashedIDKeys())); factory != nil {
+		factories["postgres-hashed"] = factory
+	}
+	for name, factory := range factories {
+		name, factory := name, factory
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			t.Cleanup(func() {
+				if err := factory.TeardownStorers(); err != nil {
+					t.Errorf("Error cleaning up after %T: %+v\n", factory, err)
+				}
+			})
+			storer, err := factory.NewStorer(context.Background())
+			if err != nil {
+				t.Fatalf("Error creating Storer from %T: %+v\n", factory, err)
+			}
+			renamer, ok := storer.(renamerStorer)
+			if !ok {
+				t.Fatalf("%T can't rename accounts", storer)
+			}
+			test(t, renamer)
+		})
+	}
+}
+
+func createAccounts(ctx context.Context, t *testing.T, storer accounts.Storer, accts ...accounts.Account) {
+	t.Helper()
+
+	for _, account := range accts {
+		err := storer.Create(ctx, account)
+		if err != nil {
+			t.Fatalf("Error creating account %q: %+v\n", account.ID, err)
+		}
+	}
+}
+
+func TestRename(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2022, time.April, 1, 12, 0, 0, 0, time.UTC)
+	runWithRenamers(t, accounts.NewFakeClock(now), func(t *testing.T, storer renamerStorer) {
+		ctx := context.Background()
+		created := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+		original := accounts.Account{
+			ID:             "paddy@carvers.co",
+			ProfileID:      "profile-1",
+			IsRegistration: true,
+			Created:        created,
+			LastUsed:       created.Add(time.Hour),
+			LastSeen:       created.Add(2 * time.Hour),
+		}
+		createAccounts(ctx, t, storer, original)
+
+		err := storer.Rename(ctx, "Paddy@Carvers.co", "paddy@impractical.co")
+		if err != nil {
+			t.Fatalf("Error renaming account: %+v\n", err)
+		}
+		_, err = storer.Get(ctx, "paddy@carvers.co")
+		if !errors.Is(err, accounts.ErrAccountNotFound) {
+			t.Errorf("Expected old ID to be gone, got %v", err)
+		}
+		renamed, err := storer.Get(ctx, "paddy@impractical.co")
+		if err != nil {
+			t.Fatalf("Error retrieving renamed account: %+v\n", err)
+		}
+		expected := original
+		expected.ID = "paddy@impractical.co"
+		if diff := cmp.Diff(expected, renamed); diff != "" {
+			t.Errorf("Unexpected diff in renamed account (-wanted, +got): %s", diff)
+		}
+
+		history, err := storer.ListHistoryByProfile(ctx, "profile-1")
+		if err != nil {
+			t.Fatalf("Error listing history: %+v\n", err)
+		}
+		expectedHistory := []accounts.HistoryRecord{{
+			AccountID: "paddy@impractical.co",
+			ProfileID: "profile-1",
+			Action:    accounts.HistoryActionRenamed,
+			At:        now,
+			Details:   map[string]string{accounts.HistoryDetailPreviousID: "paddy@carvers.co"},
+		}}
+		if diff := cmp.Diff(expectedHistory, history); diff != "" {
+			t.Errorf("Unexpected diff in history (-wanted, +got): %s", diff)
+		}
+	})
+}
+
+func TestRenameChangingCase(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2022, time.April, 1, 12, 0, 0, 0, time.UTC)
+	runWithRenamers(t, accounts.NewFakeClock(now), func(t *testing.T, storer renamerStorer) {
+		ctx := context.Background()
+		createAccounts(ctx, t, storer, accounts.Account{ID: "paddy@carvers.co", ProfileID: "profile-1"})
+
+		err := storer.Rename(ctx, "paddy@carvers.co", "Paddy@Carvers.co")
+		if err != nil {
+			t.Fatalf("Error renaming account: %+v\n", err)
+		}
+		renamed, err := storer.Get(ctx, "paddy@carvers.co")
+		if err != nil {
+			t.Fatalf("Error retrieving renamed account: %+v\n", err)
+		}
+		if renamed.ID != "Paddy@Carvers.co" {
+			t.Errorf("Expected ID to be %q, got %q", "Paddy@Carvers.co", renamed.ID)
+		}
+	})
+}
+
+func TestRenameErrors(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2022, time.April, 1, 12, 0, 0, 0, time.UTC)
+	runWithRenamers(t, accounts.NewFakeClock(now), func(t *testing.T, storer renamerStorer) {
+		ctx := context.Background()
+		createAccounts(ctx, t, storer,
+			accounts.Account{ID: "paddy@carvers.co", ProfileID: "profile-1"},
+			accounts.Account{ID: "paddy@impractical.co", ProfileID: "profile-1"},
+		)
+
+		err := storer.Rename(ctx, "nobody@example.com", "paddy@example.com")
+		if !errors.Is(err, accounts.ErrAccountNotFound) {
+			t.Errorf("Expected %v renaming a missing account, got %v", accounts.ErrAccountNotFound, err)
+		}
+		err = storer.Rename(ctx, "paddy@carvers.co", "PADDY@impractical.co")
+		if !errors.Is(err, accounts.ErrAccountAlreadyExists) {
+			t.Errorf("Expected %v renaming onto an existing ID, got %v", accounts.ErrAccountAlreadyExists, err)
+		}
+		history, err := storer.ListHistoryByProfile(ctx, "profile-1")
+		if err != nil {
+			t.Fatalf("Error listing history: %+v\n", err)
+		}
+		if len(history) != 0 {
+			t.Errorf("Expected failed renames not to be recorded, got %+v", history)
+		}
+	})
+}
+
+func TestRenameOntoReleasedID(t *testing.T) {
+	t.Parallel()
+
+	clock := accounts.NewFakeClock(time.Date(2022, time.April, 1, 12, 0, 0, 0, time.UTC))
+	runWithRenamers(t, clock, func(t *testing.T, storer renamerStorer) {
+		ctx := context.Background()
+		createAccounts(ctx, t, storer,
+			accounts.Account{ID: "paddy@carvers.co", ProfileID: "profile-1"},
+			accounts.Account{ID: "paddy@impractical.co", ProfileID: "profile-2"},
+			accounts.Account{ID: "paddy@example.com", ProfileID: "profile-2"},
+		)
+
+		err := storer.Rename(ctx, "paddy@carvers.co", "paddy@lockbox.dev")
+		if err != nil {
+			t.Fatalf("Error renaming account: %+v\n", err)
+		}
+		err = storer.Rename(ctx, "paddy@impractical.co", "Paddy@Carvers.co")
+		if !errors.Is(err, accounts.ErrAccountIDRecentlyReleased) {
+			t.Errorf("Expected %v claiming another profile's released ID, got %v", accounts.ErrAccountIDRecentlyReleased, err)
+		}
+		// the profile that released the ID can take it back
+		err = storer.Rename(ctx, "paddy@lockbox.dev", "paddy@carvers.co")
+		if err != nil {
+			t.Fatalf("Error renaming account back: %+v\n", err)
+		}
+	})
+}
+
+func TestRenameAfterQuarantine(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	clock := accounts.NewFakeClock(time.Date(2022, time.April, 1, 12, 0, 0, 0, time.UTC))
+	storer, err := memory.NewStorer(memory.WithClock(clock), memory.WithReleaseQuarantine(time.Hour, releaseKey()))
+	if err != nil {
+		t.Fatalf("Error creating memory Storer: %+v\n", err)
+	}
+	createAccounts(ctx, t, storer,
+		accounts.Account{ID: "paddy@carvers.co", ProfileID: "profile-1"},
+		accounts.Account{ID: "paddy@impractical.co", ProfileID: "profile-2"},
+	)
+	err = storer.Rename(ctx, "paddy@carvers.co", "paddy@lockbox.dev")
+	if err != nil {
+		t.Fatalf("Error renaming account: %+v\n", err)
+	}
+	clock.Advance(time.Hour - time.Second)
+	err = storer.Rename(ctx, "paddy@impractical.co", "paddy@carvers.co")
+	if !errors.Is(err, accounts.ErrAccountIDRecentlyReleased) {
+		t.Errorf("Expected %v during the quarantine, got %v", accounts.ErrAccountIDRecentlyReleased, err)
+	}
+	clock.Advance(time.Second)
+	err = storer.Rename(ctx, "paddy@impractical.co", "paddy@carvers.co")
+	if err != nil {
+		t.Errorf("Expected rename to succeed after the quarantine, got %+v", err)
+	}
+}
+
+func TestRenameAccountWithoutRenamer(t *testing.T) {
+	t.Parallel()
+
+	deps := accounts.Dependencies{Storer: struct{ accounts.Storer }{newMemoryStorer(t)}}
+	err := deps.RenameAccount(context.Background(), "paddy@carvers.co", "paddy@impractical.co")
+	if !errors.Is(err, accounts.ErrRenameNotSupported) {
+		t.Errorf("Expected %v, got %v", accounts.ErrRenameNotSupported, err)
+	}
+}
+
+func TestEraseProfileScrubsHistory(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2022, time.April, 1, 12, 0, 0, 0, time.UTC)
+	runWithRenamers(t, accounts.NewFakeClock(now), func(t *testing.T, storer renamerStorer) {
+		ctx := context.Background()
+		createAccounts(ctx, t, storer, accounts.Account{ID: "paddy@carvers.co", ProfileID: "profile-1"})
+		err := storer.Rename(ctx, "paddy@carvers.co", "paddy@impractical.co")
+		if err != nil {
+			t.Fatalf("Error renaming account: %+v\n", err)
+		}
+
+		_, err = storer.EraseProfile(ctx, "profile-1")
+		if err != nil {
+			t.Fatalf("Error erasing profile: %+v\n", err)
+		}
+		history, err := storer.ListHistoryByProfile(ctx, "profile-1")
+		if err != nil {
+			t.Fatalf("Error listing history: %+v\n", err)
+		}
+		if strings.HasSuffix(t.Name(), "/postgres-hashed") && len(history) == 1 {
+			// with hashed IDs, the placeholders are keyed hashes we can't
+			// compute here, so check they aren't the unkeyed ones and
+			// compare the rest of the record
+			for _, id := range []string{history[0].AccountID, history[0].Details[accounts.HistoryDetailPreviousID]} {
+				if !accounts.IsErasedPlaceholder(id) || id == accounts.ErasedPlaceholder("paddy@impractical.co") || id == accounts.ErasedPlaceholder("paddy@carvers.co") {
+					t.Errorf("Expected keyed erased placeholder, got %q", id)
+				}
+			}
+			history[0].AccountID = accounts.ErasedPlaceholder("paddy@impractical.co")
+			history[0].Details[accounts.HistoryDetailPreviousID] = accounts.ErasedPlaceholder("paddy@carvers.co")
+		}
+		expected := []accounts.HistoryRecord{{
+			AccountID: accounts.ErasedPlaceholder("paddy@impractical.co"),
+			ProfileID: "profile-1",
+			Action:    accounts.HistoryActionRenamed,
+			At:        now,
+			Details:   map[string]string{accounts.HistoryDetailPreviousID: accounts.ErasedPlaceholder("paddy@carvers.co")},
+		}}
+		if diff := cmp.Diff(expected, history); diff != "" {
+			t.Errorf("Unexpected diff in history (-wanted, +got): %s", diff)
+		}
+	})
+}
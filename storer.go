@@ -13,3 +13,39 @@ type Storer interface {
 	Delete(ctx context.Context, id string) error
 	ListByProfile(ctx context.Context, profileID string) ([]Account, error)
 }
+
+// LastAccountGuard is implemented by Storers that can keep a profile from
+// losing its last Account.
+type LastAccountGuard interface {
+	// DeleteUnlessLast deletes the Account that matches the passed ID,
+	// unless it is the only Account associated with its profile, in which
+	// case it returns ErrLastAccount. Checking and deleting happen in a
+	// single atomic operation, so concurrently deleting every Account of
+	// a profile leaves at least one of them behind. Like Delete, it
+	// doesn't return an error if no Account matches the passed ID.
+	DeleteUnlessLast(ctx context.Context, id string) error
+}
+
+// ProfileEraser is implemented by Storers that can erase everything they
+// store about a profile.
+type ProfileEraser interface {
+	// EraseProfile deletes every Account associated with the passed
+	// profile ID in a single atomic operation, and returns the Accounts
+	// that were deleted. Storers that keep history or audit records must
+	// replace the IDs of the deleted Accounts in those records with
+	// ErasedPlaceholder, or ErasedHashPlaceholder if they keep keyed
+	// hashes of IDs, as part of the same operation.
+	EraseProfile(ctx context.Context, profileID string) ([]Account, error)
+}
+
+// Enumerator is implemented by Storers that can list every Account they
+// store, a page at a time.
+//
+// ListAll returns up to `limit` Accounts, in an order determined by the
+// Storer that is consistent between calls. Passing the ID of the last Account
+// returned as `after` returns the next page of Accounts; passing an empty
+// `after` returns the first page. Once every Account has been returned, an
+// empty slice is returned.
+type Enumerator interface {
+	ListAll(ctx context.Context, after string, limit int) ([]Account, error)
+}
@@ -0,0 +1,40 @@
+package bolt
+
+import (
+	"time"
+
+	"lockbox.dev/accounts"
+)
+
+// Account is a representation of the accounts.Account type that is suitable
+// to be stored in a bbolt database.
+type Account struct {
+	ID             string    `json:"id"`
+	ProfileID      string    `json:"profileID"`
+	Created        time.Time `json:"createdAt"`
+	LastUsed       time.Time `json:"lastUsedAt"`
+	LastSeen       time.Time `json:"lastSeenAt"`
+	IsRegistration bool      `json:"isRegistration,omitempty"`
+}
+
+func fromBolt(account Account) accounts.Account {
+	return accounts.Account{
+		ID:             account.ID,
+		ProfileID:      account.ProfileID,
+		Created:        account.Created,
+		LastUsed:       account.LastUsed,
+		LastSeen:       account.LastSeen,
+		IsRegistration: account.IsRegistration,
+	}
+}
+
+func toBolt(account accounts.Account) Account {
+	return Account{
+		ID:             account.ID,
+		ProfileID:      account.ProfileID,
+		Created:        account.Created,
+		LastUsed:       account.LastUsed,
+		LastSeen:       account.LastSeen,
+		IsRegistration: account.IsRegistration,
+	}
+}
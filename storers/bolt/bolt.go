@@ -0,0 +1,343 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	bbolt "go.etcd.io/bbolt"
+
+	"lockbox.dev/accounts"
+)
+
+var (
+	accountsBucket = []byte("accounts")
+	profilesBucket = []byte("profiles")
+	releasedBucket = []byte("released")
+)
+
+// Storer provides a bbolt-backed implementation of the Storer interface.
+type Storer struct {
+	db         *bbolt.DB
+	quarantine time.Duration
+	clock      accounts.Clock
+}
+
+// NewStorer returns a Storer instance that is backed by the specified
+// *bbolt.DB and configured by `opts`, creating the buckets the Storer needs
+// if they don't exist. The returned Storer instance is ready to be used as a
+// Storer.
+func NewStorer(_ context.Context, db *bbolt.DB, opts ...Option) (*Storer, error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		for _, bucket := range [][]byte{accountsBucket, profilesBucket, releasedBucket} {
+			_, err := tx.CreateBucketIfNotExists(bucket)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	storer := &Storer{db: db}
+	for _, opt := range opts {
+		opt(storer)
+	}
+	return storer, nil
+}
+
+func key(id string) []byte {
+	return []byte(strings.ToLower(id))
+}
+
+// profileKey returns the key of the bucket holding the IDs of the Accounts
+// associated with `profileID`. Profile IDs are opaque, so unlike IDs their
+// case matters.
+func profileKey(profileID string) []byte {
+	return []byte(profileID)
+}
+
+func getAccount(tx *bbolt.Tx, id string) (*Account, error) {
+	val := tx.Bucket(accountsBucket).Get(key(id))
+	if val == nil {
+		return nil, nil //nolint:nilnil // a nil Account means it wasn't found
+	}
+	var account Account
+	err := json.Unmarshal(val, &account)
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func putAccount(tx *bbolt.Tx, account Account) error {
+	val, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(accountsBucket).Put(key(account.ID), val)
+}
+
+// insertAccount adds `account` to `tx`, returning an
+// ErrProfileIDAlreadyExists error if the Account is a registration and its
+// ProfileID is already in use.
+func insertAccount(tx *bbolt.Tx, account accounts.Account) error {
+	profiles := tx.Bucket(profilesBucket)
+	if account.IsRegistration && profiles.Bucket(profileKey(account.ProfileID)) != nil {
+		return accounts.ErrProfileIDAlreadyExists
+	}
+	err := putAccount(tx, toBolt(account))
+	if err != nil {
+		return err
+	}
+	profile, err := profiles.CreateBucketIfNotExists(profileKey(account.ProfileID))
+	if err != nil {
+		return err
+	}
+	return profile.Put(key(account.ID), []byte{})
+}
+
+// Create inserts the passed Account into the bbolt database, returning an
+// ErrAccountAlreadyExists error if the Account's ID already exists in the
+// database, an ErrAccountIDRecentlyReleased error if another profile
+// released the ID within the Storer's release quarantine, or an
+// ErrProfileIDAlreadyExists error if the Account is a registration and its
+// ProfileID is already in use.
+func (s *Storer) Create(_ context.Context, account accounts.Account) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		exists, err := getAccount(tx, account.ID)
+		if err != nil {
+			return err
+		}
+		if exists != nil {
+			return accounts.ErrAccountAlreadyExists
+		}
+		released, err := s.releasedByOtherProfile(tx, account.ID, account.ProfileID)
+		if err != nil {
+			return err
+		}
+		if released {
+			return accounts.ErrAccountIDRecentlyReleased
+		}
+		return insertAccount(tx, account)
+	})
+}
+
+// Replace deletes the Account with the same ID as the passed Account from
+// the bbolt database, if there is one, and inserts the passed Account, in a
+// single transaction, without releasing the ID. It returns an
+// ErrProfileIDAlreadyExists error under the same conditions Create does, in
+// which case the existing Account is left alone.
+func (s *Storer) Replace(_ context.Context, account accounts.Account) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		_, err := removeAccount(tx, account.ID, false)
+		if err != nil {
+			return err
+		}
+		return insertAccount(tx, account)
+	})
+}
+
+// Get retrieves the Account specified by the passed ID from the bbolt
+// database. If no Account matches the passed ID, an ErrAccountNotFound error
+// is returned.
+func (s *Storer) Get(_ context.Context, id string) (accounts.Account, error) {
+	var account *Account
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		account, err = getAccount(tx, id)
+		return err
+	})
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	if account == nil {
+		return accounts.Account{}, accounts.ErrAccountNotFound
+	}
+	return fromBolt(*account), nil
+}
+
+// Update applies the passed Change to the Account in the bbolt database
+// that matches the specified ID, if any Account matches the specified ID.
+func (s *Storer) Update(_ context.Context, id string, change accounts.Change) error {
+	if change.IsEmpty() {
+		return nil
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		account, err := getAccount(tx, id)
+		if err != nil {
+			return err
+		}
+		if account == nil {
+			return nil
+		}
+		return putAccount(tx, toBolt(accounts.Apply(change, fromBolt(*account))))
+	})
+}
+
+// Delete removes the Account that matches the passed ID from the bbolt
+// database, if any Account matches the passed ID, and releases its ID.
+func (s *Storer) Delete(_ context.Context, id string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return s.deleteAccount(tx, id, false)
+	})
+}
+
+// DeleteUnlessLast removes the Account that matches the passed ID from the
+// bbolt database and releases its ID, unless it is the only Account
+// associated with its profile, in which case an ErrLastAccount error is
+// returned.
+func (s *Storer) DeleteUnlessLast(_ context.Context, id string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return s.deleteAccount(tx, id, true)
+	})
+}
+
+// isOnlyKey returns whether `id` is the only key in `bucket`.
+func isOnlyKey(bucket *bbolt.Bucket, id []byte) bool {
+	cursor := bucket.Cursor()
+	first, _ := cursor.First()
+	if first == nil || !bytes.Equal(first, id) {
+		return false
+	}
+	next, _ := cursor.Next()
+	return next == nil
+}
+
+// deleteAccount removes the Account that matches `id` from `tx` and
+// releases its ID.
+func (s *Storer) deleteAccount(tx *bbolt.Tx, id string, unlessLast bool) error {
+	account, err := removeAccount(tx, id, unlessLast)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		return nil
+	}
+	return s.release(tx, account.ID, account.ProfileID)
+}
+
+// removeAccount removes the Account that matches `id` from `tx`, returning
+// it, or nil if no Account matches.
+func removeAccount(tx *bbolt.Tx, id string, unlessLast bool) (*Account, error) {
+	account, err := getAccount(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, nil //nolint:nilnil // a nil Account means it wasn't found
+	}
+	profiles := tx.Bucket(profilesBucket)
+	profile := profiles.Bucket(profileKey(account.ProfileID))
+	// bbolt only allows one read-write transaction at a time, so
+	// nothing can add to the profile before this one commits
+	if unlessLast && (profile == nil || isOnlyKey(profile, key(id))) {
+		return nil, accounts.ErrLastAccount
+	}
+	err = tx.Bucket(accountsBucket).Delete(key(id))
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return account, nil
+	}
+	err = profile.Delete(key(id))
+	if err != nil {
+		return nil, err
+	}
+	// once a profile has no Accounts left, its ID is no longer in use
+	if first, _ := profile.Cursor().First(); first == nil {
+		return account, profiles.DeleteBucket(profileKey(account.ProfileID))
+	}
+	return account, nil
+}
+
+// EraseProfile removes every Account associated with the passed profile ID
+// from the bbolt database in a single transaction, returning the Accounts
+// that were removed.
+func (s *Storer) EraseProfile(_ context.Context, profileID string) ([]accounts.Account, error) {
+	accts := []accounts.Account{}
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		profiles := tx.Bucket(profilesBucket)
+		profile := profiles.Bucket(profileKey(profileID))
+		if profile == nil {
+			return nil
+		}
+		err := profile.ForEach(func(id, _ []byte) error {
+			account, err := getAccount(tx, string(id))
+			if err != nil {
+				return err
+			}
+			if account == nil {
+				return nil
+			}
+			accts = append(accts, fromBolt(*account))
+			return tx.Bucket(accountsBucket).Delete(id)
+		})
+		if err != nil {
+			return err
+		}
+		return profiles.DeleteBucket(profileKey(profileID))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return accts, nil
+}
+
+// ListByProfile returns all the Accounts associated with the passed profile ID,
+// sorted with the most recently used Accounts coming first.
+func (s *Storer) ListByProfile(_ context.Context, profileID string) ([]accounts.Account, error) {
+	var accts []accounts.Account
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		profile := tx.Bucket(profilesBucket).Bucket(profileKey(profileID))
+		if profile == nil {
+			return nil
+		}
+		return profile.ForEach(func(id, _ []byte) error {
+			account, err := getAccount(tx, string(id))
+			if err != nil {
+				return err
+			}
+			if account != nil {
+				accts = append(accts, fromBolt(*account))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	accounts.ByLastUsedDesc(accts)
+	return accts, nil
+}
+
+// ListAll returns up to `limit` Accounts, ordered by their lowercased IDs,
+// starting after the Account whose ID is `after`. If `after` is empty, the
+// Accounts are returned starting from the beginning.
+func (s *Storer) ListAll(_ context.Context, after string, limit int) ([]accounts.Account, error) {
+	accts := []accounts.Account{}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		cursor := tx.Bucket(accountsBucket).Cursor()
+		k, v := cursor.Seek(key(after))
+		if k != nil && after != "" && bytes.Equal(k, key(after)) {
+			k, v = cursor.Next()
+		}
+		for ; k != nil && len(accts) < limit; k, v = cursor.Next() {
+			var account Account
+			err := json.Unmarshal(v, &account)
+			if err != nil {
+				return err
+			}
+			accts = append(accts, fromBolt(account))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return accts, nil
+}
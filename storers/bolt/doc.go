@@ -0,0 +1,22 @@
+// Package bolt provides an implementation of the
+// lockbox.dev/accounts.Storer interface that stores data in a bbolt
+// key/value database file.
+//
+// This implementation is written in pure Go and doesn't need a database
+// server, which makes it useful for single-binary deployments that still need
+// their data to survive restarts and crashes.
+//
+// Accounts are stored in a bucket, keyed by their lowercased ID. A second
+// bucket indexes the Accounts by their profile ID, which is case-sensitive,
+// with a nested bucket for each profile ID holding the IDs of the Accounts
+// associated with it.
+//
+// Storers created WithReleaseQuarantine quarantine IDs released by deleting
+// Accounts, so other profiles can't claim them for a while. Released IDs are
+// stored by their hash in a third bucket, and Storer.DeleteExpiredReleasedIDs
+// removes them once their quarantine ends.
+//
+// Account IDs are always stored in plain text, as bucket keys; unlike the
+// postgres Storer, this Storer can't hash them. Deployments that need IDs
+// hashed at rest should use the postgres Storer.
+package bolt
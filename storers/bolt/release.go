@@ -0,0 +1,108 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	bbolt "go.etcd.io/bbolt"
+
+	"lockbox.dev/accounts"
+)
+
+// releasedID records that an ID was released. It is stored in the released
+// bucket, keyed by the ID's hash. Only the latest release of each ID is
+// kept.
+type releasedID struct {
+	ProfileID string    `json:"profileID"`
+	Released  time.Time `json:"releasedAt"`
+}
+
+func (s *Storer) now() time.Time {
+	return accounts.Now(s.clock)
+}
+
+// release records that `profileID` released `id` in `tx`, if the Storer has
+// a release quarantine.
+func (s *Storer) release(tx *bbolt.Tx, id, profileID string) error {
+	if s.quarantine <= 0 {
+		return nil
+	}
+	val, err := json.Marshal(releasedID{ProfileID: profileID, Released: s.now()})
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(releasedBucket).Put([]byte(accounts.HashReleasedID(nil, id)), val)
+}
+
+// releasedByOtherProfile returns whether a profile other than `profileID`
+// released `id` within the Storer's release quarantine, according to `tx`.
+func (s *Storer) releasedByOtherProfile(tx *bbolt.Tx, id, profileID string) (bool, error) {
+	if s.quarantine <= 0 {
+		return false, nil
+	}
+	val := tx.Bucket(releasedBucket).Get([]byte(accounts.HashReleasedID(nil, id)))
+	if val == nil {
+		return false, nil
+	}
+	var rel releasedID
+	err := json.Unmarshal(val, &rel)
+	if err != nil {
+		return false, err
+	}
+	return rel.ProfileID != profileID && s.now().Sub(rel.Released) < s.quarantine, nil
+}
+
+// CheckReleased returns an ErrAccountIDRecentlyReleased error if a profile
+// other than `profileID` released `id` within the Storer's release
+// quarantine.
+func (s *Storer) CheckReleased(_ context.Context, id, profileID string) error {
+	return s.db.View(func(tx *bbolt.Tx) error {
+		released, err := s.releasedByOtherProfile(tx, id, profileID)
+		if err != nil {
+			return err
+		}
+		if released {
+			return accounts.ErrAccountIDRecentlyReleased
+		}
+		return nil
+	})
+}
+
+// DeleteExpiredReleasedIDs removes every released ID whose quarantine has
+// ended from the bbolt database, returning the number removed.
+func (s *Storer) DeleteExpiredReleasedIDs(_ context.Context) (int, error) {
+	var deleted int
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(releasedBucket)
+		var expired [][]byte
+		err := bucket.ForEach(func(k, v []byte) error {
+			var rel releasedID
+			err := json.Unmarshal(v, &rel)
+			if err != nil {
+				return err
+			}
+			if s.now().Sub(rel.Released) >= s.quarantine {
+				expired = append(expired, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// bbolt doesn't allow changing a bucket while iterating over
+		// it, so delete after we have everything
+		for _, k := range expired {
+			err = bucket.Delete(k)
+			if err != nil {
+				return err
+			}
+		}
+		deleted = len(expired)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
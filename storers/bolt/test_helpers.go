@@ -0,0 +1,67 @@
+package bolt
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	bbolt "go.etcd.io/bbolt"
+
+	"lockbox.dev/accounts"
+)
+
+// Factory is a generator of Storers for testing purposes. It creates a
+// temporary directory, and every Storer it creates gets its own database
+// file in that directory.
+type Factory struct {
+	dir       string
+	opts      []Option
+	databases []*bbolt.DB
+	lock      sync.Mutex
+}
+
+// NewFactory returns a Factory that is ready to be used. The databases
+// created by the Factory will be stored in a new temporary directory, which
+// is removed by TeardownStorers, and its Storers are configured by `opts`.
+func NewFactory(opts ...Option) (*Factory, error) {
+	dir, err := os.MkdirTemp("", "accounts_test_")
+	if err != nil {
+		return nil, err
+	}
+	return &Factory{dir: dir, opts: opts}, nil
+}
+
+// NewStorer opens a new database file in the Factory's temporary directory
+// and returns a Storer that uses it.
+func (f *Factory) NewStorer(ctx context.Context) (accounts.Storer, error) { //nolint:ireturn // interface requires returning an interface
+	f.lock.Lock()
+	path := filepath.Join(f.dir, "accounts_"+strconv.Itoa(len(f.databases))+".db")
+	db, err := bbolt.Open(path, 0o600, nil) //nolint:gomnd // standard file permissions
+	if err != nil {
+		f.lock.Unlock()
+		log.Printf("Error opening database %s: %+v\n", path, err)
+		return nil, err
+	}
+	f.databases = append(f.databases, db)
+	f.lock.Unlock()
+
+	return NewStorer(ctx, db, f.opts...)
+}
+
+// TeardownStorers closes all the databases opened by NewStorer and removes
+// the temporary directory they were stored in.
+func (f *Factory) TeardownStorers() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for _, db := range f.databases {
+		err := db.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(f.dir)
+}
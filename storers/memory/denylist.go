@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	memdb "github.com/hashicorp/go-memdb"
+
+	"lockbox.dev/accounts"
+)
+
+// DenyRule is a representation of the accounts.DenyRule type that is
+// suitable to be written to snapshots and journals.
+type DenyRule struct {
+	ID      string    `json:"id"`
+	Kind    string    `json:"kind"`
+	Pattern string    `json:"pattern"`
+	Reason  string    `json:"reason,omitempty"`
+	Created time.Time `json:"createdAt"`
+}
+
+func fromSnapshotDenyRule(rule DenyRule) accounts.DenyRule {
+	return accounts.DenyRule{
+		ID:      rule.ID,
+		Kind:    accounts.DenyRuleKind(rule.Kind),
+		Pattern: rule.Pattern,
+		Reason:  rule.Reason,
+		Created: rule.Created,
+	}
+}
+
+func toSnapshotDenyRule(rule accounts.DenyRule) DenyRule {
+	return DenyRule{
+		ID:      rule.ID,
+		Kind:    string(rule.Kind),
+		Pattern: rule.Pattern,
+		Reason:  rule.Reason,
+		Created: rule.Created,
+	}
+}
+
+// listDenyRules returns every DenyRule in `txn`.
+func listDenyRules(txn *memdb.Txn) ([]accounts.DenyRule, error) {
+	iter, err := txn.Get("denyRule", "id")
+	if err != nil {
+		return nil, err
+	}
+	rules := []accounts.DenyRule{}
+	for {
+		rule := iter.Next()
+		if rule == nil {
+			break
+		}
+		res, ok := rule.(*accounts.DenyRule)
+		if !ok || res == nil {
+			return nil, fmt.Errorf("unexpected response type %T", rule) //nolint:goerr113 // no handling to do, just for display
+		}
+		rules = append(rules, *res)
+	}
+	return rules, nil
+}
+
+// CreateDenyRule stores the passed DenyRule in the Storer.
+func (s *Storer) CreateDenyRule(_ context.Context, rule accounts.DenyRule) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+	err := txn.Insert("denyRule", &rule)
+	if err != nil {
+		return err
+	}
+	snap := toSnapshotDenyRule(rule)
+	err = s.record(journalEntry{Op: journalPutDenyRule, DenyRule: &snap})
+	if err != nil {
+		return err
+	}
+	txn.Commit()
+	return nil
+}
+
+// ListDenyRules returns every DenyRule in the Storer.
+func (s *Storer) ListDenyRules(_ context.Context) ([]accounts.DenyRule, error) {
+	return listDenyRules(s.db.Txn(false))
+}
+
+// DeleteDenyRule removes the DenyRule whose ID matches the passed ID from the
+// Storer, or returns an ErrDenyRuleNotFound error if no DenyRule matches.
+func (s *Storer) DeleteDenyRule(_ context.Context, id string) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+	rule, err := txn.First("denyRule", "id", id)
+	if err != nil {
+		return err
+	}
+	if rule == nil {
+		return accounts.ErrDenyRuleNotFound
+	}
+	err = txn.Delete("denyRule", rule)
+	if err != nil {
+		return err
+	}
+	err = s.record(journalEntry{Op: journalDeleteDenyRule, ID: id})
+	if err != nil {
+		return err
+	}
+	txn.Commit()
+	return nil
+}
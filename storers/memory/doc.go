@@ -2,6 +2,29 @@
 // lockbox.dev/accounts.Storer interface.
 //
 // This implementation is useful for testing and demo setups in which data is
-// not meant to be stored reliably or for a long time. All the data will be
-// permanently lost when the service process exits.
+// not meant to be stored reliably or for a long time. By default, all the
+// data will be permanently lost when the service process exits.
+//
+// For setups that want their data to outlive the process without running a
+// database, the Storer can write snapshots of its contents to a versioned
+// file and load them back on startup, optionally on a schedule using
+// SnapshotEvery. A Storer created with NewPersistentStorer also records every
+// change in a write-ahead journal, so changes made since the last snapshot
+// aren't lost either. ReadPersistentStorer loads the same files without
+// changing them, for reading them while nothing else is using them.
+//
+// Storers also implement lockbox.dev/accounts.PendingLinkStorer. Pending
+// links are never written to snapshots or the journal.
+//
+// Storers also implement lockbox.dev/accounts.Renamer, and keep a history of
+// renames that is returned by ListHistoryByProfile. The history is written to
+// snapshots and recorded in the journal along with the renames.
+//
+// Storers created WithReleaseQuarantine quarantine IDs released by deleting
+// or renaming Accounts, so other profiles can't claim them for a while.
+// Released IDs are written to snapshots and recorded in the journal by their
+// keyed hash, so the quarantine survives restarts.
+//
+// Storers also implement lockbox.dev/accounts.DenylistStorer. Deny rules are
+// written to snapshots and recorded in the journal, so they survive restarts.
 package memory
@@ -0,0 +1,225 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	memdb "github.com/hashicorp/go-memdb"
+
+	"lockbox.dev/accounts"
+)
+
+// historyEntry is a HistoryRecord kept by the Storer. History entries are
+// written to snapshots, and recorded in the journal along with the change
+// they describe, so they survive restarts.
+type historyEntry struct {
+	Seq        uint64    `json:"seq"`
+	AccountID  string    `json:"accountID"`
+	PreviousID string    `json:"previousID,omitempty"`
+	ProfileID  string    `json:"profileID"`
+	Action     string    `json:"action"`
+	At         time.Time `json:"at"`
+}
+
+func (h historyEntry) record() accounts.HistoryRecord {
+	rec := accounts.HistoryRecord{
+		AccountID: h.AccountID,
+		ProfileID: h.ProfileID,
+		Action:    h.Action,
+		At:        h.At,
+	}
+	if h.PreviousID != "" {
+		rec.Details = map[string]string{accounts.HistoryDetailPreviousID: h.PreviousID}
+	}
+	return rec
+}
+
+func (s *Storer) now() time.Time {
+	return accounts.Now(s.clock)
+}
+
+// listHistory returns every history entry in `txn`, oldest first.
+func listHistory(txn *memdb.Txn) ([]*historyEntry, error) {
+	iter, err := txn.Get("history", "id")
+	if err != nil {
+		return nil, err
+	}
+	var entries []*historyEntry
+	for {
+		entry := iter.Next()
+		if entry == nil {
+			break
+		}
+		res, ok := entry.(*historyEntry)
+		if !ok || res == nil {
+			return nil, fmt.Errorf("unexpected response type %T", entry) //nolint:goerr113 // no handling to do, just for display
+		}
+		entries = append(entries, res)
+	}
+	return entries, nil
+}
+
+// restoreHistorySeq continues numbering history entries after the newest
+// one in `txn`, so entries loaded from a snapshot or the journal keep their
+// order.
+func (s *Storer) restoreHistorySeq(txn *memdb.Txn) error {
+	last, err := txn.Last("history", "id")
+	if err != nil {
+		return err
+	}
+	var seq uint64
+	if last != nil {
+		entry, ok := last.(*historyEntry)
+		if !ok || entry == nil {
+			return fmt.Errorf("unexpected response type %T", last) //nolint:goerr113 // no handling to do, just for display
+		}
+		seq = entry.Seq
+	}
+	atomic.StoreUint64(&s.historySeq, seq)
+	return nil
+}
+
+// profileHistory returns the history entries associated with `profileID` in
+// `txn`, oldest first.
+func profileHistory(txn *memdb.Txn, profileID string) ([]*historyEntry, error) {
+	iter, err := txn.Get("history", "profileID", profileID)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*historyEntry
+	for {
+		entry := iter.Next()
+		if entry == nil {
+			break
+		}
+		res, ok := entry.(*historyEntry)
+		if !ok || res == nil {
+			return nil, fmt.Errorf("unexpected response type %T", entry) //nolint:goerr113 // no handling to do, just for display
+		}
+		entries = append(entries, res)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Seq < entries[j].Seq })
+	return entries, nil
+}
+
+// scrubHistory replaces the IDs in the history entries associated with
+// `profileID` in `txn` with their ErasedPlaceholder, returning the number of
+// entries changed.
+func scrubHistory(txn *memdb.Txn, profileID string) (int, error) {
+	entries, err := profileHistory(txn, profileID)
+	if err != nil {
+		return 0, err
+	}
+	var scrubbed int
+	for _, entry := range entries {
+		if accounts.IsErasedPlaceholder(entry.AccountID) {
+			continue
+		}
+		updated := *entry
+		updated.AccountID = accounts.ErasedPlaceholder(entry.AccountID)
+		if entry.PreviousID != "" {
+			updated.PreviousID = accounts.ErasedPlaceholder(entry.PreviousID)
+		}
+		err = txn.Insert("history", &updated)
+		if err != nil {
+			return scrubbed, err
+		}
+		scrubbed++
+	}
+	return scrubbed, nil
+}
+
+// Rename changes the ID of the Account that matches `id` to `newID`, keeping
+// everything else about it, and records the change in the Storer's history.
+// It returns an ErrAccountNotFound error if no Account matches `id`, an
+// ErrAccountAlreadyExists error if another Account matches `newID`, or an
+// ErrAccountIDRecentlyReleased error if another profile released `newID`
+// within the Storer's release quarantine.
+func (s *Storer) Rename(_ context.Context, id, newID string) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+	exists, err := txn.First("account", "id", id)
+	if err != nil {
+		return err
+	}
+	if exists == nil {
+		return accounts.ErrAccountNotFound
+	}
+	account, ok := exists.(*accounts.Account)
+	if !ok || account == nil {
+		return fmt.Errorf("unexpected response type %T", exists) //nolint:goerr113 // no handling to do, just for display
+	}
+	// changing the capitalization of an ID doesn't release it or take
+	// another one
+	changed := !strings.EqualFold(account.ID, newID)
+	if changed {
+		taken, err := txn.First("account", "id", newID)
+		if err != nil {
+			return err
+		}
+		if taken != nil {
+			return accounts.ErrAccountAlreadyExists
+		}
+		released, err := s.releasedByOtherProfile(txn, newID, account.ProfileID)
+		if err != nil {
+			return err
+		}
+		if released {
+			return accounts.ErrAccountIDRecentlyReleased
+		}
+	}
+	renamed := *account
+	renamed.ID = newID
+	err = txn.Delete("account", exists)
+	if err != nil {
+		return err
+	}
+	err = txn.Insert("account", &renamed)
+	if err != nil {
+		return err
+	}
+	entry := &historyEntry{
+		Seq:        atomic.AddUint64(&s.historySeq, 1),
+		AccountID:  newID,
+		PreviousID: account.ID,
+		ProfileID:  account.ProfileID,
+		Action:     accounts.HistoryActionRenamed,
+		At:         s.now(),
+	}
+	err = txn.Insert("history", entry)
+	if err != nil {
+		return err
+	}
+	var rel *releasedID
+	if changed {
+		rel, err = s.release(txn, account.ID, account.ProfileID)
+		if err != nil {
+			return err
+		}
+	}
+	snap := toSnapshot(renamed)
+	err = s.record(journalEntry{Op: journalRename, ID: account.ID, Account: &snap, History: entry, Released: rel})
+	if err != nil {
+		return err
+	}
+	txn.Commit()
+	return nil
+}
+
+// ListHistoryByProfile returns the history the Storer has kept about the
+// Accounts associated with the passed profile ID, oldest first.
+func (s *Storer) ListHistoryByProfile(_ context.Context, profileID string) ([]accounts.HistoryRecord, error) {
+	entries, err := profileHistory(s.db.Txn(false), profileID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]accounts.HistoryRecord, 0, len(entries))
+	for _, entry := range entries {
+		res = append(res, entry.record())
+	}
+	return res, nil
+}
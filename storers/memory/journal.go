@@ -0,0 +1,335 @@
+package memory
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	memdb "github.com/hashicorp/go-memdb"
+	yall "yall.in"
+
+	"lockbox.dev/accounts"
+)
+
+const (
+	journalPut    = "put"
+	journalDelete = "delete"
+
+	// journalDeleteProfile is no longer recorded, as erasing a profile
+	// saves a snapshot instead, but journals that have it can still be
+	// replayed
+	journalDeleteProfile = "delete_profile"
+	journalRename        = "rename"
+
+	journalPutDenyRule    = "put_deny_rule"
+	journalDeleteDenyRule = "delete_deny_rule"
+)
+
+// journalEntry is a single change recorded in the journal. Entries record
+// the resulting state of an Account or DenyRule rather than the operation
+// that produced it, so replaying them is idempotent. History is set when the
+// change was recorded in the Storer's history, and Released is set when the
+// change released an ID.
+type journalEntry struct {
+	Op        string        `json:"op"`
+	Account   *Account      `json:"account,omitempty"`
+	DenyRule  *DenyRule     `json:"denyRule,omitempty"`
+	History   *historyEntry `json:"history,omitempty"`
+	Released  *releasedID   `json:"released,omitempty"`
+	ID        string        `json:"id,omitempty"`
+	ProfileID string        `json:"profileID,omitempty"`
+}
+
+// journal is a write-ahead log of changes made to a Storer since its last
+// snapshot.
+type journal struct {
+	file *os.File
+	lock sync.Mutex
+}
+
+func openJournal(path string) (*journal, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600) //nolint:gosec,gomnd // the caller chose the path; standard file permissions
+	if err != nil {
+		return nil, err
+	}
+	return &journal{file: file}, nil
+}
+
+// record durably appends `entry` to the journal. It must be called before
+// the change is committed.
+func (j *journal) record(entry journalEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	_, err = j.file.Write(append(line, '\n'))
+	if err != nil {
+		return err
+	}
+	return j.file.Sync()
+}
+
+func (j *journal) truncate() error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	err := j.file.Truncate(0)
+	if err != nil {
+		return err
+	}
+	return j.file.Sync()
+}
+
+func (j *journal) close() error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	return j.file.Close()
+}
+
+// replay applies every entry in the journal to `txn`. A partially-written
+// final entry is discarded, as the change it describes was never committed.
+func (j *journal) replay(txn *memdb.Txn) error {
+	_, err := j.file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	offset, partial, err := replayJournal(j.file, txn)
+	if err != nil {
+		return err
+	}
+	if partial {
+		// drop the partial entry before anything gets appended after
+		// it
+		return j.file.Truncate(offset)
+	}
+	return nil
+}
+
+// replayJournal applies every complete entry read from `r` to `txn`,
+// returning the length of the complete entries and whether a partial entry
+// followed them. Anything without a trailing newline was never completely
+// written, so it isn't applied.
+func replayJournal(r io.Reader, txn *memdb.Txn) (int64, bool, error) {
+	reader := bufio.NewReader(r)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return offset, len(line) > 0, nil
+		}
+		if err != nil {
+			return offset, false, err
+		}
+		offset += int64(len(line))
+		var entry journalEntry
+		err = json.Unmarshal(line, &entry)
+		if err != nil {
+			return offset, false, err
+		}
+		err = applyJournalEntry(txn, entry)
+		if err != nil {
+			return offset, false, err
+		}
+	}
+}
+
+func applyJournalEntry(txn *memdb.Txn, entry journalEntry) error {
+	if entry.Released != nil {
+		err := txn.Insert("released", entry.Released)
+		if err != nil {
+			return err
+		}
+	}
+	switch entry.Op {
+	case journalPut:
+		if entry.Account == nil {
+			return fmt.Errorf("journal entry %q is missing its account", entry.Op) //nolint:goerr113 // no handling to do, just for display
+		}
+		account := fromSnapshot(*entry.Account)
+		return txn.Insert("account", &account)
+	case journalDelete:
+		exists, err := txn.First("account", "id", entry.ID)
+		if err != nil {
+			return err
+		}
+		if exists == nil {
+			return nil
+		}
+		return txn.Delete("account", exists)
+	case journalRename:
+		if entry.Account == nil {
+			return fmt.Errorf("journal entry %q is missing its account", entry.Op) //nolint:goerr113 // no handling to do, just for display
+		}
+		exists, err := txn.First("account", "id", entry.ID)
+		if err != nil {
+			return err
+		}
+		if exists != nil {
+			err = txn.Delete("account", exists)
+			if err != nil {
+				return err
+			}
+		}
+		account := fromSnapshot(*entry.Account)
+		err = txn.Insert("account", &account)
+		if err != nil || entry.History == nil {
+			return err
+		}
+		return txn.Insert("history", entry.History)
+	case journalDeleteProfile:
+		_, err := deleteProfile(txn, entry.ProfileID)
+		if err != nil {
+			return err
+		}
+		_, err = scrubHistory(txn, entry.ProfileID)
+		return err
+	case journalPutDenyRule:
+		if entry.DenyRule == nil {
+			return fmt.Errorf("journal entry %q is missing its deny rule", entry.Op) //nolint:goerr113 // no handling to do, just for display
+		}
+		rule := fromSnapshotDenyRule(*entry.DenyRule)
+		return txn.Insert("denyRule", &rule)
+	case journalDeleteDenyRule:
+		exists, err := txn.First("denyRule", "id", entry.ID)
+		if err != nil {
+			return err
+		}
+		if exists == nil {
+			return nil
+		}
+		return txn.Delete("denyRule", exists)
+	}
+	return fmt.Errorf("unknown journal operation %q", entry.Op) //nolint:goerr113 // no handling to do, just for display
+}
+
+// record journals `entry`, if the Storer has a journal.
+func (s *Storer) record(entry journalEntry) error {
+	if s.journal == nil {
+		return nil
+	}
+	return s.journal.record(entry)
+}
+
+func putEntry(account accounts.Account) journalEntry {
+	acct := toSnapshot(account)
+	return journalEntry{Op: journalPut, Account: &acct}
+}
+
+// NewPersistentStorer returns an in-memory Storer that survives restarts.
+// If a snapshot exists at `snapshotPath`, it is loaded, and then any
+// changes recorded in the journal at `journalPath` are replayed on top of
+// it. Every change made to the returned Storer is durably recorded in the
+// journal before it takes effect, and the journal is truncated whenever a
+// snapshot is saved using SaveSnapshotFile. Erasing a profile saves a
+// snapshot to `snapshotPath`, so no copy of the erased Accounts is left in
+// the journal or an older snapshot.
+//
+// The returned Storer should be closed with Close when it is no longer
+// needed.
+func NewPersistentStorer(snapshotPath, journalPath string, opts ...Option) (*Storer, error) {
+	storer, err := NewStorer(opts...)
+	if err != nil {
+		return nil, err
+	}
+	err = storer.LoadSnapshotFile(snapshotPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	jrnl, err := openJournal(journalPath)
+	if err != nil {
+		return nil, err
+	}
+	txn := storer.db.Txn(true)
+	defer txn.Abort()
+	err = jrnl.replay(txn)
+	if err == nil {
+		err = storer.restoreHistorySeq(txn)
+	}
+	if err != nil {
+		jrnl.close() //nolint:errcheck,gosec // already returning an error
+		return nil, err
+	}
+	txn.Commit()
+	storer.journal = jrnl
+	storer.snapshotPath = snapshotPath
+	return storer, nil
+}
+
+// ReadPersistentStorer returns an in-memory Storer holding the snapshot at
+// `snapshotPath` with the changes recorded in the journal at `journalPath`
+// replayed on top of it, like NewPersistentStorer does, but without
+// modifying either file. Changes made to the returned Storer aren't
+// persisted. It's meant for reading a persistent Storer's Accounts while
+// nothing else is using them, like when transferring them to another
+// Storer.
+func ReadPersistentStorer(snapshotPath, journalPath string, opts ...Option) (*Storer, error) {
+	storer, err := NewStorer(opts...)
+	if err != nil {
+		return nil, err
+	}
+	err = storer.LoadSnapshotFile(snapshotPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	file, err := os.Open(journalPath) //nolint:gosec // reading a file the caller chose is the point
+	if errors.Is(err, fs.ErrNotExist) {
+		return storer, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close() //nolint:errcheck // read-only file, nothing to flush
+	txn := storer.db.Txn(true)
+	defer txn.Abort()
+	_, _, err = replayJournal(file, txn)
+	if err == nil {
+		err = storer.restoreHistorySeq(txn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	txn.Commit()
+	return storer, nil
+}
+
+// Close releases the Storer's journal, if it has one. The Storer should not
+// be used after it is closed.
+func (s *Storer) Close() error {
+	if s.journal == nil {
+		return nil
+	}
+	return s.journal.close()
+}
+
+// SnapshotEvery saves a snapshot of the Storer to the file at `path` every
+// `interval`, until `ctx` is canceled. A final snapshot is saved when `ctx`
+// is canceled. Errors saving snapshots are logged using the logger in `ctx`,
+// and don't stop future snapshots from being attempted.
+//
+// SnapshotEvery blocks until `ctx` is canceled, so it should usually be run
+// in its own goroutine.
+func (s *Storer) SnapshotEvery(ctx context.Context, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := s.SaveSnapshotFile(path); err != nil {
+				yall.FromContext(ctx).WithError(err).WithField("path", path).Error("error saving final snapshot")
+			}
+			return
+		case <-ticker.C:
+			if err := s.SaveSnapshotFile(path); err != nil {
+				yall.FromContext(ctx).WithError(err).WithField("path", path).Error("error saving snapshot")
+			}
+		}
+	}
+}
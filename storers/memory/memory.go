@@ -3,6 +3,8 @@ package memory
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	memdb "github.com/hashicorp/go-memdb"
 
@@ -22,7 +24,55 @@ var (
 					},
 					"profileID": {
 						Name:    "profileID",
-						Indexer: &memdb.StringFieldIndex{Field: "ProfileID", Lowercase: true},
+						Indexer: &memdb.StringFieldIndex{Field: "ProfileID"},
+					},
+				},
+			},
+			"history": {
+				Name: "history",
+				Indexes: map[string]*memdb.IndexSchema{
+					"id": {
+						Name:    "id",
+						Unique:  true,
+						Indexer: &memdb.UintFieldIndex{Field: "Seq"},
+					},
+					"profileID": {
+						Name:    "profileID",
+						Indexer: &memdb.StringFieldIndex{Field: "ProfileID"},
+					},
+				},
+			},
+			"released": {
+				Name: "released",
+				Indexes: map[string]*memdb.IndexSchema{
+					"id": {
+						Name:    "id",
+						Unique:  true,
+						Indexer: &memdb.StringFieldIndex{Field: "IDHash"},
+					},
+				},
+			},
+			"denyRule": {
+				Name: "denyRule",
+				Indexes: map[string]*memdb.IndexSchema{
+					"id": {
+						Name:    "id",
+						Unique:  true,
+						Indexer: &memdb.StringFieldIndex{Field: "ID"},
+					},
+				},
+			},
+			"pendingLink": {
+				Name: "pendingLink",
+				Indexes: map[string]*memdb.IndexSchema{
+					"id": {
+						Name:    "id",
+						Unique:  true,
+						Indexer: &memdb.StringFieldIndex{Field: "TokenHash"},
+					},
+					"profileID": {
+						Name:    "profileID",
+						Indexer: &memdb.StringFieldIndex{Field: "ProfileID"},
 					},
 				},
 			},
@@ -33,24 +83,66 @@ var (
 // Storer is an in-memory implementation of the Storer
 // interface.
 type Storer struct {
-	db *memdb.MemDB
+	db           *memdb.MemDB
+	journal      *journal
+	snapshotPath string
+	limits       accounts.ProfileLimits
+	clock        accounts.Clock
+	quarantine   time.Duration
+	releaseKey   []byte
+	historySeq   uint64
 }
 
 // NewStorer returns an in-memory Storer instance that is ready
 // to be used as a Storer.
-func NewStorer() (*Storer, error) {
+func NewStorer(opts ...Option) (*Storer, error) {
 	db, err := memdb.NewMemDB(schema)
 	if err != nil {
 		return nil, err
 	}
-	return &Storer{
+	storer := &Storer{
 		db: db,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(storer)
+	}
+	if storer.quarantine > 0 && len(storer.releaseKey) < minReleaseKeySize {
+		return nil, ErrInvalidReleaseKey
+	}
+	return storer, nil
+}
+
+// countProfile returns the number of Accounts associated with `profileID` in
+// `txn`. If `kind` is set, only Accounts of that Kind are counted.
+func countProfile(txn *memdb.Txn, profileID string, kind accounts.Kind) (int, error) {
+	acctIter, err := txn.Get("account", "profileID", profileID)
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	for {
+		acct := acctIter.Next()
+		if acct == nil {
+			return count, nil
+		}
+		res, ok := acct.(*accounts.Account)
+		if !ok || res == nil {
+			return 0, fmt.Errorf("unexpected response type %T", acct) //nolint:goerr113 // no handling to do, just for display
+		}
+		if kind == "" || accounts.KindOf(res.ID) == kind {
+			count++
+		}
+	}
 }
 
 // Create inserts the passed Account into the Storer,
 // returning an ErrAccountAlreadyExists error if an Account
-// with the same ID already exists in the Storer.
+// with the same ID already exists in the Storer, an
+// ErrAccountIDRecentlyReleased error if another profile
+// released the ID within the Storer's release quarantine, or an
+// ErrProfileAccountLimitReached error if the Account's profile
+// already has as many Accounts as the Storer's ProfileLimits
+// allow.
 func (s *Storer) Create(_ context.Context, account accounts.Account) error {
 	txn := s.db.Txn(true)
 	defer txn.Abort()
@@ -61,8 +153,52 @@ func (s *Storer) Create(_ context.Context, account accounts.Account) error {
 	if exists != nil {
 		return accounts.ErrAccountAlreadyExists
 	}
+	released, err := s.releasedByOtherProfile(txn, account.ID, account.ProfileID)
+	if err != nil {
+		return err
+	}
+	if released {
+		return accounts.ErrAccountIDRecentlyReleased
+	}
+	err = s.insert(txn, account)
+	if err != nil {
+		return err
+	}
+	txn.Commit()
+	return nil
+}
+
+// Replace deletes the Account with the same ID as the passed Account from
+// the Storer, if there is one, and inserts the passed Account, without
+// releasing the ID. It returns an ErrProfileIDAlreadyExists error or an
+// ErrProfileAccountLimitReached error under the same conditions Create
+// does, in which case the existing Account is left alone.
+func (s *Storer) Replace(_ context.Context, account accounts.Account) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+	exists, err := txn.First("account", "id", account.ID)
+	if err != nil {
+		return err
+	}
+	if exists != nil {
+		err = txn.Delete("account", exists)
+		if err != nil {
+			return err
+		}
+	}
+	err = s.insert(txn, account)
+	if err != nil {
+		return err
+	}
+	txn.Commit()
+	return nil
+}
+
+// insert adds `account` to `txn` and records it in the journal, as long as
+// its profile can have it.
+func (s *Storer) insert(txn *memdb.Txn, account accounts.Account) error {
 	if account.IsRegistration {
-		exists, err = txn.First("account", "profileID", account.ProfileID)
+		exists, err := txn.First("account", "profileID", account.ProfileID)
 		if err != nil {
 			return err
 		}
@@ -70,12 +206,27 @@ func (s *Storer) Create(_ context.Context, account accounts.Account) error {
 			return accounts.ErrProfileIDAlreadyExists
 		}
 	}
-	err = txn.Insert("account", &account)
+	kind := accounts.KindOf(account.ID)
+	if limit, sameKind := s.limits.For(kind); limit > 0 {
+		var countKind accounts.Kind
+		if sameKind {
+			countKind = kind
+		}
+		count, err := countProfile(txn, account.ProfileID, countKind)
+		if err != nil {
+			return err
+		}
+		if !s.limits.Allows(kind, count) {
+			return accounts.ErrProfileAccountLimitReached
+		}
+	}
+	err := txn.Insert("account", &account)
 	if err != nil {
 		return err
 	}
-	txn.Commit()
-	return nil
+	// replaying a put replaces any Account with the same ID, so this
+	// entry is enough to replay a Replace too
+	return s.record(putEntry(account))
 }
 
 // Get retrieves the Account specified by the passed ID from
@@ -119,14 +270,29 @@ func (s *Storer) Update(_ context.Context, id string, change accounts.Change) er
 	if err != nil {
 		return err
 	}
+	err = s.record(putEntry(updated))
+	if err != nil {
+		return err
+	}
 	txn.Commit()
 	return nil
 }
 
 // Delete removes the Account that matches the specified ID from
 // the Storer, if any Account matches the specified ID in the
-// Storer.
+// Storer, and releases its ID.
 func (s *Storer) Delete(_ context.Context, id string) error {
+	return s.delete(id, false)
+}
+
+// DeleteUnlessLast removes the Account that matches the specified ID from
+// the Storer and releases its ID, unless it is the only Account associated
+// with its profile, in which case an ErrLastAccount error is returned.
+func (s *Storer) DeleteUnlessLast(_ context.Context, id string) error {
+	return s.delete(id, true)
+}
+
+func (s *Storer) delete(id string, unlessLast bool) error {
 	txn := s.db.Txn(true)
 	defer txn.Abort()
 	exists, err := txn.First("account", "id", id)
@@ -136,14 +302,113 @@ func (s *Storer) Delete(_ context.Context, id string) error {
 	if exists == nil {
 		return nil
 	}
+	res, ok := exists.(*accounts.Account)
+	if !ok || res == nil {
+		return fmt.Errorf("unexpected response type %T", exists) //nolint:goerr113 // no handling to do, just for display
+	}
+	if unlessLast {
+		// write transactions are serialized, so nothing can change the
+		// count before this one commits
+		count, err := countProfile(txn, res.ProfileID, "")
+		if err != nil {
+			return err
+		}
+		if count < 2 { //nolint:gomnd // the Account being deleted and at least one other
+			return accounts.ErrLastAccount
+		}
+	}
 	err = txn.Delete("account", exists)
 	if err != nil {
 		return err
 	}
+	rel, err := s.release(txn, res.ID, res.ProfileID)
+	if err != nil {
+		return err
+	}
+	err = s.record(journalEntry{Op: journalDelete, ID: id, Released: rel})
+	if err != nil {
+		return err
+	}
 	txn.Commit()
 	return nil
 }
 
+// deleteProfile removes every Account associated with `profileID` in
+// `txn`, returning the Accounts that were removed.
+func deleteProfile(txn *memdb.Txn, profileID string) ([]accounts.Account, error) {
+	acctIter, err := txn.Get("account", "profileID", profileID)
+	if err != nil {
+		return nil, err
+	}
+	var accts []*accounts.Account
+	for {
+		acct := acctIter.Next()
+		if acct == nil {
+			break
+		}
+		res, ok := acct.(*accounts.Account)
+		if !ok || res == nil {
+			return nil, fmt.Errorf("unexpected response type %T", acct) //nolint:goerr113 // no handling to do, just for display
+		}
+		accts = append(accts, res)
+	}
+	// deleting while iterating invalidates the iterator, so delete
+	// after we have everything
+	deleted := make([]accounts.Account, 0, len(accts))
+	for _, acct := range accts {
+		err = txn.Delete("account", acct)
+		if err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, *acct)
+	}
+	return deleted, nil
+}
+
+// EraseProfile removes every Account associated with the passed profile ID
+// from the Storer in a single transaction, returning the Accounts that were
+// removed. The IDs in the profile's history are replaced with their
+// ErasedPlaceholder, and the profile's PendingLinks are deleted, in the same
+// transaction.
+//
+// If the Storer was created with NewPersistentStorer, a snapshot without the
+// erased Accounts is saved and the journal is truncated before the erasure
+// takes effect, so no copy of them is left on disk. If that fails, nothing
+// is erased and the error is returned. Snapshots saved to other paths aren't
+// changed.
+func (s *Storer) EraseProfile(_ context.Context, profileID string) ([]accounts.Account, error) {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+	deleted, err := deleteProfile(txn, profileID)
+	if err != nil {
+		return nil, err
+	}
+	scrubbed, err := scrubHistory(txn, profileID)
+	if err != nil {
+		return nil, err
+	}
+	// PendingLinks aren't persisted, so deleting them doesn't need the
+	// snapshot replaced
+	err = deletePendingLinks(txn, profileID)
+	if err != nil {
+		return nil, err
+	}
+	if len(deleted) < 1 && scrubbed < 1 {
+		txn.Commit()
+		return deleted, nil
+	}
+	if s.journal != nil {
+		// journaling the erasure would leave the erased Accounts in
+		// the journal and the last snapshot, so replace both instead
+		err = s.saveSnapshot(txn, s.snapshotPath)
+		if err != nil {
+			return nil, fmt.Errorf("error compacting erased profile: %w", err)
+		}
+	}
+	txn.Commit()
+	return deleted, nil
+}
+
 // ListByProfile returns all the Accounts associated with the passed profile ID,
 // sorted with the most recently used Accounts coming first.
 func (s *Storer) ListByProfile(_ context.Context, profileID string) ([]accounts.Account, error) {
@@ -167,3 +432,36 @@ func (s *Storer) ListByProfile(_ context.Context, profileID string) ([]accounts.
 	accounts.ByLastUsedDesc(accts)
 	return accts, nil
 }
+
+// ListAll returns up to `limit` Accounts, ordered by their lowercased IDs,
+// starting after the Account whose ID is `after`. If `after` is empty, the
+// Accounts are returned starting from the beginning.
+func (s *Storer) ListAll(_ context.Context, after string, limit int) ([]accounts.Account, error) {
+	txn := s.db.Txn(false)
+	var acctIter memdb.ResultIterator
+	var err error
+	if after == "" {
+		acctIter, err = txn.Get("account", "id_prefix", "")
+	} else {
+		acctIter, err = txn.LowerBound("account", "id", after)
+	}
+	if err != nil {
+		return nil, err
+	}
+	accts := []accounts.Account{}
+	for len(accts) < limit {
+		acct := acctIter.Next()
+		if acct == nil {
+			break
+		}
+		res, ok := acct.(*accounts.Account)
+		if !ok || res == nil {
+			return nil, fmt.Errorf("unexpected response type %T", acct) //nolint:goerr113 // no handling to do, just for display
+		}
+		if after != "" && strings.EqualFold(res.ID, after) {
+			continue
+		}
+		accts = append(accts, *res)
+	}
+	return accts, nil
+}
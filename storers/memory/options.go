@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"time"
+
+	"lockbox.dev/accounts"
+)
+
+// Option configures optional behavior of a Storer.
+type Option func(*Storer)
+
+// WithProfileLimits limits how many Accounts each profile can have. Create
+// returns ErrProfileAccountLimitReached for Accounts that would go over the
+// limits.
+//
+// The limits are only checked when Accounts are created; Accounts restored
+// from a snapshot or journal are never rejected.
+func WithProfileLimits(limits accounts.ProfileLimits) Option {
+	return func(s *Storer) {
+		s.limits = limits
+	}
+}
+
+// WithReleaseQuarantine sets how long an ID released by deleting or renaming
+// an Account is kept from being used by other profiles. Create and Rename
+// return ErrAccountIDRecentlyReleased for IDs that are still quarantined. A
+// period of zero turns the quarantine off, and stops released IDs from being
+// recorded. If WithReleaseQuarantine isn't used, released IDs aren't
+// quarantined; accounts.DefaultReleaseQuarantine is a reasonable period.
+//
+// Released IDs are recorded by their hash, keyed with `key`, so snapshots
+// and journals don't reveal them. The key must be at least 32 bytes, or
+// NewStorer returns ErrInvalidReleaseKey, and must stay the same across
+// restarts, or IDs released before a restart stop being quarantined.
+func WithReleaseQuarantine(period time.Duration, key []byte) Option {
+	return func(s *Storer) {
+		s.quarantine = period
+		s.releaseKey = key
+	}
+}
+
+// WithClock sets the Clock the Storer uses to timestamp history and check
+// quarantines. If WithClock isn't used, the system clock is used.
+func WithClock(clock accounts.Clock) Option {
+	return func(s *Storer) {
+		s.clock = clock
+	}
+}
@@ -0,0 +1,93 @@
+package memory_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"lockbox.dev/accounts"
+	"lockbox.dev/accounts/storers/memory"
+)
+
+func TestProfileLimits(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storer, err := memory.NewStorer(memory.WithProfileLimits(accounts.ProfileLimits{
+		Max:     3,
+		PerKind: map[accounts.Kind]int{accounts.KindEmail: 2},
+	}))
+	if err != nil {
+		t.Fatalf("Error creating storer: %+v\n", err)
+	}
+	create := func(id string, isRegistration bool) error {
+		return storer.Create(ctx, accounts.Account{ID: id, ProfileID: "profile", IsRegistration: isRegistration})
+	}
+
+	for pos, id := range []string{"paddy@impractical.co", "paddy@carvers.co"} {
+		err = create(id, pos == 0)
+		if err != nil {
+			t.Fatalf("Unexpected error creating %s: %+v\n", id, err)
+		}
+	}
+	// emails only count against the email limit
+	err = create("paddy@lockbox.dev", false)
+	if !errors.Is(err, accounts.ErrProfileAccountLimitReached) {
+		t.Errorf("Expected %v creating a third email, got %v", accounts.ErrProfileAccountLimitReached, err)
+	}
+	// everything else counts against the profile's total
+	err = create("paddy", false)
+	if err != nil {
+		t.Fatalf("Unexpected error creating username: %+v\n", err)
+	}
+	err = create("github:1234567", false)
+	if !errors.Is(err, accounts.ErrProfileAccountLimitReached) {
+		t.Errorf("Expected %v creating a fourth account, got %v", accounts.ErrProfileAccountLimitReached, err)
+	}
+
+	// deleting an account makes room again
+	err = storer.Delete(ctx, "paddy@carvers.co")
+	if err != nil {
+		t.Fatalf("Unexpected error deleting account: %+v\n", err)
+	}
+	err = create("github:1234567", false)
+	if err != nil {
+		t.Errorf("Unexpected error creating account after deleting one: %+v\n", err)
+	}
+
+	accts, err := storer.ListByProfile(ctx, "profile")
+	if err != nil {
+		t.Fatalf("Unexpected error listing accounts: %+v\n", err)
+	}
+	if len(accts) != 3 { //nolint:gomnd // the profile's limit
+		t.Errorf("Expected 3 accounts, got %d", len(accts))
+	}
+}
+
+func TestReleaseQuarantineKey(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		period  time.Duration
+		key     []byte
+		wantErr error
+	}{
+		"off":       {},
+		"valid":     {period: time.Hour, key: bytes.Repeat([]byte{0x24}, 32)},
+		"no-key":    {period: time.Hour, wantErr: memory.ErrInvalidReleaseKey},
+		"short-key": {period: time.Hour, key: bytes.Repeat([]byte{0x24}, 31), wantErr: memory.ErrInvalidReleaseKey},
+	}
+	for name, test := range tests {
+		name, test := name, test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := memory.NewStorer(memory.WithReleaseQuarantine(test.period, test.key))
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("Expected error %v, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	memdb "github.com/hashicorp/go-memdb"
+
+	"lockbox.dev/accounts"
+)
+
+// CreatePendingLink stores the passed PendingLink in the Storer.
+//
+// PendingLinks aren't included in snapshots or the journal, so Storers
+// created with NewPersistentStorer forget them when they're restarted.
+func (s *Storer) CreatePendingLink(_ context.Context, link accounts.PendingLink) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+	err := txn.Insert("pendingLink", &link)
+	if err != nil {
+		return err
+	}
+	txn.Commit()
+	return nil
+}
+
+// TakePendingLink removes the PendingLink whose TokenHash matches the passed
+// hash from the Storer and returns it, or returns an ErrPendingLinkNotFound
+// error if no PendingLink matches.
+func (s *Storer) TakePendingLink(_ context.Context, tokenHash string) (accounts.PendingLink, error) {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+	link, err := txn.First("pendingLink", "id", tokenHash)
+	if err != nil {
+		return accounts.PendingLink{}, err
+	}
+	if link == nil {
+		return accounts.PendingLink{}, accounts.ErrPendingLinkNotFound
+	}
+	res, ok := link.(*accounts.PendingLink)
+	if !ok || res == nil {
+		return accounts.PendingLink{}, fmt.Errorf("unexpected response type %T", link) //nolint:goerr113 // no handling to do, just for display
+	}
+	err = txn.Delete("pendingLink", link)
+	if err != nil {
+		return accounts.PendingLink{}, err
+	}
+	txn.Commit()
+	return *res, nil
+}
+
+// DeleteExpiredPendingLinks removes every PendingLink that expired before
+// the passed time from the Storer, returning the number removed.
+func (s *Storer) DeleteExpiredPendingLinks(_ context.Context, now time.Time) (int, error) {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+	iter, err := txn.Get("pendingLink", "id")
+	if err != nil {
+		return 0, err
+	}
+	var expired []*accounts.PendingLink
+	for {
+		link := iter.Next()
+		if link == nil {
+			break
+		}
+		res, ok := link.(*accounts.PendingLink)
+		if !ok || res == nil {
+			return 0, fmt.Errorf("unexpected response type %T", link) //nolint:goerr113 // no handling to do, just for display
+		}
+		if res.Expires.Before(now) {
+			expired = append(expired, res)
+		}
+	}
+	// deleting while iterating invalidates the iterator, so delete
+	// after we have everything
+	for _, link := range expired {
+		err = txn.Delete("pendingLink", link)
+		if err != nil {
+			return 0, err
+		}
+	}
+	txn.Commit()
+	return len(expired), nil
+}
+
+// ListPendingLinksByProfile returns every PendingLink for the passed profile
+// ID in the Storer, including expired PendingLinks that haven't been deleted
+// yet.
+func (s *Storer) ListPendingLinksByProfile(_ context.Context, profileID string) ([]accounts.PendingLink, error) {
+	txn := s.db.Txn(false)
+	iter, err := txn.Get("pendingLink", "profileID", profileID)
+	if err != nil {
+		return nil, err
+	}
+	var links []accounts.PendingLink
+	for {
+		link := iter.Next()
+		if link == nil {
+			break
+		}
+		res, ok := link.(*accounts.PendingLink)
+		if !ok || res == nil {
+			return nil, fmt.Errorf("unexpected response type %T", link) //nolint:goerr113 // no handling to do, just for display
+		}
+		links = append(links, *res)
+	}
+	return links, nil
+}
+
+// deletePendingLinks removes every PendingLink for `profileID` in `txn`.
+func deletePendingLinks(txn *memdb.Txn, profileID string) error {
+	_, err := txn.DeleteAll("pendingLink", "profileID", profileID)
+	return err
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	memdb "github.com/hashicorp/go-memdb"
+
+	"lockbox.dev/accounts"
+)
+
+// minReleaseKeySize is the smallest key WithReleaseQuarantine accepts, in
+// bytes.
+const minReleaseKeySize = 32
+
+// ErrInvalidReleaseKey is returned by NewStorer when WithReleaseQuarantine
+// turns the quarantine on with a key shorter than 32 bytes.
+var ErrInvalidReleaseKey = errors.New("release quarantine key must be at least 32 bytes")
+
+// releasedID records that an ID was released, by its keyed hash. Only the latest
+// release of each ID is kept. Released IDs are written to snapshots, and
+// recorded in the journal along with the change that released them, so the
+// quarantine outlasts restarts.
+type releasedID struct {
+	IDHash    string    `json:"idHash"`
+	ProfileID string    `json:"profileID"`
+	Released  time.Time `json:"releasedAt"`
+}
+
+// release records that `profileID` released `id` in `txn`, if the Storer
+// has a release quarantine, and returns the record so it can be journaled.
+// It returns nil if the Storer has no release quarantine.
+func (s *Storer) release(txn *memdb.Txn, id, profileID string) (*releasedID, error) {
+	if s.quarantine <= 0 {
+		return nil, nil //nolint:nilnil // nothing was released
+	}
+	rel := &releasedID{
+		IDHash:    accounts.HashReleasedID(s.releaseKey, id),
+		ProfileID: profileID,
+		Released:  s.now(),
+	}
+	return rel, txn.Insert("released", rel)
+}
+
+// listReleased returns every released ID in `txn`.
+func listReleased(txn *memdb.Txn) ([]*releasedID, error) {
+	iter, err := txn.Get("released", "id")
+	if err != nil {
+		return nil, err
+	}
+	var released []*releasedID
+	for {
+		found := iter.Next()
+		if found == nil {
+			break
+		}
+		res, ok := found.(*releasedID)
+		if !ok || res == nil {
+			return nil, fmt.Errorf("unexpected response type %T", found) //nolint:goerr113 // no handling to do, just for display
+		}
+		released = append(released, res)
+	}
+	return released, nil
+}
+
+// releasedByOtherProfile returns whether a profile other than `profileID`
+// released `id` within the Storer's release quarantine.
+func (s *Storer) releasedByOtherProfile(txn *memdb.Txn, id, profileID string) (bool, error) {
+	if s.quarantine <= 0 {
+		return false, nil
+	}
+	found, err := txn.First("released", "id", accounts.HashReleasedID(s.releaseKey, id))
+	if err != nil {
+		return false, err
+	}
+	if found == nil {
+		return false, nil
+	}
+	res, ok := found.(*releasedID)
+	if !ok || res == nil {
+		return false, fmt.Errorf("unexpected response type %T", found) //nolint:goerr113 // no handling to do, just for display
+	}
+	return res.ProfileID != profileID && s.now().Sub(res.Released) < s.quarantine, nil
+}
+
+// CheckReleased returns an ErrAccountIDRecentlyReleased error if a profile
+// other than `profileID` released `id` within the Storer's release
+// quarantine.
+func (s *Storer) CheckReleased(_ context.Context, id, profileID string) error {
+	released, err := s.releasedByOtherProfile(s.db.Txn(false), id, profileID)
+	if err != nil {
+		return err
+	}
+	if released {
+		return accounts.ErrAccountIDRecentlyReleased
+	}
+	return nil
+}
+
+// DeleteExpiredReleasedIDs forgets every released ID whose quarantine has
+// ended, and returns how many were forgotten.
+//
+// Forgetting released IDs isn't journaled, as expired releases don't affect
+// anything; they're left out of the next snapshot, and any replayed from the
+// journal are forgotten again by the next call.
+func (s *Storer) DeleteExpiredReleasedIDs(_ context.Context) (int, error) {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+	released, err := listReleased(txn)
+	if err != nil {
+		return 0, err
+	}
+	var expired []*releasedID
+	for _, res := range released {
+		if s.now().Sub(res.Released) >= s.quarantine {
+			expired = append(expired, res)
+		}
+	}
+	// deleting while iterating invalidates the iterator, so delete
+	// after we have everything
+	for _, res := range expired {
+		err = txn.Delete("released", res)
+		if err != nil {
+			return 0, err
+		}
+	}
+	txn.Commit()
+	return len(expired), nil
+}
@@ -0,0 +1,260 @@
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	memdb "github.com/hashicorp/go-memdb"
+
+	"lockbox.dev/accounts"
+)
+
+const (
+	// SnapshotVersion is the version of the snapshot format written by
+	// WriteSnapshot. It is incremented whenever the format changes in a
+	// way older versions of this package can't read.
+	SnapshotVersion = 2
+)
+
+var (
+	// ErrUnsupportedSnapshotVersion is returned when attempting to load a
+	// snapshot written in a format this package doesn't know how to
+	// read.
+	ErrUnsupportedSnapshotVersion = errors.New("unsupported snapshot version")
+)
+
+// Account is a representation of the accounts.Account type that is suitable
+// to be written to snapshots and journals.
+type Account struct {
+	ID             string    `json:"id"`
+	ProfileID      string    `json:"profileID"`
+	Created        time.Time `json:"createdAt"`
+	LastUsed       time.Time `json:"lastUsedAt"`
+	LastSeen       time.Time `json:"lastSeenAt"`
+	IsRegistration bool      `json:"isRegistration,omitempty"`
+}
+
+func fromSnapshot(account Account) accounts.Account {
+	return accounts.Account{
+		ID:             account.ID,
+		ProfileID:      account.ProfileID,
+		Created:        account.Created,
+		LastUsed:       account.LastUsed,
+		LastSeen:       account.LastSeen,
+		IsRegistration: account.IsRegistration,
+	}
+}
+
+func toSnapshot(account accounts.Account) Account {
+	return Account{
+		ID:             account.ID,
+		ProfileID:      account.ProfileID,
+		Created:        account.Created,
+		LastUsed:       account.LastUsed,
+		LastSeen:       account.LastSeen,
+		IsRegistration: account.IsRegistration,
+	}
+}
+
+// snapshot is the format snapshots are written in. Version 2 added History
+// and Released; version 1 snapshots can still be read, and have no history
+// or released IDs.
+type snapshot struct {
+	Version   int             `json:"version"`
+	Accounts  []Account       `json:"accounts"`
+	DenyRules []DenyRule      `json:"denyRules,omitempty"`
+	History   []*historyEntry `json:"history,omitempty"`
+	Released  []*releasedID   `json:"released,omitempty"`
+}
+
+func writeSnapshot(txn *memdb.Txn, w io.Writer) error {
+	released, err := listReleased(txn)
+	if err != nil {
+		return err
+	}
+	history, err := listHistory(txn)
+	if err != nil {
+		return err
+	}
+	snap := snapshot{Version: SnapshotVersion, Accounts: []Account{}, History: history, Released: released}
+	rules, err := listDenyRules(txn)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		snap.DenyRules = append(snap.DenyRules, toSnapshotDenyRule(rule))
+	}
+	iter, err := txn.Get("account", "id_prefix", "")
+	if err != nil {
+		return err
+	}
+	for {
+		acct := iter.Next()
+		if acct == nil {
+			break
+		}
+		res, ok := acct.(*accounts.Account)
+		if !ok || res == nil {
+			return fmt.Errorf("unexpected response type %T", acct) //nolint:goerr113 // no handling to do, just for display
+		}
+		snap.Accounts = append(snap.Accounts, toSnapshot(*res))
+	}
+	return json.NewEncoder(w).Encode(snap)
+}
+
+// WriteSnapshot writes the Accounts, DenyRules, history, and released IDs in
+// the Storer to `w`, in a versioned JSON format that LoadSnapshot can read.
+// The snapshot is a consistent view of the Storer at a single point in time.
+func (s *Storer) WriteSnapshot(w io.Writer) error {
+	return writeSnapshot(s.db.Txn(false), w)
+}
+
+// LoadSnapshot replaces the contents of the Storer with the Accounts,
+// DenyRules, history, and released IDs in the snapshot read from `r`. If the
+// snapshot was written in a format this package can't read,
+// ErrUnsupportedSnapshotVersion is returned and the Storer is left
+// unchanged.
+func (s *Storer) LoadSnapshot(r io.Reader) error {
+	var snap snapshot
+	err := json.NewDecoder(r).Decode(&snap)
+	if err != nil {
+		return err
+	}
+	if snap.Version < 1 || snap.Version > SnapshotVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedSnapshotVersion, snap.Version)
+	}
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+	_, err = txn.DeleteAll("account", "id_prefix", "")
+	if err != nil {
+		return err
+	}
+	for _, acct := range snap.Accounts {
+		account := fromSnapshot(acct)
+		err = txn.Insert("account", &account)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = txn.DeleteAll("denyRule", "id")
+	if err != nil {
+		return err
+	}
+	for _, snapRule := range snap.DenyRules {
+		rule := fromSnapshotDenyRule(snapRule)
+		err = txn.Insert("denyRule", &rule)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = txn.DeleteAll("released", "id")
+	if err != nil {
+		return err
+	}
+	for _, rel := range snap.Released {
+		err = txn.Insert("released", rel)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = txn.DeleteAll("history", "id")
+	if err != nil {
+		return err
+	}
+	for _, entry := range snap.History {
+		err = txn.Insert("history", entry)
+		if err != nil {
+			return err
+		}
+	}
+	err = s.restoreHistorySeq(txn)
+	if err != nil {
+		return err
+	}
+	txn.Commit()
+	return nil
+}
+
+// SaveSnapshotFile writes a snapshot of the Storer to the file at `path`,
+// replacing it atomically so a crash never leaves a partially-written
+// snapshot behind. If the Storer has a journal, the journal is truncated
+// once the snapshot is safely written, as everything in it is captured by
+// the snapshot.
+func (s *Storer) SaveSnapshotFile(path string) error {
+	// hold the write lock so nothing can be journaled between taking the
+	// snapshot and truncating the journal
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+	return s.saveSnapshot(txn, path)
+}
+
+// saveSnapshot writes a snapshot of the Storer, as `txn` sees it, to the
+// file at `path`, and truncates the journal if the Storer has one. `txn`
+// must be a write transaction, so nothing else is journaled meanwhile.
+func (s *Storer) saveSnapshot(txn *memdb.Txn, path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // cleanup of a file that is usually already renamed
+	err = writeSnapshot(txn, tmp)
+	if err != nil {
+		tmp.Close() //nolint:errcheck,gosec // already returning an error
+		return err
+	}
+	err = tmp.Sync()
+	if err != nil {
+		tmp.Close() //nolint:errcheck,gosec // already returning an error
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return err
+	}
+	// the rename has to be durable before the journal is truncated, or a
+	// crash could lose both the new snapshot and the journal
+	err = syncDir(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	if s.journal != nil {
+		return s.journal.truncate()
+	}
+	return nil
+}
+
+// syncDir flushes the directory at `path` to disk, so changes to which
+// files are in it, like renames, survive a crash.
+func syncDir(path string) error {
+	dir, err := os.Open(path) //nolint:gosec // the directory of a file the caller chose
+	if err != nil {
+		return err
+	}
+	err = dir.Sync()
+	if err != nil {
+		dir.Close() //nolint:errcheck,gosec // already returning an error
+		return err
+	}
+	return dir.Close()
+}
+
+// LoadSnapshotFile replaces the contents of the Storer with the Accounts,
+// DenyRules, history, and released IDs in the snapshot stored in the file at
+// `path`.
+func (s *Storer) LoadSnapshotFile(path string) error {
+	file, err := os.Open(path) //nolint:gosec // reading a file the caller chose is the point
+	if err != nil {
+		return err
+	}
+	defer file.Close() //nolint:errcheck // read-only file, nothing to flush
+	return s.LoadSnapshot(file)
+}
@@ -0,0 +1,509 @@
+package memory_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"lockbox.dev/accounts"
+	"lockbox.dev/accounts/storers/memory"
+)
+
+func testAccounts() []accounts.Account {
+	now := time.Date(2022, time.March, 14, 15, 9, 26, 535000000, time.UTC)
+	return []accounts.Account{
+		{
+			ID:             "paddy@impractical.co",
+			ProfileID:      "3c6f4fd8-8e6a-4c8e-9d3c-7fb1e0b5a6f1",
+			Created:        now,
+			LastUsed:       now.Add(time.Hour),
+			LastSeen:       now.Add(2 * time.Hour),
+			IsRegistration: true,
+		},
+		{
+			ID:        "paddy@carvers.co",
+			ProfileID: "3c6f4fd8-8e6a-4c8e-9d3c-7fb1e0b5a6f1",
+			Created:   now.Add(time.Minute),
+			LastUsed:  now.Add(time.Minute),
+			LastSeen:  now.Add(time.Minute),
+		},
+	}
+}
+
+func createAll(ctx context.Context, t *testing.T, storer accounts.Storer, accts []accounts.Account) {
+	t.Helper()
+	for _, account := range accts {
+		err := storer.Create(ctx, account)
+		if err != nil {
+			t.Fatalf("Unexpected error creating account %s: %+v\n", account.ID, err)
+		}
+	}
+}
+
+func checkProfile(ctx context.Context, t *testing.T, storer accounts.Storer, want []accounts.Account) {
+	t.Helper()
+	got, err := storer.ListByProfile(ctx, want[0].ProfileID)
+	if err != nil {
+		t.Fatalf("Unexpected error listing accounts: %+v\n", err)
+	}
+	want = append([]accounts.Account{}, want...)
+	accounts.ByLastUsedDesc(want)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storer, err := memory.NewStorer()
+	if err != nil {
+		t.Fatalf("Unexpected error creating storer: %+v\n", err)
+	}
+	createAll(ctx, t, storer, testAccounts())
+
+	var buf bytes.Buffer
+	err = storer.WriteSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error writing snapshot: %+v\n", err)
+	}
+
+	restored, err := memory.NewStorer()
+	if err != nil {
+		t.Fatalf("Unexpected error creating storer: %+v\n", err)
+	}
+	err = restored.LoadSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error loading snapshot: %+v\n", err)
+	}
+	checkProfile(ctx, t, restored, testAccounts())
+}
+
+func TestSnapshotUnsupportedVersion(t *testing.T) {
+	t.Parallel()
+
+	storer, err := memory.NewStorer()
+	if err != nil {
+		t.Fatalf("Unexpected error creating storer: %+v\n", err)
+	}
+	err = storer.LoadSnapshot(strings.NewReader(`{"version": 9000, "accounts": []}`))
+	if !errors.Is(err, memory.ErrUnsupportedSnapshotVersion) {
+		t.Fatalf("Expected ErrUnsupportedSnapshotVersion, got %v", err)
+	}
+}
+
+func TestPersistentStorerJournal(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	snapshotPath := filepath.Join(dir, "accounts.snapshot")
+	journalPath := filepath.Join(dir, "accounts.journal")
+
+	storer, err := memory.NewPersistentStorer(snapshotPath, journalPath)
+	if err != nil {
+		t.Fatalf("Unexpected error creating storer: %+v\n", err)
+	}
+	accts := testAccounts()
+	createAll(ctx, t, storer, accts[:1])
+	err = storer.SaveSnapshotFile(snapshotPath)
+	if err != nil {
+		t.Fatalf("Unexpected error saving snapshot: %+v\n", err)
+	}
+
+	// these changes only exist in the journal
+	createAll(ctx, t, storer, accts[1:])
+	used := accts[0].LastUsed.Add(time.Hour)
+	err = storer.Update(ctx, accts[0].ID, accounts.Change{LastUsed: &used})
+	if err != nil {
+		t.Fatalf("Unexpected error updating account: %+v\n", err)
+	}
+	accts[0].LastUsed = used
+	err = storer.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error closing storer: %+v\n", err)
+	}
+
+	// simulate a crash partway through writing a journal entry
+	journal, err := os.OpenFile(journalPath, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatalf("Unexpected error opening journal: %+v\n", err)
+	}
+	_, err = journal.WriteString(`{"op":"delete","id":"paddy@impr`)
+	if err != nil {
+		t.Fatalf("Unexpected error writing journal: %+v\n", err)
+	}
+	err = journal.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error closing journal: %+v\n", err)
+	}
+
+	restored, err := memory.NewPersistentStorer(snapshotPath, journalPath)
+	if err != nil {
+		t.Fatalf("Unexpected error restoring storer: %+v\n", err)
+	}
+	checkProfile(ctx, t, restored, accts)
+
+	// the partial entry shouldn't corrupt entries written after it
+	err = restored.Delete(ctx, accts[1].ID)
+	if err != nil {
+		t.Fatalf("Unexpected error deleting account: %+v\n", err)
+	}
+	err = restored.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error closing storer: %+v\n", err)
+	}
+	restored, err = memory.NewPersistentStorer(snapshotPath, journalPath)
+	if err != nil {
+		t.Fatalf("Unexpected error restoring storer: %+v\n", err)
+	}
+	defer restored.Close() //nolint:errcheck // test cleanup
+	checkProfile(ctx, t, restored, accts[:1])
+}
+
+func TestReadPersistentStorer(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	snapshotPath := filepath.Join(dir, "accounts.snapshot")
+	journalPath := filepath.Join(dir, "accounts.journal")
+
+	storer, err := memory.NewPersistentStorer(snapshotPath, journalPath)
+	if err != nil {
+		t.Fatalf("Unexpected error creating storer: %+v\n", err)
+	}
+	accts := testAccounts()
+	createAll(ctx, t, storer, accts[:1])
+	err = storer.SaveSnapshotFile(snapshotPath)
+	if err != nil {
+		t.Fatalf("Unexpected error saving snapshot: %+v\n", err)
+	}
+	createAll(ctx, t, storer, accts[1:])
+	err = storer.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error closing storer: %+v\n", err)
+	}
+	journal, err := os.OpenFile(journalPath, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatalf("Unexpected error opening journal: %+v\n", err)
+	}
+	_, err = journal.WriteString(`{"op":"delete","id":"paddy@impr`)
+	if err != nil {
+		t.Fatalf("Unexpected error writing journal: %+v\n", err)
+	}
+	err = journal.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error closing journal: %+v\n", err)
+	}
+	snapshotBefore, err := os.ReadFile(snapshotPath)
+	if err != nil {
+		t.Fatalf("Unexpected error reading snapshot: %+v\n", err)
+	}
+	journalBefore, err := os.ReadFile(journalPath)
+	if err != nil {
+		t.Fatalf("Unexpected error reading journal: %+v\n", err)
+	}
+
+	read, err := memory.ReadPersistentStorer(snapshotPath, journalPath)
+	if err != nil {
+		t.Fatalf("Unexpected error reading storer: %+v\n", err)
+	}
+	checkProfile(ctx, t, read, accts)
+	err = read.Delete(ctx, accts[0].ID)
+	if err != nil {
+		t.Fatalf("Unexpected error deleting account: %+v\n", err)
+	}
+	err = read.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error closing storer: %+v\n", err)
+	}
+
+	// neither file should have changed, not even to drop the partial
+	// journal entry
+	snapshotAfter, err := os.ReadFile(snapshotPath)
+	if err != nil {
+		t.Fatalf("Unexpected error reading snapshot: %+v\n", err)
+	}
+	if !bytes.Equal(snapshotBefore, snapshotAfter) {
+		t.Error("Expected snapshot not to change")
+	}
+	journalAfter, err := os.ReadFile(journalPath)
+	if err != nil {
+		t.Fatalf("Unexpected error reading journal: %+v\n", err)
+	}
+	if !bytes.Equal(journalBefore, journalAfter) {
+		t.Error("Expected journal not to change")
+	}
+}
+
+func TestPersistentStorerJournalErase(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	snapshotPath := filepath.Join(dir, "accounts.snapshot")
+	journalPath := filepath.Join(dir, "accounts.journal")
+
+	storer, err := memory.NewPersistentStorer(snapshotPath, journalPath)
+	if err != nil {
+		t.Fatalf("Unexpected error creating storer: %+v\n", err)
+	}
+	accts := testAccounts()
+	// leave one Account in the snapshot and the other in the journal
+	createAll(ctx, t, storer, accts[:1])
+	err = storer.SaveSnapshotFile(snapshotPath)
+	if err != nil {
+		t.Fatalf("Unexpected error saving snapshot: %+v\n", err)
+	}
+	createAll(ctx, t, storer, accts[1:])
+	erased, err := storer.EraseProfile(ctx, accts[0].ProfileID)
+	if err != nil {
+		t.Fatalf("Unexpected error erasing profile: %+v\n", err)
+	}
+	if len(erased) != len(accts) {
+		t.Errorf("Expected %d accounts to be erased, got %d", len(accts), len(erased))
+	}
+	// no copy of the erased Accounts is left on disk
+	for _, path := range []string{snapshotPath, journalPath} {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Unexpected error reading %s: %+v\n", path, err)
+		}
+		for _, account := range accts {
+			if bytes.Contains(contents, []byte(account.ID)) {
+				t.Errorf("Expected %s not to contain erased account %s", filepath.Base(path), account.ID)
+			}
+		}
+	}
+	err = storer.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error closing storer: %+v\n", err)
+	}
+
+	restored, err := memory.NewPersistentStorer(snapshotPath, journalPath)
+	if err != nil {
+		t.Fatalf("Unexpected error restoring storer: %+v\n", err)
+	}
+	defer restored.Close() //nolint:errcheck // test cleanup
+	got, err := restored.ListByProfile(ctx, accts[0].ProfileID)
+	if err != nil {
+		t.Fatalf("Unexpected error listing accounts: %+v\n", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Expected erased accounts to stay erased, got %+v", got)
+	}
+}
+
+func TestPersistentStorerJournalRename(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	snapshotPath := filepath.Join(dir, "accounts.snapshot")
+	journalPath := filepath.Join(dir, "accounts.journal")
+
+	storer, err := memory.NewPersistentStorer(snapshotPath, journalPath)
+	if err != nil {
+		t.Fatalf("Unexpected error creating storer: %+v\n", err)
+	}
+	accts := testAccounts()
+	createAll(ctx, t, storer, accts)
+	err = storer.Rename(ctx, accts[1].ID, "paddy@lockbox.dev")
+	if err != nil {
+		t.Fatalf("Unexpected error renaming account: %+v\n", err)
+	}
+	accts[1].ID = "paddy@lockbox.dev"
+	err = storer.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error closing storer: %+v\n", err)
+	}
+
+	restored, err := memory.NewPersistentStorer(snapshotPath, journalPath)
+	if err != nil {
+		t.Fatalf("Unexpected error restoring storer: %+v\n", err)
+	}
+	checkProfile(ctx, t, restored, accts)
+	checkRenames(ctx, t, restored, accts[0].ProfileID, []string{"paddy@carvers.co"})
+
+	// history survives being saved in a snapshot, and is added to in
+	// order after being restored
+	err = restored.SaveSnapshotFile(snapshotPath)
+	if err != nil {
+		t.Fatalf("Unexpected error saving snapshot: %+v\n", err)
+	}
+	err = restored.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error closing storer: %+v\n", err)
+	}
+	restored, err = memory.NewPersistentStorer(snapshotPath, journalPath)
+	if err != nil {
+		t.Fatalf("Unexpected error restoring storer: %+v\n", err)
+	}
+	defer restored.Close() //nolint:errcheck // test cleanup
+	err = restored.Rename(ctx, accts[0].ID, "paddy@example.com")
+	if err != nil {
+		t.Fatalf("Unexpected error renaming account: %+v\n", err)
+	}
+	checkRenames(ctx, t, restored, accts[0].ProfileID, []string{"paddy@carvers.co", accts[0].ID})
+}
+
+// checkRenames checks that the history of `profileID` in `storer` records
+// renaming Accounts from `previousIDs`, in order.
+func checkRenames(ctx context.Context, t *testing.T, storer *memory.Storer, profileID string, previousIDs []string) {
+	t.Helper()
+	history, err := storer.ListHistoryByProfile(ctx, profileID)
+	if err != nil {
+		t.Fatalf("Unexpected error listing history: %+v\n", err)
+	}
+	got := make([]string, 0, len(history))
+	for _, rec := range history {
+		got = append(got, rec.Details[accounts.HistoryDetailPreviousID])
+	}
+	if diff := cmp.Diff(previousIDs, got); diff != "" {
+		t.Errorf("Unexpected diff in renamed IDs (-wanted, +got): %s", diff)
+	}
+}
+
+func TestPersistentStorerDenyRules(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	snapshotPath := filepath.Join(dir, "accounts.snapshot")
+	journalPath := filepath.Join(dir, "accounts.journal")
+
+	storer, err := memory.NewPersistentStorer(snapshotPath, journalPath)
+	if err != nil {
+		t.Fatalf("Unexpected error creating storer: %+v\n", err)
+	}
+	now := time.Date(2022, time.March, 14, 15, 9, 26, 0, time.UTC)
+	rules := []accounts.DenyRule{
+		{ID: "rule-1", Kind: accounts.DenyRuleExact, Pattern: "admin", Reason: "reserved", Created: now},
+		{ID: "rule-2", Kind: accounts.DenyRuleSuffix, Pattern: "@lockbox.dev", Created: now.Add(time.Minute)},
+	}
+	for _, rule := range rules {
+		err = storer.CreateDenyRule(ctx, rule)
+		if err != nil {
+			t.Fatalf("Unexpected error creating deny rule: %+v\n", err)
+		}
+	}
+	err = storer.DeleteDenyRule(ctx, "rule-1")
+	if err != nil {
+		t.Fatalf("Unexpected error deleting deny rule: %+v\n", err)
+	}
+	err = storer.DeleteDenyRule(ctx, "rule-1")
+	if !errors.Is(err, accounts.ErrDenyRuleNotFound) {
+		t.Errorf("Expected %v deleting a deleted rule, got %v", accounts.ErrDenyRuleNotFound, err)
+	}
+
+	// rules survive being replayed from the journal, and being saved
+	// in a snapshot
+	for i := 0; i < 2; i++ {
+		err = storer.Close()
+		if err != nil {
+			t.Fatalf("Unexpected error closing storer: %+v\n", err)
+		}
+		storer, err = memory.NewPersistentStorer(snapshotPath, journalPath)
+		if err != nil {
+			t.Fatalf("Unexpected error restoring storer: %+v\n", err)
+		}
+		got, err := storer.ListDenyRules(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error listing deny rules: %+v\n", err)
+		}
+		if diff := cmp.Diff(rules[1:], got); diff != "" {
+			t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+		}
+		err = storer.SaveSnapshotFile(snapshotPath)
+		if err != nil {
+			t.Fatalf("Unexpected error saving snapshot: %+v\n", err)
+		}
+	}
+	defer storer.Close() //nolint:errcheck // test cleanup
+}
+
+func TestPersistentStorerReleasedIDs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	snapshotPath := filepath.Join(dir, "accounts.snapshot")
+	journalPath := filepath.Join(dir, "accounts.journal")
+	quarantine := memory.WithReleaseQuarantine(accounts.DefaultReleaseQuarantine, bytes.Repeat([]byte{0x24}, 32))
+
+	storer, err := memory.NewPersistentStorer(snapshotPath, journalPath, quarantine)
+	if err != nil {
+		t.Fatalf("Unexpected error creating storer: %+v\n", err)
+	}
+	accts := testAccounts()
+	createAll(ctx, t, storer, accts)
+	err = storer.Delete(ctx, accts[0].ID)
+	if err != nil {
+		t.Fatalf("Unexpected error deleting account: %+v\n", err)
+	}
+	err = storer.Rename(ctx, accts[1].ID, "paddy@lockbox.dev")
+	if err != nil {
+		t.Fatalf("Unexpected error renaming account: %+v\n", err)
+	}
+
+	// the quarantine survives being replayed from the journal, and
+	// being saved in a snapshot
+	for i := 0; i < 2; i++ {
+		err = storer.Close()
+		if err != nil {
+			t.Fatalf("Unexpected error closing storer: %+v\n", err)
+		}
+		storer, err = memory.NewPersistentStorer(snapshotPath, journalPath, quarantine)
+		if err != nil {
+			t.Fatalf("Unexpected error restoring storer: %+v\n", err)
+		}
+		for _, account := range accts {
+			err = storer.Create(ctx, accounts.Account{ID: account.ID, ProfileID: "someone-else"})
+			if !errors.Is(err, accounts.ErrAccountIDRecentlyReleased) {
+				t.Errorf("Expected %v claiming %q after a restart, got %v", accounts.ErrAccountIDRecentlyReleased, account.ID, err)
+			}
+		}
+		err = storer.SaveSnapshotFile(snapshotPath)
+		if err != nil {
+			t.Fatalf("Unexpected error saving snapshot: %+v\n", err)
+		}
+	}
+
+	// the released IDs are only recorded by their keyed hash
+	snapshot, err := os.ReadFile(snapshotPath)
+	if err != nil {
+		t.Fatalf("Unexpected error reading snapshot: %+v\n", err)
+	}
+	for _, account := range accts[:2] {
+		if bytes.Contains(snapshot, []byte(accounts.HashReleasedID(nil, account.ID))) {
+			t.Errorf("Expected %q not to be recorded by its unkeyed hash", account.ID)
+		}
+	}
+	defer storer.Close() //nolint:errcheck // test cleanup
+}
+
+func TestSnapshotVersion1(t *testing.T) {
+	t.Parallel()
+
+	storer, err := memory.NewStorer()
+	if err != nil {
+		t.Fatalf("Unexpected error creating storer: %+v\n", err)
+	}
+	err = storer.LoadSnapshot(strings.NewReader(`{"version": 1, "accounts": [{"id": "paddy@impractical.co", "profileID": "paddy"}]}`))
+	if err != nil {
+		t.Fatalf("Unexpected error loading version 1 snapshot: %+v\n", err)
+	}
+	_, err = storer.Get(context.Background(), "paddy@impractical.co")
+	if err != nil {
+		t.Errorf("Unexpected error retrieving account from version 1 snapshot: %+v\n", err)
+	}
+}
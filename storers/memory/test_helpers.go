@@ -7,11 +7,14 @@ import (
 )
 
 // Factory is a generator of Storers for testing purposes.
-type Factory struct{}
+type Factory struct {
+	// Options are passed to every Storer the Factory creates.
+	Options []Option
+}
 
 // NewStorer creates a new, isolated, in-memory Storer for tests.
-func (Factory) NewStorer(_ context.Context) (accounts.Storer, error) { //nolint:ireturn // interface requires returning an interface
-	return NewStorer()
+func (f Factory) NewStorer(_ context.Context) (accounts.Storer, error) { //nolint:ireturn // interface requires returning an interface
+	return NewStorer(f.Options...)
 }
 
 // TeardownStorers does nothing and is only included to fill an interface.
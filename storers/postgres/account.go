@@ -16,6 +16,16 @@ type Account struct {
 	LastUsed       time.Time    `sql_column:"last_used_at"`
 	LastSeen       time.Time    `sql_column:"last_seen_at"`
 	IsRegistration sql.NullBool `sql_column:"is_registration"`
+
+	// DisplayID is the encrypted form of the Account's ID, when the ID
+	// column holds a keyed hash. It is NULL when the ID is stored as-is.
+	DisplayID sql.NullString `sql_column:"display_id"`
+
+	// Kind is the accounts.Kind of the Account's ID, so profile limits
+	// can be checked without reading the ID. It is NULL for Accounts
+	// whose IDs were hashed before it was added, until RotateKeys is
+	// called.
+	Kind sql.NullString `sql_column:"kind"`
 }
 
 func fromPostgres(account Account) accounts.Account {
@@ -43,6 +53,10 @@ func toPostgres(account accounts.Account) Account {
 			Valid: account.IsRegistration,
 			Bool:  account.IsRegistration,
 		},
+		Kind: sql.NullString{
+			String: string(accounts.KindOf(account.ID)),
+			Valid:  true,
+		},
 	}
 }
 
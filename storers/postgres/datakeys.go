@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"darlinggo.co/pan"
+	"yall.in"
+)
+
+// queryDataKeys runs `query` and returns the data keys it selects.
+func (s *Storer) queryDataKeys(ctx context.Context, query *pan.Query) ([]dataKey, error) {
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(queryStr, query.Args()...) //nolint:sqlclosecheck // the closeRows helper isn't picked up
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+	keys := []dataKey{}
+	for rows.Next() {
+		var key dataKey
+		err = pan.Unmarshal(rows, &key)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (s *Storer) latestDataKey(ctx context.Context) (dataKey, bool, error) {
+	keys, err := s.queryDataKeys(ctx, latestDataKeySQL(ctx))
+	if err != nil {
+		return dataKey{}, false, err
+	}
+	if len(keys) < 1 {
+		return dataKey{}, false, nil
+	}
+	return keys[0], true, nil
+}
+
+func (s *Storer) getDataKey(ctx context.Context, id string) (dataKey, error) {
+	keys, err := s.queryDataKeys(ctx, getDataKeySQL(ctx, id))
+	if err != nil {
+		return dataKey{}, err
+	}
+	if len(keys) < 1 {
+		return dataKey{}, ErrDataKeyNotFound
+	}
+	return keys[0], nil
+}
+
+func (s *Storer) createDataKey(ctx context.Context, key dataKey) error {
+	_, err := s.exec(ctx, createDataKeySQL(ctx, key))
+	return err
+}
+
+func (s *Storer) staleDataKeys(ctx context.Context, masterKeyID string, limit int) ([]dataKey, error) {
+	return s.queryDataKeys(ctx, staleDataKeysSQL(ctx, masterKeyID, limit))
+}
+
+func (s *Storer) rewrapDataKey(ctx context.Context, oldMasterKeyID string, key dataKey) error {
+	_, err := s.exec(ctx, rewrapDataKeySQL(ctx, oldMasterKeyID, key))
+	return err
+}
+
+// ReencryptDisplayIDs brings the encryption of stored Account IDs up to date
+// with the KMS passed to WithEnvelopeEncryption, up to `batchSize` rows at a
+// time. Every data key is wrapped with the KMS's current master key, and
+// every Account ID that wasn't encrypted with the current data key is
+// encrypted with it. A new data key is created whenever the KMS's master key
+// changes, so master keys and data keys are rotated together. It returns the
+// number of Accounts whose IDs were encrypted again.
+//
+// IDs that haven't been hashed yet are left alone; RotateKeys takes care of
+// those. ReencryptDisplayIDs does nothing if the Storer wasn't created
+// WithHashedIDs and WithEnvelopeEncryption.
+func (s *Storer) ReencryptDisplayIDs(ctx context.Context, batchSize int) (int, error) {
+	if s.keys == nil || s.envelope == nil {
+		return 0, nil
+	}
+	// make sure a data key wrapped with the current master key exists
+	// before rewrapping, so an old data key doesn't become current
+	current, err := s.envelope.currentKey(ctx)
+	if err != nil {
+		return 0, err
+	}
+	rewrapped, err := s.envelope.rewrap(ctx, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	yall.FromContext(ctx).WithField("rewrapped", rewrapped).Debug("rewrapped data keys")
+
+	var reencrypted int
+	for {
+		batch, err := s.query(ctx, unencryptedSQL(ctx, current, batchSize))
+		if err != nil {
+			return reencrypted, err
+		}
+		if len(batch) < 1 {
+			return reencrypted, nil
+		}
+		plain, err := s.fromStored(ctx, batch)
+		if err != nil {
+			return reencrypted, err
+		}
+		for pos, account := range plain {
+			display, err := s.envelope.encrypt(ctx, account.ID, batch[pos].ID)
+			if err != nil {
+				return reencrypted, err
+			}
+			updated := batch[pos]
+			updated.DisplayID = sql.NullString{String: display, Valid: true}
+			_, err = s.exec(ctx, reencryptSQL(ctx, batch[pos].DisplayID.String, updated))
+			if err != nil {
+				return reencrypted, err
+			}
+			reencrypted++
+		}
+		yall.FromContext(ctx).WithField("reencrypted", reencrypted).Debug("re-encrypted account IDs")
+	}
+}
+
+// ReencryptEvery calls ReencryptDisplayIDs every `interval`, until `ctx` is
+// canceled, so key rotations are picked up without any intervention. Errors
+// are logged using the logger in `ctx`, and don't stop future attempts.
+//
+// ReencryptEvery blocks until `ctx` is canceled, so it should usually be run
+// in its own goroutine.
+func (s *Storer) ReencryptEvery(ctx context.Context, interval time.Duration, batchSize int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.ReencryptDisplayIDs(ctx, batchSize); err != nil {
+				yall.FromContext(ctx).WithError(err).Error("error re-encrypting account IDs")
+			}
+		}
+	}
+}
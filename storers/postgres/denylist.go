@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"darlinggo.co/pan"
+
+	"lockbox.dev/accounts"
+)
+
+// denyRule is the representation of an accounts.DenyRule that is stored in
+// PostgreSQL.
+type denyRule struct {
+	ID      string    `sql_column:"id"`
+	Kind    string    `sql_column:"kind"`
+	Pattern string    `sql_column:"pattern"`
+	Reason  string    `sql_column:"reason"`
+	Created time.Time `sql_column:"created_at"`
+}
+
+// GetSQLTableName returns the name of the SQL table that the data for this
+// type will be stored in.
+func (denyRule) GetSQLTableName() string {
+	return "account_deny_rules"
+}
+
+// CreateDenyRule inserts the passed DenyRule into the PostgreSQL database.
+func (s *Storer) CreateDenyRule(ctx context.Context, rule accounts.DenyRule) error {
+	_, err := s.exec(ctx, createDenyRuleSQL(ctx, denyRule{
+		ID:      rule.ID,
+		Kind:    string(rule.Kind),
+		Pattern: rule.Pattern,
+		Reason:  rule.Reason,
+		Created: rule.Created,
+	}))
+	return err
+}
+
+// ListDenyRules returns every DenyRule in the PostgreSQL database, oldest
+// first.
+func (s *Storer) ListDenyRules(ctx context.Context) ([]accounts.DenyRule, error) {
+	query := listDenyRulesSQL(ctx)
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(queryStr, query.Args()...) //nolint:sqlclosecheck // the closeRows helper isn't picked up
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+	rules := []accounts.DenyRule{}
+	for rows.Next() {
+		var rule denyRule
+		err = pan.Unmarshal(rows, &rule)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, accounts.DenyRule{
+			ID:      rule.ID,
+			Kind:    accounts.DenyRuleKind(rule.Kind),
+			Pattern: rule.Pattern,
+			Reason:  rule.Reason,
+			Created: rule.Created,
+		})
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// DeleteDenyRule removes the DenyRule whose ID matches the passed ID from the
+// PostgreSQL database. If no DenyRule matches, an ErrDenyRuleNotFound error
+// is returned.
+func (s *Storer) DeleteDenyRule(ctx context.Context, id string) error {
+	res, err := s.exec(ctx, deleteDenyRuleSQL(ctx, id))
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows < 1 {
+		return accounts.ErrDenyRuleNotFound
+	}
+	return nil
+}
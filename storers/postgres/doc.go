@@ -9,4 +9,34 @@
 // the sql folder packaged using go-bindata to make them easy to include in Go
 // binaries. Migrations should be applied in lexicographical order, with
 // numbers coming before letters.
+//
+// Storers created WithHashedIDs store Account IDs as keyed hashes, so a copy
+// of the database doesn't reveal users' email addresses or usernames. The IDs
+// as they were entered are stored encrypted, and are only readable with the
+// keys from the KeyProvider. Keys can be rotated by adding a new current Key
+// and calling Storer.RotateKeys.
+//
+// Storers also created WithEnvelopeEncryption encrypt those IDs with data
+// keys instead, which are stored in the database wrapped by a KMS master key.
+// FileKMS keeps master keys in a local file, and is meant for tests; other
+// KMSes can be used by implementing the KMS interface. Storer.ReencryptEvery
+// keeps the encryption up to date as master keys are rotated.
+//
+// Storers also implement lockbox.dev/accounts.PendingLinkStorer. The IDs in
+// pending links are hashed and encrypted like Account IDs, but expired
+// pending links should still be deleted regularly.
+//
+// Storers also implement lockbox.dev/accounts.Renamer, and keep a history of
+// renames in the account_history table, which is returned by
+// ListHistoryByProfile. The IDs in the history are hashed and encrypted like
+// Account IDs, and are replaced with placeholders when their profile is
+// erased.
+//
+// Storers created WithReleaseQuarantine quarantine IDs released by deleting
+// or renaming Accounts, so other profiles can't claim them for a while.
+// Released IDs are stored by their hash in the account_released_ids table, and
+// Storer.DeleteExpiredReleasedIDs removes them once their quarantine ends.
+//
+// Storers also implement lockbox.dev/accounts.DenylistStorer, keeping deny
+// rules in the account_deny_rules table.
 package postgres
@@ -0,0 +1,265 @@
+package postgres
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	uuid "github.com/hashicorp/go-uuid"
+)
+
+const (
+	// envelopePrefix marks display IDs encrypted with a data key, to tell
+	// them apart from display IDs encrypted directly with a Key.
+	envelopePrefix = "env1:"
+
+	dataKeySize = 32
+)
+
+var (
+	// ErrDataKeyNotFound is returned when a display ID was encrypted with
+	// a data key that isn't in the database.
+	ErrDataKeyNotFound = errors.New("data key not found")
+	// ErrNoKMS is returned when a display ID was encrypted with a data
+	// key, but the Storer wasn't created WithEnvelopeEncryption.
+	ErrNoKMS = errors.New("display ID was encrypted with a data key, but no KMS is configured")
+)
+
+// dataKey is a key used to encrypt display IDs, stored wrapped by a KMS
+// master key.
+type dataKey struct {
+	ID          string    `sql_column:"id"`
+	MasterKeyID string    `sql_column:"master_key_id"`
+	WrappedKey  []byte    `sql_column:"wrapped_key"`
+	Created     time.Time `sql_column:"created_at"`
+}
+
+// GetSQLTableName returns the name of the SQL table that the data for this
+// type will be stored in.
+func (dataKey) GetSQLTableName() string {
+	return "account_data_keys"
+}
+
+// dataKeyStore persists wrapped data keys.
+type dataKeyStore interface {
+	// latestDataKey returns the most recently created data key, and
+	// false if there are no data keys.
+	latestDataKey(ctx context.Context) (dataKey, bool, error)
+
+	// getDataKey returns the data key identified by `id`, or
+	// ErrDataKeyNotFound.
+	getDataKey(ctx context.Context, id string) (dataKey, error)
+
+	// createDataKey stores a new data key.
+	createDataKey(ctx context.Context, key dataKey) error
+
+	// staleDataKeys returns up to `limit` data keys that aren't wrapped
+	// with the master key identified by `masterKeyID`.
+	staleDataKeys(ctx context.Context, masterKeyID string, limit int) ([]dataKey, error)
+
+	// rewrapDataKey replaces the wrapped form of `key`, as long as it is
+	// still wrapped with the master key identified by `oldMasterKeyID`.
+	rewrapDataKey(ctx context.Context, oldMasterKeyID string, key dataKey) error
+}
+
+// envelope encrypts display IDs with data keys that are themselves
+// encrypted by a KMS. Unwrapped data keys are cached in memory, so the KMS
+// is only called once per data key.
+type envelope struct {
+	kms   KMS
+	store dataKeyStore
+	now   func() time.Time
+
+	lock          sync.Mutex
+	plain         map[string][]byte
+	current       string
+	currentMaster string
+}
+
+// newEnvelope returns an envelope that wraps data keys using `kms`, stores
+// them in `store`, and reads the time data keys are created from `now`.
+func newEnvelope(kms KMS, store dataKeyStore, now func() time.Time) *envelope {
+	return &envelope{
+		kms:   kms,
+		store: store,
+		now:   now,
+		plain: map[string][]byte{},
+	}
+}
+
+// currentKey returns the ID of the data key to encrypt with. The most
+// recently created data key is used, unless it isn't wrapped with the KMS's
+// current master key, in which case a new data key is created. That way
+// rotating the master key also rotates the data key.
+func (e *envelope) currentKey(ctx context.Context) (string, error) {
+	master, err := e.kms.CurrentKeyID(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error retrieving current master key: %w", err)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.current != "" && e.currentMaster == master {
+		return e.current, nil
+	}
+	latest, ok, err := e.store.latestDataKey(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error retrieving latest data key: %w", err)
+	}
+	if ok && latest.MasterKeyID == master {
+		plain, err := e.kms.UnwrapKey(ctx, latest.MasterKeyID, latest.WrappedKey)
+		if err != nil {
+			return "", fmt.Errorf("error unwrapping data key %s: %w", latest.ID, err)
+		}
+		e.plain[latest.ID] = plain
+		e.current, e.currentMaster = latest.ID, master
+		return e.current, nil
+	}
+	plain := make([]byte, dataKeySize)
+	_, err = io.ReadFull(rand.Reader, plain)
+	if err != nil {
+		return "", err
+	}
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return "", err
+	}
+	wrappedWith, wrapped, err := e.kms.WrapKey(ctx, plain)
+	if err != nil {
+		return "", fmt.Errorf("error wrapping data key: %w", err)
+	}
+	err = e.store.createDataKey(ctx, dataKey{
+		ID:          id,
+		MasterKeyID: wrappedWith,
+		WrappedKey:  wrapped,
+		Created:     e.now(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error storing data key: %w", err)
+	}
+	e.plain[id] = plain
+	e.current, e.currentMaster = id, wrappedWith
+	return id, nil
+}
+
+// key returns the unwrapped data key identified by `id`.
+func (e *envelope) key(ctx context.Context, id string) ([]byte, error) {
+	e.lock.Lock()
+	plain, ok := e.plain[id]
+	e.lock.Unlock()
+	if ok {
+		return plain, nil
+	}
+	stored, err := e.store.getDataKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	plain, err = e.kms.UnwrapKey(ctx, stored.MasterKeyID, stored.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("error unwrapping data key %s: %w", id, err)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.plain[id] = plain
+	return plain, nil
+}
+
+func (e *envelope) aead(ctx context.Context, id string) (cipher.AEAD, error) {
+	plain, err := e.key(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(plain)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt returns `id`, encrypted with AES-GCM using the current data key
+// and bound to the hashed ID it's stored alongside.
+func (e *envelope) encrypt(ctx context.Context, id, hashedID string) (string, error) {
+	keyID, err := e.currentKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	aead, err := e.aead(ctx, keyID)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(id), []byte(hashedID))
+	return envelopePrefix + keyID + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt returns the plain ID from a display ID encrypted by encrypt,
+// checking that it was stored alongside `hashedID`.
+func (e *envelope) decrypt(ctx context.Context, displayID, hashedID string) (string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(displayID, envelopePrefix), ":", 2) //nolint:gomnd // data key ID and ciphertext
+	if len(parts) != 2 {                                                           //nolint:gomnd // data key ID and ciphertext
+		return "", ErrMalformedDisplayID
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrMalformedDisplayID, err.Error())
+	}
+	aead, err := e.aead(ctx, parts[0])
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", ErrMalformedDisplayID
+	}
+	id, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(hashedID))
+	if err != nil {
+		return "", fmt.Errorf("error decrypting display ID: %w", err)
+	}
+	return string(id), nil
+}
+
+// rewrap wraps every data key that isn't wrapped with the KMS's current
+// master key with the current master key instead, up to `batchSize` at a
+// time. It returns the number of data keys rewrapped.
+func (e *envelope) rewrap(ctx context.Context, batchSize int) (int, error) {
+	master, err := e.kms.CurrentKeyID(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error retrieving current master key: %w", err)
+	}
+	var rewrapped int
+	for {
+		stale, err := e.store.staleDataKeys(ctx, master, batchSize)
+		if err != nil {
+			return rewrapped, err
+		}
+		if len(stale) < 1 {
+			return rewrapped, nil
+		}
+		for _, key := range stale {
+			plain, err := e.kms.UnwrapKey(ctx, key.MasterKeyID, key.WrappedKey)
+			if err != nil {
+				return rewrapped, fmt.Errorf("error unwrapping data key %s: %w", key.ID, err)
+			}
+			oldMaster := key.MasterKeyID
+			key.MasterKeyID, key.WrappedKey, err = e.kms.WrapKey(ctx, plain)
+			if err != nil {
+				return rewrapped, fmt.Errorf("error wrapping data key %s: %w", key.ID, err)
+			}
+			err = e.store.rewrapDataKey(ctx, oldMaster, key)
+			if err != nil {
+				return rewrapped, err
+			}
+			rewrapped++
+		}
+	}
+}
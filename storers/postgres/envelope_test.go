@@ -0,0 +1,233 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"lockbox.dev/accounts"
+)
+
+// memoryDataKeys is an in-memory dataKeyStore.
+type memoryDataKeys struct {
+	keys []dataKey
+	lock sync.Mutex
+}
+
+func (m *memoryDataKeys) latestDataKey(_ context.Context) (dataKey, bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if len(m.keys) < 1 {
+		return dataKey{}, false, nil
+	}
+	return m.keys[len(m.keys)-1], true, nil
+}
+
+func (m *memoryDataKeys) getDataKey(_ context.Context, id string) (dataKey, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, key := range m.keys {
+		if key.ID == id {
+			return key, nil
+		}
+	}
+	return dataKey{}, ErrDataKeyNotFound
+}
+
+func (m *memoryDataKeys) createDataKey(_ context.Context, key dataKey) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.keys = append(m.keys, key)
+	return nil
+}
+
+func (m *memoryDataKeys) staleDataKeys(_ context.Context, masterKeyID string, limit int) ([]dataKey, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var res []dataKey
+	for _, key := range m.keys {
+		if key.MasterKeyID != masterKeyID && len(res) < limit {
+			res = append(res, key)
+		}
+	}
+	return res, nil
+}
+
+func (m *memoryDataKeys) rewrapDataKey(_ context.Context, oldMasterKeyID string, key dataKey) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for pos, existing := range m.keys {
+		if existing.ID == key.ID && existing.MasterKeyID == oldMasterKeyID {
+			m.keys[pos] = key
+		}
+	}
+	return nil
+}
+
+// writeKMSFile writes a FileKMS file holding a key for each of `ids`, with
+// the first as the current key.
+func writeKMSFile(t *testing.T, path string, ids ...string) {
+	t.Helper()
+
+	contents := fileKMSContents{Current: ids[0], Keys: map[string]string{}}
+	for _, id := range ids {
+		// derive the key from its ID, so it stays the same across
+		// rewrites of the file
+		key := bytes.Repeat([]byte(id), minKeySize)[:minKeySize]
+		contents.Keys[id] = base64.StdEncoding.EncodeToString(key)
+	}
+	raw, err := json.Marshal(contents)
+	if err != nil {
+		t.Fatalf("Error encoding KMS file: %+v\n", err)
+	}
+	err = os.WriteFile(path, raw, 0o600)
+	if err != nil {
+		t.Fatalf("Error writing KMS file: %+v\n", err)
+	}
+}
+
+func newTestKMS(t *testing.T, ids ...string) (*FileKMS, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "kms.json")
+	writeKMSFile(t, path, ids...)
+	kms, err := NewFileKMS(path)
+	if err != nil {
+		t.Fatalf("Error creating FileKMS: %+v\n", err)
+	}
+	return kms, path
+}
+
+func TestFileKMSWrapRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	kms, _ := newTestKMS(t, "2022-03", "2021-01")
+	dataKey := bytes.Repeat([]byte{0x42}, dataKeySize)
+	keyID, wrapped, err := kms.WrapKey(ctx, dataKey)
+	if err != nil {
+		t.Fatalf("Unexpected error wrapping key: %+v\n", err)
+	}
+	if keyID != "2022-03" {
+		t.Errorf("Expected key to be wrapped with %q, got %q", "2022-03", keyID)
+	}
+	if bytes.Contains(wrapped, dataKey) {
+		t.Error("Expected wrapped key not to contain the data key")
+	}
+	unwrapped, err := kms.UnwrapKey(ctx, keyID, wrapped)
+	if err != nil {
+		t.Fatalf("Unexpected error unwrapping key: %+v\n", err)
+	}
+	if !bytes.Equal(dataKey, unwrapped) {
+		t.Errorf("Expected %x, got %x", dataKey, unwrapped)
+	}
+
+	// wrapped keys are bound to the master key ID
+	_, err = kms.UnwrapKey(ctx, "2021-01", wrapped)
+	if err == nil {
+		t.Error("Expected an error unwrapping with a different master key")
+	}
+	_, err = kms.UnwrapKey(ctx, "1999-12", wrapped)
+	if !errors.Is(err, ErrUnknownMasterKey) {
+		t.Errorf("Expected %v, got %v", ErrUnknownMasterKey, err)
+	}
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	kms, _ := newTestKMS(t, "2022-03")
+	store := &memoryDataKeys{}
+	clock := accounts.NewFakeClock(time.Date(2022, time.March, 1, 12, 0, 0, 0, time.UTC))
+	env := newEnvelope(kms, store, clock.Now)
+	hashed := testKeys()[0].hashID("Paddy@Impractical.co")
+	display, err := env.encrypt(ctx, "Paddy@Impractical.co", hashed)
+	if err != nil {
+		t.Fatalf("Unexpected error encrypting: %+v\n", err)
+	}
+	if !strings.HasPrefix(display, envelopePrefix) || strings.Contains(strings.ToLower(display), "paddy") {
+		t.Errorf("Unexpected display ID %q", display)
+	}
+	if len(store.keys) != 1 {
+		t.Fatalf("Expected 1 data key to be stored, got %d", len(store.keys))
+	}
+	if !store.keys[0].Created.Equal(clock.Now()) {
+		t.Errorf("Expected the data key to be created at %s, got %s", clock.Now(), store.keys[0].Created)
+	}
+
+	// a fresh envelope has to unwrap the data key from the store
+	id, err := newEnvelope(kms, store, time.Now).decrypt(ctx, display, hashed)
+	if err != nil {
+		t.Fatalf("Unexpected error decrypting: %+v\n", err)
+	}
+	if id != "Paddy@Impractical.co" {
+		t.Errorf("Expected %q, got %q", "Paddy@Impractical.co", id)
+	}
+
+	// display IDs can't be moved to another account's row
+	_, err = env.decrypt(ctx, display, testKeys()[0].hashID("someone@impractical.co"))
+	if err == nil {
+		t.Error("Expected an error decrypting with the wrong hashed ID")
+	}
+}
+
+func TestEnvelopeRotation(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	kms, path := newTestKMS(t, "2021-01")
+	store := &memoryDataKeys{}
+	env := newEnvelope(kms, store, time.Now)
+	hashed := testKeys()[0].hashID("paddy@impractical.co")
+	oldDisplay, err := env.encrypt(ctx, "paddy@impractical.co", hashed)
+	if err != nil {
+		t.Fatalf("Unexpected error encrypting: %+v\n", err)
+	}
+
+	writeKMSFile(t, path, "2022-03", "2021-01")
+	err = kms.Reload()
+	if err != nil {
+		t.Fatalf("Unexpected error reloading KMS: %+v\n", err)
+	}
+	newDisplay, err := env.encrypt(ctx, "paddy@impractical.co", hashed)
+	if err != nil {
+		t.Fatalf("Unexpected error encrypting: %+v\n", err)
+	}
+	if strings.SplitN(oldDisplay, ":", 3)[1] == strings.SplitN(newDisplay, ":", 3)[1] { //nolint:gomnd // prefix, data key ID, ciphertext
+		t.Error("Expected a new data key after rotating the master key")
+	}
+
+	rewrapped, err := env.rewrap(ctx, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error rewrapping: %+v\n", err)
+	}
+	if rewrapped != 1 {
+		t.Errorf("Expected 1 data key to be rewrapped, got %d", rewrapped)
+	}
+
+	// once everything is rewrapped, the old master key isn't needed to
+	// read old display IDs
+	writeKMSFile(t, path, "2022-03")
+	err = kms.Reload()
+	if err != nil {
+		t.Fatalf("Unexpected error reloading KMS: %+v\n", err)
+	}
+	for _, display := range []string{oldDisplay, newDisplay} {
+		id, err := newEnvelope(kms, store, time.Now).decrypt(ctx, display, hashed)
+		if err != nil {
+			t.Fatalf("Unexpected error decrypting %q: %+v\n", display, err)
+		}
+		if id != "paddy@impractical.co" {
+			t.Errorf("Expected %q, got %q", "paddy@impractical.co", id)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"darlinggo.co/pan"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/lib/pq"
+	"yall.in"
+
+	"lockbox.dev/accounts"
+)
+
+// historyRecord is the representation of an accounts.HistoryRecord that is
+// stored in PostgreSQL. Its IDs are hashed and encrypted the same way
+// Account IDs are.
+type historyRecord struct {
+	ID                string         `sql_column:"id"`
+	AccountID         string         `sql_column:"account_id"`
+	DisplayID         sql.NullString `sql_column:"display_id"`
+	PreviousID        sql.NullString `sql_column:"previous_id"`
+	PreviousDisplayID sql.NullString `sql_column:"previous_display_id"`
+	ProfileID         string         `sql_column:"profile_id"`
+	Action            string         `sql_column:"action"`
+	At                time.Time      `sql_column:"created_at"`
+}
+
+// GetSQLTableName returns the name of the SQL table that the data for this
+// type will be stored in.
+func (historyRecord) GetSQLTableName() string {
+	return "account_history"
+}
+
+func (s *Storer) now() time.Time {
+	return accounts.Now(s.clock)
+}
+
+// queryHistory runs `query` using `run` and returns the history records it
+// selects.
+func queryHistory(ctx context.Context, run runner, query *pan.Query) ([]historyRecord, error) {
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := run.Query(queryStr, query.Args()...) //nolint:sqlclosecheck // the closeRows helper isn't picked up
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+	recs := []historyRecord{}
+	for rows.Next() {
+		var rec historyRecord
+		err = pan.Unmarshal(rows, &rec)
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return recs, nil
+}
+
+// openID returns the plain form of an ID stored as `stored`, decrypting
+// `display` if the ID is hashed.
+func (s *Storer) openID(ctx context.Context, keys *keyring, stored string, display sql.NullString) (string, error) {
+	if !display.Valid {
+		return stored, nil
+	}
+	return s.openDisplayID(ctx, keys, Account{ID: stored, DisplayID: display})
+}
+
+// fromStoredHistory converts stored history records back to
+// accounts.HistoryRecords, decrypting their IDs if needed.
+func (s *Storer) fromStoredHistory(ctx context.Context, recs []historyRecord) ([]accounts.HistoryRecord, error) {
+	var keys keyring
+	res := make([]accounts.HistoryRecord, 0, len(recs))
+	for _, rec := range recs {
+		id, err := s.openID(ctx, &keys, rec.AccountID, rec.DisplayID)
+		if err != nil {
+			return nil, err
+		}
+		record := accounts.HistoryRecord{
+			AccountID: id,
+			ProfileID: rec.ProfileID,
+			Action:    rec.Action,
+			At:        rec.At,
+		}
+		if rec.PreviousID.Valid {
+			previous, err := s.openID(ctx, &keys, rec.PreviousID.String, rec.PreviousDisplayID)
+			if err != nil {
+				return nil, err
+			}
+			record.Details = map[string]string{accounts.HistoryDetailPreviousID: previous}
+		}
+		res = append(res, record)
+	}
+	return res, nil
+}
+
+// Rename changes the ID of the Account that matches `id` to `newID` in the
+// PostgreSQL database, keeping everything else about it, and records the
+// change in the account_history table, in a single transaction. It returns
+// an ErrAccountNotFound error if no Account matches `id`, an
+// ErrAccountAlreadyExists error if another Account matches `newID`, or an
+// ErrAccountIDRecentlyReleased error if another profile released `newID`
+// within the Storer's release quarantine.
+func (s *Storer) Rename(ctx context.Context, id, newID string) error {
+	ids, err := s.lookupIDs(ctx, id)
+	if err != nil {
+		return err
+	}
+	renamed, err := s.toStored(ctx, accounts.Account{ID: newID})
+	if err != nil {
+		return err
+	}
+	recID, err := uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+	txn, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer rollback(ctx, txn)
+	stored, err := queryIn(ctx, txn, lockAccountSQL(ctx, ids))
+	if err != nil {
+		return err
+	}
+	if len(stored) < 1 {
+		return accounts.ErrAccountNotFound
+	}
+	account := stored[0]
+	// changing the capitalization of an ID doesn't release it or take
+	// another one
+	changed := !strings.EqualFold(id, newID)
+	if changed {
+		err = s.checkClaimable(ctx, txn, newID, account.ProfileID)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = execIn(ctx, txn, rekeySQL(ctx, account.ID, renamed))
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && (pqErr.Constraint == "accounts_pkey" || pqErr.Constraint == "accounts_lower_id") {
+		// another Account was created with the new ID since we
+		// checked
+		return accounts.ErrAccountAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+	if changed && s.quarantine > 0 {
+		rel, err := s.releaseOf(ctx, id, account.ProfileID)
+		if err != nil {
+			return err
+		}
+		_, err = execIn(ctx, txn, releaseSQL(ctx, rel))
+		if err != nil {
+			return err
+		}
+	}
+	_, err = execIn(ctx, txn, createHistorySQL(ctx, historyRecord{
+		ID:                recID,
+		AccountID:         renamed.ID,
+		DisplayID:         renamed.DisplayID,
+		PreviousID:        sql.NullString{String: account.ID, Valid: true},
+		PreviousDisplayID: account.DisplayID,
+		ProfileID:         account.ProfileID,
+		Action:            accounts.HistoryActionRenamed,
+		At:                s.now(),
+	}))
+	if err != nil {
+		return err
+	}
+	return txn.Commit()
+}
+
+// ListHistoryByProfile returns the history the Storer has kept about the
+// Accounts associated with the passed profile ID, oldest first.
+func (s *Storer) ListHistoryByProfile(ctx context.Context, profileID string) ([]accounts.HistoryRecord, error) {
+	recs, err := queryHistory(ctx, s.db, profileHistorySQL(ctx, profileID))
+	if err != nil {
+		return nil, err
+	}
+	return s.fromStoredHistory(ctx, recs)
+}
+
+// erasedPlaceholder returns the placeholder that replaces `id` when its
+// profile is erased. If the Storer hashes IDs, it is the hash of `id` under
+// the current Key in `keys`, so erasing an ID doesn't replace its keyed hash
+// with a hash anyone can check guesses against.
+func (s *Storer) erasedPlaceholder(ctx context.Context, keys *keyring, id string) (string, error) {
+	if s.keys == nil {
+		return accounts.ErasedPlaceholder(id), nil
+	}
+	if *keys == nil {
+		loaded, err := loadKeys(ctx, s.keys)
+		if err != nil {
+			return "", err
+		}
+		*keys = loaded
+	}
+	return accounts.ErasedHashPlaceholder(keys.current().hashID(id)), nil
+}
+
+// scrubHistory replaces the IDs in the history records associated with
+// `profileID` with their erased placeholders, as part of `txn`.
+func (s *Storer) scrubHistory(ctx context.Context, txn *sql.Tx, profileID string) error {
+	recs, err := queryHistory(ctx, txn, lockProfileHistorySQL(ctx, profileID))
+	if err != nil {
+		return err
+	}
+	var keys keyring
+	for _, rec := range recs {
+		if accounts.IsErasedPlaceholder(rec.AccountID) {
+			continue
+		}
+		scrubbed := rec
+		id, err := s.openID(ctx, &keys, rec.AccountID, rec.DisplayID)
+		if err != nil {
+			return err
+		}
+		scrubbed.AccountID, err = s.erasedPlaceholder(ctx, &keys, id)
+		if err != nil {
+			return err
+		}
+		scrubbed.DisplayID = sql.NullString{}
+		if rec.PreviousID.Valid {
+			previous, err := s.openID(ctx, &keys, rec.PreviousID.String, rec.PreviousDisplayID)
+			if err != nil {
+				return err
+			}
+			placeholder, err := s.erasedPlaceholder(ctx, &keys, previous)
+			if err != nil {
+				return err
+			}
+			scrubbed.PreviousID = sql.NullString{String: placeholder, Valid: true}
+			scrubbed.PreviousDisplayID = sql.NullString{}
+		}
+		_, err = execIn(ctx, txn, rewriteHistoryIDsSQL(ctx, rec.AccountID, scrubbed))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rotateHistory rewrites every history record whose IDs aren't hashed with
+// the current Key in `keys`, `batchSize` records at a time.
+func (s *Storer) rotateHistory(ctx context.Context, keys keyring, batchSize int) error {
+	prefix := hashedIDPrefix + keys.current().ID + ":"
+	needsRotating := func(id string) bool {
+		return !accounts.IsErasedPlaceholder(id) && !strings.HasPrefix(id, prefix)
+	}
+	var after string
+	var rotated int
+	for {
+		batch, err := queryHistory(ctx, s.db, historyAfterSQL(ctx, after, batchSize))
+		if err != nil {
+			return err
+		}
+		if len(batch) < 1 {
+			return nil
+		}
+		for _, rec := range batch {
+			after = rec.ID
+			if !needsRotating(rec.AccountID) && (!rec.PreviousID.Valid || !needsRotating(rec.PreviousID.String)) {
+				continue
+			}
+			updated := rec
+			if needsRotating(rec.AccountID) {
+				updated.AccountID, updated.DisplayID, err = s.resealID(ctx, keys, rec.AccountID, rec.DisplayID)
+				if err != nil {
+					return err
+				}
+			}
+			if rec.PreviousID.Valid && needsRotating(rec.PreviousID.String) {
+				var previous string
+				previous, updated.PreviousDisplayID, err = s.resealID(ctx, keys, rec.PreviousID.String, rec.PreviousDisplayID)
+				if err != nil {
+					return err
+				}
+				updated.PreviousID = sql.NullString{String: previous, Valid: true}
+			}
+			_, err = s.exec(ctx, rewriteHistoryIDsSQL(ctx, rec.AccountID, updated))
+			if err != nil {
+				return err
+			}
+			rotated++
+		}
+		yall.FromContext(ctx).WithField("rotated", rotated).Debug("rotated account history ID keys")
+	}
+}
+
+// resealID decrypts the ID stored as `stored` and `display`, and hashes and
+// encrypts it again with the current Key in `keys`.
+func (s *Storer) resealID(ctx context.Context, keys keyring, stored string, display sql.NullString) (string, sql.NullString, error) {
+	id, err := s.openID(ctx, &keys, stored, display)
+	if err != nil {
+		return "", sql.NullString{}, err
+	}
+	sealed, err := s.sealAccount(ctx, keys.current(), Account{ID: id})
+	if err != nil {
+		return "", sql.NullString{}, err
+	}
+	return sealed.ID, sealed.DisplayID, nil
+}
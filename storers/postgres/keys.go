@@ -0,0 +1,205 @@
+package postgres
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// minKeySize is the smallest Key.Secret accepted, in bytes.
+	minKeySize = 32
+
+	// maxKeyIDSize is the longest Key.ID accepted, so hashed IDs fit in
+	// the id column.
+	maxKeyIDSize = 32
+
+	hashedIDPrefix = "hmac-sha256:"
+
+	// labels for deriving separate hashing and encryption keys from a
+	// single Key.Secret
+	hashKeyLabel       = "lockbox.dev/accounts id hash"
+	encryptionKeyLabel = "lockbox.dev/accounts id display encryption"
+)
+
+var (
+	// ErrNoKeys is returned when a KeyProvider doesn't return any keys.
+	ErrNoKeys = errors.New("no keys available to hash account IDs")
+	// ErrInvalidKey is returned when a KeyProvider returns a Key with an
+	// empty or overly long ID, an ID containing a colon, or a Secret
+	// shorter than 32 bytes.
+	ErrInvalidKey = errors.New("invalid account ID key")
+	// ErrUnknownKey is returned when an account's display ID was
+	// encrypted with a key the KeyProvider no longer returns.
+	ErrUnknownKey = errors.New("account ID was encrypted with an unknown key")
+	// ErrMalformedDisplayID is returned when an account's encrypted
+	// display ID can't be parsed.
+	ErrMalformedDisplayID = errors.New("malformed encrypted display ID")
+)
+
+// Key is a secret used to hash account IDs and encrypt their display form.
+type Key struct {
+	// ID identifies the Key. It is stored alongside everything the Key
+	// is used for, so it must be unique and must never change. It can't
+	// be empty, longer than 32 bytes, or contain a colon.
+	ID string
+
+	// Secret is the key material. It must be at least 32 bytes, and should
+	// be generated by a cryptographically secure random number generator.
+	Secret []byte
+}
+
+// KeyProvider supplies the Keys a Storer uses to hash account IDs and
+// encrypt their display form.
+//
+// To rotate keys, put a new Key first, and keep the old Keys after it until
+// Storer.RotateKeys has rewritten every account using them.
+type KeyProvider interface {
+	// Keys returns every Key that account IDs may have been hashed or
+	// encrypted with. The first Key is the current Key, which is used
+	// for everything new.
+	Keys(ctx context.Context) ([]Key, error)
+}
+
+// StaticKeys is a KeyProvider that always returns the same Keys, with the
+// first as the current Key.
+type StaticKeys []Key
+
+// Keys returns the StaticKeys.
+func (s StaticKeys) Keys(_ context.Context) ([]Key, error) {
+	return s, nil
+}
+
+func (k Key) validate() error {
+	if k.ID == "" || len(k.ID) > maxKeyIDSize || strings.Contains(k.ID, ":") {
+		return fmt.Errorf("%w: bad ID %q", ErrInvalidKey, k.ID)
+	}
+	if len(k.Secret) < minKeySize {
+		return fmt.Errorf("%w: secret for %q must be at least %d bytes", ErrInvalidKey, k.ID, minKeySize)
+	}
+	return nil
+}
+
+func (k Key) derive(label string) []byte {
+	mac := hmac.New(sha256.New, k.Secret)
+	mac.Write([]byte(label)) //nolint:errcheck // hash.Hash never returns an error
+	return mac.Sum(nil)
+}
+
+// hashID returns the keyed hash of the normalized form of `id`, prefixed
+// with the ID of the Key that produced it.
+func (k Key) hashID(id string) string {
+	mac := hmac.New(sha256.New, k.derive(hashKeyLabel))
+	mac.Write([]byte(strings.ToLower(id))) //nolint:errcheck // hash.Hash never returns an error
+	return hashedIDPrefix + k.ID + ":" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (k Key) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k.derive(encryptionKeyLabel))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt returns `id`, encrypted with AES-GCM and bound to the hashed ID
+// it's stored alongside, prefixed with the ID of the Key used.
+func (k Key) encrypt(id, hashedID string) (string, error) {
+	aead, err := k.aead()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(id), []byte(hashedID))
+	return k.ID + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// keyring is the validated set of Keys returned by a KeyProvider.
+type keyring []Key
+
+func loadKeys(ctx context.Context, provider KeyProvider) (keyring, error) {
+	if provider == nil {
+		// only happens when hashed IDs are stored, but the Storer
+		// wasn't created WithHashedIDs
+		return nil, ErrNoKeys
+	}
+	keys, err := provider.Keys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving keys: %w", err)
+	}
+	if len(keys) < 1 {
+		return nil, ErrNoKeys
+	}
+	for _, key := range keys {
+		err = key.validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+func (k keyring) current() Key {
+	return k[0]
+}
+
+func (k keyring) find(id string) (Key, bool) {
+	for _, key := range k {
+		if key.ID == id {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+// lookupIDs returns every value `id` may be stored as: its hash under each
+// Key, and the plain ID itself, for accounts that haven't been hashed yet.
+func (k keyring) lookupIDs(id string) []string {
+	res := make([]string, 0, len(k)+1)
+	for _, key := range k {
+		res = append(res, key.hashID(id))
+	}
+	return append(res, id)
+}
+
+// decrypt returns the plain ID from an encrypted display ID, checking that
+// it was stored alongside `hashedID`.
+func (k keyring) decrypt(displayID, hashedID string) (string, error) {
+	parts := strings.SplitN(displayID, ":", 2) //nolint:gomnd // key ID and ciphertext
+	if len(parts) != 2 {                       //nolint:gomnd // key ID and ciphertext
+		return "", ErrMalformedDisplayID
+	}
+	key, ok := k.find(parts[0])
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownKey, parts[0])
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrMalformedDisplayID, err.Error())
+	}
+	aead, err := key.aead()
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", ErrMalformedDisplayID
+	}
+	id, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(hashedID))
+	if err != nil {
+		return "", fmt.Errorf("error decrypting display ID: %w", err)
+	}
+	return string(id), nil
+}
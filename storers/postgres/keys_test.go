@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKeys() StaticKeys {
+	return StaticKeys{
+		{ID: "2022-03", Secret: bytes.Repeat([]byte{0x42}, 32)},
+		{ID: "2021-01", Secret: bytes.Repeat([]byte{0x17}, 32)},
+	}
+}
+
+func TestHashIDNormalizes(t *testing.T) {
+	t.Parallel()
+
+	key := testKeys()[0]
+	if key.hashID("Paddy@Impractical.co") != key.hashID("paddy@impractical.co") {
+		t.Error("Expected IDs differing only by case to hash the same")
+	}
+	hashed := key.hashID("paddy@impractical.co")
+	if !strings.HasPrefix(hashed, "hmac-sha256:2022-03:") || strings.Contains(hashed, "paddy") {
+		t.Errorf("Unexpected hashed ID %q", hashed)
+	}
+	if hashed == testKeys()[1].hashID("paddy@impractical.co") {
+		t.Error("Expected different keys to produce different hashes")
+	}
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	keys, err := loadKeys(context.Background(), testKeys())
+	if err != nil {
+		t.Fatalf("Unexpected error loading keys: %+v\n", err)
+	}
+	// encrypt with the old key, to make sure it can still be read
+	key := keys[1]
+	hashed := key.hashID("Paddy@Impractical.co")
+	display, err := key.encrypt("Paddy@Impractical.co", hashed)
+	if err != nil {
+		t.Fatalf("Unexpected error encrypting: %+v\n", err)
+	}
+	if strings.Contains(strings.ToLower(display), "paddy") {
+		t.Errorf("Expected display ID to be encrypted, got %q", display)
+	}
+	id, err := keys.decrypt(display, hashed)
+	if err != nil {
+		t.Fatalf("Unexpected error decrypting: %+v\n", err)
+	}
+	if id != "Paddy@Impractical.co" {
+		t.Errorf("Expected %q, got %q", "Paddy@Impractical.co", id)
+	}
+
+	// display IDs can't be moved to another account's row
+	_, err = keys.decrypt(display, key.hashID("someone@impractical.co"))
+	if err == nil {
+		t.Error("Expected decrypting with the wrong hashed ID to fail")
+	}
+
+	_, err = keyring(testKeys()[:1]).decrypt(display, hashed)
+	if !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestLoadKeysValidates(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]StaticKeys{
+		"none":         {},
+		"empty-id":     {{Secret: bytes.Repeat([]byte{1}, 32)}},
+		"colon-id":     {{ID: "a:b", Secret: bytes.Repeat([]byte{1}, 32)}},
+		"short-secret": {{ID: "short", Secret: []byte("too short")}},
+	}
+	for name, keys := range tests {
+		name, keys := name, keys
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := loadKeys(context.Background(), keys)
+			if !errors.Is(err, ErrInvalidKey) && !errors.Is(err, ErrNoKeys) {
+				t.Errorf("Expected an invalid key error, got %v", err)
+			}
+		})
+	}
+}
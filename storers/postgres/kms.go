@@ -0,0 +1,156 @@
+package postgres
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+var (
+	// ErrUnknownMasterKey is returned when a KMS is asked to unwrap a data
+	// key with a master key it doesn't have.
+	ErrUnknownMasterKey = errors.New("unknown master key")
+)
+
+// KMS is a key management service that protects the data keys used to
+// encrypt account IDs. Data keys are only ever stored wrapped by one of the
+// KMS's master keys, so the database alone isn't enough to decrypt them.
+type KMS interface {
+	// CurrentKeyID returns the ID of the master key new data keys should
+	// be wrapped with.
+	CurrentKeyID(ctx context.Context) (string, error)
+
+	// WrapKey encrypts `dataKey` with the current master key, returning
+	// the ID of the master key used.
+	WrapKey(ctx context.Context, dataKey []byte) (keyID string, wrapped []byte, err error)
+
+	// UnwrapKey decrypts `wrapped` with the master key identified by
+	// `keyID`.
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// fileKMSContents is the format of a FileKMS's file.
+type fileKMSContents struct {
+	// Current is the ID of the master key to wrap new data keys with.
+	Current string `json:"current"`
+
+	// Keys maps master key IDs to base64-encoded 32 byte keys.
+	Keys map[string]string `json:"keys"`
+}
+
+// FileKMS is a KMS that reads its master keys from a local JSON file. It is
+// meant for tests and local development; production deployments should use
+// a KMS that keeps master keys out of reach of the application.
+//
+// The file looks like:
+//
+//	{"current": "2022-03", "keys": {"2022-03": "<base64>", "2021-01": "<base64>"}}
+//
+// Each key must be 32 bytes before it is base64-encoded.
+type FileKMS struct {
+	path    string
+	current string
+	keys    map[string][]byte
+	lock    sync.RWMutex
+}
+
+// NewFileKMS returns a FileKMS using the master keys in the file at `path`.
+func NewFileKMS(path string) (*FileKMS, error) {
+	kms := &FileKMS{path: path}
+	err := kms.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return kms, nil
+}
+
+// Reload reads the FileKMS's file again, so master keys can be rotated
+// without restarting.
+func (f *FileKMS) Reload() error {
+	raw, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("error reading KMS keys: %w", err)
+	}
+	var contents fileKMSContents
+	err = json.Unmarshal(raw, &contents)
+	if err != nil {
+		return fmt.Errorf("error decoding KMS keys: %w", err)
+	}
+	keys := make(map[string][]byte, len(contents.Keys))
+	for id, encoded := range contents.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("error decoding KMS key %q: %w", id, err)
+		}
+		if len(key) != minKeySize {
+			return fmt.Errorf("%w: KMS key %q must be %d bytes", ErrInvalidKey, id, minKeySize)
+		}
+		keys[id] = key
+	}
+	if _, ok := keys[contents.Current]; !ok {
+		return fmt.Errorf("%w: current KMS key %q", ErrUnknownMasterKey, contents.Current)
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.current, f.keys = contents.Current, keys
+	return nil
+}
+
+func (f *FileKMS) aead(keyID string) (cipher.AEAD, error) {
+	f.lock.RLock()
+	key, ok := f.keys[keyID]
+	f.lock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownMasterKey, keyID)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// CurrentKeyID returns the ID of the master key the file marks as current.
+func (f *FileKMS) CurrentKeyID(_ context.Context) (string, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return f.current, nil
+}
+
+// WrapKey encrypts `dataKey` with the current master key using AES-GCM.
+func (f *FileKMS) WrapKey(ctx context.Context, dataKey []byte) (string, []byte, error) {
+	keyID, err := f.CurrentKeyID(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	aead, err := f.aead(keyID)
+	if err != nil {
+		return "", nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return "", nil, err
+	}
+	return keyID, aead.Seal(nonce, nonce, dataKey, []byte(keyID)), nil
+}
+
+// UnwrapKey decrypts `wrapped` with the master key identified by `keyID`.
+func (f *FileKMS) UnwrapKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, err := f.aead(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: wrapped key is too short", ErrInvalidKey)
+	}
+	return aead.Open(nil, wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():], []byte(keyID))
+}
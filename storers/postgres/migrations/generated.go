@@ -1,9 +1,17 @@
-// Code generated by go-bindata.
+// Code generated for package migrations by go-bindata DO NOT EDIT. (@generated)
 // sources:
 // sql/accounts_20161012_init.sql
 // sql/accounts_20180619_1_unique_insert.sql
-// DO NOT EDIT!
-
+// sql/accounts_20261018_01_case_conflicts.sql
+// sql/accounts_20261018_02_case_insensitive_id.sql
+// sql/accounts_20261018_03_hashed_ids.sql
+// sql/accounts_20261018_04_data_keys.sql
+// sql/accounts_20261018_05_profile_limits.sql
+// sql/accounts_20261018_06_pending_links.sql
+// sql/accounts_20261018_07_rename_history.sql
+// sql/accounts_20261018_08_reuse_quarantine.sql
+// sql/accounts_20261018_09_sign_up_denylist.sql
+// sql/accounts_20261018_10_pending_link_display_ids.sql
 package migrations
 
 import (
@@ -50,26 +58,37 @@ type bindataFileInfo struct {
 	modTime time.Time
 }
 
+// Name return file name
 func (fi bindataFileInfo) Name() string {
 	return fi.name
 }
+
+// Size return file size
 func (fi bindataFileInfo) Size() int64 {
 	return fi.size
 }
+
+// Mode return file mode
 func (fi bindataFileInfo) Mode() os.FileMode {
 	return fi.mode
 }
+
+// Mode return file modify time
 func (fi bindataFileInfo) ModTime() time.Time {
 	return fi.modTime
 }
+
+// IsDir return file whether a directory
 func (fi bindataFileInfo) IsDir() bool {
-	return false
+	return fi.mode&os.ModeDir != 0
 }
+
+// Sys return file is sys mode
 func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _sqlAccounts_20161012_initSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x84\xcf\xbb\xaa\x83\x40\x10\x00\xd0\xda\xf9\x8a\x29\x95\xab\xd5\x0d\x36\x56\x1b\x5d\x88\xc4\x17\x9b\x31\x60\x1a\x59\x74\x12\x04\xa3\xe2\xae\xe4\xf7\xd3\x4a\x20\xa4\x3f\xcd\x09\x02\xfc\x7b\x0e\x8f\x55\x5b\xc6\x7a\x81\x58\x49\x41\x12\x49\x1c\x33\x89\xba\xeb\xe6\x6d\xb2\x06\x5d\x70\x86\x1e\xaf\x42\xc5\x27\xa1\xdc\xf0\xe0\x61\xa5\xd2\x5c\xa8\x06\xcf\xb2\xf1\xc1\x59\xd6\xf9\x3e\x8c\xdc\xee\xd0\x7f\xe8\x61\x51\x12\x16\x75\x96\xf9\xe0\x74\x2b\x6b\xcb\x7d\xab\x2d\x52\x9a\xcb\x0b\x89\xbc\xa2\xdb\x5e\x8c\xda\xd8\x76\x33\xbf\x8d\x61\x9e\xbe\x19\xf0\x22\x80\x7d\x29\x99\x5f\x13\x24\xaa\xac\x3e\x4a\x11\xbc\x03\x00\x00\xff\xff\x2b\x2d\x1c\x92\xf9\x00\x00\x00")
+var _sqlAccounts_20161012_initSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x84\xcf\xbb\xaa\x83\x40\x10\x00\xd0\xda\xf9\x8a\x29\x95\xab\xd5\x0d\x36\x56\x1b\x5d\x88\xc4\x17\x9b\x31\x60\x1a\x59\x74\x12\x04\xa3\xe2\xae\xe4\xf7\xd3\x4a\x20\xa4\x3f\xcd\x09\x02\xfc\x7b\x0e\x8f\x55\x5b\xc6\x7a\x81\x58\x49\x41\x12\x49\x1c\x33\x89\xba\xeb\xe6\x6d\xb2\x06\x5d\x70\x86\x1e\xaf\x42\xc5\x27\xa1\xdc\xf0\xe0\x61\xa5\xd2\x5c\xa8\x06\xcf\xb2\xf1\xc1\x59\xd6\xf9\x3e\x8c\xdc\xee\xd0\x7f\xe8\x61\x51\x12\x16\x75\x96\xf9\xe0\x74\x2b\x6b\xcb\x7d\xab\x2d\x52\x9a\xcb\x0b\x89\xbc\xa2\xdb\x5e\x8c\xda\xd8\x76\x33\xbf\x8d\x61\x9e\xbe\x19\xf0\x22\x80\x7d\x29\x99\x5f\x13\x24\xaa\xac\x3e\x4a\x11\xbc\x07\x00\x2b\x2d\x1c\x92\xf9\x00\x00\x00")
 
 func sqlAccounts_20161012_initSqlBytes() ([]byte, error) {
 	return bindataRead(
@@ -84,12 +103,12 @@ func sqlAccounts_20161012_initSql() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "sql/accounts_20161012_init.sql", size: 249, mode: os.FileMode(436), modTime: time.Unix(1476321115, 0)}
+	info := bindataFileInfo{name: "sql/accounts_20161012_init.sql", size: 249, mode: os.FileMode(436), modTime: time.Unix(1676180165, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
 
-var _sqlAccounts_20180619_1_unique_insertSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd2\xd5\x55\xd0\xce\xcd\x4c\x2f\x4a\x2c\x49\x55\x08\x2d\xe0\x72\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x48\x4c\x4e\xce\x2f\xcd\x2b\x29\x56\x70\x74\x71\x51\x70\xf6\xf7\x09\xf5\xf5\x53\xc8\x2c\x8e\x2f\x4a\x4d\xcf\x2c\x2e\x29\x4a\x2c\xc9\xcc\xcf\x53\x70\xf2\xf7\xf7\x71\x75\xf4\xd3\xe1\xe2\xe4\x54\x00\x01\x88\x52\xbf\xe0\x90\x20\x47\x4f\xbf\x10\x85\xd2\xbc\xcc\xc2\xd2\x54\x54\x2d\x10\x31\x05\x8d\x82\xa2\xfc\xb4\xcc\x9c\xd4\xf8\xcc\x14\x1d\x74\x63\x35\xad\xb9\xb8\x90\x1d\xe6\x92\x5f\x9e\x87\xdd\x69\x2e\x41\xfe\x01\x30\xb7\x21\x9b\x00\x77\x10\x54\x01\x3e\x17\x59\x73\x01\x02\x00\x00\xff\xff\xc6\x76\x5d\xcd\x05\x01\x00\x00")
+var _sqlAccounts_20180619_1_unique_insertSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd2\xd5\x55\xd0\xce\xcd\x4c\x2f\x4a\x2c\x49\x55\x08\x2d\xe0\x72\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x48\x4c\x4e\xce\x2f\xcd\x2b\x29\x56\x70\x74\x71\x51\x70\xf6\xf7\x09\xf5\xf5\x53\xc8\x2c\x8e\x2f\x4a\x4d\xcf\x2c\x2e\x29\x4a\x2c\xc9\xcc\xcf\x53\x70\xf2\xf7\xf7\x71\x75\xf4\xd3\xe1\xe2\xe4\x54\x00\x01\x88\x52\xbf\xe0\x90\x20\x47\x4f\xbf\x10\x85\xd2\xbc\xcc\xc2\xd2\x54\x54\x2d\x10\x31\x05\x8d\x82\xa2\xfc\xb4\xcc\x9c\xd4\xf8\xcc\x14\x1d\x74\x63\x35\xad\xb9\xb8\x90\x1d\xe6\x92\x5f\x9e\x87\xdd\x69\x2e\x41\xfe\x01\x30\xb7\x21\x9b\x00\x77\x10\x54\x01\x3e\x17\x59\x73\x01\x06\x00\xc6\x76\x5d\xcd\x05\x01\x00\x00")
 
 func sqlAccounts_20180619_1_unique_insertSqlBytes() ([]byte, error) {
 	return bindataRead(
@@ -104,7 +123,207 @@ func sqlAccounts_20180619_1_unique_insertSql() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "sql/accounts_20180619_1_unique_insert.sql", size: 261, mode: os.FileMode(436), modTime: time.Unix(1529462975, 0)}
+	info := bindataFileInfo{name: "sql/accounts_20180619_1_unique_insert.sql", size: 261, mode: os.FileMode(436), modTime: time.Unix(1676180165, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlAccounts_20261018_01_case_conflictsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x9d\x52\x4d\x8f\xda\x30\x14\x3c\xc7\xbf\x62\x0e\x54\x81\x16\x2a\xf5\xba\xa8\x95\xf8\x30\x10\x89\x26\xab\x10\xda\xed\x09\x19\x62\x82\xa5\x60\xd3\xd8\x01\xf1\xef\xfb\x9c\x00\xbb\xad\xda\x4b\x2f\x49\x9c\x37\x6f\xde\x8c\xdf\x0c\x06\xf8\x70\x54\x45\x25\x9c\xc4\xfa\xc4\x06\x03\x44\x53\x0b\x77\x10\x0e\xb9\xda\xef\x65\x05\xa3\xcb\x2b\xb6\x57\xec\x84\x95\xf4\xd0\xa1\xc3\x56\xe2\x28\x72\x89\x5a\xab\x9f\xb5\xf4\x45\x77\x90\xbe\xd7\x63\x36\x4a\x5b\xa9\xad\x72\xea\x4c\xdf\x39\x5a\x76\x65\x74\xcb\xba\x37\x65\x69\x2e\xb6\x0f\x6b\x60\x9d\x39\xe1\xa2\xdc\x01\x02\xa5\xb2\x0e\x66\xef\x99\x8e\x9e\x8a\x58\x9c\x14\xb9\xff\x25\xb0\x15\xd5\x63\xda\x59\x99\xb2\xe1\xfb\x08\x2e\x76\x07\x14\x95\xa9\x4f\x38\x08\x52\x6d\x1a\x65\xb2\x2a\x64\x4e\xfd\x74\x34\xba\x91\x35\xda\xed\x4c\xad\x5d\x1f\xa6\x22\xe0\x59\x42\x94\x25\xb6\xb5\xf3\x75\x3f\x40\x39\x7b\xc7\x58\xe4\xb2\x94\x4e\xe6\x7d\xe2\xda\x1b\x1a\x5b\xd5\x5a\x2b\x5d\xdc\x2d\x3e\xec\x58\x88\x42\x28\x52\x91\x99\x56\xbc\x57\xee\xef\xe2\x20\x74\xfe\x44\x50\x8f\x06\x56\x7c\xc9\x27\x19\x96\xc9\x77\x9e\x76\x55\xde\xeb\x43\x54\x95\xb8\x6e\x44\x51\xf8\x23\x66\x69\xf2\x15\xe2\x3e\x7c\x9e\x26\xeb\x67\x8c\x7f\xbc\xe2\xb1\x18\x7d\x8b\xe2\x39\x26\xc9\x3a\xce\xba\xef\x7b\xf8\x82\x4f\x43\x4f\xfd\x58\xdb\xca\xd1\xf3\x28\xb5\x1b\xcb\x42\x69\x36\x4d\xd0\xe9\xb0\x29\x9f\x2c\x47\x29\x67\xc1\xce\xe8\x7d\xa9\x76\xc4\x9d\xf1\x97\x6c\xc8\xc6\x7c\x1e\xc5\x2c\xb8\xc9\xb2\xae\x22\x6f\x37\x31\xb4\x94\x70\x88\xb0\x87\x28\xce\x12\xbc\x36\x36\x12\xbb\x2c\xb8\x37\xb5\x06\x9c\xd9\xb4\xdd\xdd\xb7\x86\x90\xa4\x53\x9e\x7a\x07\x8d\xd7\xb0\xef\xf9\x46\x2b\x3a\x59\x22\xf8\x1f\xb3\x2c\x68\x08\xf2\xfa\x44\x6a\xc8\xa9\x1d\xb2\x20\x9a\xbd\x91\x17\xad\x10\x27\x19\xe2\xf5\x72\x89\x6c\xc1\xc9\x5c\x90\x8e\xa2\x15\x07\x7f\x99\xf0\xe7\x2c\x4a\x62\x84\xb7\x99\x4d\xb8\xff\x92\xeb\x27\xbc\x23\xa5\x0f\x4a\x62\x08\xd6\x2b\x2f\x64\x41\x57\x81\xcf\x08\x9b\x50\xf9\xf8\xb4\xe9\xf0\xbb\x7e\xc0\x7d\x38\xee\x9e\xfa\xbe\xa2\x7d\x66\x1a\xc8\x9f\x61\x09\x49\x3b\x8f\xa7\x88\x66\x43\x46\x6f\xd6\xe9\xfc\x63\x95\x5c\xe7\xec\xb7\xca\xd4\x5c\x34\xfb\x05\x29\x56\xba\xa1\xad\x03\x00\x00")
+
+func sqlAccounts_20261018_01_case_conflictsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlAccounts_20261018_01_case_conflictsSql,
+		"sql/accounts_20261018_01_case_conflicts.sql",
+	)
+}
+
+func sqlAccounts_20261018_01_case_conflictsSql() (*asset, error) {
+	bytes, err := sqlAccounts_20261018_01_case_conflictsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/accounts_20261018_01_case_conflicts.sql", size: 941, mode: os.FileMode(420), modTime: time.Unix(1792360767, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlAccounts_20261018_02_case_insensitive_idSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\xd3\xd5\x55\xd0\xce\xcd\x4c\x2f\x4a\x2c\x49\x55\x08\x2d\xe0\x72\x0e\x72\x75\x0c\x71\x55\x08\xf5\xf3\x0c\x0c\x75\x55\xf0\xf4\x73\x71\x8d\x50\x48\x4c\x4e\xce\x2f\xcd\x2b\x29\x8e\xcf\xc9\x2f\x4f\x2d\x8a\xcf\x4c\x51\xf0\xf7\x83\x0b\x2a\x68\xf8\xf8\x87\xbb\x06\x69\x64\xa6\x68\x6a\x5a\x73\x71\xe9\x22\x19\xe7\x92\x5f\x9e\xc7\xe5\x12\xe4\x1f\x80\xcb\x1c\x6b\x2e\x00\x6d\x22\xd2\x43\x7e\x00\x00\x00")
+
+func sqlAccounts_20261018_02_case_insensitive_idSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlAccounts_20261018_02_case_insensitive_idSql,
+		"sql/accounts_20261018_02_case_insensitive_id.sql",
+	)
+}
+
+func sqlAccounts_20261018_02_case_insensitive_idSql() (*asset, error) {
+	bytes, err := sqlAccounts_20261018_02_case_insensitive_idSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/accounts_20261018_02_case_insensitive_id.sql", size: 126, mode: os.FileMode(420), modTime: time.Unix(1792360737, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlAccounts_20261018_03_hashed_idsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x7d\x52\x5d\x6f\xda\x30\x14\x7d\x8e\x7f\xc5\x79\x40\x0a\x68\x30\x69\xd3\xba\x17\xd4\x49\x81\x18\x12\x29\x4d\x50\x62\xb6\xee\x69\x72\xb1\x01\x6b\xc4\x46\x89\x11\xe3\xdf\xef\x26\xa8\xa5\xd3\xaa\xfa\xc1\x96\xae\xcf\x87\x7d\xee\x9d\x4c\xf0\xa1\x36\xbb\x46\x7a\x8d\xf5\x91\x45\x99\xe0\x25\x44\x34\xcb\x38\xe4\x66\xe3\x4e\xd6\xb7\xb8\x16\xe7\x45\xb6\x7e\xc8\x61\x14\xc4\xcf\x15\xc7\xf7\xa8\x9c\x27\x51\x39\xfc\x7c\x77\x37\x1a\xb3\x20\x40\xb7\xa2\x38\x7e\xc6\x29\xd3\x1e\x0f\xf2\xf2\xab\xc3\xf3\x47\x31\x65\x6c\xf2\xca\x2a\x76\x67\xdb\x15\x12\xd9\xee\xb5\x42\x1a\xb7\xd8\x48\x1b\x7a\x3c\x69\xf8\x53\x63\xa9\xf6\x24\x37\xbf\x61\xac\x77\xf0\x7b\xdd\x23\xe8\xbc\xe0\xac\x1b\x8d\x5a\x2a\x8d\x6d\xe3\xea\x31\xa4\x55\x9d\x90\x72\x1d\x7b\x6b\x3c\x51\x7a\x82\x3b\x28\x6c\xdc\xe1\x54\xdb\x31\x5a\x87\x46\x6f\x4f\x2d\x69\x3b\xa8\xc6\x1d\x7b\xc4\xf5\xb6\x57\x0d\x1b\xdd\x8b\x5c\xdf\x4c\xe6\x9d\x36\xce\x7b\x73\xd0\x64\x70\x81\x24\xcf\xd6\xbb\x46\xab\x8f\xff\x7c\xa3\xf2\xb4\xd7\xda\xfa\x99\xde\x19\xcb\xe2\x02\x83\x01\x9b\xf1\x65\x9a\xb3\x20\x5d\x80\x3f\xa6\x95\xa8\x30\xac\x78\xc6\xe7\x02\x9f\xb0\x28\x8b\x87\x5b\xae\x3f\x12\x5e\xf2\xd7\x41\xa5\x15\xf2\x42\x20\x5f\x67\x19\x8a\x12\x19\xcf\x97\x22\x19\x1a\x35\xc2\x37\x7c\xfd\x32\x82\x48\x38\x09\x07\x65\x94\x56\x9c\xc4\xe7\x7c\x25\xd2\x22\x47\xf8\xa2\x78\x7b\x27\xce\xc6\xef\xb1\xbf\xc6\xeb\x1a\x1c\x9c\xdd\x75\x21\x86\xc4\x0f\xd6\x55\x9a\x2f\x91\xa4\xb9\xc0\x3d\x42\xfd\xe7\xe8\x1a\xdf\x47\xf2\x22\xd4\xb3\x25\xfd\x8f\xb4\x1a\xba\x92\xbe\xeb\x0f\xa5\x28\x55\x07\xa4\xdc\x69\xb7\x30\xf5\x33\xb5\x86\xdc\x7a\x82\x5e\x93\x31\x64\xa6\xa8\xc5\xe1\x94\x05\x3c\x8f\x91\x2e\xa6\x8c\x4e\x36\x18\x4c\xdf\xce\x8f\x53\x17\xdf\x9c\xbd\xb8\x2c\x56\xff\x8f\xd4\x6d\xe0\xde\x1b\x4d\x8a\x6c\xca\xfe\x02\x61\xe9\x9b\xfa\xe1\x02\x00\x00")
+
+func sqlAccounts_20261018_03_hashed_idsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlAccounts_20261018_03_hashed_idsSql,
+		"sql/accounts_20261018_03_hashed_ids.sql",
+	)
+}
+
+func sqlAccounts_20261018_03_hashed_idsSql() (*asset, error) {
+	bytes, err := sqlAccounts_20261018_03_hashed_idsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/accounts_20261018_03_hashed_ids.sql", size: 737, mode: os.FileMode(420), modTime: time.Unix(1792360767, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlAccounts_20261018_04_data_keysSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x75\xce\xbb\x0e\x82\x30\x14\x80\xe1\x99\xf3\x14\x67\x84\x28\x8b\x06\x17\xa6\x0a\x4d\x24\x72\x4b\x2d\x26\xb8\x34\x0d\x6d\x0c\x31\x5c\x52\x6a\x88\x6f\x2f\x2c\x86\xc5\xf9\xff\x86\xdf\xf7\x71\xd7\xb5\x4f\x23\xad\xc6\x6a\x84\x88\x51\xc2\x29\x72\x72\x4e\x29\xca\xa6\x19\xde\xbd\x15\x4a\x5a\x29\x5e\xfa\x33\xa1\x0b\x4e\xab\xf0\x4e\x58\x74\x21\xcc\x3d\x9e\x3c\x2c\x59\x92\x11\x56\xe3\x95\xd6\x7b\x70\x3a\x39\x59\x6d\x56\x2b\x36\xee\x10\x04\x1e\xe6\x05\xc7\xbc\x4a\xd3\x45\xcd\x46\x8e\xa3\x56\x2b\xc3\x73\xcd\x29\xd9\xc6\xc6\xe8\xe5\x45\x09\x69\x91\x27\x19\xbd\x71\x92\x95\xfc\xf1\x13\xe0\x85\x00\xfe\x66\x3a\x1e\xe6\x1e\x62\x56\x94\xff\xa6\x43\xf8\x02\xe2\x0c\xd7\x35\xe4\x00\x00\x00")
+
+func sqlAccounts_20261018_04_data_keysSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlAccounts_20261018_04_data_keysSql,
+		"sql/accounts_20261018_04_data_keys.sql",
+	)
+}
+
+func sqlAccounts_20261018_04_data_keysSql() (*asset, error) {
+	bytes, err := sqlAccounts_20261018_04_data_keysSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/accounts_20261018_04_data_keys.sql", size: 228, mode: os.FileMode(420), modTime: time.Unix(1792356473, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlAccounts_20261018_05_profile_limitsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x7d\x51\x5d\x4b\xc3\x30\x14\x7d\x36\xbf\xe2\x3e\x38\x32\x99\x05\x7d\x11\xb4\x08\xc6\x26\xb0\xb2\xd8\x8d\xb4\x55\xc1\x8f\x12\xdb\xcc\x06\xbb\xb4\x34\x1d\xb2\x17\x7f\xbb\xd9\xe6\xba\x81\xe2\xeb\x39\xf7\x9c\x7b\xcf\x3d\x9e\x07\xa3\x85\x7e\x6f\x65\xa7\x20\x6d\x10\xe1\x09\x13\x90\x90\x5b\xce\x40\xe6\x79\xbd\x34\x9d\x05\x42\x29\x04\x53\x9e\xde\x45\xf0\xa1\x4d\x01\xf7\x44\x04\x63\x22\x86\xe7\x17\x27\x3e\x42\x9e\x07\xa5\xb4\xa5\x2a\x20\xa4\x16\x72\x69\x70\x07\x6f\x0a\xf2\x4a\x5a\xab\xe7\xda\xe1\xa5\x6a\x95\x0f\xa2\xee\xdc\x92\x89\x5a\x59\x98\xeb\xaa\xb2\xd0\x95\x4a\xb7\x5b\x47\x6d\x50\x3a\xa3\x24\x39\x58\x1a\xb3\x64\xcb\x5d\x43\x40\x62\x86\x8e\x1e\xc6\x2c\x02\x5d\x00\x0f\x27\x0c\xf0\xe0\x66\x80\x21\x59\x43\x58\x2d\xa4\xae\xf0\x7e\xe0\x0b\xf0\xeb\xf3\xe8\xe9\xcc\xbb\x7c\x19\x1d\xef\x86\x9a\xb2\x36\x0a\xff\x72\xb9\xea\x5d\x6c\x9d\x6b\xb9\xb6\x61\x3c\x76\xcc\xd2\xaa\xd6\xc8\x85\x93\xb0\x88\x82\x53\x09\x06\x85\xb6\x4d\x25\x57\x99\x93\x87\x31\x44\x29\xe7\x2e\x7e\x20\xd8\xfa\xee\x30\xa2\xec\xb1\xbf\x3e\x6b\xda\xda\x85\x54\xd9\x26\xc1\x34\xda\xc7\x1a\xee\x18\x5d\x9c\x6e\xf2\xfd\xbc\xb0\x2f\x81\xd6\x9f\x06\x51\x31\x9d\xfd\x67\xe9\xff\x5d\xd4\x46\x76\xd0\x94\x8f\xbe\x01\x91\x6f\xdb\x28\xdf\x01\x00\x00")
+
+func sqlAccounts_20261018_05_profile_limitsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlAccounts_20261018_05_profile_limitsSql,
+		"sql/accounts_20261018_05_profile_limits.sql",
+	)
+}
+
+func sqlAccounts_20261018_05_profile_limitsSql() (*asset, error) {
+	bytes, err := sqlAccounts_20261018_05_profile_limitsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/accounts_20261018_05_profile_limits.sql", size: 479, mode: os.FileMode(420), modTime: time.Unix(1792356666, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlAccounts_20261018_06_pending_linksSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x7d\x90\x3f\x0f\x82\x30\x14\xc4\x67\xfa\x29\xde\x28\x51\x26\x8d\x8b\x53\x85\x26\x12\xf9\x97\x5a\x8d\xb8\x34\x0d\x54\x69\xd0\x96\x00\x46\x3f\xbe\x38\x10\x3b\xa8\xc3\x9b\xee\x77\x97\x77\xe7\x79\x30\xbd\xa9\x4b\x2b\x7a\x09\xfb\x06\xf9\x94\x60\x46\x80\xe1\x75\x44\x40\x14\x85\xb9\xeb\x9e\x37\x52\x97\x4a\x5f\xf8\x55\xe9\xba\x83\x09\x72\x7a\x53\x4b\xcd\x2b\xd1\x55\x70\xc0\xd4\xdf\x60\x3a\x59\x2e\x5c\xc8\x68\x18\x63\x9a\xc3\x96\xe4\x33\xe4\x8c\x6e\x55\x02\x23\x47\x06\x49\x3a\xdc\x3e\x8a\x06\xa9\x69\xcd\x59\x5d\xe5\x5b\x1a\xfd\xf3\xa5\x6b\x13\x45\x2b\x87\x8f\x4a\x2e\x7a\x60\x61\x4c\x76\x0c\xc7\x19\x3b\xd9\x84\x7c\x36\xaa\x95\xdd\x2f\x02\xb9\x2b\x34\xb6\x09\x93\x80\x1c\xbf\xb7\xe1\x56\x4c\x9a\xfc\x6a\xfc\x81\xde\xa9\x9e\x35\x59\x60\x1e\x1a\x05\x34\xcd\xfe\x4d\xb6\x42\x2f\xed\x98\x67\xa4\x66\x01\x00\x00")
+
+func sqlAccounts_20261018_06_pending_linksSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlAccounts_20261018_06_pending_linksSql,
+		"sql/accounts_20261018_06_pending_links.sql",
+	)
+}
+
+func sqlAccounts_20261018_06_pending_linksSql() (*asset, error) {
+	bytes, err := sqlAccounts_20261018_06_pending_linksSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/accounts_20261018_06_pending_links.sql", size: 358, mode: os.FileMode(420), modTime: time.Unix(1792357392, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlAccounts_20261018_07_rename_historySql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x7d\x91\x3f\x6f\x83\x30\x10\xc5\xe7\xf8\x53\xdc\x08\x6a\x98\x52\x65\x61\x72\x83\xa5\xa2\xf2\x4f\xae\xd3\x24\x5d\x2c\x0b\x9c\xd6\x12\xc5\xc8\x98\x46\xf9\xf6\x75\x07\x52\x37\x41\x19\x6e\xb9\xdf\xd3\xbb\x7b\x77\x51\x04\x0f\x5f\xea\xc3\x08\x2b\x61\xdb\xa3\x0d\x25\x98\x11\x60\xf8\x29\x23\x20\xea\x5a\x8f\x9d\xe5\x9f\x6a\xb0\xda\x9c\x21\x40\x0b\xd5\xc0\x1b\xa6\x9b\x67\x4c\x83\xd5\x3a\x84\x8a\xa6\x39\xa6\x07\x78\x21\x87\x25\x5a\x4c\x7a\x27\x62\x64\xcf\xa0\x28\x5d\x6d\xb3\xcc\xa1\x46\x0d\x7d\x2b\xce\x13\x72\x9d\xde\xc8\x6f\xa5\xc7\x61\xae\x35\xa7\xd6\x47\xd5\x4a\x7e\x35\xdf\x9b\x20\x6a\xab\x74\x77\xa1\xeb\xc7\x7f\xb4\x36\xd2\x25\x6c\xb8\xb0\xc0\xd2\x9c\xbc\x32\x9c\x57\xec\xfd\xa2\x40\x61\x8c\xa6\xec\x69\x91\x90\xfd\x75\x76\xee\x2d\x50\x16\xb7\x97\xf9\xc3\xce\xe9\xae\x51\xab\x4f\xd2\x70\x3f\xfd\x9c\x5f\x56\xee\x08\x0d\x3c\x55\xf8\xbb\x61\xe4\x3d\x2b\xd1\xa7\x0e\x25\xb4\xac\xe6\x9f\x15\xa3\x1f\x21\x10\xfd\x00\xda\x01\x00\x00")
+
+func sqlAccounts_20261018_07_rename_historySqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlAccounts_20261018_07_rename_historySql,
+		"sql/accounts_20261018_07_rename_history.sql",
+	)
+}
+
+func sqlAccounts_20261018_07_rename_historySql() (*asset, error) {
+	bytes, err := sqlAccounts_20261018_07_rename_historySqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/accounts_20261018_07_rename_history.sql", size: 474, mode: os.FileMode(420), modTime: time.Unix(1792357722, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlAccounts_20261018_08_reuse_quarantineSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x7d\x8f\xb1\x0e\x82\x30\x14\x45\x67\xfa\x15\x6f\x84\x28\x8b\x06\x17\xa6\x0a\x4d\x24\x42\x21\xb5\x18\x70\x69\x1a\xa8\xd2\x04\x81\x00\xc6\xdf\x17\x07\x09\x83\xba\x9f\x7b\x72\xae\x6d\xc3\xea\xae\x6f\xbd\x1c\x15\xa4\x1d\xf2\x18\xc1\x9c\x00\xc7\xfb\x90\x80\x2c\x8a\xf6\xd1\x8c\xa2\x57\xb5\x92\x83\x2a\x85\x2e\x07\x30\x91\xa1\x4b\x51\xc9\xa1\x82\x33\x66\xde\x01\x33\x73\xe3\x38\x16\x24\x2c\x88\x30\xcb\xe1\x48\xf2\x35\x32\xba\xbe\xbd\xea\x5a\x4d\x8b\x99\xda\xee\x2c\xa0\x31\x07\x9a\x86\xe1\x44\xcc\x52\x39\x02\x0f\x22\x72\xe2\x38\x4a\xf8\x65\x46\x90\xe5\xa2\x4f\x4e\x40\x7d\x92\x7d\xcd\x11\x4b\x4d\x4c\x7f\x24\x2f\xa0\xb7\xd5\x5e\x7c\xf6\xdb\x67\x83\x7c\x16\x27\x7f\x3e\xbb\xe8\x05\x8f\x7e\x79\x29\x26\x01\x00\x00")
+
+func sqlAccounts_20261018_08_reuse_quarantineSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlAccounts_20261018_08_reuse_quarantineSql,
+		"sql/accounts_20261018_08_reuse_quarantine.sql",
+	)
+}
+
+func sqlAccounts_20261018_08_reuse_quarantineSql() (*asset, error) {
+	bytes, err := sqlAccounts_20261018_08_reuse_quarantineSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/accounts_20261018_08_reuse_quarantine.sql", size: 294, mode: os.FileMode(420), modTime: time.Unix(1792358006, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlAccounts_20261018_09_sign_up_denylistSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x75\x8e\x4d\x0b\x82\x40\x14\x00\xcf\xbe\x5f\xf1\x8e\x4a\x79\x88\xa0\x8b\xa7\x4d\x17\x92\xfc\x62\x7b\x46\x76\x91\x45\x97\x90\x6a\x95\x6d\x25\xfa\xf7\x79\x2a\x21\xba\xce\xcc\x61\x7c\x1f\x17\xf7\xee\x62\xa4\x55\x58\x0e\x10\x0a\xce\x88\x23\xb1\x6d\xc2\x51\x36\x4d\x3f\x6a\x5b\xb7\x4a\xbf\x6a\x33\xde\xd4\x03\x5d\x70\xba\x16\x8f\x4c\x84\x3b\x26\xdc\xf5\xc6\xc3\x42\xc4\x29\x13\x15\xee\x79\xb5\x04\xe7\xda\xe9\xaf\x5e\x4d\x3a\xcb\x09\xb3\x32\x49\x26\x37\x48\x6b\x95\xd1\x48\xfc\x44\x73\x6e\x94\x7c\xf4\xbf\xb8\x99\xb8\x55\x6d\x2d\x2d\x52\x9c\xf2\x03\xb1\xb4\xa0\xf3\xa7\x00\x2f\x00\xf0\x67\xf7\x51\xff\xd4\x10\x89\xbc\xf8\x7b\x1f\xc0\x1b\x64\x7f\x65\x61\xee\x00\x00\x00")
+
+func sqlAccounts_20261018_09_sign_up_denylistSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlAccounts_20261018_09_sign_up_denylistSql,
+		"sql/accounts_20261018_09_sign_up_denylist.sql",
+	)
+}
+
+func sqlAccounts_20261018_09_sign_up_denylistSql() (*asset, error) {
+	bytes, err := sqlAccounts_20261018_09_sign_up_denylistSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/accounts_20261018_09_sign_up_denylist.sql", size: 238, mode: os.FileMode(420), modTime: time.Unix(1792358304, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _sqlAccounts_20261018_10_pending_link_display_idsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x8d\x90\xc1\x6a\xc3\x30\x10\x44\xef\xfa\x8a\xb9\xe5\xd0\x3a\x3f\xe0\x93\x5b\xa9\x34\xa0\xd8\xc5\x95\x69\x6f\x46\xb5\x36\x91\x88\x23\x19\x49\x69\xc8\xdf\xd7\x4e\x29\x04\x4a\xa1\xd7\xd9\xd9\x37\xcc\x14\x05\xee\x8e\x6e\x1f\x75\x26\x74\x13\xab\xa4\x12\x2d\x54\xf5\x20\x05\xf4\x30\x84\x93\xcf\xfd\x44\xde\x38\xbf\xef\x47\xe7\x0f\x09\x15\xe7\x78\x6c\x64\xb7\xad\x61\x5c\x9a\x46\x7d\xe9\x9d\x81\x12\xef\xaa\x64\xac\xb8\xa1\xf1\x70\xf6\x8b\x20\xaf\x6f\x67\x97\x2d\xac\x4e\x96\x0c\xaa\x6f\x30\x36\x3c\x61\xd0\x7e\x95\xf1\x41\x18\x82\xdf\xb9\x78\x9c\xcf\x8b\x35\x9c\x32\xb2\x25\x17\x7f\x42\x16\xd2\xec\x5f\x43\x59\xba\xac\x22\x21\xd9\x10\x73\x31\xba\x4f\x32\xf7\x48\x61\x71\x5f\x75\x43\x23\xe5\x99\xe2\x7c\xca\xa4\x0d\xc2\x0e\x07\x9a\x32\x74\x9c\x33\xcd\x9a\x71\x21\x85\x12\x78\x6a\x9b\xed\x1f\x0d\xdf\x9e\x45\x2b\x6e\xcb\x6d\x5e\x51\x37\x0a\x75\x27\x65\xf9\x8f\x85\x78\xdb\xbc\xfc\x9e\xa8\x64\x5f\xe9\x0c\xe0\xc5\x6b\x01\x00\x00")
+
+func sqlAccounts_20261018_10_pending_link_display_idsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_sqlAccounts_20261018_10_pending_link_display_idsSql,
+		"sql/accounts_20261018_10_pending_link_display_ids.sql",
+	)
+}
+
+func sqlAccounts_20261018_10_pending_link_display_idsSql() (*asset, error) {
+	bytes, err := sqlAccounts_20261018_10_pending_link_display_idsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "sql/accounts_20261018_10_pending_link_display_ids.sql", size: 363, mode: os.FileMode(420), modTime: time.Unix(1792360817, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -161,8 +380,18 @@ func AssetNames() []string {
 
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
-	"sql/accounts_20161012_init.sql": sqlAccounts_20161012_initSql,
-	"sql/accounts_20180619_1_unique_insert.sql": sqlAccounts_20180619_1_unique_insertSql,
+	"sql/accounts_20161012_init.sql":                        sqlAccounts_20161012_initSql,
+	"sql/accounts_20180619_1_unique_insert.sql":             sqlAccounts_20180619_1_unique_insertSql,
+	"sql/accounts_20261018_01_case_conflicts.sql":           sqlAccounts_20261018_01_case_conflictsSql,
+	"sql/accounts_20261018_02_case_insensitive_id.sql":      sqlAccounts_20261018_02_case_insensitive_idSql,
+	"sql/accounts_20261018_03_hashed_ids.sql":               sqlAccounts_20261018_03_hashed_idsSql,
+	"sql/accounts_20261018_04_data_keys.sql":                sqlAccounts_20261018_04_data_keysSql,
+	"sql/accounts_20261018_05_profile_limits.sql":           sqlAccounts_20261018_05_profile_limitsSql,
+	"sql/accounts_20261018_06_pending_links.sql":            sqlAccounts_20261018_06_pending_linksSql,
+	"sql/accounts_20261018_07_rename_history.sql":           sqlAccounts_20261018_07_rename_historySql,
+	"sql/accounts_20261018_08_reuse_quarantine.sql":         sqlAccounts_20261018_08_reuse_quarantineSql,
+	"sql/accounts_20261018_09_sign_up_denylist.sql":         sqlAccounts_20261018_09_sign_up_denylistSql,
+	"sql/accounts_20261018_10_pending_link_display_ids.sql": sqlAccounts_20261018_10_pending_link_display_idsSql,
 }
 
 // AssetDir returns the file names below a certain
@@ -204,10 +433,21 @@ type bintree struct {
 	Func     func() (*asset, error)
 	Children map[string]*bintree
 }
+
 var _bintree = &bintree{nil, map[string]*bintree{
 	"sql": &bintree{nil, map[string]*bintree{
-		"accounts_20161012_init.sql": &bintree{sqlAccounts_20161012_initSql, map[string]*bintree{}},
-		"accounts_20180619_1_unique_insert.sql": &bintree{sqlAccounts_20180619_1_unique_insertSql, map[string]*bintree{}},
+		"accounts_20161012_init.sql":                        &bintree{sqlAccounts_20161012_initSql, map[string]*bintree{}},
+		"accounts_20180619_1_unique_insert.sql":             &bintree{sqlAccounts_20180619_1_unique_insertSql, map[string]*bintree{}},
+		"accounts_20261018_01_case_conflicts.sql":           &bintree{sqlAccounts_20261018_01_case_conflictsSql, map[string]*bintree{}},
+		"accounts_20261018_02_case_insensitive_id.sql":      &bintree{sqlAccounts_20261018_02_case_insensitive_idSql, map[string]*bintree{}},
+		"accounts_20261018_03_hashed_ids.sql":               &bintree{sqlAccounts_20261018_03_hashed_idsSql, map[string]*bintree{}},
+		"accounts_20261018_04_data_keys.sql":                &bintree{sqlAccounts_20261018_04_data_keysSql, map[string]*bintree{}},
+		"accounts_20261018_05_profile_limits.sql":           &bintree{sqlAccounts_20261018_05_profile_limitsSql, map[string]*bintree{}},
+		"accounts_20261018_06_pending_links.sql":            &bintree{sqlAccounts_20261018_06_pending_linksSql, map[string]*bintree{}},
+		"accounts_20261018_07_rename_history.sql":           &bintree{sqlAccounts_20261018_07_rename_historySql, map[string]*bintree{}},
+		"accounts_20261018_08_reuse_quarantine.sql":         &bintree{sqlAccounts_20261018_08_reuse_quarantineSql, map[string]*bintree{}},
+		"accounts_20261018_09_sign_up_denylist.sql":         &bintree{sqlAccounts_20261018_09_sign_up_denylistSql, map[string]*bintree{}},
+		"accounts_20261018_10_pending_link_display_ids.sql": &bintree{sqlAccounts_20261018_10_pending_link_display_idsSql, map[string]*bintree{}},
 	}},
 }}
 
@@ -257,4 +497,3 @@ func _filePath(dir, name string) string {
 	cannonicalName := strings.Replace(name, "\\", "/", -1)
 	return filepath.Join(append([]string{dir}, strings.Split(cannonicalName, "/")...)...)
 }
-
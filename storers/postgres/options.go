@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"time"
+
+	"lockbox.dev/accounts"
+)
+
+// Option configures optional behavior of a Storer.
+type Option func(*Storer)
+
+// WithHashedIDs stores Account IDs as keyed hashes of their lowercased form,
+// so a copy of the database doesn't reveal them. The ID as it was entered is
+// encrypted and stored separately, so the Storer can still return it. Keys
+// for both come from `keys`.
+//
+// Accounts created before WithHashedIDs was used are still found, but their
+// IDs stay in plain text until RotateKeys is called.
+func WithHashedIDs(keys KeyProvider) Option {
+	return func(s *Storer) {
+		s.keys = keys
+	}
+}
+
+// WithEnvelopeEncryption encrypts the stored form of Account IDs with data
+// keys that are themselves encrypted by `kms`, instead of with the Keys
+// passed to WithHashedIDs. The Keys are still used to hash the IDs. It has
+// no effect unless WithHashedIDs is also used.
+//
+// IDs that were encrypted with the Keys can still be read, and are moved to
+// data keys by ReencryptDisplayIDs.
+func WithEnvelopeEncryption(kms KMS) Option {
+	return func(s *Storer) {
+		s.envelope = newEnvelope(kms, s, s.now)
+	}
+}
+
+// WithProfileLimits limits how many Accounts each profile can have. Create
+// counts the profile's Accounts and inserts the new Account in a single
+// transaction, returning ErrProfileAccountLimitReached if the Account would
+// go over the limits.
+func WithProfileLimits(limits accounts.ProfileLimits) Option {
+	return func(s *Storer) {
+		s.limits = limits
+	}
+}
+
+// WithReleaseQuarantine sets how long an ID released by deleting or renaming
+// an Account is kept from being used by other profiles. Create and Rename
+// return ErrAccountIDRecentlyReleased for IDs that are still quarantined. A
+// period of zero turns the quarantine off, and stops released IDs from being
+// recorded. If WithReleaseQuarantine isn't used, released IDs aren't
+// quarantined; accounts.DefaultReleaseQuarantine is a reasonable period.
+//
+// Released IDs are recorded by their hash. When the Storer is created
+// WithHashedIDs, they are hashed with the current Key, and stop being
+// quarantined if that Key is removed, so old Keys should be kept for the
+// length of the quarantine after they're rotated out.
+func WithReleaseQuarantine(period time.Duration) Option {
+	return func(s *Storer) {
+		s.quarantine = period
+	}
+}
+
+// WithClock sets the Clock the Storer uses to timestamp history and check
+// quarantines. If WithClock isn't used, the system clock is used.
+func WithClock(clock accounts.Clock) Option {
+	return func(s *Storer) {
+		s.clock = clock
+	}
+}
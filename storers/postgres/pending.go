@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"darlinggo.co/pan"
+
+	"lockbox.dev/accounts"
+)
+
+// pendingLink is the representation of an accounts.PendingLink that is
+// stored in PostgreSQL.
+type pendingLink struct {
+	TokenHash string         `sql_column:"token_hash"`
+	AccountID string         `sql_column:"account_id"`
+	DisplayID sql.NullString `sql_column:"display_id"`
+	ProfileID string         `sql_column:"profile_id"`
+	Created   time.Time      `sql_column:"created_at"`
+	Expires   time.Time      `sql_column:"expires_at"`
+}
+
+// GetSQLTableName returns the name of the SQL table that the data for this
+// type will be stored in.
+func (pendingLink) GetSQLTableName() string {
+	return "account_pending_links"
+}
+
+// toStoredPendingLink converts `link` to the form it should be stored in,
+// hashing and encrypting its Account ID the same way Account IDs are if the
+// Storer was created WithHashedIDs.
+func (s *Storer) toStoredPendingLink(ctx context.Context, link accounts.PendingLink) (pendingLink, error) {
+	account, err := s.toStored(ctx, accounts.Account{ID: link.AccountID})
+	if err != nil {
+		return pendingLink{}, err
+	}
+	return pendingLink{
+		TokenHash: link.TokenHash,
+		AccountID: account.ID,
+		DisplayID: account.DisplayID,
+		ProfileID: link.ProfileID,
+		Created:   link.Created,
+		Expires:   link.Expires,
+	}, nil
+}
+
+// fromStoredPendingLinks converts stored PendingLinks back to
+// accounts.PendingLinks, decrypting their Account IDs if needed.
+func (s *Storer) fromStoredPendingLinks(ctx context.Context, links []pendingLink) ([]accounts.PendingLink, error) {
+	var keys keyring
+	res := make([]accounts.PendingLink, 0, len(links))
+	for _, link := range links {
+		id, err := s.openID(ctx, &keys, link.AccountID, link.DisplayID)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, accounts.PendingLink{
+			TokenHash: link.TokenHash,
+			AccountID: id,
+			ProfileID: link.ProfileID,
+			Created:   link.Created,
+			Expires:   link.Expires,
+		})
+	}
+	return res, nil
+}
+
+// CreatePendingLink inserts the passed PendingLink into the PostgreSQL
+// database. If the Storer was created WithHashedIDs, the PendingLink's
+// Account ID is stored as a keyed hash alongside its encrypted form, like
+// Account IDs are.
+func (s *Storer) CreatePendingLink(ctx context.Context, link accounts.PendingLink) error {
+	stored, err := s.toStoredPendingLink(ctx, link)
+	if err != nil {
+		return err
+	}
+	_, err = s.exec(ctx, createPendingLinkSQL(ctx, stored))
+	return err
+}
+
+// TakePendingLink deletes the PendingLink whose TokenHash matches the passed
+// hash from the PostgreSQL database and returns it, in a single statement.
+// If no PendingLink matches, an ErrPendingLinkNotFound error is returned.
+func (s *Storer) TakePendingLink(ctx context.Context, tokenHash string) (accounts.PendingLink, error) {
+	query := takePendingLinkSQL(ctx, tokenHash)
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return accounts.PendingLink{}, err
+	}
+	rows, err := s.db.Query(queryStr, query.Args()...) //nolint:sqlclosecheck // the closeRows helper isn't picked up
+	if err != nil {
+		return accounts.PendingLink{}, err
+	}
+	defer closeRows(ctx, rows)
+	var links []pendingLink
+	for rows.Next() {
+		var link pendingLink
+		err = pan.Unmarshal(rows, &link)
+		if err != nil {
+			return accounts.PendingLink{}, err
+		}
+		links = append(links, link)
+	}
+	if err = rows.Err(); err != nil {
+		return accounts.PendingLink{}, err
+	}
+	if len(links) < 1 {
+		return accounts.PendingLink{}, accounts.ErrPendingLinkNotFound
+	}
+	res, err := s.fromStoredPendingLinks(ctx, links)
+	if err != nil {
+		return accounts.PendingLink{}, err
+	}
+	return res[0], nil
+}
+
+// ListPendingLinksByProfile returns every PendingLink for the passed profile
+// ID in the PostgreSQL database, including expired PendingLinks that haven't
+// been deleted yet.
+func (s *Storer) ListPendingLinksByProfile(ctx context.Context, profileID string) ([]accounts.PendingLink, error) {
+	query := listPendingLinksByProfileSQL(ctx, profileID)
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(queryStr, query.Args()...) //nolint:sqlclosecheck // the closeRows helper isn't picked up
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+	var links []pendingLink
+	for rows.Next() {
+		var link pendingLink
+		err = pan.Unmarshal(rows, &link)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return s.fromStoredPendingLinks(ctx, links)
+}
+
+// DeleteExpiredPendingLinks removes every PendingLink that expired before
+// the passed time from the PostgreSQL database, returning the number
+// removed.
+func (s *Storer) DeleteExpiredPendingLinks(ctx context.Context, now time.Time) (int, error) {
+	res, err := s.exec(ctx, deleteExpiredPendingLinksSQL(ctx, now))
+	if err != nil {
+		return 0, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(rows), nil
+}
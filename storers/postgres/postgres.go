@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
+	"time"
 
 	"darlinggo.co/pan"
 	"github.com/lib/pq"
@@ -25,65 +27,336 @@ const (
 // Storer provides a PostgreSQL-backed implementation of the Storer
 // interface.
 type Storer struct {
-	db *sql.DB
+	db         *sql.DB
+	keys       KeyProvider
+	envelope   *envelope
+	limits     accounts.ProfileLimits
+	clock      accounts.Clock
+	quarantine time.Duration
 }
 
 // NewStorer returns a Storer instance that is backed by the specified
 // *sql.DB. The returned Storer instance is ready to be used as a Storer.
-func NewStorer(_ context.Context, conn *sql.DB) *Storer {
-	return &Storer{db: conn}
+func NewStorer(_ context.Context, conn *sql.DB, opts ...Option) *Storer {
+	storer := &Storer{
+		db: conn,
+	}
+	for _, opt := range opts {
+		opt(storer)
+	}
+	return storer
+}
+
+// lookupIDs returns every form `id` may be stored as.
+func (s *Storer) lookupIDs(ctx context.Context, id string) ([]string, error) {
+	if s.keys == nil {
+		return []string{id}, nil
+	}
+	keys, err := loadKeys(ctx, s.keys)
+	if err != nil {
+		return nil, err
+	}
+	return keys.lookupIDs(id), nil
+}
+
+// toStored converts `account` to the form it should be stored in.
+func (s *Storer) toStored(ctx context.Context, account accounts.Account) (Account, error) {
+	res := toPostgres(account)
+	if s.keys == nil {
+		return res, nil
+	}
+	keys, err := loadKeys(ctx, s.keys)
+	if err != nil {
+		return res, err
+	}
+	return s.sealAccount(ctx, keys.current(), res)
+}
+
+// sealAccount replaces the ID of `account` with its hash under `key`, and
+// stores the encrypted ID in its DisplayID. The ID is encrypted with a data
+// key if the Storer was created WithEnvelopeEncryption, and with `key`
+// otherwise.
+func (s *Storer) sealAccount(ctx context.Context, key Key, account Account) (Account, error) {
+	id := account.ID
+	account.ID = key.hashID(id)
+	var display string
+	var err error
+	if s.envelope != nil {
+		display, err = s.envelope.encrypt(ctx, id, account.ID)
+	} else {
+		display, err = key.encrypt(id, account.ID)
+	}
+	if err != nil {
+		return account, err
+	}
+	account.DisplayID = sql.NullString{String: display, Valid: true}
+	return account, nil
+}
+
+// openDisplayID returns the plain ID from `account`'s DisplayID, loading
+// `keys` if they're needed and haven't been loaded yet.
+func (s *Storer) openDisplayID(ctx context.Context, keys *keyring, account Account) (string, error) {
+	if strings.HasPrefix(account.DisplayID.String, envelopePrefix) {
+		if s.envelope == nil {
+			return "", ErrNoKMS
+		}
+		return s.envelope.decrypt(ctx, account.DisplayID.String, account.ID)
+	}
+	if *keys == nil {
+		loaded, err := loadKeys(ctx, s.keys)
+		if err != nil {
+			return "", err
+		}
+		*keys = loaded
+	}
+	return keys.decrypt(account.DisplayID.String, account.ID)
+}
+
+// fromStored converts stored Accounts back to accounts.Accounts, decrypting
+// their IDs if needed.
+func (s *Storer) fromStored(ctx context.Context, accts []Account) ([]accounts.Account, error) {
+	var keys keyring
+	res := make([]accounts.Account, 0, len(accts))
+	for _, account := range accts {
+		if account.DisplayID.Valid {
+			id, err := s.openDisplayID(ctx, &keys, account)
+			if err != nil {
+				return nil, err
+			}
+			account.ID = id
+		}
+		res = append(res, fromPostgres(account))
+	}
+	return res, nil
+}
+
+// runner runs queries. It is implemented by both *sql.DB and *sql.Tx, so
+// the same helpers can be used inside and outside of transactions.
+type runner interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// query runs `query` and returns the accounts it selects.
+func (s *Storer) query(ctx context.Context, query *pan.Query) ([]Account, error) {
+	return queryIn(ctx, s.db, query)
+}
+
+// queryIn runs `query` using `run` and returns the accounts it selects.
+func queryIn(ctx context.Context, run runner, query *pan.Query) ([]Account, error) {
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := run.Query(queryStr, query.Args()...) //nolint:sqlclosecheck // the closeRows helper isn't picked up
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+	accts := []Account{}
+	for rows.Next() {
+		var account Account
+		err = pan.Unmarshal(rows, &account)
+		if err != nil {
+			return nil, err
+		}
+		accts = append(accts, account)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return accts, nil
+}
+
+// exec runs `query`, which doesn't select anything.
+func (s *Storer) exec(ctx context.Context, query *pan.Query) (sql.Result, error) {
+	return execIn(ctx, s.db, query)
+}
+
+// execIn runs `query`, which doesn't select anything, using `run`.
+func execIn(_ context.Context, run runner, query *pan.Query) (sql.Result, error) {
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return nil, err
+	}
+	return run.Exec(queryStr, query.Args()...)
+}
+
+// rollback rolls back `txn` if it hasn't been committed, logging any error.
+func rollback(ctx context.Context, txn *sql.Tx) {
+	if err := txn.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		yall.FromContext(ctx).WithError(err).Error("error rolling back transaction")
+	}
 }
 
 // Create inserts the passed Account into the PostgreSQL database, returning
 // an ErrAccountAlreadyExists error if the Account's ID already exists in the
-// database.
+// database, an ErrAccountIDRecentlyReleased error if another profile
+// released the ID within the Storer's release quarantine, an
+// ErrProfileIDAlreadyExists error if the Account is a registration and its
+// ProfileID is already in use, or an
+// ErrProfileAccountLimitReached error if the Account's profile already has as
+// many Accounts as the Storer's ProfileLimits allow.
 func (s *Storer) Create(ctx context.Context, account accounts.Account) error {
-	query := createSQL(ctx, toPostgres(account))
-	queryStr, err := query.PostgreSQLString()
+	stored, err := s.toStored(ctx, account)
 	if err != nil {
 		return err
 	}
-	_, err = s.db.Exec(queryStr, query.Args()...)
+	conds := createConditions{limits: s.limits}
+	if s.keys != nil {
+		conds.existingIDs, err = s.lookupIDs(ctx, account.ID)
+		if err != nil {
+			return err
+		}
+	}
+	if s.quarantine > 0 {
+		conds.releasedHashes, err = s.releaseHashes(ctx, account.ID)
+		if err != nil {
+			return err
+		}
+		conds.releasedSince = s.now().Add(-s.quarantine)
+	}
+	query := createSQL(ctx, stored, conds)
+	var res sql.Result
+	if limit, _ := s.limits.For(accounts.Kind(stored.Kind.String)); limit > 0 {
+		res, err = s.execLocked(ctx, stored.ProfileID, query)
+	} else {
+		res, err = s.exec(ctx, query)
+	}
 	var pqErr *pq.Error
 	if errors.As(err, &pqErr) {
 		switch pqErr.Constraint {
-		case "accounts_pkey":
+		case "accounts_pkey", "accounts_lower_id":
 			err = accounts.ErrAccountAlreadyExists
 		case "unique_registration":
 			err = accounts.ErrProfileIDAlreadyExists
 		}
 	}
-	return err
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows > 0 {
+		return nil
+	}
+	// the account wasn't inserted because its ID or its profile ID is
+	// in use, its ID is quarantined, or its profile is full, and
+	// problems with the ID take precedence
+	_, err = s.Get(ctx, account.ID)
+	if err == nil {
+		return accounts.ErrAccountAlreadyExists
+	}
+	if !errors.Is(err, accounts.ErrAccountNotFound) {
+		return err
+	}
+	released, err := s.releasedByOtherProfile(ctx, s.db, account.ID, account.ProfileID)
+	if err != nil {
+		return err
+	}
+	if released {
+		return accounts.ErrAccountIDRecentlyReleased
+	}
+	if account.IsRegistration {
+		return accounts.ErrProfileIDAlreadyExists
+	}
+	return accounts.ErrProfileAccountLimitReached
+}
+
+// execLocked runs `query`, which counts the accounts in a profile before
+// changing it, while holding a lock on `profileID`. Without the lock,
+// concurrent queries could each count the profile before the others were
+// committed, and together take it over its limit or leave it empty.
+func (s *Storer) execLocked(ctx context.Context, profileID string, query *pan.Query) (sql.Result, error) {
+	txn, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(ctx, txn)
+	_, err = execIn(ctx, txn, profileLockSQL(ctx, profileID))
+	if err != nil {
+		return nil, err
+	}
+	res, err := execIn(ctx, txn, query)
+	if err != nil {
+		return nil, err
+	}
+	return res, txn.Commit()
+}
+
+// Replace deletes the Account with the same ID as the passed Account from
+// the PostgreSQL database, if there is one, and inserts the passed Account,
+// in a single transaction, without releasing the ID. It returns an
+// ErrProfileIDAlreadyExists error or an ErrProfileAccountLimitReached error
+// under the same conditions Create does, in which case the existing Account
+// is left alone.
+func (s *Storer) Replace(ctx context.Context, account accounts.Account) error {
+	ids, err := s.lookupIDs(ctx, account.ID)
+	if err != nil {
+		return err
+	}
+	stored, err := s.toStored(ctx, account)
+	if err != nil {
+		return err
+	}
+	txn, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer rollback(ctx, txn)
+	if limit, _ := s.limits.For(accounts.Kind(stored.Kind.String)); limit > 0 {
+		_, err = execIn(ctx, txn, profileLockSQL(ctx, stored.ProfileID))
+		if err != nil {
+			return err
+		}
+	}
+	_, err = execIn(ctx, txn, deleteSQL(ctx, ids, nil))
+	if err != nil {
+		return err
+	}
+	res, err := execIn(ctx, txn, createSQL(ctx, stored, createConditions{limits: s.limits}))
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Constraint == "unique_registration" {
+		return accounts.ErrProfileIDAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows < 1 {
+		if account.IsRegistration {
+			return accounts.ErrProfileIDAlreadyExists
+		}
+		return accounts.ErrProfileAccountLimitReached
+	}
+	return txn.Commit()
 }
 
 // Get retrieves the Account specified by the passed ID from the PostgreSQL
 // database. If no Account matches the passed ID, an ErrAccountNotFound error
 // is returned.
 func (s *Storer) Get(ctx context.Context, id string) (accounts.Account, error) {
-	query := getSQL(ctx, id)
-	queryStr, err := query.PostgreSQLString()
+	ids, err := s.lookupIDs(ctx, id)
 	if err != nil {
 		return accounts.Account{}, err
 	}
-	rows, err := s.db.Query(queryStr, query.Args()...) //nolint:sqlclosecheck // the closeRows helper isn't picked up
+	stored, err := s.query(ctx, getSQL(ctx, ids))
 	if err != nil {
 		return accounts.Account{}, err
 	}
-	defer closeRows(ctx, rows)
-	var account Account
-	for rows.Next() {
-		err = pan.Unmarshal(rows, &account)
-		if err != nil {
-			return accounts.Account{}, err
-		}
-	}
-	if err = rows.Err(); err != nil {
+	accts, err := s.fromStored(ctx, stored)
+	if err != nil {
 		return accounts.Account{}, err
 	}
-	if account.ID == "" {
+	if len(accts) < 1 {
 		return accounts.Account{}, accounts.ErrAccountNotFound
 	}
-	return fromPostgres(account), nil
+	return accts[0], nil
 }
 
 // Update applies the passed Change to the Account in the PostgreSQL database
@@ -92,7 +365,11 @@ func (s *Storer) Update(ctx context.Context, id string, change accounts.Change)
 	if change.IsEmpty() {
 		return nil
 	}
-	query := updateSQL(ctx, id, change)
+	ids, err := s.lookupIDs(ctx, id)
+	if err != nil {
+		return err
+	}
+	query := updateSQL(ctx, ids, change)
 	queryStr, err := query.PostgreSQLString()
 	if err != nil {
 		return err
@@ -105,9 +382,18 @@ func (s *Storer) Update(ctx context.Context, id string, change accounts.Change)
 }
 
 // Delete removes the Account that matches the passed ID from the PostgreSQL
-// database, if any Account matches the passed ID.
+// database, if any Account matches the passed ID, and releases its ID in the
+// same statement.
 func (s *Storer) Delete(ctx context.Context, id string) error {
-	query := deleteSQL(ctx, id)
+	ids, err := s.lookupIDs(ctx, id)
+	if err != nil {
+		return err
+	}
+	rel, err := s.deleteRelease(ctx, id)
+	if err != nil {
+		return err
+	}
+	query := deleteSQL(ctx, ids, rel)
 	queryStr, err := query.PostgreSQLString()
 	if err != nil {
 		return err
@@ -119,33 +405,167 @@ func (s *Storer) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// DeleteUnlessLast removes the Account that matches the passed ID from the
+// PostgreSQL database and releases its ID, unless it is the only Account
+// associated with its profile, in which case an ErrLastAccount error is
+// returned.
+func (s *Storer) DeleteUnlessLast(ctx context.Context, id string) error {
+	ids, err := s.lookupIDs(ctx, id)
+	if err != nil {
+		return err
+	}
+	stored, err := s.query(ctx, getSQL(ctx, ids))
+	if err != nil {
+		return err
+	}
+	if len(stored) < 1 {
+		return nil
+	}
+	rel, err := s.deleteRelease(ctx, id)
+	if err != nil {
+		return err
+	}
+	res, err := s.execLocked(ctx, stored[0].ProfileID, deleteUnlessLastSQL(ctx, ids, stored[0].ProfileID, rel))
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows > 0 {
+		return nil
+	}
+	// nothing was deleted because the Account is the last one in its
+	// profile, or because it was deleted while we waited for the lock
+	_, err = s.Get(ctx, id)
+	if errors.Is(err, accounts.ErrAccountNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return accounts.ErrLastAccount
+}
+
+// EraseProfile removes every Account associated with the passed profile ID
+// from the PostgreSQL database, returning the Accounts that were removed. The
+// IDs in the profile's history are replaced with erased placeholders, and
+// the profile's PendingLinks are deleted, in the same transaction.
+func (s *Storer) EraseProfile(ctx context.Context, profileID string) ([]accounts.Account, error) {
+	txn, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(ctx, txn)
+	stored, err := queryIn(ctx, txn, eraseProfileSQL(ctx, profileID))
+	if err != nil {
+		return nil, err
+	}
+	err = s.scrubHistory(ctx, txn, profileID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = execIn(ctx, txn, deletePendingLinksByProfileSQL(ctx, profileID))
+	if err != nil {
+		return nil, err
+	}
+	accts, err := s.fromStored(ctx, stored)
+	if err != nil {
+		return nil, err
+	}
+	return accts, txn.Commit()
+}
+
 // ListByProfile returns all the Accounts associated with the passed profile ID,
 // sorted with the most recently used Accounts coming first.
 func (s *Storer) ListByProfile(ctx context.Context, profileID string) ([]accounts.Account, error) {
-	query := listByProfileSQL(ctx, profileID)
-	queryStr, err := query.PostgreSQLString()
+	stored, err := s.query(ctx, listByProfileSQL(ctx, profileID))
 	if err != nil {
 		return nil, err
 	}
-	rows, err := s.db.Query(queryStr, query.Args()...) //nolint:sqlclosecheck // the closeRows helper isn't picked up
+	accts, err := s.fromStored(ctx, stored)
 	if err != nil {
 		return nil, err
 	}
-	defer closeRows(ctx, rows)
-	var accts []accounts.Account
-	for rows.Next() {
-		var account Account
-		err = pan.Unmarshal(rows, &account)
+	accounts.ByLastUsedDesc(accts)
+	return accts, nil
+}
+
+// ListAll returns up to `limit` Accounts, ordered by their lowercased IDs,
+// starting after the Account whose ID is `after`. If `after` is empty, the
+// Accounts are returned starting from the beginning.
+//
+// When IDs are hashed, Accounts are ordered by their hashed IDs instead. If
+// the Account whose ID is `after` was deleted and had been hashed with an
+// old Key, some Accounts may be skipped or returned again.
+func (s *Storer) ListAll(ctx context.Context, after string, limit int) ([]accounts.Account, error) {
+	var afterIDs []string
+	if s.keys != nil && after != "" {
+		keys, err := loadKeys(ctx, s.keys)
 		if err != nil {
-			return accts, err
+			return nil, err
 		}
-		accts = append(accts, fromPostgres(account))
+		afterIDs = keys.lookupIDs(after)
+		after = keys.current().hashID(after)
 	}
-	if err = rows.Err(); err != nil {
+	stored, err := s.query(ctx, listAllSQL(ctx, after, afterIDs, limit))
+	if err != nil {
 		return nil, err
 	}
-	accounts.ByLastUsedDesc(accts)
-	return accts, nil
+	return s.fromStored(ctx, stored)
+}
+
+// RotateKeys rewrites every Account whose ID isn't hashed with the current
+// Key, hashing it with the current Key and encrypting it again, up to
+// `batchSize` Accounts at a time, and then does the same for the IDs in the
+// history kept by Rename. It returns the number of Accounts rewritten. Once
+// it returns successfully, Keys other than the current Key are no longer
+// needed.
+//
+// RotateKeys is also how Accounts created before WithHashedIDs was used get
+// their IDs hashed, and how Accounts hashed before their Kind was recorded
+// get it recorded. It does nothing if the Storer wasn't created
+// WithHashedIDs.
+func (s *Storer) RotateKeys(ctx context.Context, batchSize int) (int, error) {
+	if s.keys == nil {
+		return 0, nil
+	}
+	keys, err := loadKeys(ctx, s.keys)
+	if err != nil {
+		return 0, err
+	}
+	var rotated int
+	for {
+		batch, err := s.query(ctx, unrotatedSQL(ctx, keys.current().ID, batchSize))
+		if err != nil {
+			return rotated, err
+		}
+		if len(batch) < 1 {
+			return rotated, s.rotateHistory(ctx, keys, batchSize)
+		}
+		plain, err := s.fromStored(ctx, batch)
+		if err != nil {
+			return rotated, err
+		}
+		for pos, account := range plain {
+			sealed, err := s.sealAccount(ctx, keys.current(), toPostgres(account))
+			if err != nil {
+				return rotated, err
+			}
+			query := rekeySQL(ctx, batch[pos].ID, sealed)
+			queryStr, err := query.PostgreSQLString()
+			if err != nil {
+				return rotated, err
+			}
+			_, err = s.db.Exec(queryStr, query.Args()...)
+			if err != nil {
+				return rotated, err
+			}
+			rotated++
+		}
+		yall.FromContext(ctx).WithField("rotated", rotated).Debug("rotated account ID keys")
+	}
 }
 
 func closeRows(ctx context.Context, rows *sql.Rows) {
@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"lockbox.dev/accounts"
+)
+
+// releasedID is the record of an ID being released that is stored in
+// PostgreSQL. Only the latest release of each ID is kept.
+type releasedID struct {
+	IDHash    string    `sql_column:"id_hash"`
+	ProfileID string    `sql_column:"profile_id"`
+	Released  time.Time `sql_column:"released_at"`
+}
+
+// GetSQLTableName returns the name of the SQL table that the data for this
+// type will be stored in.
+func (releasedID) GetSQLTableName() string {
+	return "account_released_ids"
+}
+
+// releaseOf returns the record of `profileID` releasing `id` now. When IDs
+// are hashed, the ID is recorded by its hash under the current Key, and
+// otherwise by its unkeyed accounts.HashReleasedID, as the IDs of Accounts
+// are stored in plain text anyway.
+func (s *Storer) releaseOf(ctx context.Context, id, profileID string) (releasedID, error) {
+	rel := releasedID{
+		IDHash:    accounts.HashReleasedID(nil, id),
+		ProfileID: profileID,
+		Released:  s.now(),
+	}
+	if s.keys == nil {
+		return rel, nil
+	}
+	keys, err := loadKeys(ctx, s.keys)
+	if err != nil {
+		return rel, err
+	}
+	rel.IDHash = keys.current().hashID(id)
+	return rel, nil
+}
+
+// releaseHashes returns every hash a release of `id` may be recorded under.
+func (s *Storer) releaseHashes(ctx context.Context, id string) ([]string, error) {
+	hashes := []string{accounts.HashReleasedID(nil, id)}
+	if s.keys == nil {
+		return hashes, nil
+	}
+	keys, err := loadKeys(ctx, s.keys)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		hashes = append(hashes, key.hashID(id))
+	}
+	return hashes, nil
+}
+
+// deleteRelease returns the release to record when deleting the Account
+// identified by `id`, or nil if the Storer has no release quarantine. The
+// profile ID is filled in from the deleted Account by the database.
+func (s *Storer) deleteRelease(ctx context.Context, id string) (*releasedID, error) {
+	if s.quarantine <= 0 {
+		return nil, nil //nolint:nilnil // no release to record isn't an error
+	}
+	rel, err := s.releaseOf(ctx, id, "")
+	if err != nil {
+		return nil, err
+	}
+	return &rel, nil
+}
+
+// releasedByOtherProfile returns whether a profile other than `profileID`
+// released `id` within the Storer's release quarantine, using `run`.
+func (s *Storer) releasedByOtherProfile(ctx context.Context, run runner, id, profileID string) (bool, error) {
+	if s.quarantine <= 0 {
+		return false, nil
+	}
+	hashes, err := s.releaseHashes(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	query := releasedSQL(ctx, hashes, profileID, s.now().Add(-s.quarantine))
+	queryStr, err := query.PostgreSQLString()
+	if err != nil {
+		return false, err
+	}
+	rows, err := run.Query(queryStr, query.Args()...) //nolint:sqlclosecheck // the closeRows helper isn't picked up
+	if err != nil {
+		return false, err
+	}
+	defer closeRows(ctx, rows)
+	released := rows.Next()
+	return released, rows.Err()
+}
+
+// checkClaimable returns an ErrAccountAlreadyExists error if an Account
+// matches `id`, or an ErrAccountIDRecentlyReleased error if a profile other
+// than `profileID` released `id` within the Storer's release quarantine.
+func (s *Storer) checkClaimable(ctx context.Context, txn *sql.Tx, id, profileID string) error {
+	ids, err := s.lookupIDs(ctx, id)
+	if err != nil {
+		return err
+	}
+	taken, err := queryIn(ctx, txn, getSQL(ctx, ids))
+	if err != nil {
+		return err
+	}
+	if len(taken) > 0 {
+		return accounts.ErrAccountAlreadyExists
+	}
+	released, err := s.releasedByOtherProfile(ctx, txn, id, profileID)
+	if err != nil {
+		return err
+	}
+	if released {
+		return accounts.ErrAccountIDRecentlyReleased
+	}
+	return nil
+}
+
+// CheckReleased returns an ErrAccountIDRecentlyReleased error if a profile
+// other than `profileID` released `id` within the Storer's release
+// quarantine.
+func (s *Storer) CheckReleased(ctx context.Context, id, profileID string) error {
+	released, err := s.releasedByOtherProfile(ctx, s.db, id, profileID)
+	if err != nil {
+		return err
+	}
+	if released {
+		return accounts.ErrAccountIDRecentlyReleased
+	}
+	return nil
+}
+
+// DeleteExpiredReleasedIDs removes every released ID whose quarantine has
+// ended from the PostgreSQL database, returning the number removed.
+func (s *Storer) DeleteExpiredReleasedIDs(ctx context.Context) (int, error) {
+	res, err := s.exec(ctx, deleteExpiredReleasedIDsSQL(ctx, s.now().Add(-s.quarantine)))
+	if err != nil {
+		return 0, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(rows), nil
+}
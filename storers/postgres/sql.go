@@ -2,25 +2,119 @@ package postgres
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"darlinggo.co/pan"
 
 	"lockbox.dev/accounts"
 )
 
-func getSQL(_ context.Context, id string) *pan.Query {
+// idIn returns a case-insensitive check of whether the ID column matches
+// any of `num` placeholders.
+func idIn(num int) string {
+	var account Account
+	if num == 1 {
+		return "LOWER(" + pan.Column(account, "ID") + ") = LOWER(?)"
+	}
+	return "LOWER(" + pan.Column(account, "ID") + ") IN (" + strings.Repeat("LOWER(?), ", num-1) + "LOWER(?))"
+}
+
+func interfaces(values []string) []interface{} {
+	res := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		res = append(res, value)
+	}
+	return res
+}
+
+// idMatches adds a case-insensitive comparison against the ID column to the
+// passed query, matching any of the passed IDs.
+func idMatches(q *pan.Query, ids []string) {
+	q.Expression(idIn(len(ids)), interfaces(ids)...)
+}
+
+func getSQL(_ context.Context, ids []string) *pan.Query {
 	var account Account
 	q := pan.New("SELECT " + pan.Columns(account).String() + " FROM " + pan.Table(account))
 	q.Where()
-	q.Comparison(account, "ID", "=", id)
+	idMatches(q, ids)
+	return q.Flush(" ")
+}
+
+// createConditions are the checks createSQL makes before inserting an
+// account.
+type createConditions struct {
+	// existingIDs are the forms of the account's ID that must not be in
+	// use already; this catches accounts stored under other forms of the
+	// same ID, which the database's constraints can't.
+	existingIDs []string
+
+	// limits are the limits on how many accounts the account's profile
+	// can have.
+	limits accounts.ProfileLimits
+
+	// releasedHashes are the hashes a release of the account's ID may be
+	// recorded under. The account isn't inserted if another profile
+	// released its ID after releasedSince.
+	releasedHashes []string
+	releasedSince  time.Time
+}
+
+// createSQL inserts `account`, as long as it passes the checks in `conds`.
+func createSQL(_ context.Context, account Account, conds createConditions) *pan.Query {
+	existingIDs := conds.existingIDs
+	limit, sameKind := conds.limits.For(accounts.Kind(account.Kind.String))
+	if !account.IsRegistration.Bool && len(existingIDs) < 1 && limit < 1 && len(conds.releasedHashes) < 1 {
+		return pan.Insert(account)
+	}
+	q := pan.New("INSERT INTO " + pan.Table(account) + " (" + pan.Columns(account).String() + ")")
+	q.Expression("SELECT ?::VARCHAR, ?::VARCHAR, ?::TIMESTAMPTZ, ?::TIMESTAMPTZ, ?::TIMESTAMPTZ, ?::BOOLEAN, ?::TEXT, ?::VARCHAR",
+		account.ID, account.ProfileID, account.Created, account.LastUsed, account.LastSeen, account.IsRegistration,
+		account.DisplayID, account.Kind)
+	conjunction := "WHERE"
+	if account.IsRegistration.Bool {
+		// registrations can only be inserted if nothing is using their
+		// profile ID yet
+		q.Expression(conjunction+" NOT EXISTS (SELECT 1 FROM "+pan.Table(account)+" WHERE "+pan.Column(account, "ProfileID")+" = ?)",
+			account.ProfileID)
+		conjunction = "AND"
+	}
+	if len(existingIDs) > 0 {
+		q.Expression(conjunction+" NOT EXISTS (SELECT 1 FROM "+pan.Table(account)+" WHERE "+idIn(len(existingIDs))+")",
+			interfaces(existingIDs)...)
+		conjunction = "AND"
+	}
+	if len(conds.releasedHashes) > 0 {
+		var rel releasedID
+		args := append(interfaces(conds.releasedHashes), account.ProfileID, conds.releasedSince)
+		q.Expression(conjunction+" NOT EXISTS (SELECT 1 FROM "+pan.Table(rel)+" WHERE "+
+			pan.Column(rel, "IDHash")+" IN ("+pan.Placeholders(len(conds.releasedHashes))+") AND "+
+			pan.Column(rel, "ProfileID")+" != ? AND "+pan.Column(rel, "Released")+" > ?)", args...)
+		conjunction = "AND"
+	}
+	if limit > 0 {
+		count := "SELECT COUNT(*) FROM " + pan.Table(account) + " WHERE " + pan.Column(account, "ProfileID") + " = ?"
+		args := []interface{}{account.ProfileID}
+		if sameKind {
+			count += " AND " + pan.Column(account, "Kind") + " = ?"
+			args = append(args, account.Kind)
+		}
+		q.Expression(conjunction+" ("+count+") < ?", append(args, limit)...)
+	}
 	return q.Flush(" ")
 }
 
-func createSQL(_ context.Context, account Account) *pan.Query {
-	return pan.Insert(account)
+// profileLockSQL takes a lock on `profileID` that is held until the end of
+// the transaction, so accounts being added to or deleted from the same
+// profile concurrently are counted one at a time.
+func profileLockSQL(_ context.Context, profileID string) *pan.Query {
+	q := pan.New("SELECT")
+	q.Expression("pg_advisory_xact_lock(hashtext(?))", profileID)
+	return q.Flush(" ")
 }
 
-func updateSQL(_ context.Context, id string, change accounts.Change) *pan.Query {
+func updateSQL(_ context.Context, ids []string, change accounts.Change) *pan.Query {
 	var account Account
 	query := pan.New("UPDATE " + pan.Table(account) + " SET ")
 	if change.LastUsed != nil {
@@ -31,15 +125,76 @@ func updateSQL(_ context.Context, id string, change accounts.Change) *pan.Query
 	}
 	query.Flush(", ")
 	query.Where()
-	query.Comparison(account, "ID", "=", id)
+	idMatches(query, ids)
 	return query.Flush(" ")
 }
 
-func deleteSQL(_ context.Context, id string) *pan.Query {
+// releasingPrefix starts a statement that deletes accounts and, if `rel`
+// isn't nil, records their IDs as released, when followed by the DELETE
+// statement and releaseDeleted.
+func releasingPrefix(rel *releasedID) string {
+	if rel == nil {
+		return ""
+	}
+	return "WITH deleted AS ("
+}
+
+// releaseDeleted finishes a statement started by releasingPrefix, recording
+// the ID of the deleted account as released by its profile, as described by
+// `rel`. The statement affects one row for each account deleted.
+func releaseDeleted(q *pan.Query, rel *releasedID) {
+	if rel == nil {
+		return
+	}
+	var account Account
+	q.Expression("RETURNING " + pan.Column(account, "ProfileID") + ")")
+	q.Expression("INSERT INTO "+pan.Table(*rel)+" ("+pan.Columns(*rel).String()+") SELECT ?::VARCHAR, "+
+		pan.Column(account, "ProfileID")+", ?::TIMESTAMPTZ FROM deleted", rel.IDHash, rel.Released)
+	onReleasedConflict(q)
+}
+
+// onReleasedConflict replaces the existing record of an ID being released
+// with the one being inserted by `q`.
+func onReleasedConflict(q *pan.Query) {
+	var rel releasedID
+	q.Expression("ON CONFLICT (" + pan.Column(rel, "IDHash") + ") DO UPDATE SET " +
+		pan.Column(rel, "ProfileID") + " = EXCLUDED." + pan.Column(rel, "ProfileID") + ", " +
+		pan.Column(rel, "Released") + " = EXCLUDED." + pan.Column(rel, "Released"))
+}
+
+// deleteSQL deletes the account matching `ids`, recording its ID as
+// released unless `rel` is nil.
+func deleteSQL(_ context.Context, ids []string, rel *releasedID) *pan.Query {
+	var account Account
+	q := pan.New(releasingPrefix(rel) + "DELETE FROM " + pan.Table(account))
+	q.Where()
+	idMatches(q, ids)
+	releaseDeleted(q, rel)
+	return q.Flush(" ")
+}
+
+// deleteUnlessLastSQL deletes the account matching `ids`, as long as
+// another account is associated with `profileID`, recording its ID as
+// released unless `rel` is nil. It should be run while holding the lock
+// from profileLockSQL, so concurrent deletes see each other.
+func deleteUnlessLastSQL(_ context.Context, ids []string, profileID string, rel *releasedID) *pan.Query {
+	var account Account
+	q := pan.New(releasingPrefix(rel) + "DELETE FROM " + pan.Table(account))
+	q.Where()
+	idMatches(q, ids)
+	// the unqualified columns in the subquery refer to the other accounts
+	q.Expression("AND EXISTS (SELECT 1 FROM "+pan.Table(account)+" WHERE "+pan.Column(account, "ProfileID")+" = ? AND NOT ("+idIn(len(ids))+"))",
+		append([]interface{}{profileID}, interfaces(ids)...)...)
+	releaseDeleted(q, rel)
+	return q.Flush(" ")
+}
+
+func eraseProfileSQL(_ context.Context, profileID string) *pan.Query {
 	var account Account
 	q := pan.New("DELETE FROM " + pan.Table(account))
 	q.Where()
-	q.Comparison(account, "ID", "=", id)
+	q.Comparison(account, "ProfileID", "=", profileID)
+	q.Expression("RETURNING " + pan.Columns(account).String())
 	return q.Flush(" ")
 }
 
@@ -51,3 +206,247 @@ func listByProfileSQL(_ context.Context, profileID string) *pan.Query {
 	q.OrderByDesc("last_used_at")
 	return q.Flush(" ")
 }
+
+// listAllSQL lists accounts in order of their stored IDs. When the stored
+// IDs are hashes, the position to start from is found by looking up the
+// stored form of the previous page's last ID using `afterIDs`, falling back
+// to `after` if it no longer exists.
+func listAllSQL(_ context.Context, after string, afterIDs []string, limit int) *pan.Query {
+	var account Account
+	q := pan.New("SELECT " + pan.Columns(account).String() + " FROM " + pan.Table(account))
+	q.Where()
+	if len(afterIDs) > 0 {
+		args := append(interfaces(afterIDs), after)
+		q.Expression("LOWER("+pan.Column(account, "ID")+") > COALESCE((SELECT MAX(LOWER("+pan.Column(account, "ID")+")) FROM "+
+			pan.Table(account)+" WHERE "+idIn(len(afterIDs))+"), LOWER(?))", args...)
+	} else {
+		q.Expression("LOWER("+pan.Column(account, "ID")+") > LOWER(?)", after)
+	}
+	q.OrderBy("LOWER(" + pan.Column(account, "ID") + ")")
+	q.Limit(int64(limit))
+	return q.Flush(" ")
+}
+
+// unrotatedSQL selects up to `limit` accounts whose IDs aren't hashed with
+// the Key identified by `keyID`, or whose kind isn't known.
+func unrotatedSQL(_ context.Context, keyID string, limit int) *pan.Query {
+	var account Account
+	q := pan.New("SELECT " + pan.Columns(account).String() + " FROM " + pan.Table(account))
+	q.Where()
+	q.Expression(pan.Column(account, "DisplayID")+" IS NULL OR "+pan.Column(account, "ID")+" NOT LIKE ? OR "+
+		pan.Column(account, "Kind")+" IS NULL", hashedIDPrefix+keyID+":%")
+	q.Limit(int64(limit))
+	return q.Flush(" ")
+}
+
+func rekeySQL(_ context.Context, oldID string, account Account) *pan.Query {
+	q := pan.New("UPDATE " + pan.Table(account) + " SET ")
+	q.Comparison(account, "ID", "=", account.ID)
+	q.Comparison(account, "DisplayID", "=", account.DisplayID)
+	q.Comparison(account, "Kind", "=", account.Kind)
+	q.Flush(", ")
+	q.Where()
+	q.Comparison(account, "ID", "=", oldID)
+	return q.Flush(" ")
+}
+
+// unencryptedSQL selects up to `limit` accounts whose display IDs weren't
+// encrypted with the data key identified by `dataKeyID`.
+func unencryptedSQL(_ context.Context, dataKeyID string, limit int) *pan.Query {
+	var account Account
+	q := pan.New("SELECT " + pan.Columns(account).String() + " FROM " + pan.Table(account))
+	q.Where()
+	q.Expression(pan.Column(account, "DisplayID") + " IS NOT NULL")
+	q.Expression("AND "+pan.Column(account, "DisplayID")+" NOT LIKE ?", envelopePrefix+dataKeyID+":%")
+	q.Limit(int64(limit))
+	return q.Flush(" ")
+}
+
+// reencryptSQL replaces the display ID of `account`, as long as it hasn't
+// changed from `oldDisplayID` in the meantime.
+func reencryptSQL(_ context.Context, oldDisplayID string, account Account) *pan.Query {
+	q := pan.New("UPDATE " + pan.Table(account) + " SET ")
+	q.Comparison(account, "DisplayID", "=", account.DisplayID)
+	q.Where()
+	q.Comparison(account, "ID", "=", account.ID)
+	q.Expression("AND "+pan.Column(account, "DisplayID")+" = ?", oldDisplayID)
+	return q.Flush(" ")
+}
+
+func latestDataKeySQL(_ context.Context) *pan.Query {
+	var key dataKey
+	q := pan.New("SELECT " + pan.Columns(key).String() + " FROM " + pan.Table(key))
+	q.OrderByDesc(pan.Column(key, "Created"))
+	q.Limit(1)
+	return q.Flush(" ")
+}
+
+func getDataKeySQL(_ context.Context, id string) *pan.Query {
+	var key dataKey
+	q := pan.New("SELECT " + pan.Columns(key).String() + " FROM " + pan.Table(key))
+	q.Where()
+	q.Comparison(key, "ID", "=", id)
+	return q.Flush(" ")
+}
+
+func createDataKeySQL(_ context.Context, key dataKey) *pan.Query {
+	return pan.Insert(key)
+}
+
+func staleDataKeysSQL(_ context.Context, masterKeyID string, limit int) *pan.Query {
+	var key dataKey
+	q := pan.New("SELECT " + pan.Columns(key).String() + " FROM " + pan.Table(key))
+	q.Where()
+	q.Comparison(key, "MasterKeyID", "!=", masterKeyID)
+	q.Limit(int64(limit))
+	return q.Flush(" ")
+}
+
+func rewrapDataKeySQL(_ context.Context, oldMasterKeyID string, key dataKey) *pan.Query {
+	q := pan.New("UPDATE " + pan.Table(key) + " SET ")
+	q.Comparison(key, "MasterKeyID", "=", key.MasterKeyID)
+	q.Comparison(key, "WrappedKey", "=", key.WrappedKey)
+	q.Flush(", ")
+	q.Where()
+	q.Comparison(key, "ID", "=", key.ID)
+	q.Comparison(key, "MasterKeyID", "=", oldMasterKeyID)
+	return q.Flush(" AND ")
+}
+
+func createPendingLinkSQL(_ context.Context, link pendingLink) *pan.Query {
+	return pan.Insert(link)
+}
+
+func takePendingLinkSQL(_ context.Context, tokenHash string) *pan.Query {
+	var link pendingLink
+	q := pan.New("DELETE FROM " + pan.Table(link))
+	q.Where()
+	q.Comparison(link, "TokenHash", "=", tokenHash)
+	q.Expression("RETURNING " + pan.Columns(link).String())
+	return q.Flush(" ")
+}
+
+func listPendingLinksByProfileSQL(_ context.Context, profileID string) *pan.Query {
+	var link pendingLink
+	q := pan.New("SELECT " + pan.Columns(link).String() + " FROM " + pan.Table(link))
+	q.Where()
+	q.Comparison(link, "ProfileID", "=", profileID)
+	return q.Flush(" ")
+}
+
+func deletePendingLinksByProfileSQL(_ context.Context, profileID string) *pan.Query {
+	var link pendingLink
+	q := pan.New("DELETE FROM " + pan.Table(link))
+	q.Where()
+	q.Comparison(link, "ProfileID", "=", profileID)
+	return q.Flush(" ")
+}
+
+func deleteExpiredPendingLinksSQL(_ context.Context, now time.Time) *pan.Query {
+	var link pendingLink
+	q := pan.New("DELETE FROM " + pan.Table(link))
+	q.Where()
+	q.Comparison(link, "Expires", "<", now)
+	return q.Flush(" ")
+}
+
+// lockAccountSQL selects the account matching `ids`, locking it until the
+// end of the transaction.
+func lockAccountSQL(ctx context.Context, ids []string) *pan.Query {
+	q := getSQL(ctx, ids)
+	q.Expression("FOR UPDATE")
+	return q.Flush(" ")
+}
+
+func createHistorySQL(_ context.Context, rec historyRecord) *pan.Query {
+	return pan.Insert(rec)
+}
+
+func profileHistorySQL(_ context.Context, profileID string) *pan.Query {
+	var rec historyRecord
+	q := pan.New("SELECT " + pan.Columns(rec).String() + " FROM " + pan.Table(rec))
+	q.Where()
+	q.Comparison(rec, "ProfileID", "=", profileID)
+	q.OrderBy(pan.Column(rec, "At"))
+	return q.Flush(" ")
+}
+
+// lockProfileHistorySQL selects the history records associated with
+// `profileID`, locking them until the end of the transaction.
+func lockProfileHistorySQL(ctx context.Context, profileID string) *pan.Query {
+	q := profileHistorySQL(ctx, profileID)
+	q.Expression("FOR UPDATE")
+	return q.Flush(" ")
+}
+
+// rewriteHistoryIDsSQL replaces the IDs of the history record `rec`, as long
+// as its account ID hasn't changed from `oldAccountID` in the meantime.
+func rewriteHistoryIDsSQL(_ context.Context, oldAccountID string, rec historyRecord) *pan.Query {
+	q := pan.New("UPDATE " + pan.Table(rec) + " SET ")
+	q.Comparison(rec, "AccountID", "=", rec.AccountID)
+	q.Comparison(rec, "DisplayID", "=", rec.DisplayID)
+	q.Comparison(rec, "PreviousID", "=", rec.PreviousID)
+	q.Comparison(rec, "PreviousDisplayID", "=", rec.PreviousDisplayID)
+	q.Flush(", ")
+	q.Where()
+	q.Comparison(rec, "ID", "=", rec.ID)
+	q.Comparison(rec, "AccountID", "=", oldAccountID)
+	return q.Flush(" AND ")
+}
+
+// historyAfterSQL selects up to `limit` history records, in order of their
+// IDs, starting after the record whose ID is `after`.
+func historyAfterSQL(_ context.Context, after string, limit int) *pan.Query {
+	var rec historyRecord
+	q := pan.New("SELECT " + pan.Columns(rec).String() + " FROM " + pan.Table(rec))
+	q.Where()
+	q.Comparison(rec, "ID", ">", after)
+	q.OrderBy(pan.Column(rec, "ID"))
+	q.Limit(int64(limit))
+	return q.Flush(" ")
+}
+
+func releaseSQL(_ context.Context, rel releasedID) *pan.Query {
+	q := pan.Insert(rel)
+	onReleasedConflict(q)
+	return q.Flush(" ")
+}
+
+// releasedSQL selects the records of IDs matching any of `hashes` being
+// released by profiles other than `profileID` after `since`.
+func releasedSQL(_ context.Context, hashes []string, profileID string, since time.Time) *pan.Query {
+	var rel releasedID
+	q := pan.New("SELECT " + pan.Columns(rel).String() + " FROM " + pan.Table(rel))
+	q.Where()
+	q.Expression(pan.Column(rel, "IDHash")+" IN ("+pan.Placeholders(len(hashes))+")", interfaces(hashes)...)
+	q.Comparison(rel, "ProfileID", "!=", profileID)
+	q.Comparison(rel, "Released", ">", since)
+	return q.Flush(" AND ")
+}
+
+func deleteExpiredReleasedIDsSQL(_ context.Context, before time.Time) *pan.Query {
+	var rel releasedID
+	q := pan.New("DELETE FROM " + pan.Table(rel))
+	q.Where()
+	q.Comparison(rel, "Released", "<=", before)
+	return q.Flush(" ")
+}
+
+func createDenyRuleSQL(_ context.Context, rule denyRule) *pan.Query {
+	return pan.Insert(rule)
+}
+
+func listDenyRulesSQL(_ context.Context) *pan.Query {
+	var rule denyRule
+	q := pan.New("SELECT " + pan.Columns(rule).String() + " FROM " + pan.Table(rule))
+	q.OrderBy(pan.Column(rule, "Created"))
+	return q.Flush(" ")
+}
+
+func deleteDenyRuleSQL(_ context.Context, id string) *pan.Query {
+	var rule denyRule
+	q := pan.New("DELETE FROM " + pan.Table(rule))
+	q.Where()
+	q.Comparison(rule, "ID", "=", id)
+	return q.Flush(" ")
+}
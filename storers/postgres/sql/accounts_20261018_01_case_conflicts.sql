@@ -0,0 +1,26 @@
+-- +migrate Up
+-- IDs that differ only by case can't be made unique by the
+-- case_insensitive_id migration that follows, so stop with a list of them
+-- instead of a bare unique violation. Each group has to be merged into one
+-- Account, or have all but one of its Accounts deleted, before running the
+-- migrations again. To list them by hand:
+--
+--   SELECT LOWER(id), array_agg(id) FROM accounts GROUP BY LOWER(id) HAVING COUNT(*) > 1;
+-- +migrate StatementBegin
+DO $$
+DECLARE
+	conflicts TEXT;
+BEGIN
+	SELECT string_agg(ids, '; ') INTO conflicts FROM (
+		SELECT array_to_string(array_agg(id ORDER BY id), ', ') AS ids
+		FROM accounts GROUP BY LOWER(id) HAVING COUNT(*) > 1
+	) AS duplicates;
+	IF conflicts IS NOT NULL THEN
+		RAISE EXCEPTION 'account IDs differ only by case: %', conflicts
+			USING HINT = 'merge or delete the conflicting accounts, then run the migrations again';
+	END IF;
+END
+$$;
+-- +migrate StatementEnd
+
+-- +migrate Down
@@ -0,0 +1,5 @@
+-- +migrate Up
+CREATE UNIQUE INDEX accounts_lower_id ON accounts (LOWER(id));
+
+-- +migrate Down
+DROP INDEX accounts_lower_id;
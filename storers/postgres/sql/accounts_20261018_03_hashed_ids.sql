@@ -0,0 +1,20 @@
+-- +migrate Up
+ALTER TABLE accounts ALTER COLUMN id TYPE VARCHAR(255),
+		     ADD COLUMN display_id TEXT;
+
+-- +migrate Down
+-- Hashed IDs can't be turned back into the IDs they were made from, and
+-- don't fit in the old column, so refuse to drop the column they're
+-- displayed from while any are stored.
+-- +migrate StatementBegin
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM accounts WHERE display_id IS NOT NULL OR LENGTH(id) > 64) THEN
+		RAISE EXCEPTION 'accounts are stored with hashed or long IDs'
+			USING HINT = 'export the accounts with a Storer that can read them, then import them after migrating down';
+	END IF;
+END
+$$;
+-- +migrate StatementEnd
+ALTER TABLE accounts DROP COLUMN display_id,
+		     ALTER COLUMN id TYPE VARCHAR(64);
@@ -0,0 +1,10 @@
+-- +migrate Up
+CREATE TABLE account_data_keys (
+	id VARCHAR(36) PRIMARY KEY,
+	master_key_id VARCHAR(255) NOT NULL,
+	wrapped_key BYTEA NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL
+);
+
+-- +migrate Down
+DROP TABLE account_data_keys;
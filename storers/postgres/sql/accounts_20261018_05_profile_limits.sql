@@ -0,0 +1,16 @@
+-- +migrate Up
+ALTER TABLE accounts ADD COLUMN kind VARCHAR(16);
+
+-- hashed IDs can't be classified here; RotateKeys fills their kind in
+UPDATE accounts SET kind = CASE
+	WHEN id LIKE '%@%' THEN 'email'
+	WHEN id ~ '^\+[0-9]+$' THEN 'phone'
+	WHEN id LIKE '%:%' THEN 'social'
+	ELSE 'username'
+END WHERE display_id IS NULL;
+
+CREATE INDEX accounts_profile_kind ON accounts (profile_id, kind);
+
+-- +migrate Down
+DROP INDEX accounts_profile_kind;
+ALTER TABLE accounts DROP COLUMN kind;
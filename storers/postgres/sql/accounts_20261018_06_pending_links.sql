@@ -0,0 +1,13 @@
+-- +migrate Up
+CREATE TABLE account_pending_links (
+	token_hash VARCHAR(64) PRIMARY KEY,
+	account_id TEXT NOT NULL,
+	profile_id VARCHAR(36) NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX account_pending_links_expires_at ON account_pending_links (expires_at);
+
+-- +migrate Down
+DROP TABLE account_pending_links;
@@ -0,0 +1,17 @@
+-- +migrate Up
+CREATE TABLE account_history (
+	id VARCHAR(36) PRIMARY KEY,
+	account_id TEXT NOT NULL,
+	display_id TEXT,
+	previous_id TEXT,
+	previous_display_id TEXT,
+	profile_id VARCHAR(36) NOT NULL,
+	action VARCHAR(64) NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX account_history_profile_id ON account_history (profile_id);
+CREATE INDEX account_history_lower_previous_id ON account_history (LOWER(previous_id));
+
+-- +migrate Down
+DROP TABLE account_history;
@@ -0,0 +1,11 @@
+-- +migrate Up
+CREATE TABLE account_released_ids (
+	id_hash VARCHAR(255) PRIMARY KEY,
+	profile_id VARCHAR(36) NOT NULL,
+	released_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX account_released_ids_released_at ON account_released_ids (released_at);
+
+-- +migrate Down
+DROP TABLE account_released_ids;
@@ -0,0 +1,11 @@
+-- +migrate Up
+CREATE TABLE account_deny_rules (
+	id VARCHAR(36) PRIMARY KEY,
+	kind VARCHAR(16) NOT NULL,
+	pattern TEXT NOT NULL,
+	reason TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL
+);
+
+-- +migrate Down
+DROP TABLE account_deny_rules;
@@ -0,0 +1,8 @@
+-- +migrate Up
+ALTER TABLE account_pending_links ADD COLUMN display_id TEXT;
+
+-- +migrate Down
+-- Links with hashed Account IDs can't be confirmed without their display
+-- IDs. They're short-lived, so they're deleted instead of kept around.
+DELETE FROM account_pending_links WHERE display_id IS NOT NULL;
+ALTER TABLE account_pending_links DROP COLUMN display_id;
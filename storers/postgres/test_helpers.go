@@ -22,16 +22,19 @@ import (
 // against.
 type Factory struct {
 	db        *sql.DB
+	opts      []Option
 	databases map[string]*sql.DB
 	lock      sync.Mutex
 }
 
 // NewFactory returns a Factory that is ready to be used. The passed sql.DB
 // will be used as a control plane connection, but each test will have its own
-// database created for that test.
-func NewFactory(db *sql.DB) *Factory {
+// database created for that test. Storers are created with the passed
+// Options.
+func NewFactory(db *sql.DB, opts ...Option) *Factory {
 	return &Factory{
 		db:        db,
+		opts:      opts,
 		databases: map[string]*sql.DB{},
 	}
 }
@@ -86,7 +89,7 @@ func (p *Factory) NewStorer(ctx context.Context) (accounts.Storer, error) { //no
 		return nil, err
 	}
 
-	storer := NewStorer(ctx, newConn)
+	storer := NewStorer(ctx, newConn, p.opts...)
 
 	return storer, nil
 }
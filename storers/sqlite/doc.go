@@ -0,0 +1,21 @@
+// Package sqlite provides an implementation of the
+// lockbox.dev/accounts.Storer interface that stores data in a SQLite
+// database.
+//
+// This implementation is useful for small deployments and local development,
+// where running a database server is more trouble than it's worth but data
+// should survive the service restarting.
+//
+// The migrations to set up the database are embedded in the package, and can
+// be applied with the Migrate function. Account IDs are compared using
+// SQLite's NOCASE collation, which only folds the case of ASCII characters.
+//
+// Storers created WithReleaseQuarantine quarantine IDs released by deleting
+// Accounts, so other profiles can't claim them for a while. Released IDs are
+// stored by their hash in the account_released_ids table, and
+// Storer.DeleteExpiredReleasedIDs removes them once their quarantine ends.
+//
+// Account IDs are always stored in plain text; unlike the postgres Storer,
+// this Storer can't hash them. Deployments that need IDs hashed at rest
+// should use the postgres Storer.
+package sqlite
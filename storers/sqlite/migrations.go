@@ -0,0 +1,35 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"io/fs"
+	"net/http"
+
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+//go:embed sql/*.sql
+var migrationFiles embed.FS
+
+// Migrations returns the migrations used to set up a SQLite database for the
+// Storer, in a form that can be used with github.com/rubenv/sql-migrate.
+func Migrations() (migrate.MigrationSource, error) { //nolint:ireturn // sql-migrate works with the interface
+	dir, err := fs.Sub(migrationFiles, "sql")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.HttpFileSystemMigrationSource{FileSystem: http.FS(dir)}, nil
+}
+
+// Migrate applies any of the migrations embedded in this package that
+// haven't been applied to the database yet. It returns the number of
+// migrations that were applied.
+func Migrate(_ context.Context, conn *sql.DB) (int, error) {
+	migs, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	return migrate.Exec(conn, "sqlite3", migs, migrate.Up)
+}
@@ -0,0 +1,33 @@
+package sqlite
+
+import (
+	"time"
+
+	"lockbox.dev/accounts"
+)
+
+// Option configures optional behavior of a Storer.
+type Option func(*Storer)
+
+// WithReleaseQuarantine sets how long an ID released by deleting an Account
+// is kept from being used by other profiles. Create returns
+// ErrAccountIDRecentlyReleased for IDs that are still quarantined. A period
+// of zero turns the quarantine off, and stops released IDs from being
+// recorded. If WithReleaseQuarantine isn't used, released IDs aren't
+// quarantined; accounts.DefaultReleaseQuarantine is a reasonable period.
+//
+// Released IDs are recorded by their unkeyed hash, as the IDs of Accounts
+// are stored in plain text anyway.
+func WithReleaseQuarantine(period time.Duration) Option {
+	return func(s *Storer) {
+		s.quarantine = period
+	}
+}
+
+// WithClock sets the Clock the Storer uses to timestamp released IDs and
+// check quarantines. If WithClock isn't used, the system clock is used.
+func WithClock(clock accounts.Clock) Option {
+	return func(s *Storer) {
+		s.clock = clock
+	}
+}
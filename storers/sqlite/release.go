@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"lockbox.dev/accounts"
+)
+
+func (s *Storer) now() time.Time {
+	return accounts.Now(s.clock)
+}
+
+// releasedElsewhere returns a subquery that finds a release of `id` by a
+// profile other than `profileID` within the Storer's release quarantine.
+func (s *Storer) releasedElsewhere(id, profileID string) subquery {
+	return subquery{
+		sql:  "SELECT 1 FROM account_released_ids WHERE id_hash = ? AND profile_id != ? AND released_at > ?",
+		args: []interface{}{accounts.HashReleasedID(nil, id), profileID, toSQLite(s.now().Add(-s.quarantine))},
+	}
+}
+
+// release records that `profileID` released `id` in `txn`, if the Storer
+// has a release quarantine. Only the latest release of each ID is kept.
+func (s *Storer) release(ctx context.Context, txn *sql.Tx, id, profileID string) error {
+	if s.quarantine <= 0 {
+		return nil
+	}
+	_, err := txn.ExecContext(ctx, "INSERT INTO account_released_ids (id_hash, profile_id, released_at) VALUES (?, ?, ?) "+
+		"ON CONFLICT (id_hash) DO UPDATE SET profile_id = excluded.profile_id, released_at = excluded.released_at",
+		accounts.HashReleasedID(nil, id), profileID, toSQLite(s.now()))
+	return err
+}
+
+// releasedByOtherProfile returns whether a profile other than `profileID`
+// released `id` within the Storer's release quarantine.
+func (s *Storer) releasedByOtherProfile(ctx context.Context, id, profileID string) (bool, error) {
+	if s.quarantine <= 0 {
+		return false, nil
+	}
+	query := s.releasedElsewhere(id, profileID)
+	var found int
+	err := s.db.QueryRowContext(ctx, query.sql, query.args...).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CheckReleased returns an ErrAccountIDRecentlyReleased error if a profile
+// other than `profileID` released `id` within the Storer's release
+// quarantine.
+func (s *Storer) CheckReleased(ctx context.Context, id, profileID string) error {
+	released, err := s.releasedByOtherProfile(ctx, id, profileID)
+	if err != nil {
+		return err
+	}
+	if released {
+		return accounts.ErrAccountIDRecentlyReleased
+	}
+	return nil
+}
+
+// DeleteExpiredReleasedIDs removes every released ID whose quarantine has
+// ended from the SQLite database, returning the number removed.
+func (s *Storer) DeleteExpiredReleasedIDs(ctx context.Context) (int, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM account_released_ids WHERE released_at <= ?", toSQLite(s.now().Add(-s.quarantine)))
+	if err != nil {
+		return 0, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(rows), nil
+}
@@ -0,0 +1,13 @@
+-- +migrate Up
+CREATE TABLE accounts (
+	id TEXT COLLATE NOCASE PRIMARY KEY,
+	profile_id TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	last_used_at TIMESTAMP NOT NULL,
+	last_seen_at TIMESTAMP NOT NULL,
+	is_registration BOOLEAN,
+	CONSTRAINT unique_registration UNIQUE (profile_id, is_registration)
+);
+
+-- +migrate Down
+DROP TABLE accounts;
@@ -0,0 +1,9 @@
+-- +migrate Up
+CREATE TABLE account_released_ids (
+	id_hash TEXT PRIMARY KEY,
+	profile_id TEXT NOT NULL,
+	released_at TIMESTAMP NOT NULL
+);
+
+-- +migrate Down
+DROP TABLE account_released_ids;
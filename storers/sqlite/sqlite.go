@@ -0,0 +1,367 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"yall.in"
+
+	"lockbox.dev/accounts"
+)
+
+const (
+	accountColumns = "id, profile_id, created_at, last_used_at, last_seen_at, is_registration"
+)
+
+// Storer provides a SQLite-backed implementation of the Storer interface.
+type Storer struct {
+	db         *sql.DB
+	quarantine time.Duration
+	clock      accounts.Clock
+}
+
+// NewStorer returns a Storer instance that is backed by the specified
+// *sql.DB, configured by `opts`. The returned Storer instance is ready to be
+// used as a Storer, once the database has been migrated.
+func NewStorer(_ context.Context, conn *sql.DB, opts ...Option) *Storer {
+	storer := &Storer{db: conn}
+	for _, opt := range opts {
+		opt(storer)
+	}
+	return storer
+}
+
+func rollback(ctx context.Context, txn *sql.Tx) {
+	if err := txn.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		yall.FromContext(ctx).WithError(err).Error("error rolling back transaction")
+	}
+}
+
+// timestamps are always stored in UTC, so they sort correctly
+func toSQLite(t time.Time) time.Time {
+	return t.UTC()
+}
+
+func registration(account accounts.Account) sql.NullBool {
+	return sql.NullBool{
+		Valid: account.IsRegistration,
+		Bool:  account.IsRegistration,
+	}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAccount(row scanner) (accounts.Account, error) {
+	var account accounts.Account
+	var isRegistration sql.NullBool
+	err := row.Scan(&account.ID, &account.ProfileID, &account.Created, &account.LastUsed, &account.LastSeen, &isRegistration)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	account.IsRegistration = isRegistration.Valid && isRegistration.Bool
+	return account, nil
+}
+
+// subquery is a SELECT statement and its arguments.
+type subquery struct {
+	sql  string
+	args []interface{}
+}
+
+// profileInUse returns a subquery that finds an Account associated with
+// `profileID`.
+func profileInUse(profileID string) subquery {
+	return subquery{sql: "SELECT 1 FROM accounts WHERE profile_id = ?", args: []interface{}{profileID}}
+}
+
+// insertSQL returns a statement that inserts `account` unless one of the
+// `unless` subqueries finds a row, and its arguments.
+func insertSQL(account accounts.Account, unless ...subquery) (string, []interface{}) {
+	args := []interface{}{
+		account.ID, account.ProfileID, toSQLite(account.Created),
+		toSQLite(account.LastUsed), toSQLite(account.LastSeen), registration(account),
+	}
+	if len(unless) < 1 {
+		return "INSERT INTO accounts (" + accountColumns + ") VALUES (?, ?, ?, ?, ?, ?)", args
+	}
+	conds := make([]string, 0, len(unless))
+	for _, query := range unless {
+		conds = append(conds, "NOT EXISTS ("+query.sql+")")
+		args = append(args, query.args...)
+	}
+	return "INSERT INTO accounts (" + accountColumns + ") SELECT ?, ?, ?, ?, ?, ? WHERE " + strings.Join(conds, " AND "), args
+}
+
+// insertErr translates the constraint violations inserting an Account can
+// cause into the errors the Storer interface defines.
+func insertErr(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.ExtendedCode { //nolint:exhaustive // we only care about constraint violations
+		case sqlite3.ErrConstraintPrimaryKey:
+			return accounts.ErrAccountAlreadyExists
+		case sqlite3.ErrConstraintUnique:
+			return accounts.ErrProfileIDAlreadyExists
+		}
+	}
+	return err
+}
+
+// Create inserts the passed Account into the SQLite database, returning an
+// ErrAccountAlreadyExists error if the Account's ID already exists in the
+// database, an ErrAccountIDRecentlyReleased error if another profile
+// released the ID within the Storer's release quarantine, or an
+// ErrProfileIDAlreadyExists error if the Account is a registration and its
+// ProfileID is already in use.
+func (s *Storer) Create(ctx context.Context, account accounts.Account) error {
+	var unless []subquery
+	if account.IsRegistration {
+		// registrations can only be inserted if nothing is using
+		// their profile ID yet
+		unless = append(unless, profileInUse(account.ProfileID))
+	}
+	if s.quarantine > 0 {
+		unless = append(unless, s.releasedElsewhere(account.ID, account.ProfileID))
+	}
+	query, args := insertSQL(account, unless...)
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return insertErr(err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows > 0 {
+		return nil
+	}
+	// the Account wasn't inserted because its ID was released or its
+	// profile ID is in use, but a conflicting ID takes precedence over
+	// both, and a released ID over a profile ID in use
+	_, err = s.Get(ctx, account.ID)
+	if err == nil {
+		return accounts.ErrAccountAlreadyExists
+	}
+	if !errors.Is(err, accounts.ErrAccountNotFound) {
+		return err
+	}
+	err = s.CheckReleased(ctx, account.ID, account.ProfileID)
+	if err != nil {
+		return err
+	}
+	return accounts.ErrProfileIDAlreadyExists
+}
+
+// Replace deletes the Account with the same ID as the passed Account from
+// the SQLite database, if there is one, and inserts the passed Account, in a
+// single transaction, without releasing the ID. It returns an
+// ErrProfileIDAlreadyExists error under the same conditions Create does, in
+// which case the existing Account is left alone.
+func (s *Storer) Replace(ctx context.Context, account accounts.Account) error {
+	txn, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer rollback(ctx, txn)
+	_, err = txn.ExecContext(ctx, "DELETE FROM accounts WHERE id = ?", account.ID)
+	if err != nil {
+		return err
+	}
+	var unless []subquery
+	if account.IsRegistration {
+		unless = append(unless, profileInUse(account.ProfileID))
+	}
+	query, args := insertSQL(account, unless...)
+	res, err := txn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return insertErr(err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows < 1 {
+		return accounts.ErrProfileIDAlreadyExists
+	}
+	return txn.Commit()
+}
+
+// Get retrieves the Account specified by the passed ID from the SQLite
+// database. If no Account matches the passed ID, an ErrAccountNotFound error
+// is returned.
+func (s *Storer) Get(ctx context.Context, id string) (accounts.Account, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+accountColumns+" FROM accounts WHERE id = ?", id)
+	account, err := scanAccount(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return accounts.Account{}, accounts.ErrAccountNotFound
+	}
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	return account, nil
+}
+
+// Update applies the passed Change to the Account in the SQLite database
+// that matches the specified ID, if any Account matches the specified ID.
+func (s *Storer) Update(ctx context.Context, id string, change accounts.Change) error {
+	if change.IsEmpty() {
+		return nil
+	}
+	var sets []string
+	var args []interface{}
+	if change.LastUsed != nil {
+		sets = append(sets, "last_used_at = ?")
+		args = append(args, toSQLite(*change.LastUsed))
+	}
+	if change.LastSeen != nil {
+		sets = append(sets, "last_seen_at = ?")
+		args = append(args, toSQLite(*change.LastSeen))
+	}
+	query := "UPDATE accounts SET " + sets[0]
+	for _, set := range sets[1:] {
+		query += ", " + set
+	}
+	query += " WHERE id = ?"
+	args = append(args, id)
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// deleteReleasing runs `query`, which deletes at most one Account and
+// returns its ID and profile ID, and releases the deleted Account's ID, in a
+// single transaction. It returns whether an Account was deleted.
+func (s *Storer) deleteReleasing(ctx context.Context, query string, id string) (bool, error) {
+	txn, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer rollback(ctx, txn)
+	var deletedID, profileID string
+	err = txn.QueryRowContext(ctx, query, id).Scan(&deletedID, &profileID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	err = s.release(ctx, txn, deletedID, profileID)
+	if err != nil {
+		return false, err
+	}
+	return true, txn.Commit()
+}
+
+// Delete removes the Account that matches the passed ID from the SQLite
+// database, if any Account matches the passed ID, and releases its ID.
+func (s *Storer) Delete(ctx context.Context, id string) error {
+	_, err := s.deleteReleasing(ctx, "DELETE FROM accounts WHERE id = ? RETURNING id, profile_id", id)
+	return err
+}
+
+// DeleteUnlessLast removes the Account that matches the passed ID from the
+// SQLite database and releases its ID, unless it is the only Account
+// associated with its profile, in which case an ErrLastAccount error is
+// returned. The check and the delete are a single statement, so concurrent
+// deletes can't both remove the last Accounts of a profile.
+func (s *Storer) DeleteUnlessLast(ctx context.Context, id string) error {
+	deleted, err := s.deleteReleasing(ctx, "DELETE FROM accounts WHERE id = ? AND EXISTS "+
+		"(SELECT 1 FROM accounts AS other WHERE other.profile_id = accounts.profile_id AND other.id != accounts.id) "+
+		"RETURNING id, profile_id", id)
+	if err != nil {
+		return err
+	}
+	if deleted {
+		return nil
+	}
+	// nothing was deleted because the Account doesn't exist, or because
+	// it's the last one in its profile
+	_, err = s.Get(ctx, id)
+	if errors.Is(err, accounts.ErrAccountNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return accounts.ErrLastAccount
+}
+
+// EraseProfile removes every Account associated with the passed profile ID
+// from the SQLite database in a single statement, returning the Accounts
+// that were removed.
+func (s *Storer) EraseProfile(ctx context.Context, profileID string) ([]accounts.Account, error) {
+	rows, err := s.db.QueryContext(ctx, "DELETE FROM accounts WHERE profile_id = ? RETURNING "+accountColumns, profileID) //nolint:sqlclosecheck // the closeRows helper isn't picked up
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+	accts := []accounts.Account{}
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return accts, err
+		}
+		accts = append(accts, account)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return accts, nil
+}
+
+// ListByProfile returns all the Accounts associated with the passed profile ID,
+// sorted with the most recently used Accounts coming first.
+func (s *Storer) ListByProfile(ctx context.Context, profileID string) ([]accounts.Account, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+accountColumns+" FROM accounts WHERE profile_id = ? ORDER BY last_used_at DESC", profileID) //nolint:sqlclosecheck // the closeRows helper isn't picked up
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+	var accts []accounts.Account
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return accts, err
+		}
+		accts = append(accts, account)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	accounts.ByLastUsedDesc(accts)
+	return accts, nil
+}
+
+// ListAll returns up to `limit` Accounts, ordered by their IDs without
+// regard to case, starting after the Account whose ID is `after`. If `after`
+// is empty, the Accounts are returned starting from the beginning.
+func (s *Storer) ListAll(ctx context.Context, after string, limit int) ([]accounts.Account, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+accountColumns+" FROM accounts WHERE id > ? ORDER BY id LIMIT ?", after, limit) //nolint:sqlclosecheck // the closeRows helper isn't picked up
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+	accts := []accounts.Account{}
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return accts, err
+		}
+		accts = append(accts, account)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return accts, nil
+}
+
+func closeRows(ctx context.Context, rows *sql.Rows) {
+	if err := rows.Close(); err != nil {
+		yall.FromContext(ctx).WithError(err).Error("failed to close rows")
+	}
+}
@@ -0,0 +1,77 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"lockbox.dev/accounts"
+)
+
+// Factory is a generator of Storers for testing purposes. It creates a
+// temporary directory, and every Storer it creates gets its own database
+// file in that directory.
+type Factory struct {
+	dir       string
+	opts      []Option
+	databases []*sql.DB
+	lock      sync.Mutex
+}
+
+// NewFactory returns a Factory that is ready to be used. The databases
+// created by the Factory will be stored in a new temporary directory, which
+// is removed by TeardownStorers, and its Storers are configured by `opts`.
+func NewFactory(opts ...Option) (*Factory, error) {
+	dir, err := os.MkdirTemp("", "accounts_test_")
+	if err != nil {
+		return nil, err
+	}
+	return &Factory{dir: dir, opts: opts}, nil
+}
+
+// NewStorer creates a new database file in the Factory's temporary
+// directory, runs the migrations against it, and returns a Storer that uses
+// it.
+func (f *Factory) NewStorer(ctx context.Context) (accounts.Storer, error) { //nolint:ireturn // interface requires returning an interface
+	file, err := os.CreateTemp(f.dir, "*.db")
+	if err != nil {
+		log.Printf("Error creating database file: %+v\n", err)
+		return nil, err
+	}
+	err = file.Close()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sql.Open("sqlite3", "file:"+filepath.ToSlash(file.Name())+"?_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+
+	f.lock.Lock()
+	f.databases = append(f.databases, conn)
+	f.lock.Unlock()
+
+	_, err = Migrate(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	return NewStorer(ctx, conn, f.opts...), nil
+}
+
+// TeardownStorers closes all the databases created by NewStorer and removes
+// the temporary directory they were stored in.
+func (f *Factory) TeardownStorers() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for _, conn := range f.databases {
+		err := conn.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(f.dir)
+}
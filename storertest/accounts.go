@@ -0,0 +1,680 @@
+package storertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"lockbox.dev/accounts"
+)
+
+func testCreateAndGetAccount(t *testing.T, storer accounts.Storer, ctx context.Context) {
+	clock := newTestClock()
+	account := accounts.Account{
+		ID:        "paddy@impractical.co",
+		ProfileID: uuidOrFail(t),
+		Created:   clock.Now(),
+		LastUsed:  clock.Now(),
+		LastSeen:  clock.Now(),
+	}
+	err := storer.Create(ctx, account)
+	if err != nil {
+		t.Fatalf("Unexpected error creating account: %+v\n", err)
+	}
+
+	resp, err := storer.Get(ctx, account.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error retrieving account: %+v\n", err)
+	}
+	if diff := cmp.Diff(account, resp); diff != "" {
+		t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+	}
+}
+
+func testCreateWithDefaults(t *testing.T, storer accounts.Storer, ctx context.Context) {
+	clock := newTestClock()
+	account := accounts.FillDefaultsWithClock(accounts.Account{
+		ID:        "paddy@impractical.co",
+		ProfileID: uuidOrFail(t),
+	}, clock)
+	clock.Advance(time.Hour)
+
+	err := storer.Create(ctx, account)
+	if err != nil {
+		t.Fatalf("Unexpected error creating account: %+v\n", err)
+	}
+
+	resp, err := storer.Get(ctx, account.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error retrieving account: %+v\n", err)
+	}
+	expected := newTestClock().Now()
+	if !resp.Created.Equal(expected) {
+		t.Errorf("Expected Created to be %s, got %s", expected, resp.Created)
+	}
+	if !resp.LastUsed.Equal(expected) {
+		t.Errorf("Expected LastUsed to be %s, got %s", expected, resp.LastUsed)
+	}
+	if !resp.LastSeen.Equal(expected) {
+		t.Errorf("Expected LastSeen to be %s, got %s", expected, resp.LastSeen)
+	}
+}
+
+func testGetNonexistentAccount(t *testing.T, storer accounts.Storer, ctx context.Context) {
+	_, err := storer.Get(ctx, "myaccount@impractical.co")
+	if !errors.Is(err, accounts.ErrAccountNotFound) {
+		t.Fatalf("Expected ErrAccountNotFound, got %v\n", err)
+	}
+}
+
+func testCreateDuplicateID(t *testing.T, storer accounts.Storer, ctx context.Context) {
+	clock := newTestClock()
+	account := accounts.Account{
+		ID:        "paddy@impractical.co",
+		ProfileID: uuidOrFail(t),
+		Created:   clock.Now(),
+		LastUsed:  clock.Now(),
+		LastSeen:  clock.Now(),
+	}
+	err := storer.Create(ctx, account)
+	if err != nil {
+		t.Fatalf("Unexpected error creating account: %+v\n", err)
+	}
+	account2 := accounts.Account{
+		ID:        account.ID,
+		ProfileID: uuidOrFail(t),
+		Created:   clock.Now().Add(time.Hour),
+		LastUsed:  clock.Now().Add(time.Hour),
+		LastSeen:  clock.Now().Add(time.Hour),
+	}
+
+	err = storer.Create(ctx, account2)
+	if !errors.Is(err, accounts.ErrAccountAlreadyExists) {
+		t.Fatalf("Expected ErrAccountAlreadyExists, got (%T) %s", err, err.Error())
+	}
+
+	// we shouldn't have changed anything about what was stored
+	resp, err := storer.Get(ctx, account.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error retrieving account: %+v\n", err)
+	}
+
+	if diff := cmp.Diff(account, resp); diff != "" {
+		t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+	}
+}
+
+func testCreateSecondaryAccounts(t *testing.T, storer accounts.Storer, ctx context.Context) {
+	clock := newTestClock()
+	account := accounts.Account{
+		ID:             "paddy@impractical.co",
+		ProfileID:      uuidOrFail(t),
+		Created:        clock.Now(),
+		LastUsed:       clock.Now(),
+		LastSeen:       clock.Now(),
+		IsRegistration: true,
+	}
+	err := storer.Create(ctx, account)
+	if err != nil {
+		t.Fatalf("Unexpected error creating account: %+v\n", err)
+	}
+	account2 := accounts.Account{
+		ID:        "paddy@impracticallabs.com",
+		ProfileID: account.ProfileID,
+		Created:   clock.Now().Add(time.Hour),
+		LastUsed:  clock.Now().Add(time.Hour),
+		LastSeen:  clock.Now().Add(time.Hour),
+	}
+
+	err = storer.Create(ctx, account2)
+	if err != nil {
+		t.Fatalf("Unexpected error creating second account: %+v\n", err)
+	}
+	account3 := accounts.Account{
+		ID:        "paddy@carvers.co",
+		ProfileID: account.ProfileID,
+		Created:   clock.Now().Add(time.Hour),
+		LastUsed:  clock.Now().Add(time.Hour),
+		LastSeen:  clock.Now().Add(time.Hour),
+	}
+
+	err = storer.Create(ctx, account3)
+	if err != nil {
+		t.Fatalf("Unexpected error creating third account: %+v\n", err)
+	}
+
+	resp, err := storer.Get(ctx, account.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error retrieving account: %+v\n", err)
+	}
+
+	if diff := cmp.Diff(account, resp); diff != "" {
+		t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+	}
+
+	resp, err = storer.Get(ctx, account2.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error retrieving account: %+v\n", err)
+	}
+
+	if diff := cmp.Diff(account2, resp); diff != "" {
+		t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+	}
+
+	resp, err = storer.Get(ctx, account3.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error retrieving account: %+v\n", err)
+	}
+
+	if diff := cmp.Diff(account3, resp); diff != "" {
+		t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+	}
+}
+
+func testCreateDuplicateRegistration(t *testing.T, storer accounts.Storer, ctx context.Context) {
+	clock := newTestClock()
+	account := accounts.Account{
+		ID:             "paddy@impractical.co",
+		ProfileID:      uuidOrFail(t),
+		Created:        clock.Now(),
+		LastUsed:       clock.Now(),
+		LastSeen:       clock.Now(),
+		IsRegistration: true,
+	}
+	err := storer.Create(ctx, account)
+	if err != nil {
+		t.Fatalf("Unexpected error creating account: %+v\n", err)
+	}
+	account2 := accounts.Account{
+		ID:             "paddy@impracticallabs.com",
+		ProfileID:      account.ProfileID,
+		Created:        clock.Now().Add(time.Hour),
+		LastUsed:       clock.Now().Add(time.Hour),
+		LastSeen:       clock.Now().Add(time.Hour),
+		IsRegistration: true,
+	}
+
+	err = storer.Create(ctx, account2)
+	if !errors.Is(err, accounts.ErrProfileIDAlreadyExists) {
+		t.Fatalf("Expected ErrProfileIDAlreadyExists, got (%T) %v", err, err)
+	}
+
+	// we shouldn't have changed anything about what was stored
+	resp, err := storer.Get(ctx, account.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error retrieving account: %+v\n", err)
+	}
+
+	if diff := cmp.Diff(account, resp); diff != "" {
+		t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+	}
+}
+
+func testCreateMultipleAccounts(t *testing.T, storer accounts.Storer, ctx context.Context) {
+	clock := newTestClock()
+	account := accounts.Account{
+		ID:        "paddy@impractical.co",
+		ProfileID: uuidOrFail(t),
+		Created:   clock.Now(),
+		LastUsed:  clock.Now(),
+		LastSeen:  clock.Now(),
+	}
+	err := storer.Create(ctx, account)
+	if err != nil {
+		t.Fatalf("Unexpected error creating account: %+v\n", err)
+	}
+	account2 := account
+	account2.ID = "paddy@impracticallabs.com"
+	err = storer.Create(ctx, account2)
+	if err != nil {
+		t.Fatalf("Unexpected error creating account: %+v\n", err)
+	}
+
+	resp, err := storer.Get(ctx, account.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error retrieving account: %+v\n", err)
+	}
+	if diff := cmp.Diff(account, resp); diff != "" {
+		t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+	}
+
+	resp, err = storer.Get(ctx, account2.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error retrieving account: %+v\n", err)
+	}
+	if diff := cmp.Diff(account2, resp); diff != "" {
+		t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+	}
+}
+
+func testListAccountsByProfile(t *testing.T, storer accounts.Storer, ctx context.Context) {
+	clock := newTestClock()
+	account := accounts.Account{
+		ID:        "paddy@impractical.co",
+		ProfileID: uuidOrFail(t),
+		Created:   clock.Now(),
+		LastUsed:  clock.Now(),
+		LastSeen:  clock.Now(),
+	}
+	err := storer.Create(ctx, account)
+	if err != nil {
+		t.Fatalf("Unexpected error creating account: %+v\n", err)
+	}
+	account2 := account
+	account2.ID = "paddy@impracticallabs.com"
+	account2.LastUsed = account2.LastUsed.Add(-1 * time.Minute)
+	err = storer.Create(ctx, account2)
+	if err != nil {
+		t.Fatalf("Unexpected error creating account: %+v\n", err)
+	}
+
+	accounts, err := storer.ListByProfile(ctx, account.ProfileID)
+	if err != nil {
+		t.Fatalf("Unexpected error listing accounts: %+v\n", err)
+	}
+
+	if len(accounts) != 2 {
+		t.Fatalf("Expected %d accounts, got %d: %+v\n", 2, len(accounts), accounts)
+	}
+	if diff := cmp.Diff(account, accounts[0]); diff != "" {
+		t.Errorf("Unexpected diff for %s (-wanted, +got): %s", account.ID, diff)
+	}
+	if diff := cmp.Diff(account2, accounts[1]); diff != "" {
+		t.Errorf("Unexpected diff for %s (-wanted, +got): %s", account2.ID, diff)
+	}
+}
+
+func testUpdateOneOfMany(t *testing.T, storer accounts.Storer, ctx context.Context) {
+	clock := newTestClock()
+	for iter := 1; iter < changeVariations; iter++ {
+		iter := iter
+		t.Run(fmt.Sprintf("iter=%d", iter), func(t *testing.T) {
+			t.Parallel()
+
+			account := accounts.Account{
+				ID:        fmt.Sprintf("paddy+%d@impractical.co", iter),
+				ProfileID: uuidOrFail(t),
+				Created:   clock.Now(),
+				LastUsed:  clock.Now(),
+				LastSeen:  clock.Now(),
+			}
+			err := storer.Create(ctx, account)
+			if err != nil {
+				t.Fatalf("Unexpected error creating account: %+v\n", err)
+			}
+
+			var throwaways []accounts.Account
+			for throwawayNum := 0; throwawayNum < 5; throwawayNum++ {
+				throwaway := accounts.Account{
+					ID:        fmt.Sprintf("paddy+%d+%d@impractical.co", iter, throwawayNum),
+					ProfileID: uuidOrFail(t),
+					Created:   clock.Now().Add(time.Duration(throwawayNum) * time.Minute),
+					LastUsed:  clock.Now().Add(time.Duration(throwawayNum) * time.Hour),
+					LastSeen:  clock.Now().Add(time.Duration(throwawayNum) * time.Second),
+				}
+				if throwawayNum%2 == 0 {
+					throwaway.ProfileID = account.ProfileID
+				}
+
+				err = storer.Create(ctx, throwaway)
+				if err != nil {
+					t.Fatalf("Unexpected error creating account: %+v\n", err)
+				}
+				throwaways = append(throwaways, throwaway)
+			}
+
+			var change accounts.Change
+			if iter&changeLastSeen != 0 {
+				seen := clock.Now().Add(time.Duration(iter) * time.Minute)
+				change.LastSeen = &seen
+			}
+			if iter&changeLastUsed != 0 {
+				used := clock.Now().Add(time.Duration(iter) * time.Hour)
+				change.LastUsed = &used
+			}
+			expectation := accounts.Apply(change, account)
+
+			err = storer.Update(ctx, account.ID, change)
+			if err != nil {
+				t.Fatalf("Unexpected error updating account: %+v\n", err)
+			}
+			result, err := storer.Get(ctx, account.ID)
+			if err != nil {
+				t.Fatalf("Unexpected error retrieving account: %+v\n", err)
+			}
+			if diff := cmp.Diff(expectation, result); diff != "" {
+				t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+			}
+			for _, throwaway := range throwaways {
+				result, err := storer.Get(ctx, throwaway.ID)
+				if err != nil {
+					t.Errorf("Unexpected error retrieving account: %+v\n", err)
+				}
+				if diff := cmp.Diff(throwaway, result); diff != "" {
+					t.Errorf("Unexpected diff for %s (-wanted, +got): %s", throwaway.ID, diff)
+				}
+			}
+		})
+	}
+}
+
+func testUpdateNonExistent(t *testing.T, storer accounts.Storer, ctx context.Context) {
+	// updating an account that doesn't exist is not an error
+	clock := newTestClock()
+	used := clock.Now()
+	change := accounts.Change{
+		LastUsed: &used,
+	}
+	err := storer.Update(ctx, "notanactualaccount@impractical.co", change)
+	if err != nil {
+		t.Fatalf("Unexpected error updating account: %+v\n", err)
+	}
+}
+
+func testUpdateNoChange(t *testing.T, storer accounts.Storer, ctx context.Context) {
+	// updating an account with an empty change should not error
+	var change accounts.Change
+	err := storer.Update(ctx, "notanactualaccount@impractical.co", change)
+	if err != nil {
+		t.Fatalf("Unexpected error updating account: %+v\n", err)
+	}
+}
+
+func testDeleteOneOfMany(t *testing.T, storer accounts.Storer, ctx context.Context) {
+	clock := newTestClock()
+	account := accounts.Account{
+		ID:        "paddy@impractical.co",
+		ProfileID: uuidOrFail(t),
+		Created:   clock.Now(),
+		LastUsed:  clock.Now(),
+		LastSeen:  clock.Now(),
+	}
+	err := storer.Create(ctx, account)
+	if err != nil {
+		t.Fatalf("Unexpected error creating account: %+v\n", err)
+	}
+
+	var throwaways []accounts.Account
+	for throwawayNum := 0; throwawayNum < 5; throwawayNum++ {
+		throwaway := accounts.Account{
+			ID:        fmt.Sprintf("paddy+%d@impractical.co", throwawayNum),
+			ProfileID: uuidOrFail(t),
+			Created:   clock.Now().Add(time.Duration(throwawayNum) * time.Minute),
+			LastUsed:  clock.Now().Add(time.Duration(throwawayNum) * time.Hour),
+			LastSeen:  clock.Now().Add(time.Duration(throwawayNum) * time.Second),
+		}
+		if throwawayNum%2 == 0 {
+			throwaway.ProfileID = account.ProfileID
+		}
+
+		err = storer.Create(ctx, throwaway)
+		if err != nil {
+			t.Fatalf("Unexpected error creating account: %+v\n", err)
+		}
+		throwaways = append(throwaways, throwaway)
+	}
+
+	err = storer.Delete(ctx, account.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error deleting account: %+v\n", err)
+	}
+	res, err := storer.Get(ctx, account.ID)
+	if !errors.Is(err, accounts.ErrAccountNotFound) {
+		t.Logf("Account: %+v\n", res)
+		t.Errorf("Expected error to be ErrAccountNotFound, got %v\n", err)
+	}
+	for _, throwaway := range throwaways {
+		result, err := storer.Get(ctx, throwaway.ID)
+		if err != nil {
+			t.Errorf("Unexpected error retrieving account: %+v\n", err)
+		}
+		if diff := cmp.Diff(throwaway, result); diff != "" {
+			t.Errorf("Unexpected diff for %s (-wanted, +got): %s", throwaway.ID, diff)
+		}
+	}
+}
+
+func testDeleteNonExistent(t *testing.T, storer accounts.Storer, ctx context.Context) {
+	// we shouldn't get an error deleting an account that doesn't exist
+	err := storer.Delete(ctx, "notarealaccount@impractical.co")
+	if err != nil {
+		t.Fatalf("Unexpected error deleting account: %+v\n", err)
+	}
+}
+
+func testDeleteUnlessLast(t *testing.T, storer accounts.Storer, ctx context.Context) {
+	guard, ok := storer.(accounts.LastAccountGuard)
+	if !ok {
+		t.Skipf("%T doesn't implement accounts.LastAccountGuard", storer)
+	}
+	clock := newTestClock()
+	profileID := uuidOrFail(t)
+	first := accounts.Account{
+		ID:             "paddy@impractical.co",
+		ProfileID:      profileID,
+		Created:        clock.Now(),
+		LastUsed:       clock.Now(),
+		LastSeen:       clock.Now(),
+		IsRegistration: true,
+	}
+	second := accounts.Account{
+		ID:        "paddy@carvers.co",
+		ProfileID: profileID,
+		Created:   clock.Now(),
+		LastUsed:  clock.Now(),
+		LastSeen:  clock.Now(),
+	}
+	for _, account := range []accounts.Account{first, second} {
+		err := storer.Create(ctx, account)
+		if err != nil {
+			t.Fatalf("Unexpected error creating account: %+v\n", err)
+		}
+	}
+
+	err := guard.DeleteUnlessLast(ctx, "PADDY@impractical.co")
+	if err != nil {
+		t.Fatalf("Unexpected error deleting account: %+v\n", err)
+	}
+	_, err = storer.Get(ctx, first.ID)
+	if !errors.Is(err, accounts.ErrAccountNotFound) {
+		t.Errorf("Expected error to be ErrAccountNotFound, got %v\n", err)
+	}
+
+	err = guard.DeleteUnlessLast(ctx, second.ID)
+	if !errors.Is(err, accounts.ErrLastAccount) {
+		t.Errorf("Expected error to be ErrLastAccount, got %v\n", err)
+	}
+	result, err := storer.Get(ctx, second.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error retrieving account: %+v\n", err)
+	}
+	if diff := cmp.Diff(second, result); diff != "" {
+		t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+	}
+
+	// we shouldn't get an error deleting an account that doesn't exist
+	err = guard.DeleteUnlessLast(ctx, "notarealaccount@impractical.co")
+	if err != nil {
+		t.Errorf("Unexpected error deleting nonexistent account: %+v\n", err)
+	}
+}
+
+func testDeleteUnlessLastConcurrently(t *testing.T, storer accounts.Storer, ctx context.Context) {
+	guard, ok := storer.(accounts.LastAccountGuard)
+	if !ok {
+		t.Skipf("%T doesn't implement accounts.LastAccountGuard", storer)
+	}
+	clock := newTestClock()
+	for iter := 0; iter < 10; iter++ {
+		profileID := uuidOrFail(t)
+		var ids []string
+		for num := 0; num < 2; num++ {
+			account := accounts.Account{
+				ID:        fmt.Sprintf("paddy+%d+%d@impractical.co", iter, num),
+				ProfileID: profileID,
+				Created:   clock.Now(),
+				LastUsed:  clock.Now(),
+				LastSeen:  clock.Now(),
+			}
+			err := storer.Create(ctx, account)
+			if err != nil {
+				t.Fatalf("Unexpected error creating account: %+v\n", err)
+			}
+			ids = append(ids, account.ID)
+		}
+
+		errs := make([]error, len(ids))
+		var wg sync.WaitGroup
+		for pos, id := range ids {
+			wg.Add(1)
+			go func(pos int, id string) {
+				defer wg.Done()
+				errs[pos] = guard.DeleteUnlessLast(ctx, id)
+			}(pos, id)
+		}
+		wg.Wait()
+
+		var deleted, refused int
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				deleted++
+			case errors.Is(err, accounts.ErrLastAccount):
+				refused++
+			default:
+				t.Errorf("Unexpected error deleting account: %+v\n", err)
+			}
+		}
+		if deleted != 1 || refused != 1 {
+			t.Errorf("Expected one delete to succeed and one to be refused, got %d and %d", deleted, refused)
+		}
+		remaining, err := storer.ListByProfile(ctx, profileID)
+		if err != nil {
+			t.Fatalf("Unexpected error listing accounts: %+v\n", err)
+		}
+		if len(remaining) != 1 {
+			t.Errorf("Expected one account to remain, got %d: %+v", len(remaining), remaining)
+		}
+	}
+}
+
+func testEraseProfile(t *testing.T, storer accounts.Storer, ctx context.Context) {
+	eraser, ok := storer.(accounts.ProfileEraser)
+	if !ok {
+		t.Skipf("%T doesn't implement accounts.ProfileEraser", storer)
+	}
+	clock := newTestClock()
+	profileID, otherProfileID := uuidOrFail(t), uuidOrFail(t)
+	var erased, kept []accounts.Account
+	for num := 0; num < 5; num++ {
+		account := accounts.Account{
+			ID:             fmt.Sprintf("paddy+%d@impractical.co", num),
+			ProfileID:      profileID,
+			Created:        clock.Now().Add(time.Duration(num) * time.Minute),
+			LastUsed:       clock.Now().Add(time.Duration(num) * time.Hour),
+			LastSeen:       clock.Now().Add(time.Duration(num) * time.Second),
+			IsRegistration: num == 0,
+		}
+		if num%2 == 1 {
+			account.ProfileID = otherProfileID
+			account.IsRegistration = num == 1
+		}
+		err := storer.Create(ctx, account)
+		if err != nil {
+			t.Fatalf("Unexpected error creating account: %+v\n", err)
+		}
+		if account.ProfileID == profileID {
+			erased = append(erased, account)
+		} else {
+			kept = append(kept, account)
+		}
+	}
+
+	result, err := eraser.EraseProfile(ctx, profileID)
+	if err != nil {
+		t.Fatalf("Unexpected error erasing profile: %+v\n", err)
+	}
+	byID := cmpopts.SortSlices(func(a, b accounts.Account) bool { return a.ID < b.ID })
+	if diff := cmp.Diff(erased, result, byID); diff != "" {
+		t.Errorf("Unexpected erased accounts (-wanted, +got): %s", diff)
+	}
+	for _, account := range erased {
+		_, err := storer.Get(ctx, account.ID)
+		if !errors.Is(err, accounts.ErrAccountNotFound) {
+			t.Errorf("Expected error retrieving %s to be ErrAccountNotFound, got %v\n", account.ID, err)
+		}
+	}
+	remaining, err := storer.ListByProfile(ctx, otherProfileID)
+	if err != nil {
+		t.Fatalf("Unexpected error listing accounts: %+v\n", err)
+	}
+	if diff := cmp.Diff(kept, remaining, byID); diff != "" {
+		t.Errorf("Unexpected remaining accounts (-wanted, +got): %s", diff)
+	}
+
+	// erasing a profile with no accounts isn't an error
+	result, err = eraser.EraseProfile(ctx, profileID)
+	if err != nil {
+		t.Fatalf("Unexpected error erasing profile again: %+v\n", err)
+	}
+	if len(result) != 0 {
+		t.Errorf("Expected no accounts to be erased, got %+v", result)
+	}
+}
+
+func testListAll(t *testing.T, storer accounts.Storer, ctx context.Context) {
+	enumerator, ok := storer.(accounts.Enumerator)
+	if !ok {
+		t.Skipf("%T doesn't implement accounts.Enumerator", storer)
+	}
+	clock := newTestClock()
+	expected := map[string]accounts.Account{}
+	for i := 0; i < 7; i++ {
+		account := accounts.Account{
+			ID:        fmt.Sprintf("Paddy+%d@impractical.co", i),
+			ProfileID: uuidOrFail(t),
+			Created:   clock.Now().Add(time.Duration(i) * time.Minute),
+			LastUsed:  clock.Now().Add(time.Duration(i) * time.Hour),
+			LastSeen:  clock.Now().Add(time.Duration(i) * time.Second),
+		}
+		err := storer.Create(ctx, account)
+		if err != nil {
+			t.Fatalf("Unexpected error creating account: %+v\n", err)
+		}
+		expected[account.ID] = account
+	}
+
+	var after string
+	seen := map[string]accounts.Account{}
+	for pages := 0; ; pages++ {
+		if pages > len(expected) {
+			t.Fatalf("Expected to run out of accounts after %d pages", len(expected))
+		}
+		page, err := enumerator.ListAll(ctx, after, 3)
+		if err != nil {
+			t.Fatalf("Unexpected error listing accounts: %+v\n", err)
+		}
+		if len(page) > 3 {
+			t.Fatalf("Expected at most %d accounts, got %d: %+v\n", 3, len(page), page)
+		}
+		if len(page) == 0 {
+			break
+		}
+		for _, account := range page {
+			if _, ok := seen[account.ID]; ok {
+				t.Errorf("Account %s returned more than once", account.ID)
+			}
+			seen[account.ID] = account
+		}
+		after = page[len(page)-1].ID
+	}
+	if diff := cmp.Diff(expected, seen); diff != "" {
+		t.Errorf("Unexpected diff (-wanted, +got): %s", diff)
+	}
+}
@@ -0,0 +1,399 @@
+package storertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	yall "yall.in"
+	"yall.in/colour"
+
+	"lockbox.dev/accounts"
+)
+
+const (
+	defaultSequences   = 50
+	defaultLength      = 40
+	maxShrinkAttempts  = 500
+	maxTimestampJitter = time.Hour
+)
+
+// DifferentialOptions controls the operations generated by RunDifferential.
+type DifferentialOptions struct {
+	// Seed is used to seed the random operation generator. If it is 0, a
+	// seed is chosen based on the current time. The seed in use is always
+	// logged, so failures can be reproduced.
+	Seed int64
+
+	// Sequences is the number of random sequences of operations to run.
+	// It defaults to 50.
+	Sequences int
+
+	// Length is the number of operations in each sequence. It defaults to
+	// 40.
+	Length int
+
+	// QuarantinesReleasedIDs expects the Storers to reject creating
+	// Accounts with IDs that another profile deleted, as Storers with a
+	// release quarantine do. The quarantine must outlast the sequences.
+	QuarantinesReleasedIDs bool
+}
+
+type opKind int
+
+const (
+	opCreate opKind = iota
+	opGet
+	opUpdate
+	opDelete
+	opDeleteUnlessLast
+	opListByProfile
+	opEraseProfile
+	numOpKinds
+)
+
+type operation struct {
+	kind      opKind
+	account   accounts.Account
+	id        string
+	profileID string
+	change    accounts.Change
+}
+
+func (op operation) String() string {
+	switch op.kind {
+	case opCreate:
+		return fmt.Sprintf("Create(%+v)", op.account)
+	case opGet:
+		return fmt.Sprintf("Get(%q)", op.id)
+	case opUpdate:
+		var changes []string
+		if op.change.LastUsed != nil {
+			changes = append(changes, "LastUsed: "+op.change.LastUsed.String())
+		}
+		if op.change.LastSeen != nil {
+			changes = append(changes, "LastSeen: "+op.change.LastSeen.String())
+		}
+		return fmt.Sprintf("Update(%q, {%s})", op.id, strings.Join(changes, ", "))
+	case opDelete:
+		return fmt.Sprintf("Delete(%q)", op.id)
+	case opDeleteUnlessLast:
+		return fmt.Sprintf("DeleteUnlessLast(%q)", op.id)
+	case opListByProfile:
+		return fmt.Sprintf("ListByProfile(%q)", op.profileID)
+	case opEraseProfile:
+		return fmt.Sprintf("EraseProfile(%q)", op.profileID)
+	case numOpKinds:
+	}
+	return fmt.Sprintf("unknown operation %d", op.kind)
+}
+
+// result is the observable outcome of applying an operation to a Storer.
+type result struct {
+	Err      string
+	Accounts []accounts.Account
+}
+
+func errClass(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, accounts.ErrAccountNotFound):
+		return "ErrAccountNotFound"
+	case errors.Is(err, accounts.ErrAccountAlreadyExists):
+		return "ErrAccountAlreadyExists"
+	case errors.Is(err, accounts.ErrProfileIDAlreadyExists):
+		return "ErrProfileIDAlreadyExists"
+	case errors.Is(err, accounts.ErrLastAccount):
+		return "ErrLastAccount"
+	case errors.Is(err, accounts.ErrAccountIDRecentlyReleased):
+		return "ErrAccountIDRecentlyReleased"
+	}
+	return "unexpected error: " + err.Error()
+}
+
+// errNotSupported is the result of an operation from an optional interface
+// the Storer doesn't implement.
+const errNotSupported = "operation not supported"
+
+func apply(ctx context.Context, storer accounts.Storer, op operation) result {
+	switch op.kind {
+	case opCreate:
+		return result{Err: errClass(storer.Create(ctx, op.account))}
+	case opGet:
+		account, err := storer.Get(ctx, op.id)
+		if err != nil {
+			return result{Err: errClass(err)}
+		}
+		return result{Accounts: []accounts.Account{account}}
+	case opUpdate:
+		return result{Err: errClass(storer.Update(ctx, op.id, op.change))}
+	case opDelete:
+		return result{Err: errClass(storer.Delete(ctx, op.id))}
+	case opDeleteUnlessLast:
+		guard, ok := storer.(accounts.LastAccountGuard)
+		if !ok {
+			return result{Err: errNotSupported}
+		}
+		return result{Err: errClass(guard.DeleteUnlessLast(ctx, op.id))}
+	case opListByProfile:
+		accts, err := storer.ListByProfile(ctx, op.profileID)
+		if err != nil {
+			return result{Err: errClass(err)}
+		}
+		if !sort.SliceIsSorted(accts, func(i, j int) bool { return accts[i].LastUsed.After(accts[j].LastUsed) }) {
+			return result{Err: "results not sorted by LastUsed"}
+		}
+		// accounts with the same LastUsed can come back in any order, so
+		// put them in a predictable one before comparing
+		sorted := make([]accounts.Account, len(accts))
+		copy(sorted, accts)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			if !sorted[i].LastUsed.Equal(sorted[j].LastUsed) {
+				return sorted[i].LastUsed.After(sorted[j].LastUsed)
+			}
+			return strings.ToLower(sorted[i].ID) < strings.ToLower(sorted[j].ID)
+		})
+		return result{Accounts: sorted}
+	case opEraseProfile:
+		eraser, ok := storer.(accounts.ProfileEraser)
+		if !ok {
+			return result{Err: errNotSupported}
+		}
+		accts, err := eraser.EraseProfile(ctx, op.profileID)
+		if err != nil {
+			return result{Err: errClass(err)}
+		}
+		// erased accounts can come back in any order
+		sort.Slice(accts, func(i, j int) bool { return strings.ToLower(accts[i].ID) < strings.ToLower(accts[j].ID) })
+		return result{Accounts: accts}
+	case numOpKinds:
+	}
+	return result{Err: fmt.Sprintf("unknown operation %d", op.kind)}
+}
+
+// generator produces random operations. It draws IDs and profile IDs from
+// small pools, so that operations frequently interact with each other.
+type generator struct {
+	rand       *rand.Rand
+	clock      *accounts.FakeClock
+	ids        []string
+	profileIDs []string
+}
+
+func newGenerator(seed int64) *generator {
+	return &generator{
+		rand:  rand.New(rand.NewSource(seed)), //nolint:gosec // predictable randomness is the point
+		clock: newTestClock(),
+		ids: []string{
+			"paddy@impractical.co",
+			"paddy@impracticallabs.com",
+			"paddy@carvers.co",
+			"paddycarver",
+			"google:1234567890",
+		},
+		// profile IDs are opaque, so profile IDs that differ only by
+		// case are different profiles
+		profileIDs: []string{
+			"3c6f4fd8-8e6a-4c8e-9d3c-7fb1e0b5a6f1",
+			"3C6F4FD8-8E6A-4C8E-9D3C-7FB1E0B5A6F1",
+			"a0c4b3e2-1d6f-4e0a-8b7c-2f9e8d1c0b5a",
+			"A0c4B3e2-1D6f-4E0a-8B7c-2F9e8D1c0B5a",
+			"f2e1d0c9-b8a7-4c6d-9e5f-0a1b2c3d4e5f",
+		},
+	}
+}
+
+// id returns an ID from the pool, with the case of each letter randomized
+// to exercise case insensitivity.
+func (g *generator) id() string {
+	id := []rune(g.ids[g.rand.Intn(len(g.ids))])
+	for pos, char := range id {
+		if g.rand.Intn(4) == 0 { //nolint:gomnd // a one in four chance
+			id[pos] = []rune(strings.ToUpper(string(char)))[0]
+		}
+	}
+	return string(id)
+}
+
+func (g *generator) profileID() string {
+	return g.profileIDs[g.rand.Intn(len(g.profileIDs))]
+}
+
+func (g *generator) timestamp() time.Time {
+	return g.clock.Now().Add(time.Duration(g.rand.Int63n(int64(maxTimestampJitter/time.Millisecond))) * time.Millisecond)
+}
+
+func (g *generator) operation() operation {
+	g.clock.Advance(time.Second)
+	switch kind := opKind(g.rand.Intn(int(numOpKinds))); kind {
+	case opCreate:
+		created := g.timestamp()
+		return operation{kind: kind, account: accounts.Account{
+			ID:             g.id(),
+			ProfileID:      g.profileID(),
+			Created:        created,
+			LastUsed:       g.timestamp(),
+			LastSeen:       g.timestamp(),
+			IsRegistration: g.rand.Intn(2) == 0,
+		}}
+	case opUpdate:
+		var change accounts.Change
+		if g.rand.Intn(2) == 0 {
+			used := g.timestamp()
+			change.LastUsed = &used
+		}
+		if g.rand.Intn(2) == 0 {
+			seen := g.timestamp()
+			change.LastSeen = &seen
+		}
+		return operation{kind: kind, id: g.id(), change: change}
+	case opListByProfile, opEraseProfile:
+		return operation{kind: kind, profileID: g.profileID()}
+	case opGet, opDelete, opDeleteUnlessLast:
+		return operation{kind: kind, id: g.id()}
+	case numOpKinds:
+	}
+	panic("unreachable")
+}
+
+func (g *generator) sequence(length int) []operation {
+	ops := make([]operation, 0, length)
+	for i := 0; i < length; i++ {
+		ops = append(ops, g.operation())
+	}
+	return ops
+}
+
+// divergence runs the passed operations against a new Storer from the
+// Factory and against a reference model configured by `opts`. It returns the index of the
+// first operation whose result differed, and a description of the
+// difference. If no operation differed, the index will be -1.
+func divergence(ctx context.Context, factory Factory, opts DifferentialOptions, ops []operation) (int, string, error) {
+	storer, err := factory.NewStorer(ctx)
+	if err != nil {
+		return -1, "", err
+	}
+	reference := newModel(opts.QuarantinesReleasedIDs)
+	for pos, op := range ops {
+		// operations the Storer doesn't support are skipped for the model,
+		// too, so the rest of the sequence still lines up
+		got := apply(ctx, storer, op)
+		if got.Err == errNotSupported {
+			continue
+		}
+		want := apply(ctx, reference, op)
+		if diff := cmp.Diff(want, got, cmpopts.EquateEmpty()); diff != "" {
+			return pos, diff, nil
+		}
+	}
+	return -1, "", nil
+}
+
+// shrink finds a shorter sequence of operations that still causes a
+// divergence, by repeatedly removing operations that aren't needed to
+// reproduce it.
+func shrink(ctx context.Context, factory Factory, opts DifferentialOptions, ops []operation) ([]operation, string, error) {
+	pos, diff, err := divergence(ctx, factory, opts, ops)
+	if err != nil || pos < 0 {
+		return ops, diff, err
+	}
+	ops = ops[:pos+1]
+	attempts := 0
+	for changed := true; changed && attempts < maxShrinkAttempts; {
+		changed = false
+		for i := len(ops) - 1; i >= 0 && attempts < maxShrinkAttempts; i-- {
+			attempts++
+			candidate := make([]operation, 0, len(ops)-1)
+			candidate = append(candidate, ops[:i]...)
+			candidate = append(candidate, ops[i+1:]...)
+			candidatePos, candidateDiff, err := divergence(ctx, factory, opts, candidate)
+			if err != nil {
+				return ops, diff, err
+			}
+			if candidatePos < 0 {
+				continue
+			}
+			ops, diff, changed = candidate[:candidatePos+1], candidateDiff, true
+			if i > len(ops) {
+				i = len(ops)
+			}
+		}
+	}
+	return ops, diff, nil
+}
+
+// RunDifferential generates random sequences of Create, Get, Update, Delete,
+// ListByProfile, and EraseProfile operations, and runs each sequence against
+// a reference model of a Storer and against Storers from each of the passed
+// Factories. When a Storer's results diverge from the model's, the sequence
+// is shrunk to the shortest sequence that still diverges, and that sequence
+// is reported as a test failure. DeleteUnlessLast and EraseProfile operations
+// are skipped for Storers that don't implement accounts.LastAccountGuard or
+// accounts.ProfileEraser.
+//
+// Each Factory is tested as a parallel subtest of `t`, and its
+// TeardownStorers method is called when its subtest has completed.
+func RunDifferential(t *testing.T, opts DifferentialOptions, factories ...Factory) {
+	t.Helper()
+
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+	if opts.Sequences <= 0 {
+		opts.Sequences = defaultSequences
+	}
+	if opts.Length <= 0 {
+		opts.Length = defaultLength
+	}
+	t.Logf("Generating operations with seed %d", opts.Seed)
+
+	gen := newGenerator(opts.Seed)
+	sequences := make([][]operation, 0, opts.Sequences)
+	for i := 0; i < opts.Sequences; i++ {
+		sequences = append(sequences, gen.sequence(opts.Length))
+	}
+
+	logger := yall.New(colour.New(os.Stdout, yall.Error))
+	for _, factory := range factories {
+		factory := factory
+		t.Run(fmt.Sprintf("Factory=%T", factory), func(t *testing.T) {
+			t.Parallel()
+			t.Cleanup(func() {
+				err := factory.TeardownStorers()
+				if err != nil {
+					t.Errorf("Error cleaning up after %T: %+v\n", factory, err)
+				}
+			})
+
+			ctx := yall.InContext(context.Background(), logger)
+			for seq, ops := range sequences {
+				pos, _, err := divergence(ctx, factory, opts, ops)
+				if err != nil {
+					t.Fatalf("Error creating Storer from %T: %+v\n", factory, err)
+				}
+				if pos < 0 {
+					continue
+				}
+				minimal, diff, err := shrink(ctx, factory, opts, ops)
+				if err != nil {
+					t.Fatalf("Error creating Storer from %T: %+v\n", factory, err)
+				}
+				steps := make([]string, 0, len(minimal))
+				for step, op := range minimal {
+					steps = append(steps, fmt.Sprintf("\t%d: %s", step+1, op))
+				}
+				t.Fatalf("Sequence %d (seed %d) diverged from the reference model; minimal diverging sequence:\n%s\nDiff for the last operation (-model, +storer): %s",
+					seq, opts.Seed, strings.Join(steps, "\n"), diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+// Package storertest provides an acceptance test suite for implementations of
+// the lockbox.dev/accounts.Storer interface.
+//
+// The suite is exported so that Storer implementations that live outside this
+// repository can be held to the same expectations as the ones inside it. To
+// run the suite, implement the Factory interface for your Storer and call Run
+// from a test function.
+//
+// RunDifferential complements the acceptance tests by running random
+// sequences of operations against a reference model of a Storer and against
+// any number of Storer implementations, reporting the shortest sequence of
+// operations it can find that makes an implementation behave differently from
+// the model.
+package storertest
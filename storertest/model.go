@@ -0,0 +1,123 @@
+package storertest
+
+import (
+	"context"
+	"strings"
+
+	"lockbox.dev/accounts"
+)
+
+// model is a reference implementation of the accounts.Storer interface. It
+// is deliberately as simple as possible, so it can serve as the definition
+// of correct behavior that other Storers are compared against.
+//
+// IDs are compared case-insensitively, and profile IDs, which are opaque,
+// case-sensitively.
+//
+// model is not safe for concurrent use.
+type model struct {
+	accounts map[string]accounts.Account
+
+	// released maps the lowercased IDs of deleted accounts to the
+	// profile that deleted them last, if the model quarantines released
+	// IDs. The quarantine is assumed to outlast every sequence.
+	released map[string]string
+}
+
+func newModel(quarantine bool) *model {
+	m := &model{accounts: map[string]accounts.Account{}}
+	if quarantine {
+		m.released = map[string]string{}
+	}
+	return m
+}
+
+// release records that `account`'s ID was released, if the model
+// quarantines released IDs.
+func (m *model) release(account accounts.Account) {
+	if m.released != nil {
+		m.released[strings.ToLower(account.ID)] = account.ProfileID
+	}
+}
+
+func (m *model) Create(_ context.Context, account accounts.Account) error {
+	if _, ok := m.accounts[strings.ToLower(account.ID)]; ok {
+		return accounts.ErrAccountAlreadyExists
+	}
+	if profileID, ok := m.released[strings.ToLower(account.ID)]; ok && profileID != account.ProfileID {
+		return accounts.ErrAccountIDRecentlyReleased
+	}
+	if account.IsRegistration {
+		for _, acct := range m.accounts {
+			if acct.ProfileID == account.ProfileID {
+				return accounts.ErrProfileIDAlreadyExists
+			}
+		}
+	}
+	m.accounts[strings.ToLower(account.ID)] = account
+	return nil
+}
+
+func (m *model) Get(_ context.Context, id string) (accounts.Account, error) {
+	account, ok := m.accounts[strings.ToLower(id)]
+	if !ok {
+		return accounts.Account{}, accounts.ErrAccountNotFound
+	}
+	return account, nil
+}
+
+func (m *model) Update(_ context.Context, id string, change accounts.Change) error {
+	account, ok := m.accounts[strings.ToLower(id)]
+	if !ok {
+		return nil
+	}
+	m.accounts[strings.ToLower(id)] = accounts.Apply(change, account)
+	return nil
+}
+
+func (m *model) Delete(_ context.Context, id string) error {
+	account, ok := m.accounts[strings.ToLower(id)]
+	if !ok {
+		return nil
+	}
+	delete(m.accounts, strings.ToLower(id))
+	m.release(account)
+	return nil
+}
+
+func (m *model) DeleteUnlessLast(_ context.Context, id string) error {
+	account, ok := m.accounts[strings.ToLower(id)]
+	if !ok {
+		return nil
+	}
+	for otherID, other := range m.accounts {
+		if otherID != strings.ToLower(id) && other.ProfileID == account.ProfileID {
+			delete(m.accounts, strings.ToLower(id))
+			m.release(account)
+			return nil
+		}
+	}
+	return accounts.ErrLastAccount
+}
+
+func (m *model) EraseProfile(_ context.Context, profileID string) ([]accounts.Account, error) {
+	var res []accounts.Account
+	for id, account := range m.accounts {
+		if account.ProfileID == profileID {
+			res = append(res, account)
+			delete(m.accounts, id)
+		}
+	}
+	return res, nil
+}
+
+func (m *model) ListByProfile(_ context.Context, profileID string) ([]accounts.Account, error) {
+	var res []accounts.Account
+	for _, account := range m.accounts {
+		if account.ProfileID == profileID {
+			res = append(res, account)
+		}
+	}
+	accounts.ByLastUsedDesc(res)
+	return res, nil
+}
@@ -0,0 +1,98 @@
+package storertest
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	uuid "github.com/hashicorp/go-uuid"
+	yall "yall.in"
+	"yall.in/colour"
+
+	"lockbox.dev/accounts"
+)
+
+const (
+	changeLastUsed = 1 << iota
+	changeLastSeen
+	changeVariations
+)
+
+// Factory is a generator of Storers for testing purposes. Every test in the
+// suite gets its own Storer from NewStorer, and TeardownStorers is called
+// once all the tests using the Factory have completed.
+type Factory interface {
+	NewStorer(ctx context.Context) (accounts.Storer, error)
+	TeardownStorers() error
+}
+
+type storerTest struct {
+	name string
+	fn   func(*testing.T, accounts.Storer, context.Context)
+}
+
+var storerTests = []storerTest{
+	{name: "CreateAndGetAccount", fn: testCreateAndGetAccount},
+	{name: "CreateWithDefaults", fn: testCreateWithDefaults},
+	{name: "GetNonexistentAccount", fn: testGetNonexistentAccount},
+	{name: "CreateDuplicateID", fn: testCreateDuplicateID},
+	{name: "CreateSecondaryAccounts", fn: testCreateSecondaryAccounts},
+	{name: "CreateDuplicateRegistration", fn: testCreateDuplicateRegistration},
+	{name: "CreateMultipleAccounts", fn: testCreateMultipleAccounts},
+	{name: "ListAccountsByProfile", fn: testListAccountsByProfile},
+	{name: "UpdateOneOfMany", fn: testUpdateOneOfMany},
+	{name: "UpdateNonExistent", fn: testUpdateNonExistent},
+	{name: "UpdateNoChange", fn: testUpdateNoChange},
+	{name: "DeleteOneOfMany", fn: testDeleteOneOfMany},
+	{name: "DeleteNonExistent", fn: testDeleteNonExistent},
+	{name: "DeleteUnlessLast", fn: testDeleteUnlessLast},
+	{name: "DeleteUnlessLastConcurrently", fn: testDeleteUnlessLastConcurrently},
+	{name: "EraseProfile", fn: testEraseProfile},
+	{name: "ListAll", fn: testListAll},
+}
+
+// Run executes the acceptance test suite against Storers created by the
+// passed Factory, each test as a parallel subtest of `t`. The Factory's
+// TeardownStorers method is called when all the tests have completed.
+func Run(t *testing.T, factory Factory) {
+	t.Helper()
+
+	t.Cleanup(func() {
+		err := factory.TeardownStorers()
+		if err != nil {
+			t.Errorf("Error cleaning up after %T: %+v\n", factory, err)
+		}
+	})
+
+	logger := yall.New(colour.New(os.Stdout, yall.Debug))
+	for _, test := range storerTests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := yall.InContext(context.Background(), logger)
+			storer, err := factory.NewStorer(ctx)
+			if err != nil {
+				t.Fatalf("Error creating Storer from %T: %+v\n", factory, err)
+			}
+			test.fn(t, storer, ctx)
+		})
+	}
+}
+
+// newTestClock returns a FakeClock set to a fixed time, so tests can check
+// exact timestamps. The time has millisecond precision, which every Storer
+// is expected to be able to persist without loss.
+func newTestClock() *accounts.FakeClock {
+	return accounts.NewFakeClock(time.Date(2022, time.March, 14, 15, 9, 26, 535000000, time.UTC))
+}
+
+func uuidOrFail(t *testing.T) string {
+	t.Helper()
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		t.Fatalf("Unexpected error generating ID: %s", err.Error())
+	}
+	return id
+}
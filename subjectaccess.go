@@ -0,0 +1,191 @@
+package accounts
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+const (
+	// SubjectAccessVersion is the version of the archive format written
+	// by WriteSubjectAccessArchive.
+	SubjectAccessVersion = 1
+
+	subjectAccessManifestFile = "manifest.json"
+	subjectAccessAccountsFile = "accounts.jsonl"
+	subjectAccessHistoryFile  = "history.json"
+	subjectAccessPendingFile  = "pending_links.json"
+)
+
+// HistoryRecord is an audit or history record kept about an Account.
+type HistoryRecord struct {
+	// AccountID is the ID of the Account the record is about.
+	AccountID string `json:"accountID"`
+
+	// ProfileID is the profile ID the Account belonged to when the record
+	// was made.
+	ProfileID string `json:"profileID"`
+
+	// Action describes what happened to the Account.
+	Action string `json:"action"`
+
+	// At is when it happened.
+	At time.Time `json:"at"`
+
+	// Details holds any extra information about what happened.
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// HistoryLister is implemented by Storers that keep history or audit
+// records about the Accounts they store.
+type HistoryLister interface {
+	ListHistoryByProfile(ctx context.Context, profileID string) ([]HistoryRecord, error)
+}
+
+// subjectAccessPendingLink is a PendingLink as it appears in a subject
+// access archive. The token hash is left out; it is a credential, not
+// information about the subject.
+type subjectAccessPendingLink struct {
+	AccountID string    `json:"accountID"`
+	Created   time.Time `json:"created"`
+	Expires   time.Time `json:"expires"`
+}
+
+// SubjectAccessManifest describes the contents of a subject access
+// archive. It is stored in the archive as manifest.json.
+type SubjectAccessManifest struct {
+	Version     int                 `json:"version"`
+	ProfileID   string              `json:"profileID"`
+	GeneratedAt time.Time           `json:"generatedAt"`
+	Files       []SubjectAccessFile `json:"files"`
+}
+
+// SubjectAccessFile describes a single file in a subject access archive.
+type SubjectAccessFile struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Records     int    `json:"records"`
+	SHA256      string `json:"sha256"`
+}
+
+// addArchiveFile writes `contents` to `archive` as `file`, filling in the
+// file's checksum.
+func addArchiveFile(archive *zip.Writer, file *SubjectAccessFile, contents []byte, modified time.Time) error {
+	writer, err := archive.CreateHeader(&zip.FileHeader{Name: file.Name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", file.Name, err)
+	}
+	_, err = writer.Write(contents)
+	if err != nil {
+		return fmt.Errorf("error writing %s: %w", file.Name, err)
+	}
+	sum := sha256.Sum256(contents)
+	file.SHA256 = hex.EncodeToString(sum[:])
+	return nil
+}
+
+// addArchiveJSON writes `v`, encoded as JSON, to `archive` as `file`,
+// filling in the file's checksum.
+func addArchiveJSON(archive *zip.Writer, file *SubjectAccessFile, v interface{}, modified time.Time) error {
+	contents, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding %s: %w", file.Name, err)
+	}
+	return addArchiveFile(archive, file, contents, modified)
+}
+
+// WriteSubjectAccessArchive writes a zip archive of everything stored
+// about `profileID` to `w`, for answering data subject access requests.
+//
+// The archive holds the profile's Accounts, in the JSON Lines export
+// format, as accounts.jsonl. If the Storer implements HistoryLister, the
+// history records kept about those Accounts are included as history.json.
+// If the PendingLinkStorer implements PendingLinkLister, the profile's
+// PendingLinks, which hold the IDs of Accounts that were requested but not
+// confirmed yet, are included as pending_links.json. A manifest.json
+// describing each file, with its checksum, is always included.
+func (d Dependencies) WriteSubjectAccessArchive(ctx context.Context, w io.Writer, profileID string) (SubjectAccessManifest, error) {
+	now := d.Now()
+	manifest := SubjectAccessManifest{
+		Version:     SubjectAccessVersion,
+		ProfileID:   profileID,
+		GeneratedAt: now,
+	}
+	archive := zip.NewWriter(w)
+
+	var accts bytes.Buffer
+	written, err := ExportProfiles(ctx, d.Storer, &accts, profileID)
+	if err != nil {
+		return manifest, err
+	}
+	file := SubjectAccessFile{
+		Name:        subjectAccessAccountsFile,
+		Description: "Every Account associated with the profile, in the accounts JSON Lines export format.",
+		Records:     written,
+	}
+	err = addArchiveFile(archive, &file, accts.Bytes(), now)
+	if err != nil {
+		return manifest, err
+	}
+	manifest.Files = append(manifest.Files, file)
+
+	if lister, ok := d.Storer.(HistoryLister); ok {
+		history, err := lister.ListHistoryByProfile(ctx, profileID)
+		if err != nil {
+			return manifest, fmt.Errorf("error listing history for profile %s: %w", profileID, err)
+		}
+		if history == nil {
+			history = []HistoryRecord{}
+		}
+		file := SubjectAccessFile{
+			Name:        subjectAccessHistoryFile,
+			Description: "History and audit records kept about the profile's Accounts.",
+			Records:     len(history),
+		}
+		err = addArchiveJSON(archive, &file, history, now)
+		if err != nil {
+			return manifest, err
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	if lister, ok := d.PendingLinks.(PendingLinkLister); ok {
+		links, err := lister.ListPendingLinksByProfile(ctx, profileID)
+		if err != nil {
+			return manifest, fmt.Errorf("error listing pending links for profile %s: %w", profileID, err)
+		}
+		sort.Slice(links, func(i, j int) bool { return links[i].Created.Before(links[j].Created) })
+		pending := make([]subjectAccessPendingLink, 0, len(links))
+		for _, link := range links {
+			pending = append(pending, subjectAccessPendingLink{AccountID: link.AccountID, Created: link.Created, Expires: link.Expires})
+		}
+		file := SubjectAccessFile{
+			Name:        subjectAccessPendingFile,
+			Description: "Accounts that were requested for the profile, but haven't been confirmed yet.",
+			Records:     len(pending),
+		}
+		err = addArchiveJSON(archive, &file, pending, now)
+		if err != nil {
+			return manifest, err
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	manifestFile := SubjectAccessFile{Name: subjectAccessManifestFile}
+	err = addArchiveJSON(archive, &manifestFile, manifest, now)
+	if err != nil {
+		return manifest, err
+	}
+	err = archive.Close()
+	if err != nil {
+		return manifest, fmt.Errorf("error writing archive: %w", err)
+	}
+	return manifest, nil
+}
@@ -0,0 +1,180 @@
+package accounts_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"lockbox.dev/accounts"
+	"lockbox.dev/accounts/storers/memory"
+)
+
+type historyStorer struct {
+	*memory.Storer
+	history []accounts.HistoryRecord
+}
+
+func (h historyStorer) ListHistoryByProfile(_ context.Context, profileID string) ([]accounts.HistoryRecord, error) {
+	var res []accounts.HistoryRecord
+	for _, record := range h.history {
+		if record.ProfileID == profileID {
+			res = append(res, record)
+		}
+	}
+	return res, nil
+}
+
+// readArchive returns the contents of every file in the zip archive in
+// `archive`, after checking them against the manifest.
+func readArchive(t *testing.T, archive []byte) (accounts.SubjectAccessManifest, map[string][]byte) {
+	t.Helper()
+
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("Error opening archive: %+v\n", err)
+	}
+	files := map[string][]byte{}
+	for _, file := range reader.File {
+		f, err := file.Open()
+		if err != nil {
+			t.Fatalf("Error opening %s: %+v\n", file.Name, err)
+		}
+		contents, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("Error reading %s: %+v\n", file.Name, err)
+		}
+		files[file.Name] = contents
+	}
+	var manifest accounts.SubjectAccessManifest
+	err = json.Unmarshal(files["manifest.json"], &manifest)
+	if err != nil {
+		t.Fatalf("Error decoding manifest: %+v\n", err)
+	}
+	for _, file := range manifest.Files {
+		sum := sha256.Sum256(files[file.Name])
+		if hex.EncodeToString(sum[:]) != file.SHA256 {
+			t.Errorf("Checksum for %s doesn't match the manifest", file.Name)
+		}
+	}
+	if len(files) != len(manifest.Files)+1 {
+		t.Errorf("Expected %d files in the archive, got %d", len(manifest.Files)+1, len(files))
+	}
+	return manifest, files
+}
+
+func TestSubjectAccessArchive(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storer := historyStorer{Storer: newMemoryStorer(t)}
+	fixtures := exportFixtures(ctx, t, storer)
+	at := time.Date(2022, time.March, 15, 0, 0, 0, 0, time.UTC)
+	storer.history = []accounts.HistoryRecord{
+		{AccountID: "old@impractical.co", ProfileID: "profile-1", Action: "deleted", At: at},
+		{AccountID: "other@impractical.co", ProfileID: "profile-2", Action: "deleted", At: at},
+	}
+	links := []accounts.PendingLink{
+		{TokenHash: "token-1", AccountID: "new@impractical.co", ProfileID: "profile-1", Created: at, Expires: at.Add(time.Hour)},
+		{TokenHash: "token-2", AccountID: "other-new@impractical.co", ProfileID: "profile-2", Created: at, Expires: at.Add(time.Hour)},
+	}
+	for _, link := range links {
+		err := storer.CreatePendingLink(ctx, link)
+		if err != nil {
+			t.Fatalf("Error creating pending link: %+v\n", err)
+		}
+	}
+	now := time.Date(2022, time.April, 1, 12, 0, 0, 0, time.UTC)
+	deps := accounts.Dependencies{Storer: storer, PendingLinks: storer, Clock: accounts.NewFakeClock(now)}
+
+	var buf bytes.Buffer
+	_, err := deps.WriteSubjectAccessArchive(ctx, &buf, "profile-1")
+	if err != nil {
+		t.Fatalf("Error writing archive: %+v\n", err)
+	}
+	manifest, files := readArchive(t, buf.Bytes())
+
+	if manifest.ProfileID != "profile-1" || !manifest.GeneratedAt.Equal(now) || manifest.Version != accounts.SubjectAccessVersion {
+		t.Errorf("Unexpected manifest: %+v", manifest)
+	}
+	records := map[string]int{}
+	for _, file := range manifest.Files {
+		records[file.Name] = file.Records
+	}
+	if diff := cmp.Diff(map[string]int{"accounts.jsonl": 2, "history.json": 1, "pending_links.json": 1}, records); diff != "" {
+		t.Errorf("Unexpected files in manifest (-wanted, +got): %s", diff)
+	}
+
+	dec, err := accounts.NewDecoder(bytes.NewReader(files["accounts.jsonl"]))
+	if err != nil {
+		t.Fatalf("Error reading accounts: %+v\n", err)
+	}
+	var got []accounts.Account
+	for {
+		account, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Error reading accounts: %+v\n", err)
+		}
+		got = append(got, account)
+	}
+	if diff := cmp.Diff(fixtures[:2], got); diff != "" {
+		t.Errorf("Unexpected accounts (-wanted, +got): %s", diff)
+	}
+
+	var history []accounts.HistoryRecord
+	err = json.Unmarshal(files["history.json"], &history)
+	if err != nil {
+		t.Fatalf("Error decoding history: %+v\n", err)
+	}
+	if diff := cmp.Diff(storer.history[:1], history); diff != "" {
+		t.Errorf("Unexpected history (-wanted, +got): %s", diff)
+	}
+
+	// token hashes are credentials, so they're left out
+	var pending []map[string]interface{}
+	err = json.Unmarshal(files["pending_links.json"], &pending)
+	if err != nil {
+		t.Fatalf("Error decoding pending links: %+v\n", err)
+	}
+	wantPending := []map[string]interface{}{{
+		"accountID": "new@impractical.co",
+		"created":   at.Format(time.RFC3339),
+		"expires":   at.Add(time.Hour).Format(time.RFC3339),
+	}}
+	if diff := cmp.Diff(wantPending, pending); diff != "" {
+		t.Errorf("Unexpected pending links (-wanted, +got): %s", diff)
+	}
+}
+
+func TestSubjectAccessArchiveWithoutHistory(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	storer := newMemoryStorer(t)
+	exportFixtures(ctx, t, storer)
+	// hide the memory Storer's history, so it doesn't implement
+	// HistoryLister
+	deps := accounts.Dependencies{Storer: struct{ accounts.Storer }{storer}}
+
+	var buf bytes.Buffer
+	_, err := deps.WriteSubjectAccessArchive(ctx, &buf, "profile-2")
+	if err != nil {
+		t.Fatalf("Error writing archive: %+v\n", err)
+	}
+	manifest, _ := readArchive(t, buf.Bytes())
+	if len(manifest.Files) != 1 || manifest.Files[0].Name != "accounts.jsonl" {
+		t.Errorf("Expected only accounts in the archive, got %+v", manifest.Files)
+	}
+}
@@ -0,0 +1,64 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Checkpoint records the progress of a transfer, so it can be resumed if
+// it is interrupted.
+//
+// Load returns the ID of the last Account that was transferred, or an empty
+// string if nothing has been transferred yet. Save records the ID of the
+// last Account that was transferred.
+type Checkpoint interface {
+	Load(ctx context.Context) (string, error)
+	Save(ctx context.Context, after string) error
+}
+
+// FileCheckpoint is a Checkpoint that records progress in a file.
+type FileCheckpoint struct {
+	Path string
+}
+
+// Load returns the ID stored in the FileCheckpoint's file. If the file
+// doesn't exist, an empty string is returned.
+func (f FileCheckpoint) Load(_ context.Context) (string, error) {
+	contents, err := os.ReadFile(f.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(contents), "\n"), nil
+}
+
+// Save atomically replaces the contents of the FileCheckpoint's file with
+// `after`.
+func (f FileCheckpoint) Save(_ context.Context, after string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), filepath.Base(f.Path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // cleanup of a file that is usually already renamed
+	_, err = tmp.WriteString(after + "\n")
+	if err != nil {
+		tmp.Close() //nolint:errcheck,gosec // already returning an error
+		return err
+	}
+	err = tmp.Sync()
+	if err != nil {
+		tmp.Close() //nolint:errcheck,gosec // already returning an error
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.Path)
+}
@@ -0,0 +1,10 @@
+// Package transfer copies Accounts from one lockbox.dev/accounts.Storer to
+// another.
+//
+// It is meant for moving a deployment from one Storer implementation to
+// another, like from the memory Storer to the postgres Storer. Every property
+// of the Accounts is preserved, including their timestamps and whether they
+// were registrations. Transfers can record their progress in a Checkpoint, so
+// an interrupted transfer can pick up where it left off, and Verify can be
+// used afterwards to confirm that both Storers hold the same Accounts.
+package transfer
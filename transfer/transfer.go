@@ -0,0 +1,169 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	yall "yall.in"
+
+	"lockbox.dev/accounts"
+)
+
+const (
+	defaultBatchSize = 100
+)
+
+var (
+	// ErrConflict is returned when an Account can't be transferred because
+	// the destination Storer already has a different Account with the
+	// same ID, or a registration using the same profile ID.
+	ErrConflict = errors.New("account conflicts with destination")
+)
+
+// Source is a Storer that can be transferred from.
+type Source interface {
+	accounts.Storer
+	accounts.Enumerator
+}
+
+// Options controls how a transfer is run.
+type Options struct {
+	// BatchSize is how many Accounts to read from the source Storer at
+	// once. It defaults to 100.
+	BatchSize int
+
+	// Checkpoint, if set, is used to record progress after every batch,
+	// and to find where to resume from when the transfer starts.
+	Checkpoint Checkpoint
+}
+
+// Report describes the outcome of a transfer.
+type Report struct {
+	// Copied is the number of Accounts created in the destination Storer.
+	Copied int
+
+	// Skipped is the number of Accounts that were already in the
+	// destination Storer, usually because a transfer was resumed.
+	Skipped int
+}
+
+// sameTime reports whether `a` and `b` are the same time, at the precision
+// every Storer keeps. PostgreSQL only keeps microseconds, rounding away
+// anything smaller, so times less than a microsecond apart are the same.
+func sameTime(a, b time.Time) bool {
+	diff := a.Sub(b)
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff < time.Microsecond
+}
+
+func sameAccount(a, b accounts.Account) bool {
+	return a.ID == b.ID && a.ProfileID == b.ProfileID &&
+		sameTime(a.Created, b.Created) && sameTime(a.LastUsed, b.LastUsed) &&
+		sameTime(a.LastSeen, b.LastSeen) && a.IsRegistration == b.IsRegistration
+}
+
+// copyAccount creates `account` in `dst`, unless it's already there.
+func copyAccount(ctx context.Context, dst accounts.Storer, account accounts.Account, report *Report) error {
+	err := dst.Create(ctx, account)
+	if err == nil {
+		report.Copied++
+		return nil
+	}
+	if !errors.Is(err, accounts.ErrAccountAlreadyExists) {
+		if errors.Is(err, accounts.ErrProfileIDAlreadyExists) {
+			return fmt.Errorf("%w: registration %s for profile %s", ErrConflict, account.ID, account.ProfileID)
+		}
+		return err
+	}
+	existing, err := dst.Get(ctx, account.ID)
+	if err != nil {
+		return err
+	}
+	if !sameAccount(account, existing) {
+		return fmt.Errorf("%w: account %s", ErrConflict, account.ID)
+	}
+	report.Skipped++
+	return nil
+}
+
+// copyProfile copies every Account associated with `profileID` from `src`
+// to `dst`. Registrations are copied first, as some Storers won't accept a
+// registration for a profile ID that already has Accounts.
+func copyProfile(ctx context.Context, src Source, dst accounts.Storer, profileID string, report *Report) error {
+	accts, err := src.ListByProfile(ctx, profileID)
+	if err != nil {
+		return err
+	}
+	for _, registrations := range []bool{true, false} {
+		for _, account := range accts {
+			if account.IsRegistration != registrations {
+				continue
+			}
+			err = copyAccount(ctx, dst, account, report)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Copy streams every Account in `src` to `dst`, preserving all of their
+// properties. Accounts are copied a profile at a time, so the destination
+// Storer's registration rules are satisfied. Accounts that already exist
+// unchanged in `dst` are skipped, so interrupted transfers can safely be
+// run again; if a Checkpoint is set in `opts`, the transfer will resume
+// from the last batch it recorded.
+//
+// If `dst` already has a different Account with the same ID as an Account
+// being copied, an error wrapping ErrConflict is returned.
+func Copy(ctx context.Context, src Source, dst accounts.Storer, opts Options) (Report, error) {
+	var report Report
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	var after string
+	if opts.Checkpoint != nil {
+		var err error
+		after, err = opts.Checkpoint.Load(ctx)
+		if err != nil {
+			return report, fmt.Errorf("error loading checkpoint: %w", err)
+		}
+		if after != "" {
+			yall.FromContext(ctx).WithField("after", after).Info("resuming transfer")
+		}
+	}
+	// profiles that have already been copied during this run
+	copied := map[string]struct{}{}
+	for {
+		batch, err := src.ListAll(ctx, after, opts.BatchSize)
+		if err != nil {
+			return report, fmt.Errorf("error listing accounts: %w", err)
+		}
+		if len(batch) == 0 {
+			return report, nil
+		}
+		for _, account := range batch {
+			if _, ok := copied[account.ProfileID]; ok {
+				continue
+			}
+			err = copyProfile(ctx, src, dst, account.ProfileID, &report)
+			if err != nil {
+				return report, err
+			}
+			copied[account.ProfileID] = struct{}{}
+		}
+		after = batch[len(batch)-1].ID
+		if opts.Checkpoint != nil {
+			err = opts.Checkpoint.Save(ctx, after)
+			if err != nil {
+				return report, fmt.Errorf("error saving checkpoint: %w", err)
+			}
+		}
+		yall.FromContext(ctx).WithField("copied", report.Copied).WithField("skipped", report.Skipped).Debug("transferred batch")
+	}
+}
@@ -0,0 +1,217 @@
+package transfer_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"lockbox.dev/accounts"
+	"lockbox.dev/accounts/storers/memory"
+	"lockbox.dev/accounts/transfer"
+)
+
+var errInterrupted = errors.New("interrupted")
+
+// flakyStorer fails every Create after the first `limit`.
+type flakyStorer struct {
+	*memory.Storer
+	limit int
+}
+
+func (f *flakyStorer) Create(ctx context.Context, account accounts.Account) error {
+	if f.limit <= 0 {
+		return errInterrupted
+	}
+	f.limit--
+	return f.Storer.Create(ctx, account)
+}
+
+// microsecondStorer rounds the times in Accounts it creates to the
+// microsecond, like PostgreSQL does.
+type microsecondStorer struct {
+	*memory.Storer
+}
+
+func (m microsecondStorer) Create(ctx context.Context, account accounts.Account) error {
+	account.Created = account.Created.Round(time.Microsecond)
+	account.LastUsed = account.LastUsed.Round(time.Microsecond)
+	account.LastSeen = account.LastSeen.Round(time.Microsecond)
+	return m.Storer.Create(ctx, account)
+}
+
+func newStorer(t *testing.T) *memory.Storer {
+	t.Helper()
+	storer, err := memory.NewStorer()
+	if err != nil {
+		t.Fatalf("Unexpected error creating storer: %+v\n", err)
+	}
+	return storer
+}
+
+// fillStorer creates `profiles` profiles in `storer`, each with a
+// registration and two other Accounts.
+func fillStorer(ctx context.Context, t *testing.T, storer accounts.Storer, profiles int) {
+	t.Helper()
+	clock := accounts.NewFakeClock(time.Date(2022, time.March, 14, 15, 9, 26, 535897932, time.UTC))
+	for profile := 0; profile < profiles; profile++ {
+		profileID := fmt.Sprintf("profile-%d", profile)
+		// name the registration so it sorts after the other Accounts,
+		// to make sure registrations get copied first anyway
+		for num, id := range []string{"z%d@impractical.co", "a%d@impractical.co", "m%d@impractical.co"} {
+			err := storer.Create(ctx, accounts.Account{
+				ID:             fmt.Sprintf(id, profile),
+				ProfileID:      profileID,
+				Created:        clock.Advance(time.Minute),
+				LastUsed:       clock.Advance(time.Hour),
+				LastSeen:       clock.Advance(time.Second),
+				IsRegistration: num == 0,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error creating account: %+v\n", err)
+			}
+		}
+	}
+}
+
+func TestCopyAndVerify(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	src, dst := newStorer(t), newStorer(t)
+	fillStorer(ctx, t, src, 5)
+
+	report, err := transfer.Copy(ctx, src, dst, transfer.Options{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error copying accounts: %+v\n", err)
+	}
+	if report.Copied != 15 || report.Skipped != 0 {
+		t.Errorf("Expected 15 accounts copied and none skipped, got %+v", report)
+	}
+
+	res, err := transfer.Verify(ctx, src, dst, transfer.Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error verifying accounts: %+v\n", err)
+	}
+	if !res.OK() {
+		t.Errorf("Expected verification to pass, got %+v", res)
+	}
+}
+
+func TestCopyResume(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	src, dst := newStorer(t), newStorer(t)
+	fillStorer(ctx, t, src, 5)
+	checkpoint := transfer.FileCheckpoint{Path: filepath.Join(t.TempDir(), "checkpoint")}
+	opts := transfer.Options{BatchSize: 2, Checkpoint: checkpoint}
+
+	_, err := transfer.Copy(ctx, src, &flakyStorer{Storer: dst, limit: 7}, opts)
+	if !errors.Is(err, errInterrupted) {
+		t.Fatalf("Expected the transfer to be interrupted, got %v", err)
+	}
+	after, err := checkpoint.Load(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error loading checkpoint: %+v\n", err)
+	}
+	if after == "" {
+		t.Fatalf("Expected progress to be checkpointed")
+	}
+
+	report, err := transfer.Copy(ctx, src, dst, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error resuming copy: %+v\n", err)
+	}
+	if report.Copied+report.Skipped == 0 || report.Copied >= 15 {
+		t.Errorf("Expected the resumed transfer to pick up where it left off, got %+v", report)
+	}
+
+	res, err := transfer.Verify(ctx, src, dst, transfer.Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error verifying accounts: %+v\n", err)
+	}
+	if !res.OK() {
+		t.Errorf("Expected verification to pass, got %+v", res)
+	}
+}
+
+func TestCopyToLowerPrecision(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	src, dst := newStorer(t), microsecondStorer{Storer: newStorer(t)}
+	fillStorer(ctx, t, src, 5)
+
+	report, err := transfer.Copy(ctx, src, dst, transfer.Options{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error copying accounts: %+v\n", err)
+	}
+	if report.Copied != 15 {
+		t.Errorf("Expected 15 accounts copied, got %+v", report)
+	}
+
+	// running the transfer again compares every Account with its
+	// rounded copy
+	report, err = transfer.Copy(ctx, src, dst, transfer.Options{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error copying accounts again: %+v\n", err)
+	}
+	if report.Copied != 0 || report.Skipped != 15 {
+		t.Errorf("Expected none copied and 15 accounts skipped, got %+v", report)
+	}
+
+	res, err := transfer.Verify(ctx, src, dst, transfer.Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error verifying accounts: %+v\n", err)
+	}
+	if !res.OK() {
+		t.Errorf("Expected verification to pass, got %+v", res)
+	}
+}
+
+func TestCopyConflict(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	src, dst := newStorer(t), newStorer(t)
+	fillStorer(ctx, t, src, 1)
+	err := dst.Create(ctx, accounts.Account{ID: "a0@impractical.co", ProfileID: "someone-else"})
+	if err != nil {
+		t.Fatalf("Unexpected error creating account: %+v\n", err)
+	}
+
+	_, err = transfer.Copy(ctx, src, dst, transfer.Options{})
+	if !errors.Is(err, transfer.ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got %v", err)
+	}
+}
+
+func TestVerifyMismatch(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	src, dst := newStorer(t), newStorer(t)
+	fillStorer(ctx, t, src, 3)
+	_, err := transfer.Copy(ctx, src, dst, transfer.Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error copying accounts: %+v\n", err)
+	}
+	err = dst.Delete(ctx, "m1@impractical.co")
+	if err != nil {
+		t.Fatalf("Unexpected error deleting account: %+v\n", err)
+	}
+
+	res, err := transfer.Verify(ctx, src, dst, transfer.Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error verifying accounts: %+v\n", err)
+	}
+	if res.OK() {
+		t.Fatalf("Expected verification to fail")
+	}
+	if res.SourceCount != 9 || res.DestinationCount != 8 || len(res.Mismatches) != 1 {
+		t.Errorf("Unexpected verification result: %+v", res)
+	}
+}
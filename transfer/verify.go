@@ -0,0 +1,117 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"lockbox.dev/accounts"
+)
+
+// Verification describes the differences Verify found between two Storers.
+type Verification struct {
+	// SourceCount is the number of Accounts in the source Storer.
+	SourceCount int
+
+	// DestinationCount is the number of Accounts in the destination
+	// Storer.
+	DestinationCount int
+
+	// Mismatches describes every profile whose Accounts differ between
+	// the two Storers.
+	Mismatches []string
+}
+
+// OK returns true if the Verification found no differences.
+func (v Verification) OK() bool {
+	return v.SourceCount == v.DestinationCount && len(v.Mismatches) == 0
+}
+
+func count(ctx context.Context, storer accounts.Enumerator, batchSize int, fn func(accounts.Account) error) (int, error) {
+	var after string
+	var total int
+	for {
+		batch, err := storer.ListAll(ctx, after, batchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(batch) == 0 {
+			return total, nil
+		}
+		for _, account := range batch {
+			total++
+			if fn == nil {
+				continue
+			}
+			err = fn(account)
+			if err != nil {
+				return total, err
+			}
+		}
+		after = batch[len(batch)-1].ID
+	}
+}
+
+func sortByID(accts []accounts.Account) {
+	sort.Slice(accts, func(i, j int) bool {
+		return strings.ToLower(accts[i].ID) < strings.ToLower(accts[j].ID)
+	})
+}
+
+func compareProfile(ctx context.Context, src, dst Source, profileID string) (string, error) {
+	want, err := src.ListByProfile(ctx, profileID)
+	if err != nil {
+		return "", err
+	}
+	got, err := dst.ListByProfile(ctx, profileID)
+	if err != nil {
+		return "", err
+	}
+	if len(want) != len(got) {
+		return fmt.Sprintf("profile %s has %d accounts in the source, %d in the destination", profileID, len(want), len(got)), nil
+	}
+	sortByID(want)
+	sortByID(got)
+	for pos := range want {
+		if !sameAccount(want[pos], got[pos]) {
+			return fmt.Sprintf("profile %s: account %s differs from %s", profileID, want[pos].ID, got[pos].ID), nil
+		}
+	}
+	return "", nil
+}
+
+// Verify compares the number of Accounts in `src` and `dst`, and the
+// Accounts associated with every profile in `src`, reporting any
+// differences it finds. It is meant to be run after Copy, to confirm the
+// transfer was complete.
+func Verify(ctx context.Context, src, dst Source, opts Options) (Verification, error) {
+	var res Verification
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	profiles := map[string]struct{}{}
+	var err error
+	res.SourceCount, err = count(ctx, src, opts.BatchSize, func(account accounts.Account) error {
+		if _, ok := profiles[account.ProfileID]; ok {
+			return nil
+		}
+		profiles[account.ProfileID] = struct{}{}
+		mismatch, err := compareProfile(ctx, src, dst, account.ProfileID)
+		if err != nil {
+			return err
+		}
+		if mismatch != "" {
+			res.Mismatches = append(res.Mismatches, mismatch)
+		}
+		return nil
+	})
+	if err != nil {
+		return res, fmt.Errorf("error reading source: %w", err)
+	}
+	res.DestinationCount, err = count(ctx, dst, opts.BatchSize, nil)
+	if err != nil {
+		return res, fmt.Errorf("error reading destination: %w", err)
+	}
+	return res, nil
+}